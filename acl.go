@@ -0,0 +1,152 @@
+package consuladapter
+
+import "github.com/hashicorp/consul/api"
+
+//go:generate counterfeiter -o fakes/fake_acl.go . ACL
+
+type ACL interface {
+	PolicyCreate(policy *api.ACLPolicy, q *api.WriteOptions) (*api.ACLPolicy, *api.WriteMeta, error)
+	PolicyRead(policyID string, q *api.QueryOptions) (*api.ACLPolicy, *api.QueryMeta, error)
+	PolicyReadByName(policyName string, q *api.QueryOptions) (*api.ACLPolicy, *api.QueryMeta, error)
+	PolicyUpdate(policy *api.ACLPolicy, q *api.WriteOptions) (*api.ACLPolicy, *api.WriteMeta, error)
+	PolicyDelete(policyID string, q *api.WriteOptions) (*api.WriteMeta, error)
+	PolicyList(q *api.QueryOptions) ([]*api.ACLPolicyListEntry, *api.QueryMeta, error)
+
+	RoleCreate(role *api.ACLRole, q *api.WriteOptions) (*api.ACLRole, *api.WriteMeta, error)
+	RoleRead(roleID string, q *api.QueryOptions) (*api.ACLRole, *api.QueryMeta, error)
+	RoleReadByName(roleName string, q *api.QueryOptions) (*api.ACLRole, *api.QueryMeta, error)
+	RoleUpdate(role *api.ACLRole, q *api.WriteOptions) (*api.ACLRole, *api.WriteMeta, error)
+	RoleDelete(roleID string, q *api.WriteOptions) (*api.WriteMeta, error)
+	RoleList(q *api.QueryOptions) ([]*api.ACLRole, *api.QueryMeta, error)
+
+	TokenCreate(token *api.ACLToken, q *api.WriteOptions) (*api.ACLToken, *api.WriteMeta, error)
+	TokenRead(accessorID string, q *api.QueryOptions) (*api.ACLToken, *api.QueryMeta, error)
+	TokenReadSelf(q *api.QueryOptions) (*api.ACLToken, *api.QueryMeta, error)
+	TokenUpdate(token *api.ACLToken, q *api.WriteOptions) (*api.ACLToken, *api.WriteMeta, error)
+	TokenDelete(accessorID string, q *api.WriteOptions) (*api.WriteMeta, error)
+	TokenList(q *api.QueryOptions) ([]*api.ACLTokenListEntry, *api.QueryMeta, error)
+}
+
+type acl struct {
+	acl *api.ACL
+}
+
+func NewConsulACL(a *api.ACL) ACL {
+	return &acl{acl: a}
+}
+
+func (a *acl) PolicyCreate(policy *api.ACLPolicy, q *api.WriteOptions) (*api.ACLPolicy, *api.WriteMeta, error) {
+	return a.acl.PolicyCreate(policy, q)
+}
+
+func (a *acl) PolicyRead(policyID string, q *api.QueryOptions) (*api.ACLPolicy, *api.QueryMeta, error) {
+	return a.acl.PolicyRead(policyID, q)
+}
+
+func (a *acl) PolicyReadByName(policyName string, q *api.QueryOptions) (*api.ACLPolicy, *api.QueryMeta, error) {
+	return a.acl.PolicyReadByName(policyName, q)
+}
+
+func (a *acl) PolicyUpdate(policy *api.ACLPolicy, q *api.WriteOptions) (*api.ACLPolicy, *api.WriteMeta, error) {
+	return a.acl.PolicyUpdate(policy, q)
+}
+
+func (a *acl) PolicyDelete(policyID string, q *api.WriteOptions) (*api.WriteMeta, error) {
+	return a.acl.PolicyDelete(policyID, q)
+}
+
+func (a *acl) PolicyList(q *api.QueryOptions) ([]*api.ACLPolicyListEntry, *api.QueryMeta, error) {
+	return a.acl.PolicyList(q)
+}
+
+func (a *acl) RoleCreate(role *api.ACLRole, q *api.WriteOptions) (*api.ACLRole, *api.WriteMeta, error) {
+	return a.acl.RoleCreate(role, q)
+}
+
+func (a *acl) RoleRead(roleID string, q *api.QueryOptions) (*api.ACLRole, *api.QueryMeta, error) {
+	return a.acl.RoleRead(roleID, q)
+}
+
+func (a *acl) RoleReadByName(roleName string, q *api.QueryOptions) (*api.ACLRole, *api.QueryMeta, error) {
+	return a.acl.RoleReadByName(roleName, q)
+}
+
+func (a *acl) RoleUpdate(role *api.ACLRole, q *api.WriteOptions) (*api.ACLRole, *api.WriteMeta, error) {
+	return a.acl.RoleUpdate(role, q)
+}
+
+func (a *acl) RoleDelete(roleID string, q *api.WriteOptions) (*api.WriteMeta, error) {
+	return a.acl.RoleDelete(roleID, q)
+}
+
+func (a *acl) RoleList(q *api.QueryOptions) ([]*api.ACLRole, *api.QueryMeta, error) {
+	return a.acl.RoleList(q)
+}
+
+func (a *acl) TokenCreate(token *api.ACLToken, q *api.WriteOptions) (*api.ACLToken, *api.WriteMeta, error) {
+	return a.acl.TokenCreate(token, q)
+}
+
+func (a *acl) TokenRead(accessorID string, q *api.QueryOptions) (*api.ACLToken, *api.QueryMeta, error) {
+	return a.acl.TokenRead(accessorID, q)
+}
+
+func (a *acl) TokenReadSelf(q *api.QueryOptions) (*api.ACLToken, *api.QueryMeta, error) {
+	return a.acl.TokenReadSelf(q)
+}
+
+func (a *acl) TokenUpdate(token *api.ACLToken, q *api.WriteOptions) (*api.ACLToken, *api.WriteMeta, error) {
+	return a.acl.TokenUpdate(token, q)
+}
+
+func (a *acl) TokenDelete(accessorID string, q *api.WriteOptions) (*api.WriteMeta, error) {
+	return a.acl.TokenDelete(accessorID, q)
+}
+
+func (a *acl) TokenList(q *api.QueryOptions) ([]*api.ACLTokenListEntry, *api.QueryMeta, error) {
+	return a.acl.TokenList(q)
+}
+
+// EnsurePolicy creates the named policy with rules, or updates an existing
+// policy of that name whose rules differ.
+func EnsurePolicy(acl ACL, name, rules string, w *api.WriteOptions) (*api.ACLPolicy, error) {
+	var q *api.QueryOptions
+	if w != nil {
+		q = &api.QueryOptions{Namespace: w.Namespace, Partition: w.Partition, Datacenter: w.Datacenter, Token: w.Token}
+	}
+
+	existing, _, err := acl.PolicyReadByName(name, q)
+	if err != nil {
+		return nil, err
+	}
+
+	if existing == nil {
+		policy, _, err := acl.PolicyCreate(&api.ACLPolicy{Name: name, Rules: rules}, w)
+		return policy, err
+	}
+
+	if existing.Rules == rules {
+		return existing, nil
+	}
+
+	updated := *existing
+	updated.Rules = rules
+	policy, _, err := acl.PolicyUpdate(&updated, w)
+	return policy, err
+}
+
+// ProvisionComponentToken ensures a policy named after component grants
+// rules and creates a new token linked only to that policy, so each
+// component can run with least privilege.
+func ProvisionComponentToken(acl ACL, component, rules string, w *api.WriteOptions) (*api.ACLToken, error) {
+	policy, err := EnsurePolicy(acl, component, rules, w)
+	if err != nil {
+		return nil, err
+	}
+
+	token, _, err := acl.TokenCreate(&api.ACLToken{
+		Description: "token for " + component,
+		Policies:    []*api.ACLTokenPolicyLink{{ID: policy.ID, Name: policy.Name}},
+	}, w)
+	return token, err
+}
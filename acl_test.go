@@ -0,0 +1,67 @@
+package consuladapter_test
+
+import (
+	"code.cloudfoundry.org/consuladapter"
+	"code.cloudfoundry.org/consuladapter/fakes"
+	"github.com/hashicorp/consul/api"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ACL", func() {
+	var acl *fakes.FakeACL
+
+	BeforeEach(func() {
+		acl = &fakes.FakeACL{}
+		acl.PolicyCreateStub = func(policy *api.ACLPolicy, w *api.WriteOptions) (*api.ACLPolicy, *api.WriteMeta, error) {
+			created := *policy
+			created.ID = "policy-id"
+			return &created, nil, nil
+		}
+		acl.TokenCreateStub = func(token *api.ACLToken, w *api.WriteOptions) (*api.ACLToken, *api.WriteMeta, error) {
+			created := *token
+			created.SecretID = "secret"
+			return &created, nil, nil
+		}
+	})
+
+	Describe("EnsurePolicy", func() {
+		It("creates the policy when it does not exist", func() {
+			policy, err := consuladapter.EnsurePolicy(acl, "component", `key_prefix "component/" { policy = "write" }`, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(policy.ID).To(Equal("policy-id"))
+			Expect(acl.PolicyReadByNameArgsForCall(0)).To(Equal("component"))
+			Expect(acl.PolicyUpdateCallCount()).To(Equal(0))
+		})
+
+		It("leaves an up to date policy alone", func() {
+			acl.PolicyReadByNameReturns(&api.ACLPolicy{ID: "existing", Name: "component", Rules: "rules"}, nil, nil)
+
+			policy, err := consuladapter.EnsurePolicy(acl, "component", "rules", nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(policy.ID).To(Equal("existing"))
+			Expect(acl.PolicyCreateCallCount()).To(Equal(0))
+			Expect(acl.PolicyUpdateCallCount()).To(Equal(0))
+		})
+
+		It("updates the rules of an existing policy", func() {
+			acl.PolicyReadByNameReturns(&api.ACLPolicy{ID: "existing", Name: "component", Rules: "old"}, nil, nil)
+
+			_, err := consuladapter.EnsurePolicy(acl, "component", "new", nil)
+			Expect(err).NotTo(HaveOccurred())
+			updated, _ := acl.PolicyUpdateArgsForCall(0)
+			Expect(updated.ID).To(Equal("existing"))
+			Expect(updated.Rules).To(Equal("new"))
+		})
+	})
+
+	Describe("ProvisionComponentToken", func() {
+		It("creates a token linked only to the component's policy", func() {
+			token, err := consuladapter.ProvisionComponentToken(acl, "component", "rules", nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(token.SecretID).To(Equal("secret"))
+			Expect(token.Policies).To(Equal([]*api.ACLTokenPolicyLink{{ID: "policy-id", Name: "component"}}))
+		})
+	})
+})
@@ -14,6 +14,13 @@ type Agent interface {
 	FailTTL(checkID, note string) error
 	NodeName() (string, error)
 	CheckDeregister(checkID string) error
+	Metrics() (*api.MetricsInfo, error)
+	Members(wan bool) ([]*api.AgentMember, error)
+	MembersOpts(opts api.MembersOpts) ([]*api.AgentMember, error)
+	EnableServiceMaintenance(serviceID, reason string) error
+	DisableServiceMaintenance(serviceID string) error
+	Self() (map[string]map[string]interface{}, error)
+	Reload() error
 }
 
 type agent struct {
@@ -59,3 +66,31 @@ func (a *agent) FailTTL(checkID, note string) error {
 func (a *agent) NodeName() (string, error) {
 	return a.agent.NodeName()
 }
+
+func (a *agent) Metrics() (*api.MetricsInfo, error) {
+	return a.agent.Metrics()
+}
+
+func (a *agent) Members(wan bool) ([]*api.AgentMember, error) {
+	return a.agent.Members(wan)
+}
+
+func (a *agent) MembersOpts(opts api.MembersOpts) ([]*api.AgentMember, error) {
+	return a.agent.MembersOpts(opts)
+}
+
+func (a *agent) EnableServiceMaintenance(serviceID, reason string) error {
+	return a.agent.EnableServiceMaintenance(serviceID, reason)
+}
+
+func (a *agent) DisableServiceMaintenance(serviceID string) error {
+	return a.agent.DisableServiceMaintenance(serviceID)
+}
+
+func (a *agent) Self() (map[string]map[string]interface{}, error) {
+	return a.agent.Self()
+}
+
+func (a *agent) Reload() error {
+	return a.agent.Reload()
+}
@@ -0,0 +1,31 @@
+package agentconfig
+
+import (
+	"net"
+	"strconv"
+)
+
+const DefaultBindAddress = "127.0.0.1"
+
+// ClientHost returns the host clients and joining agents should use to reach
+// agents bound to bindAddress, preferring advertiseAddress when set.
+func ClientHost(bindAddress, advertiseAddress string) string {
+	if advertiseAddress != "" {
+		return advertiseAddress
+	}
+
+	switch bindAddress {
+	case "":
+		return DefaultBindAddress
+	case "0.0.0.0":
+		return "127.0.0.1"
+	case "::":
+		return "::1"
+	}
+
+	return bindAddress
+}
+
+func NodeAddress(host string, port int) string {
+	return net.JoinHostPort(host, strconv.Itoa(port))
+}
@@ -0,0 +1,32 @@
+package agentconfig_test
+
+import (
+	"code.cloudfoundry.org/consuladapter/agentconfig"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ClientHost", func() {
+	It("reaches agents on the address they bind to", func() {
+		Expect(agentconfig.ClientHost("", "")).To(Equal("127.0.0.1"))
+		Expect(agentconfig.ClientHost("10.0.0.5", "")).To(Equal("10.0.0.5"))
+		Expect(agentconfig.ClientHost("::1", "")).To(Equal("::1"))
+	})
+
+	It("reaches agents bound to every address over loopback", func() {
+		Expect(agentconfig.ClientHost("0.0.0.0", "")).To(Equal("127.0.0.1"))
+		Expect(agentconfig.ClientHost("::", "")).To(Equal("::1"))
+	})
+
+	It("prefers the advertise address", func() {
+		Expect(agentconfig.ClientHost("0.0.0.0", "10.0.0.5")).To(Equal("10.0.0.5"))
+	})
+})
+
+var _ = Describe("NodeAddress", func() {
+	It("brackets IPv6 hosts", func() {
+		Expect(agentconfig.NodeAddress("127.0.0.1", 5001)).To(Equal("127.0.0.1:5001"))
+		Expect(agentconfig.NodeAddress("::1", 5001)).To(Equal("[::1]:5001"))
+	})
+})
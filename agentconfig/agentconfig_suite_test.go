@@ -0,0 +1,13 @@
+package agentconfig_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestAgentconfig(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Agentconfig Suite")
+}
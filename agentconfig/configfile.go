@@ -0,0 +1,335 @@
+// Package agentconfig generates consul server agent configuration using the
+// same port layout and join addresses as the test cluster runners, so
+// deployment tooling can produce identical clusters.
+package agentconfig
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const DefaultLogLevel = "info"
+const defaultProtocolVersion = 2
+
+type ConfigFile struct {
+	Performace         map[string]int    `json:"performance,omitempty"`
+	BootstrapExpect    int               `json:"bootstrap_expect"`
+	Datacenter         string            `json:"datacenter"`
+	DataDir            string            `json:"data_dir"`
+	LogLevel           string            `json:"log_level"`
+	NodeName           string            `json:"node_name"`
+	Server             bool              `json:"server"`
+	Ports              map[string]int    `json:"ports"`
+	BindAddr           string            `json:"bind_addr"`
+	ClientAddr         string            `json:"client_addr"`
+	AdvertiseAddr      string            `json:"advertise_addr,omitempty"`
+	ProtocolVersion    int               `json:"protocol"`
+	StartJoin          []string          `json:"start_join"`
+	RetryJoin          []string          `json:"retry_join"`
+	RejoinAfterLeave   bool              `json:"rejoin_after_leave"`
+	DisableRemoteExec  bool              `json:"disable_remote_exec"`
+	DisableUpdateCheck bool              `json:"disable_update_check"`
+	EnableDebug        bool              `json:"enable_debug,omitempty"`
+	UI                 bool              `json:"ui,omitempty"`
+	SessionTTL         string            `json:"session_ttl_min"`
+	Telemetry          *telemetry        `json:"telemetry,omitempty"`
+	Autopilot          *autopilot        `json:"autopilot,omitempty"`
+	GossipLAN          *gossip           `json:"gossip_lan,omitempty"`
+	Segments           []segment         `json:"segments,omitempty"`
+	CAFile             string            `json:"ca_file,omitempty"`
+	CertFile           string            `json:"cert_file,omitempty"`
+	KeyFile            string            `json:"key_file,omitempty"`
+	VerifyIncoming     bool              `json:"verify_incoming,omitempty"`
+	VerifyOutgoing     bool              `json:"verify_outgoing,omitempty"`
+	ACL                *acl              `json:"acl,omitempty"`
+	Checks             []CheckDefinition `json:"checks,omitempty"`
+}
+
+// CheckDefinition is a check defined in the agent's configuration rather
+// than registered through the API. Exactly one of TTL, HTTP or TCP should
+// be set; Interval is required for HTTP and TCP checks.
+type CheckDefinition struct {
+	ID        string `json:"id,omitempty"`
+	Name      string `json:"name"`
+	Notes     string `json:"notes,omitempty"`
+	ServiceID string `json:"service_id,omitempty"`
+	TTL       string `json:"ttl,omitempty"`
+	HTTP      string `json:"http,omitempty"`
+	TCP       string `json:"tcp,omitempty"`
+	Interval  string `json:"interval,omitempty"`
+	Timeout   string `json:"timeout,omitempty"`
+}
+
+// TLSFiles enable an agent's HTTPS listener on its PortLayout.HTTPS port.
+type TLSFiles struct {
+	CAFile   string
+	CertFile string
+	KeyFile  string
+
+	// Verify sets verify_incoming and verify_outgoing, so agents require a
+	// certificate signed by CAFile from clients and from each other.
+	Verify bool
+}
+
+type acl struct {
+	Enabled       bool      `json:"enabled"`
+	DefaultPolicy string    `json:"default_policy"`
+	Tokens        aclTokens `json:"tokens"`
+}
+
+type aclTokens struct {
+	Master string `json:"master"`
+	Agent  string `json:"agent"`
+}
+
+type segment struct {
+	Name string `json:"name"`
+	Bind string `json:"bind"`
+	Port int    `json:"port"`
+}
+
+// SegmentPort returns the gossip port of segment on the server at index.
+// Segment ports follow the ports of all nodes in the cluster.
+func SegmentPort(clusterStartingPort, numNodes, numSegments, index, segment int) int {
+	return clusterStartingPort + PortsPerNode*numNodes + numSegments*index + segment
+}
+
+type TuningConfig struct {
+	// RaftMultiplier overrides performance.raft_multiplier. Defaults to 1 on
+	// agents that support the performance stanza.
+	RaftMultiplier int
+
+	ServerStabilizationTime time.Duration
+
+	GossipInterval time.Duration
+	ProbeInterval  time.Duration
+	ProbeTimeout   time.Duration
+	SuspicionMult  int
+}
+
+// FastConvergence returns tuning suitable for local test clusters, where
+// leader election and failure detection should be as quick as possible.
+func FastConvergence() *TuningConfig {
+	return &TuningConfig{
+		RaftMultiplier:          1,
+		ServerStabilizationTime: time.Second,
+		GossipInterval:          50 * time.Millisecond,
+		ProbeInterval:           200 * time.Millisecond,
+		ProbeTimeout:            100 * time.Millisecond,
+		SuspicionMult:           2,
+	}
+}
+
+type autopilot struct {
+	ServerStabilizationTime string `json:"server_stabilization_time,omitempty"`
+}
+
+type gossip struct {
+	GossipInterval string `json:"gossip_interval,omitempty"`
+	ProbeInterval  string `json:"probe_interval,omitempty"`
+	ProbeTimeout   string `json:"probe_timeout,omitempty"`
+	SuspicionMult  int    `json:"suspicion_mult,omitempty"`
+}
+
+type TelemetryConfig struct {
+	StatsdAddress           string
+	DogstatsdAddress        string
+	PrometheusRetentionTime time.Duration
+	DisableHostname         bool
+}
+
+type telemetry struct {
+	StatsdAddress           string `json:"statsd_address,omitempty"`
+	DogstatsdAddress        string `json:"dogstatsd_addr,omitempty"`
+	PrometheusRetentionTime string `json:"prometheus_retention_time,omitempty"`
+	DisableHostname         bool   `json:"disable_hostname"`
+}
+
+type ConfigOptions struct {
+	IncludePerformanceConfig bool
+	DataDir                  string
+	NodeName                 string
+	ClusterStartingPort      int
+	Index                    int
+	NumNodes                 int
+	SessionTTL               time.Duration
+	LogLevel                 string
+	Telemetry                *TelemetryConfig
+	Tuning                   *TuningConfig
+	BindAddress              string
+	AdvertiseAddress         string
+	EnableDebug              bool
+	EnableUI                 bool
+	Segments                 []string
+	TLS                      *TLSFiles
+
+	// ACLMasterToken, if set, enables ACLs with a default policy of deny.
+	// The token is bootstrapped as a management token, which the agents
+	// also use for their own requests.
+	ACLMasterToken string
+}
+
+func NewConfigFile(opts ConfigOptions) ConfigFile {
+	clusterStartingPort := opts.ClusterStartingPort
+	layout := PortsForNode(clusterStartingPort, opts.Index)
+	ports := layout.configPorts()
+
+	bindAddress := opts.BindAddress
+	if bindAddress == "" {
+		bindAddress = DefaultBindAddress
+	}
+
+	joinHost := ClientHost(bindAddress, opts.AdvertiseAddress)
+	joinAddresses := make([]string, opts.NumNodes)
+	for i := 0; i < opts.NumNodes; i++ {
+		joinAddresses[i] = NodeAddress(joinHost, PortsForNode(clusterStartingPort, i).SerfLAN)
+	}
+
+	logLevel := opts.LogLevel
+	if logLevel == "" {
+		logLevel = DefaultLogLevel
+	}
+
+	config := ConfigFile{
+		BootstrapExpect:    opts.NumNodes,
+		DataDir:            opts.DataDir,
+		LogLevel:           logLevel,
+		NodeName:           opts.NodeName,
+		Server:             true,
+		Ports:              ports,
+		BindAddr:           bindAddress,
+		ClientAddr:         bindAddress,
+		AdvertiseAddr:      opts.AdvertiseAddress,
+		ProtocolVersion:    defaultProtocolVersion,
+		StartJoin:          joinAddresses,
+		RetryJoin:          joinAddresses,
+		RejoinAfterLeave:   true,
+		DisableRemoteExec:  true,
+		DisableUpdateCheck: true,
+		SessionTTL:         opts.SessionTTL.String(),
+		EnableDebug:        opts.EnableDebug,
+		UI:                 opts.EnableUI,
+	}
+
+	if opts.TLS != nil {
+		ports["https"] = layout.HTTPS
+		config.CAFile = opts.TLS.CAFile
+		config.CertFile = opts.TLS.CertFile
+		config.KeyFile = opts.TLS.KeyFile
+		config.VerifyIncoming = opts.TLS.Verify
+		config.VerifyOutgoing = opts.TLS.Verify
+	}
+
+	if opts.ACLMasterToken != "" {
+		config.ACL = &acl{
+			Enabled:       true,
+			DefaultPolicy: "deny",
+			Tokens:        aclTokens{Master: opts.ACLMasterToken, Agent: opts.ACLMasterToken},
+		}
+	}
+
+	for i, name := range opts.Segments {
+		config.Segments = append(config.Segments, segment{
+			Name: name,
+			Bind: bindAddress,
+			Port: SegmentPort(clusterStartingPort, opts.NumNodes, len(opts.Segments), opts.Index, i),
+		})
+	}
+
+	if opts.Telemetry != nil {
+		config.Telemetry = &telemetry{
+			StatsdAddress:    opts.Telemetry.StatsdAddress,
+			DogstatsdAddress: opts.Telemetry.DogstatsdAddress,
+			DisableHostname:  opts.Telemetry.DisableHostname,
+		}
+		if opts.Telemetry.PrometheusRetentionTime > 0 {
+			config.Telemetry.PrometheusRetentionTime = opts.Telemetry.PrometheusRetentionTime.String()
+		}
+	}
+
+	if opts.IncludePerformanceConfig {
+		raftMultiplier := 1
+		if opts.Tuning != nil && opts.Tuning.RaftMultiplier > 0 {
+			raftMultiplier = opts.Tuning.RaftMultiplier
+		}
+		config.Performace = map[string]int{"raft_multiplier": raftMultiplier}
+	}
+
+	if opts.Tuning != nil {
+		if opts.Tuning.ServerStabilizationTime > 0 {
+			config.Autopilot = &autopilot{
+				ServerStabilizationTime: opts.Tuning.ServerStabilizationTime.String(),
+			}
+		}
+
+		gossipLAN := gossip{
+			GossipInterval: durationString(opts.Tuning.GossipInterval),
+			ProbeInterval:  durationString(opts.Tuning.ProbeInterval),
+			ProbeTimeout:   durationString(opts.Tuning.ProbeTimeout),
+			SuspicionMult:  opts.Tuning.SuspicionMult,
+		}
+		if gossipLAN != (gossip{}) {
+			config.GossipLAN = &gossipLAN
+		}
+	}
+
+	return config
+}
+
+func durationString(d time.Duration) string {
+	if d <= 0 {
+		return ""
+	}
+	return d.String()
+}
+
+// GenerateAgentConfig returns the JSON configuration for the agent
+// described by opts.
+func GenerateAgentConfig(opts ConfigOptions) ([]byte, error) {
+	return json.Marshal(NewConfigFile(opts))
+}
+
+func WriteConfigFile(configDir string, opts ConfigOptions) (string, error) {
+	configJSON, err := GenerateAgentConfig(opts)
+	if err != nil {
+		return "", err
+	}
+
+	filePath := filepath.Join(configDir, fmt.Sprintf("%s.json", opts.NodeName))
+	file, err := os.Create(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	_, err = file.Write(configJSON)
+	if err != nil {
+		return "", err
+	}
+
+	return filePath, file.Close()
+}
+
+// ReadConfigFile reads a config file written by WriteConfigFile, e.g. to
+// change it and reload the agent.
+func ReadConfigFile(filePath string) (ConfigFile, error) {
+	var config ConfigFile
+	configJSON, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return config, err
+	}
+	return config, json.Unmarshal(configJSON, &config)
+}
+
+// WriteFile replaces the config file at filePath with c.
+func (c ConfigFile) WriteFile(filePath string) error {
+	configJSON, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filePath, configJSON, 0644)
+}
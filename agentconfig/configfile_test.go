@@ -0,0 +1,249 @@
+package agentconfig_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"code.cloudfoundry.org/consuladapter/agentconfig"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("PortsForNode", func() {
+	It("gives each node its own block of ports", func() {
+		Expect(agentconfig.PortsForNode(5000, 2)).To(Equal(agentconfig.PortLayout{
+			DNS:       5016,
+			HTTP:      5017,
+			ClientRPC: 5018,
+			SerfLAN:   5019,
+			SerfWAN:   5020,
+			Server:    5021,
+			HTTPS:     5022,
+			GRPC:      5023,
+		}))
+	})
+})
+
+var _ = Describe("PortOffset constants", func() {
+	It("still match PortsForNode", func() {
+		layout := agentconfig.PortsForNode(5000, 2)
+		start := 5000 + 2*agentconfig.PortOffsetLength
+		Expect(start + agentconfig.PortOffsetHTTP).To(Equal(layout.HTTP))
+		Expect(start + agentconfig.PortOffsetSerfLAN).To(Equal(layout.SerfLAN))
+		Expect(start + agentconfig.PortOffsetServerRPC).To(Equal(layout.Server))
+	})
+})
+
+var _ = Describe("GenerateAgentConfig", func() {
+	It("uses the runner's port layout and join addresses", func() {
+		configJSON, err := agentconfig.GenerateAgentConfig(agentconfig.ConfigOptions{
+			NodeName:            "consul-1",
+			ClusterStartingPort: 5000,
+			Index:               1,
+			NumNodes:            3,
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		var config agentconfig.ConfigFile
+		Expect(json.Unmarshal(configJSON, &config)).To(Succeed())
+
+		Expect(config.BootstrapExpect).To(Equal(3))
+		Expect(config.Ports["http"]).To(Equal(agentconfig.PortsForNode(5000, 1).HTTP))
+		Expect(config.StartJoin).To(Equal([]string{"127.0.0.1:5003", "127.0.0.1:5011", "127.0.0.1:5019"}))
+	})
+})
+
+var _ = Describe("NewConfigFile", func() {
+	It("logs at info level unless told otherwise", func() {
+		config := agentconfig.NewConfigFile(agentconfig.ConfigOptions{NodeName: "0", ClusterStartingPort: 5000, NumNodes: 1})
+		Expect(config.LogLevel).To(Equal("info"))
+
+		config = agentconfig.NewConfigFile(agentconfig.ConfigOptions{NodeName: "0", ClusterStartingPort: 5000, NumNodes: 1, LogLevel: "trace"})
+		Expect(config.LogLevel).To(Equal("trace"))
+	})
+
+	It("enables the HTTPS listener when TLS files are given", func() {
+		config := agentconfig.NewConfigFile(agentconfig.ConfigOptions{
+			ClusterStartingPort: 5000,
+			NumNodes:            1,
+			TLS:                 &agentconfig.TLSFiles{CAFile: "ca.pem", CertFile: "agent.pem", KeyFile: "agent-key.pem"},
+		})
+
+		Expect(config.Ports).To(HaveKeyWithValue("https", agentconfig.PortsForNode(5000, 0).HTTPS))
+		Expect(config.CAFile).To(Equal("ca.pem"))
+		Expect(config.CertFile).To(Equal("agent.pem"))
+		Expect(config.KeyFile).To(Equal("agent-key.pem"))
+		Expect(config.VerifyIncoming).To(BeFalse())
+		Expect(config.VerifyOutgoing).To(BeFalse())
+	})
+
+	It("requires certificates when TLS verification is enabled", func() {
+		config := agentconfig.NewConfigFile(agentconfig.ConfigOptions{
+			ClusterStartingPort: 5000,
+			NumNodes:            1,
+			TLS:                 &agentconfig.TLSFiles{CAFile: "ca.pem", CertFile: "agent.pem", KeyFile: "agent-key.pem", Verify: true},
+		})
+
+		Expect(config.VerifyIncoming).To(BeTrue())
+		Expect(config.VerifyOutgoing).To(BeTrue())
+	})
+
+	It("enables ACLs bootstrapped with the master token", func() {
+		config := agentconfig.NewConfigFile(agentconfig.ConfigOptions{ClusterStartingPort: 5000, NumNodes: 1, ACLMasterToken: "root"})
+
+		encoded, err := json.Marshal(config)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(encoded).To(ContainSubstring(`"acl":{"enabled":true,"default_policy":"deny","tokens":{"master":"root","agent":"root"}}`))
+	})
+
+	It("serves the web UI when enabled", func() {
+		config := agentconfig.NewConfigFile(agentconfig.ConfigOptions{ClusterStartingPort: 5000, NumNodes: 1, EnableUI: true})
+		Expect(config.UI).To(BeTrue())
+	})
+
+	It("leaves ACLs disabled otherwise", func() {
+		config := agentconfig.NewConfigFile(agentconfig.ConfigOptions{ClusterStartingPort: 5000, NumNodes: 1})
+		Expect(config.ACL).To(BeNil())
+	})
+
+	It("gives each server a gossip port per segment, after the ports of every node", func() {
+		opts := agentconfig.ConfigOptions{ClusterStartingPort: 5000, NumNodes: 3, Index: 1, Segments: []string{"alpha", "beta"}}
+		config := agentconfig.NewConfigFile(opts)
+
+		Expect(config.Segments).To(HaveLen(2))
+		Expect(config.Segments[0].Name).To(Equal("alpha"))
+		Expect(config.Segments[0].Bind).To(Equal("127.0.0.1"))
+		Expect(config.Segments[0].Port).To(Equal(5026))
+		Expect(config.Segments[1].Name).To(Equal("beta"))
+		Expect(config.Segments[1].Port).To(Equal(5027))
+
+		lastNodePort := agentconfig.PortsForNode(5000, 2).GRPC
+		Expect(agentconfig.SegmentPort(5000, 3, 2, 0, 0)).To(Equal(lastNodePort + 1))
+		Expect(agentconfig.SegmentPort(5000, 3, 2, 2, 1)).To(Equal(lastNodePort + 6))
+
+		encoded, err := json.Marshal(config)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(encoded).To(ContainSubstring(`"segments":[{"name":"alpha","bind":"127.0.0.1","port":5026},{"name":"beta","bind":"127.0.0.1","port":5027}]`))
+	})
+
+	It("configures no segments otherwise", func() {
+		config := agentconfig.NewConfigFile(agentconfig.ConfigOptions{ClusterStartingPort: 5000, NumNodes: 1})
+		Expect(config.Segments).To(BeEmpty())
+	})
+
+	It("passes telemetry settings to the agent", func() {
+		config := agentconfig.NewConfigFile(agentconfig.ConfigOptions{
+			ClusterStartingPort: 5000,
+			NumNodes:            1,
+			Telemetry: &agentconfig.TelemetryConfig{
+				StatsdAddress:           "127.0.0.1:8125",
+				PrometheusRetentionTime: time.Minute,
+				DisableHostname:         true,
+			},
+		})
+
+		encoded, err := json.Marshal(config)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(encoded).To(ContainSubstring(`"telemetry":{"statsd_address":"127.0.0.1:8125","prometheus_retention_time":"1m0s","disable_hostname":true}`))
+	})
+
+	It("leaves telemetry unconfigured otherwise", func() {
+		config := agentconfig.NewConfigFile(agentconfig.ConfigOptions{ClusterStartingPort: 5000, NumNodes: 1})
+		Expect(config.Telemetry).To(BeNil())
+	})
+
+	It("applies raft, autopilot and gossip tuning", func() {
+		config := agentconfig.NewConfigFile(agentconfig.ConfigOptions{
+			ClusterStartingPort:      5000,
+			NumNodes:                 1,
+			IncludePerformanceConfig: true,
+			Tuning:                   agentconfig.FastConvergence(),
+		})
+
+		encoded, err := json.Marshal(config)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(encoded).To(ContainSubstring(`"performance":{"raft_multiplier":1}`))
+		Expect(encoded).To(ContainSubstring(`"autopilot":{"server_stabilization_time":"1s"}`))
+		Expect(encoded).To(ContainSubstring(`"gossip_lan":{"gossip_interval":"50ms","probe_interval":"200ms","probe_timeout":"100ms","suspicion_mult":2}`))
+	})
+
+	It("only sets the tuning given, and only sets raft_multiplier on agents that support it", func() {
+		tuning := &agentconfig.TuningConfig{RaftMultiplier: 5, ProbeTimeout: time.Second}
+
+		config := agentconfig.NewConfigFile(agentconfig.ConfigOptions{ClusterStartingPort: 5000, NumNodes: 1, IncludePerformanceConfig: true, Tuning: tuning})
+		Expect(config.Performace).To(Equal(map[string]int{"raft_multiplier": 5}))
+		Expect(config.Autopilot).To(BeNil())
+
+		encoded, err := json.Marshal(config)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(encoded).To(ContainSubstring(`"gossip_lan":{"probe_timeout":"1s"}`))
+
+		config = agentconfig.NewConfigFile(agentconfig.ConfigOptions{ClusterStartingPort: 5000, NumNodes: 1, Tuning: tuning})
+		Expect(config.Performace).To(BeNil())
+	})
+
+	It("leaves the defaults alone without tuning", func() {
+		config := agentconfig.NewConfigFile(agentconfig.ConfigOptions{ClusterStartingPort: 5000, NumNodes: 1, IncludePerformanceConfig: true})
+		Expect(config.Performace).To(Equal(map[string]int{"raft_multiplier": 1}))
+		Expect(config.Autopilot).To(BeNil())
+		Expect(config.GossipLAN).To(BeNil())
+	})
+
+	It("binds to the given address, joining over IPv6 where needed", func() {
+		config := agentconfig.NewConfigFile(agentconfig.ConfigOptions{ClusterStartingPort: 5000, NumNodes: 2, BindAddress: "::1"})
+
+		Expect(config.BindAddr).To(Equal("::1"))
+		Expect(config.ClientAddr).To(Equal("::1"))
+		Expect(config.AdvertiseAddr).To(BeEmpty())
+		Expect(config.StartJoin).To(Equal([]string{"[::1]:5003", "[::1]:5011"}))
+	})
+
+	It("joins and advertises on the advertise address", func() {
+		config := agentconfig.NewConfigFile(agentconfig.ConfigOptions{ClusterStartingPort: 5000, NumNodes: 1, BindAddress: "0.0.0.0", AdvertiseAddress: "10.0.0.5"})
+
+		Expect(config.BindAddr).To(Equal("0.0.0.0"))
+		Expect(config.AdvertiseAddr).To(Equal("10.0.0.5"))
+		Expect(config.RetryJoin).To(Equal([]string{"10.0.0.5:5003"}))
+	})
+
+	It("leaves HTTPS disabled otherwise", func() {
+		config := agentconfig.NewConfigFile(agentconfig.ConfigOptions{ClusterStartingPort: 5000, NumNodes: 1})
+		Expect(config.Ports).NotTo(HaveKey("https"))
+	})
+})
+
+var _ = Describe("ReadConfigFile", func() {
+	var configDir string
+
+	BeforeEach(func() {
+		var err error
+		configDir, err = ioutil.TempDir("", "agentconfig")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(configDir)
+	})
+
+	It("reads back a written config file, so it can be changed and rewritten", func() {
+		opts := agentconfig.ConfigOptions{NodeName: "0", ClusterStartingPort: 5000, NumNodes: 3, LogLevel: "info"}
+		filePath, err := agentconfig.WriteConfigFile(configDir, opts)
+		Expect(err).NotTo(HaveOccurred())
+
+		config, err := agentconfig.ReadConfigFile(filePath)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(config).To(Equal(agentconfig.NewConfigFile(opts)))
+
+		config.LogLevel = "debug"
+		config.Checks = append(config.Checks, agentconfig.CheckDefinition{Name: "heartbeat", TTL: "10s"})
+		Expect(config.WriteFile(filePath)).To(Succeed())
+
+		configJSON, err := ioutil.ReadFile(filePath)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(configJSON).To(ContainSubstring(`"log_level":"debug"`))
+		Expect(configJSON).To(ContainSubstring(`"checks":[{"name":"heartbeat","ttl":"10s"}]`))
+	})
+})
@@ -0,0 +1,61 @@
+package agentconfig
+
+// PortsPerNode is the number of consecutive ports reserved for each agent,
+// starting at the cluster's starting port. It was 6 before the HTTPS and
+// gRPC ports were reserved, so node N's ports now begin at 8*N rather than
+// 6*N past the starting port.
+const PortsPerNode = 8
+
+// Offsets of an agent's ports from the start of its block.
+//
+// Deprecated: use PortsForNode. PortOffsetLength is now PortsPerNode.
+const (
+	PortOffsetDNS       = 0
+	PortOffsetHTTP      = 1
+	PortOffsetClientRPC = 2
+	PortOffsetSerfLAN   = 3
+	PortOffsetSerfWAN   = 4
+	PortOffsetServerRPC = 5
+	PortOffsetLength    = PortsPerNode
+)
+
+// PortLayout holds the ports of a single agent. HTTPS is only enabled when
+// ConfigOptions.TLS is set. GRPC is reserved but not enabled, since it
+// requires a recent agent; tooling that enables it should use this port.
+type PortLayout struct {
+	DNS       int
+	HTTP      int
+	HTTPS     int
+	GRPC      int
+	ClientRPC int
+	SerfLAN   int
+	SerfWAN   int
+	Server    int
+}
+
+// PortsForNode returns the ports of the agent at index in a cluster whose
+// ports begin at clusterStartingPort.
+func PortsForNode(clusterStartingPort, index int) PortLayout {
+	start := clusterStartingPort + PortsPerNode*index
+	return PortLayout{
+		DNS:       start,
+		HTTP:      start + 1,
+		ClientRPC: start + 2,
+		SerfLAN:   start + 3,
+		SerfWAN:   start + 4,
+		Server:    start + 5,
+		HTTPS:     start + 6,
+		GRPC:      start + 7,
+	}
+}
+
+func (l PortLayout) configPorts() map[string]int {
+	return map[string]int{
+		"dns":      l.DNS,
+		"http":     l.HTTP,
+		"rpc":      l.ClientRPC,
+		"serf_lan": l.SerfLAN,
+		"serf_wan": l.SerfWAN,
+		"server":   l.Server,
+	}
+}
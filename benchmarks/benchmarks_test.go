@@ -0,0 +1,120 @@
+package benchmarks_test
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"code.cloudfoundry.org/consuladapter"
+	"code.cloudfoundry.org/consuladapter/benchmarks"
+	"code.cloudfoundry.org/consuladapter/consulrunner/execrunner"
+)
+
+var startingPort = flag.Int("benchmarks.port", 6001, "starting port of the consul cluster")
+var resultsPath = flag.String("benchmarks.results", "", "file to append JSON results to")
+
+var clusterRunner *execrunner.ClusterRunner
+
+func TestMain(m *testing.M) {
+	flag.Parse()
+
+	var err error
+	clusterRunner, err = execrunner.NewClusterRunner(execrunner.ClusterRunnerConfig{
+		StartingPort: *startingPort,
+		NumNodes:     1,
+		Scheme:       "http",
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	err = clusterRunner.Start()
+	if err == nil {
+		err = clusterRunner.WaitUntilReady(10 * time.Second)
+	}
+	if err != nil {
+		clusterRunner.Stop()
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	code := m.Run()
+
+	clusterRunner.Stop()
+	os.Exit(code)
+}
+
+func newClient(b *testing.B) consuladapter.Client {
+	err := clusterRunner.Reset()
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	client, err := clusterRunner.NewClient()
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	return client
+}
+
+func report(b *testing.B, result benchmarks.Result) {
+	b.Logf("%s: %d ops, %.1f ops/s, p50=%s p99=%s", result.Name, result.Operations, result.OpsPerSecond, result.Latency.P50, result.Latency.P99)
+
+	if *resultsPath == "" {
+		return
+	}
+
+	file, err := os.OpenFile(*resultsPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer file.Close()
+
+	err = benchmarks.WriteResults(file, result)
+	if err != nil {
+		b.Fatal(err)
+	}
+}
+
+func BenchmarkLockChurn(b *testing.B) {
+	client := newClient(b)
+	b.ResetTimer()
+
+	result, err := benchmarks.LockChurn(client, "benchmarks/lock", b.N)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.StopTimer()
+	report(b, result)
+}
+
+func BenchmarkKVWriteThroughput(b *testing.B) {
+	client := newClient(b)
+	b.ResetTimer()
+
+	result, err := benchmarks.KVWriteThroughput(client, "benchmarks/kv", 1024, b.N, 8)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.StopTimer()
+	report(b, result)
+}
+
+func BenchmarkWatchFanoutLatency(b *testing.B) {
+	client := newClient(b)
+	b.ResetTimer()
+
+	result, err := benchmarks.WatchFanoutLatency(client, "benchmarks/watch", 16, b.N)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.StopTimer()
+	report(b, result)
+}
@@ -0,0 +1,175 @@
+package benchmarks
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/consuladapter"
+	"github.com/hashicorp/consul/api"
+)
+
+// LockChurn repeatedly acquires and releases the lock on key, measuring the
+// time taken by each acquire/release cycle.
+func LockChurn(client consuladapter.Client, key string, iterations int) (Result, error) {
+	latencies := make([]time.Duration, 0, iterations)
+
+	start := time.Now()
+	for i := 0; i < iterations; i++ {
+		opStart := time.Now()
+
+		lock, err := client.LockOpts(&api.LockOptions{
+			Key:   key,
+			Value: []byte(strconv.Itoa(i)),
+		})
+		if err != nil {
+			return Result{}, err
+		}
+
+		_, err = lock.Lock(nil)
+		if err != nil {
+			return Result{}, err
+		}
+
+		err = lock.Unlock()
+		if err != nil {
+			return Result{}, err
+		}
+
+		latencies = append(latencies, time.Since(opStart))
+	}
+
+	return newResult("lock_churn", time.Since(start), latencies, 0), nil
+}
+
+// KVWriteThroughput writes writes values of valueSize bytes under prefix
+// from concurrency goroutines.
+func KVWriteThroughput(client consuladapter.Client, prefix string, valueSize, writes, concurrency int) (Result, error) {
+	if concurrency <= 0 {
+		return Result{}, fmt.Errorf("invalid concurrency: %d", concurrency)
+	}
+
+	kv := client.KV()
+	value := make([]byte, valueSize)
+
+	work := make(chan int, writes)
+	for i := 0; i < writes; i++ {
+		work <- i
+	}
+	close(work)
+
+	var mutex sync.Mutex
+	latencies := make([]time.Duration, 0, writes)
+	errors := 0
+
+	wg := sync.WaitGroup{}
+	start := time.Now()
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range work {
+				opStart := time.Now()
+				_, err := kv.Put(&api.KVPair{
+					Key:   fmt.Sprintf("%s/%d", prefix, i),
+					Value: value,
+				}, nil)
+				latency := time.Since(opStart)
+
+				mutex.Lock()
+				if err != nil {
+					errors++
+				} else {
+					latencies = append(latencies, latency)
+				}
+				mutex.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	return newResult("kv_write_throughput", time.Since(start), latencies, errors), nil
+}
+
+// WatchFanoutLatency starts watchers blocking queries on key and measures how
+// long it takes each of them to observe each of updates writes. Failed
+// queries are counted as errors and retried.
+func WatchFanoutLatency(client consuladapter.Client, key string, watchers, updates int) (Result, error) {
+	kv := client.KV()
+
+	_, err := kv.Put(&api.KVPair{Key: key, Value: []byte("0")}, nil)
+	if err != nil {
+		return Result{}, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var mutex sync.Mutex
+	latencies := make([]time.Duration, 0, watchers*updates)
+	writeTimes := make(map[string]time.Time)
+	errors := 0
+
+	onError := func(error) {
+		mutex.Lock()
+		errors++
+		mutex.Unlock()
+	}
+
+	wg := sync.WaitGroup{}
+	for w := 0; w < watchers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			seen := 0
+			consuladapter.BlockingQuery(ctx, consuladapter.BackoffRetry{}, onError, func(q *api.QueryOptions) (*api.QueryMeta, error) {
+				pair, meta, err := kv.Get(key, q)
+				observed := time.Now()
+				if err != nil || pair == nil {
+					return meta, err
+				}
+
+				value, _ := strconv.Atoi(string(pair.Value))
+				if value <= seen {
+					return meta, nil
+				}
+				seen = value
+
+				mutex.Lock()
+				written, ok := writeTimes[string(pair.Value)]
+				if ok {
+					latencies = append(latencies, observed.Sub(written))
+				}
+				mutex.Unlock()
+
+				if seen >= updates {
+					return meta, consuladapter.StopQuerying
+				}
+				return meta, nil
+			})
+		}()
+	}
+
+	start := time.Now()
+	for i := 1; i <= updates; i++ {
+		value := strconv.Itoa(i)
+
+		mutex.Lock()
+		writeTimes[value] = time.Now()
+		mutex.Unlock()
+
+		_, err := kv.Put(&api.KVPair{Key: key, Value: []byte(value)}, nil)
+		if err != nil {
+			cancel()
+			wg.Wait()
+			return Result{}, err
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+	wg.Wait()
+
+	return newResult("watch_fanout_latency", time.Since(start), latencies, errors), nil
+}
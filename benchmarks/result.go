@@ -0,0 +1,90 @@
+package benchmarks
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+	"time"
+)
+
+type Result struct {
+	Name         string        `json:"name"`
+	Operations   int           `json:"operations"`
+	Errors       int           `json:"errors"`
+	Duration     time.Duration `json:"duration_ns"`
+	OpsPerSecond float64       `json:"ops_per_second"`
+	Latency      Latency       `json:"latency"`
+}
+
+type Latency struct {
+	Min  time.Duration `json:"min_ns"`
+	Mean time.Duration `json:"mean_ns"`
+	P50  time.Duration `json:"p50_ns"`
+	P90  time.Duration `json:"p90_ns"`
+	P99  time.Duration `json:"p99_ns"`
+	Max  time.Duration `json:"max_ns"`
+}
+
+func newResult(name string, duration time.Duration, latencies []time.Duration, errors int) Result {
+	result := Result{
+		Name:       name,
+		Operations: len(latencies),
+		Errors:     errors,
+		Duration:   duration,
+		Latency:    summarize(latencies),
+	}
+
+	if duration > 0 {
+		result.OpsPerSecond = float64(len(latencies)) / duration.Seconds()
+	}
+
+	return result
+}
+
+func summarize(latencies []time.Duration) Latency {
+	if len(latencies) == 0 {
+		return Latency{}
+	}
+
+	sorted := make([]time.Duration, len(latencies))
+	copy(sorted, latencies)
+	sort.Sort(durations(sorted))
+
+	var total time.Duration
+	for _, l := range sorted {
+		total += l
+	}
+
+	return Latency{
+		Min:  sorted[0],
+		Mean: total / time.Duration(len(sorted)),
+		P50:  percentile(sorted, 0.50),
+		P90:  percentile(sorted, 0.90),
+		P99:  percentile(sorted, 0.99),
+		Max:  sorted[len(sorted)-1],
+	}
+}
+
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	i := int(float64(len(sorted)-1) * p)
+	return sorted[i]
+}
+
+type durations []time.Duration
+
+func (d durations) Len() int           { return len(d) }
+func (d durations) Less(i, j int) bool { return d[i] < d[j] }
+func (d durations) Swap(i, j int)      { d[i], d[j] = d[j], d[i] }
+
+// WriteResults writes one JSON object per line for each result.
+func WriteResults(w io.Writer, results ...Result) error {
+	encoder := json.NewEncoder(w)
+	for _, result := range results {
+		err := encoder.Encode(result)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
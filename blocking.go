@@ -0,0 +1,142 @@
+package consuladapter
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+)
+
+const DefaultBlockingQueryMinInterval = 100 * time.Millisecond
+
+// BlockingIndex tracks the index of successive blocking queries, following
+// consul's recommended rules: the index is reset to 0 if it goes backwards,
+// an index of 0 is treated as 1 so the next query still blocks, and a query
+// that returns without the index changing is not repeated within
+// MinInterval, preventing tight loops.
+type BlockingIndex struct {
+	// MinInterval defaults to DefaultBlockingQueryMinInterval.
+	MinInterval time.Duration
+
+	index     uint64
+	unchanged bool
+	lastQuery time.Time
+}
+
+// WaitIndex is the index to pass in api.QueryOptions for the next query.
+func (b *BlockingIndex) WaitIndex() uint64 {
+	return b.index
+}
+
+// Wait returns once the next query may be issued, or with ctx's error once
+// ctx is done.
+func (b *BlockingIndex) Wait(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if b.unchanged {
+		interval := b.MinInterval
+		if interval <= 0 {
+			interval = DefaultBlockingQueryMinInterval
+		}
+
+		if remaining := interval - time.Since(b.lastQuery); remaining > 0 {
+			timer := time.NewTimer(remaining)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return ctx.Err()
+			case <-timer.C:
+			}
+		}
+	}
+
+	b.lastQuery = time.Now()
+	return nil
+}
+
+// Update records the index returned by a query and reports whether it
+// changed.
+func (b *BlockingIndex) Update(meta *api.QueryMeta) bool {
+	if meta == nil {
+		return false
+	}
+
+	next := NextWaitIndex(b.index, meta.LastIndex)
+	changed := next != b.index
+	b.index = next
+	b.unchanged = !changed
+	return changed
+}
+
+// Reset starts over with a non-blocking query, e.g. after an error.
+func (b *BlockingIndex) Reset() {
+	b.index = 0
+	b.unchanged = false
+}
+
+// NextWaitIndex returns the index to block on after a query that returned
+// last, when the previous query blocked on previous.
+func NextWaitIndex(previous, last uint64) uint64 {
+	switch {
+	case last < previous:
+		return 0
+	case last == 0:
+		return 1
+	default:
+		return last
+	}
+}
+
+// StopQuerying is returned by the query passed to BlockingQuery to stop it
+// without an error, e.g. once the condition the caller waits for holds.
+var StopQuerying = errors.New("stop querying")
+
+// BlockingQuery runs query as a blocking query, with a BlockingIndex, until
+// ctx is done or query returns StopQuerying. Each query is passed options
+// carrying ctx and the index to block on, which it may adjust. After an
+// error it calls onError, if set, and retries with a non-blocking query
+// once backoff allows; a zero backoff means DefaultWatchBackoff.
+//
+// It returns nil once query returns StopQuerying, and ctx's error
+// otherwise.
+func BlockingQuery(ctx context.Context, backoff BackoffRetry, onError func(error), query func(q *api.QueryOptions) (*api.QueryMeta, error)) error {
+	if backoff == (BackoffRetry{}) {
+		backoff = DefaultWatchBackoff
+	}
+
+	var index BlockingIndex
+	attempt := 0
+	for {
+		if err := index.Wait(ctx); err != nil {
+			return err
+		}
+
+		q := (&api.QueryOptions{WaitIndex: index.WaitIndex()}).WithContext(ctx)
+		meta, err := query(q)
+		if err == StopQuerying {
+			return nil
+		}
+		if err == nil {
+			attempt = 0
+			index.Update(meta)
+			continue
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if onError != nil {
+			onError(err)
+		}
+		index.Reset()
+		attempt++
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff.Delay(attempt)):
+		}
+	}
+}
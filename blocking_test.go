@@ -0,0 +1,98 @@
+package consuladapter_test
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"code.cloudfoundry.org/consuladapter"
+	"github.com/hashicorp/consul/api"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("BlockingIndex", func() {
+	It("applies consul's index rules", func() {
+		cases := map[[2]uint64]uint64{
+			{0, 7}:  7,
+			{7, 9}:  9,
+			{9, 9}:  9,
+			{9, 3}:  0,
+			{0, 0}:  1,
+			{12, 0}: 0,
+		}
+		for args, expected := range cases {
+			Expect(consuladapter.NextWaitIndex(args[0], args[1])).To(Equal(expected), "previous %d, last %d", args[0], args[1])
+		}
+	})
+
+	It("throttles queries that return an unchanged index", func() {
+		index := consuladapter.BlockingIndex{MinInterval: 50 * time.Millisecond}
+
+		Expect(index.Wait(context.Background())).To(Succeed())
+		Expect(index.Update(&api.QueryMeta{LastIndex: 5})).To(BeTrue())
+
+		start := time.Now()
+		Expect(index.Wait(context.Background())).To(Succeed())
+		Expect(time.Since(start)).To(BeNumerically("<", 50*time.Millisecond))
+		Expect(index.Update(&api.QueryMeta{LastIndex: 5})).To(BeFalse())
+
+		Expect(index.Wait(context.Background())).To(Succeed())
+		Expect(time.Since(start)).To(BeNumerically(">=", 50*time.Millisecond))
+		Expect(index.WaitIndex()).To(BeEquivalentTo(5))
+	})
+})
+
+var _ = Describe("BlockingQuery", func() {
+	backoff := consuladapter.BackoffRetry{Initial: 10 * time.Millisecond, Max: 10 * time.Millisecond}
+
+	It("blocks on the index returned by the previous query, until told to stop", func() {
+		var waitIndexes []uint64
+		err := consuladapter.BlockingQuery(context.Background(), backoff, nil, func(q *api.QueryOptions) (*api.QueryMeta, error) {
+			waitIndexes = append(waitIndexes, q.WaitIndex)
+			if len(waitIndexes) == 3 {
+				return nil, consuladapter.StopQuerying
+			}
+			return &api.QueryMeta{LastIndex: uint64(10 * len(waitIndexes))}, nil
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(waitIndexes).To(Equal([]uint64{0, 10, 20}))
+	})
+
+	It("passes ctx to the queries and returns its error once it is done", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		err := consuladapter.BlockingQuery(ctx, backoff, nil, func(q *api.QueryOptions) (*api.QueryMeta, error) {
+			Expect(q.Context()).To(Equal(ctx))
+			cancel()
+			return &api.QueryMeta{LastIndex: 1}, nil
+		})
+		Expect(err).To(Equal(context.Canceled))
+	})
+
+	It("reports errors and retries them without blocking, after backing off", func() {
+		var (
+			errs        []error
+			waitIndexes []uint64
+			times       []time.Time
+		)
+		err := consuladapter.BlockingQuery(context.Background(), backoff, func(err error) {
+			errs = append(errs, err)
+		}, func(q *api.QueryOptions) (*api.QueryMeta, error) {
+			waitIndexes = append(waitIndexes, q.WaitIndex)
+			times = append(times, time.Now())
+			switch len(waitIndexes) {
+			case 1:
+				return &api.QueryMeta{LastIndex: 7}, nil
+			case 2:
+				return nil, errors.New("connection refused")
+			default:
+				return nil, consuladapter.StopQuerying
+			}
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(errs).To(Equal([]error{errors.New("connection refused")}))
+		Expect(waitIndexes).To(Equal([]uint64{0, 7, 0}))
+		Expect(times[2].Sub(times[1])).To(BeNumerically(">=", 10*time.Millisecond))
+	})
+})
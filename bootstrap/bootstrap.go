@@ -0,0 +1,147 @@
+// Package bootstrap brings up a freshly provisioned consul cluster: it waits
+// for the servers to form a quorum and elect a leader, then writes the
+// initial ACL policies and seeds the base KV structure.
+package bootstrap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"code.cloudfoundry.org/consuladapter"
+	"github.com/hashicorp/consul/api"
+)
+
+const quorumPollInterval = 500 * time.Millisecond
+
+type Config struct {
+	// ExpectedServers is the number of servers that must have joined the
+	// raft configuration. Defaults to the number of clients.
+	ExpectedServers int
+
+	// Policies maps ACL policy names to their rules. Existing policies are
+	// updated to match.
+	Policies map[string]string
+
+	// KV is written only where a key does not already exist, so seeding
+	// never overwrites data written since.
+	KV map[string][]byte
+}
+
+// ErrNoClients is returned when a Bootstrapper has no clients to reach the
+// cluster through.
+var ErrNoClients = errors.New("bootstrap needs a client for at least one server")
+
+type QuorumError struct {
+	Expected int
+	Peers    map[int]int
+	Err      error
+}
+
+func (e QuorumError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("quorum not formed: %s", e.Err)
+	}
+	return fmt.Sprintf("quorum not formed: expected %d servers, peers seen by each host: %v", e.Expected, e.Peers)
+}
+
+// Bootstrapper bootstraps a cluster through clients for each of its server
+// agents.
+type Bootstrapper struct {
+	clients []consuladapter.Client
+	config  Config
+}
+
+func New(clients []consuladapter.Client, config Config) *Bootstrapper {
+	if config.ExpectedServers == 0 {
+		config.ExpectedServers = len(clients)
+	}
+	return &Bootstrapper{clients: clients, config: config}
+}
+
+// Run bootstraps the cluster, returning the leader's address.
+func (b *Bootstrapper) Run(ctx context.Context) (consuladapter.ServerAddress, error) {
+	err := b.WaitForQuorum(ctx)
+	if err != nil {
+		return consuladapter.ServerAddress{}, err
+	}
+
+	leader, err := consuladapter.WaitForLeader(ctx, b.clients[0].Status())
+	if err != nil {
+		return consuladapter.ServerAddress{}, err
+	}
+
+	if len(b.config.Policies) > 0 {
+		capabilities, err := b.clients[0].Capabilities()
+		if err == nil {
+			err = capabilities.Require(consuladapter.FeatureTokenACLs)
+		}
+		if err != nil {
+			return leader, fmt.Errorf("writing policies: %s", err)
+		}
+	}
+
+	for name, rules := range b.config.Policies {
+		_, err := consuladapter.EnsurePolicy(b.clients[0].ACL(), name, rules, nil)
+		if err != nil {
+			return leader, fmt.Errorf("writing policy '%s': %s", name, err)
+		}
+	}
+
+	for key, value := range b.config.KV {
+		_, _, err := b.clients[0].KV().CAS(&api.KVPair{Key: key, Value: value, ModifyIndex: 0}, nil)
+		if err != nil {
+			return leader, fmt.Errorf("seeding key '%s': %s", key, err)
+		}
+	}
+
+	return leader, nil
+}
+
+// WaitForQuorum polls until every host reports at least ExpectedServers
+// raft peers, or returns a QuorumError once ctx is done. It returns
+// ErrNoClients if there are no hosts to poll.
+func (b *Bootstrapper) WaitForQuorum(ctx context.Context) error {
+	if len(b.clients) == 0 {
+		return ErrNoClients
+	}
+
+	ticker := time.NewTicker(quorumPollInterval)
+	defer ticker.Stop()
+
+	for {
+		err := b.checkQuorum()
+		if err == nil {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-ticker.C:
+		}
+	}
+}
+
+func (b *Bootstrapper) checkQuorum() error {
+	quorumErr := QuorumError{Expected: b.config.ExpectedServers, Peers: map[int]int{}}
+	formed := true
+
+	for i, client := range b.clients {
+		peers, err := client.Status().Peers()
+		if err != nil {
+			return QuorumError{Expected: b.config.ExpectedServers, Err: fmt.Errorf("host %d: %s", i, err)}
+		}
+
+		quorumErr.Peers[i] = len(peers)
+		if len(peers) < b.config.ExpectedServers {
+			formed = false
+		}
+	}
+
+	if !formed {
+		return quorumErr
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package bootstrap_test
+
+import (
+	"context"
+	"time"
+
+	"code.cloudfoundry.org/consuladapter"
+	"code.cloudfoundry.org/consuladapter/bootstrap"
+	"code.cloudfoundry.org/consuladapter/fakes"
+	"github.com/hashicorp/consul/api"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Bootstrapper", func() {
+	var (
+		clients    []consuladapter.Client
+		components []*fakes.FakeClientComponents
+		statuses   []*fakes.FakeStatus
+		acl        *fakes.FakeACL
+	)
+
+	BeforeEach(func() {
+		clients = nil
+		components = nil
+		statuses = nil
+		acl = &fakes.FakeACL{}
+
+		peers := []string{"10.0.0.1:8300", "10.0.0.2:8300", "10.0.0.3:8300"}
+		for i := 0; i < 3; i++ {
+			client, c := fakes.NewFakeClient()
+			status := &fakes.FakeStatus{}
+			status.PeersReturns(peers, nil)
+			status.LeaderReturns(peers[0], nil)
+			client.StatusReturns(status)
+			client.ACLReturns(acl)
+			client.CapabilitiesReturns(consuladapter.Capabilities{Version: "1.15.2", TokenACLs: true}, nil)
+
+			clients = append(clients, client)
+			components = append(components, c)
+			statuses = append(statuses, status)
+		}
+		acl.PolicyCreateReturns(&api.ACLPolicy{Name: "app"}, nil, nil)
+	})
+
+	It("writes policies and seeds keys once the cluster has a leader", func() {
+		b := bootstrap.New(clients, bootstrap.Config{
+			Policies: map[string]string{"app": `key_prefix "app/" { policy = "write" }`},
+			KV:       map[string][]byte{"app/config": []byte("{}")},
+		})
+
+		leader, err := b.Run(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(leader.String()).To(Equal("10.0.0.1:8300"))
+
+		Expect(acl.PolicyCreateCallCount()).To(Equal(1))
+		pair, _ := components[0].KV.CASArgsForCall(0)
+		Expect(pair.Key).To(Equal("app/config"))
+		Expect(pair.ModifyIndex).To(BeZero())
+	})
+
+	It("fails without clients", func() {
+		b := bootstrap.New(nil, bootstrap.Config{})
+
+		_, err := b.Run(context.Background())
+		Expect(err).To(Equal(bootstrap.ErrNoClients))
+	})
+
+	It("fails fast on servers without token ACLs", func() {
+		clients[0].(*fakes.FakeClient).CapabilitiesReturns(consuladapter.Capabilities{Version: "1.3.1"}, nil)
+		b := bootstrap.New(clients, bootstrap.Config{
+			Policies: map[string]string{"app": `key_prefix "app/" { policy = "write" }`},
+		})
+
+		_, err := b.Run(context.Background())
+		Expect(err).To(MatchError(ContainSubstring("consul 1.3.1 does not support token and policy ACLs")))
+		Expect(acl.PolicyCreateCallCount()).To(Equal(0))
+	})
+
+	It("reports the peers seen by each host when quorum does not form", func() {
+		statuses[2].PeersReturns([]string{"10.0.0.3:8300"}, nil)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		err := bootstrap.New(clients, bootstrap.Config{}).WaitForQuorum(ctx)
+		Expect(err).To(Equal(bootstrap.QuorumError{Expected: 3, Peers: map[int]int{0: 3, 1: 3, 2: 1}}))
+	})
+})
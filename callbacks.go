@@ -0,0 +1,130 @@
+package consuladapter
+
+import (
+	"bytes"
+	"fmt"
+	"runtime"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+)
+
+// Callbacks, such as LockWithProgress's progress function, run on the
+// adapter's goroutines, and the call that started them waits for them to
+// return. A callback must therefore not block on that call returning, and
+// must not make calls that wait for the callback itself, e.g. starting
+// another LockWithProgress from a progress callback, or destroying a
+// TTLSession from within its Session's Renew.
+//
+// With callback checks enabled, such re-entrant calls return a
+// ReentrantCallError instead of deadlocking, and calls left waiting on a
+// blocked callback are logged to the logger set with SetPanicLogger.
+
+const (
+	callbackLockProgress   = "LockWithProgress progress"
+	callbackSessionRenewal = "session renewal"
+)
+
+// CallbackWaitWarning is how long, with callback checks enabled, a call may
+// wait for a callback before it is logged as blocked.
+const CallbackWaitWarning = 10 * time.Second
+
+var callbackChecks int32
+
+// callbacks maps the IDs of goroutines running callbacks to the callback's
+// name. It is only maintained while checks are enabled.
+var callbacks sync.Map
+
+// EnableCallbackChecks turns re-entrancy detection on or off. It finds the
+// current goroutine by parsing stack traces, so it is meant for tests and
+// debugging rather than production.
+func EnableCallbackChecks(enabled bool) {
+	var value int32
+	if enabled {
+		value = 1
+	}
+	atomic.StoreInt32(&callbackChecks, value)
+}
+
+func callbackChecksEnabled() bool {
+	return atomic.LoadInt32(&callbackChecks) == 1
+}
+
+type ReentrantCallError struct {
+	Call     string
+	Callback string
+}
+
+func (e ReentrantCallError) Error() string {
+	return fmt.Sprintf("%s called from within a %s callback, which it would wait for: this would deadlock", e.Call, e.Callback)
+}
+
+// runCallback runs f, recording that the current goroutine is running the
+// named callback.
+func runCallback(name string, f func()) {
+	if !callbackChecksEnabled() {
+		f()
+		return
+	}
+
+	id := goroutineID()
+	callbacks.Store(id, name)
+	defer callbacks.Delete(id)
+	f()
+}
+
+// checkReentrant returns a ReentrantCallError if call is made from within
+// one of the callbacks it waits for.
+func checkReentrant(call string, waitsFor ...string) error {
+	if !callbackChecksEnabled() {
+		return nil
+	}
+
+	name, ok := callbacks.Load(goroutineID())
+	if !ok {
+		return nil
+	}
+	for _, callback := range waitsFor {
+		if name == callback {
+			return ReentrantCallError{Call: call, Callback: callback}
+		}
+	}
+	return nil
+}
+
+// waitForCallback waits for done, logging if that takes longer than
+// CallbackWaitWarning while checks are enabled.
+func waitForCallback(call, callback string, done <-chan struct{}) {
+	if !callbackChecksEnabled() {
+		<-done
+		return
+	}
+
+	timer := time.NewTimer(CallbackWaitWarning)
+	defer timer.Stop()
+
+	select {
+	case <-done:
+		return
+	case <-timer.C:
+	}
+
+	if logger := currentLogger(); logger != nil {
+		logger.Info("waiting-for-blocked-callback", lager.Data{"call": call, "callback": callback, "waited": CallbackWaitWarning.String()})
+	}
+	<-done
+}
+
+func goroutineID() int64 {
+	buf := make([]byte, 64)
+	buf = buf[:runtime.Stack(buf, false)]
+
+	// the trace starts "goroutine 123 [running]:"
+	buf = bytes.TrimPrefix(buf, []byte("goroutine "))
+	buf = buf[:bytes.IndexByte(buf, ' ')]
+	id, _ := strconv.ParseInt(string(buf), 10, 64)
+	return id
+}
@@ -0,0 +1,82 @@
+package consuladapter_test
+
+import (
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/consuladapter"
+	"code.cloudfoundry.org/consuladapter/fakes"
+	"github.com/hashicorp/consul/api"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Callback checks", func() {
+	BeforeEach(func() {
+		consuladapter.EnableCallbackChecks(true)
+	})
+
+	AfterEach(func() {
+		consuladapter.EnableCallbackChecks(false)
+	})
+
+	It("rejects LockWithProgress from within a progress callback", func() {
+		client, components := fakes.NewFakeClient()
+		components.KV.GetReturns(nil, nil, nil)
+
+		release := make(chan struct{})
+		lock := &fakes.FakeLock{}
+		lock.LockStub = func(stopCh <-chan struct{}) (<-chan struct{}, error) {
+			<-release
+			return make(chan struct{}), nil
+		}
+		client.LockOptsReturns(lock, nil)
+
+		nestedErr := make(chan error, 1)
+		progress := func(consuladapter.LockProgress) {
+			select {
+			case <-release:
+				return
+			default:
+			}
+			_, err := consuladapter.LockWithProgress(client, &api.LockOptions{Key: "other"}, nil, time.Millisecond, func(consuladapter.LockProgress) {})
+			nestedErr <- err
+			close(release)
+		}
+
+		_, err := consuladapter.LockWithProgress(client, &api.LockOptions{Key: "the-key"}, nil, time.Millisecond, progress)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(nestedErr).To(Receive(Equal(consuladapter.ReentrantCallError{
+			Call:     "LockWithProgress",
+			Callback: "LockWithProgress progress",
+		})))
+	})
+
+	It("rejects destroying a TTLSession from within its renewal", func() {
+		var ttlSession *consuladapter.TTLSession
+		destroyErr := make(chan error, 1)
+
+		session := &fakes.FakeSession{}
+		session.CreateNoChecksReturns("session-id", nil, nil)
+		ready := make(chan struct{})
+		var once sync.Once
+		session.RenewStub = func(id string, q *api.WriteOptions) (*api.SessionEntry, *api.WriteMeta, error) {
+			once.Do(func() {
+				<-ready
+				destroyErr <- ttlSession.Destroy()
+			})
+			return &api.SessionEntry{ID: id}, nil, nil
+		}
+
+		var err error
+		ttlSession, err = consuladapter.NewTTLSession(session, &api.SessionEntry{TTL: "20ms"})
+		Expect(err).NotTo(HaveOccurred())
+		close(ready)
+
+		var reentrantErr error
+		Eventually(destroyErr).Should(Receive(&reentrantErr))
+		Expect(reentrantErr).To(BeAssignableToTypeOf(consuladapter.ReentrantCallError{}))
+		Expect(ttlSession.Destroy()).To(Succeed())
+	})
+})
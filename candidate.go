@@ -0,0 +1,193 @@
+package consuladapter
+
+import (
+	"errors"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+)
+
+type Leadership int
+
+const (
+	Deposed Leadership = iota
+	Elected
+)
+
+func (l Leadership) String() string {
+	if l == Elected {
+		return "elected"
+	}
+	return "deposed"
+}
+
+type LeadershipTransition struct {
+	Leadership Leadership
+	// Err is why leadership was lost, when Deposed other than by resigning.
+	Err error
+}
+
+var ErrLeadershipLost = errors.New("leadership lost: the lock was released or its session invalidated")
+
+const defaultRecampaignDelay = time.Second
+
+type CandidateConfig struct {
+	// Retry paces campaigns while another candidate leads. Nil means
+	// DefaultLockRetryStrategy.
+	Retry LockRetryStrategy
+	// RecampaignDelay is how long to wait after losing leadership, or
+	// failing to campaign, before campaigning again. Zero means 1 second.
+	RecampaignDelay time.Duration
+}
+
+// Candidate is an ifrit.Runner that campaigns for leadership by acquiring
+// the lock described by its options, and campaigns again whenever it loses
+// the lock, e.g. because its session was invalidated. It resigns when
+// signalled.
+type Candidate struct {
+	client      Client
+	opts        api.LockOptions
+	config      CandidateConfig
+	transitions chan LeadershipTransition
+
+	mu     sync.RWMutex
+	leader bool
+}
+
+func NewCandidate(client Client, opts api.LockOptions, config CandidateConfig) *Candidate {
+	if config.RecampaignDelay <= 0 {
+		config.RecampaignDelay = defaultRecampaignDelay
+	}
+
+	return &Candidate{
+		client:      client,
+		opts:        opts,
+		config:      config,
+		transitions: make(chan LeadershipTransition, 2),
+	}
+}
+
+// Transitions delivers elections and depositions. A receiver that falls
+// behind gets only the latest transition, preceded by the latest deposition
+// if it is an election, so it never misses losing leadership; the
+// transitions skipped are counted by DroppedEvents. IsLeader always
+// reflects the latest.
+func (c *Candidate) Transitions() <-chan LeadershipTransition {
+	return c.transitions
+}
+
+func (c *Candidate) IsLeader() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.leader
+}
+
+func (c *Candidate) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
+	var (
+		wg       sync.WaitGroup
+		panicErr error
+	)
+	stop := make(chan struct{})
+	campaigned := make(chan struct{})
+	goBackground(&wg, func() {
+		defer close(campaigned)
+		c.campaign(stop)
+	}, func(err *PanicError) {
+		panicErr = err
+		if c.IsLeader() {
+			c.transition(Deposed, err)
+		}
+	})
+	close(ready)
+
+	select {
+	case <-signals:
+	case <-campaigned:
+	}
+	close(stop)
+	wg.Wait()
+	return panicErr
+}
+
+func (c *Candidate) campaign(stop <-chan struct{}) {
+	var held Lock
+	defer func() {
+		// only set if campaigning panicked while leading
+		if held != nil {
+			held.Unlock()
+		}
+	}()
+
+	for {
+		lock, lostLock, err := AcquireLock(c.client, c.opts, stop, c.config.Retry)
+		if err == nil && lock == nil {
+			return
+		}
+
+		if err == nil {
+			held = lock
+			c.transition(Elected, nil)
+
+			select {
+			case <-lostLock:
+				held = nil
+				lock.Unlock()
+				c.transition(Deposed, ErrLeadershipLost)
+			case <-stop:
+				held = nil
+				lock.Unlock()
+				c.transition(Deposed, nil)
+				return
+			}
+		}
+
+		select {
+		case <-stop:
+			return
+		case <-time.After(c.config.RecampaignDelay):
+		}
+	}
+}
+
+func (c *Candidate) transition(leadership Leadership, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.leader = leadership == Elected
+
+	pending := []LeadershipTransition{}
+	for drained := false; !drained; {
+		select {
+		case transition := <-c.transitions:
+			pending = append(pending, transition)
+		default:
+			drained = true
+		}
+	}
+	pending = append(pending, LeadershipTransition{Leadership: leadership, Err: err})
+
+	kept := latestTransitions(pending)
+	for i := len(kept); i < len(pending); i++ {
+		countDroppedEvent(LeadershipTransitionsChannel)
+	}
+	for _, transition := range kept {
+		c.transitions <- transition
+	}
+}
+
+// latestTransitions reduces the unreceived transitions to the last one,
+// preceded by the last deposition if the last one is an election.
+func latestTransitions(pending []LeadershipTransition) []LeadershipTransition {
+	last := pending[len(pending)-1]
+	if last.Leadership == Deposed {
+		return []LeadershipTransition{last}
+	}
+
+	for i := len(pending) - 2; i >= 0; i-- {
+		if pending[i].Leadership == Deposed {
+			return []LeadershipTransition{pending[i], last}
+		}
+	}
+	return []LeadershipTransition{last}
+}
@@ -0,0 +1,108 @@
+package consuladapter_test
+
+import (
+	"os"
+	"time"
+
+	"code.cloudfoundry.org/consuladapter"
+	"code.cloudfoundry.org/consuladapter/fakes"
+	"github.com/hashicorp/consul/api"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Candidate", func() {
+	const key = "v1/locks/bbs"
+
+	var (
+		backend *fakes.FakeBackend
+		client  *fakes.FakeClient
+	)
+
+	BeforeEach(func() {
+		backend = fakes.NewFakeBackend()
+		client, _ = backend.Client()
+	})
+
+	run := func(candidate *consuladapter.Candidate) (chan os.Signal, chan error) {
+		signals := make(chan os.Signal)
+		done := make(chan error, 1)
+		ready := make(chan struct{})
+		go func() {
+			done <- candidate.Run(signals, ready)
+		}()
+		Eventually(ready).Should(BeClosed())
+		return signals, done
+	}
+
+	newCandidate := func(value string) *consuladapter.Candidate {
+		return consuladapter.NewCandidate(client, api.LockOptions{Key: key, Value: []byte(value)}, consuladapter.CandidateConfig{
+			Retry:           consuladapter.FixedRetry{Interval: 10 * time.Millisecond},
+			RecampaignDelay: 10 * time.Millisecond,
+		})
+	}
+
+	It("is elected, and resigns when signalled", func() {
+		candidate := newCandidate("bbs-1")
+		signals, done := run(candidate)
+
+		Eventually(candidate.Transitions()).Should(Receive(Equal(consuladapter.LeadershipTransition{Leadership: consuladapter.Elected})))
+		Expect(candidate.IsLeader()).To(BeTrue())
+		Expect(backend.Holder(key)).NotTo(BeEmpty())
+
+		signals <- os.Interrupt
+		Eventually(done).Should(Receive(BeNil()))
+		Expect(candidate.Transitions()).To(Receive(Equal(consuladapter.LeadershipTransition{Leadership: consuladapter.Deposed})))
+		Expect(candidate.IsLeader()).To(BeFalse())
+		Expect(backend.Holder(key)).To(BeEmpty())
+	})
+
+	It("hands leadership over when the leader's session is lost, and campaigns again", func() {
+		first := newCandidate("bbs-1")
+		firstSignals, firstDone := run(first)
+		Eventually(first.Transitions()).Should(Receive())
+
+		second := newCandidate("bbs-2")
+		secondSignals, secondDone := run(second)
+		Consistently(second.IsLeader).Should(BeFalse())
+
+		backend.Expire(backend.Holder(key))
+
+		var transition consuladapter.LeadershipTransition
+		Eventually(first.Transitions()).Should(Receive(&transition))
+		Expect(transition.Leadership).To(Equal(consuladapter.Deposed))
+		Expect(transition.Err).To(Equal(consuladapter.ErrLeadershipLost))
+		Eventually(second.Transitions()).Should(Receive(Equal(consuladapter.LeadershipTransition{Leadership: consuladapter.Elected})))
+
+		secondSignals <- os.Interrupt
+		Eventually(secondDone).Should(Receive())
+		Eventually(first.IsLeader).Should(BeTrue())
+
+		firstSignals <- os.Interrupt
+		Eventually(firstDone).Should(Receive())
+	})
+
+	It("never lets a slow receiver miss a deposition", func() {
+		candidate := newCandidate("bbs-1")
+		signals, done := run(candidate)
+		Eventually(candidate.IsLeader).Should(BeTrue())
+
+		first := backend.Holder(key)
+		backend.Expire(first)
+		Eventually(func() bool {
+			holder := backend.Holder(key)
+			return holder != "" && holder != first
+		}).Should(BeTrue())
+		Eventually(candidate.IsLeader).Should(BeTrue())
+
+		var transition consuladapter.LeadershipTransition
+		Expect(candidate.Transitions()).To(Receive(&transition))
+		Expect(transition).To(Equal(consuladapter.LeadershipTransition{Leadership: consuladapter.Deposed, Err: consuladapter.ErrLeadershipLost}))
+		Expect(candidate.Transitions()).To(Receive(Equal(consuladapter.LeadershipTransition{Leadership: consuladapter.Elected})))
+		Expect(candidate.Transitions()).NotTo(Receive())
+
+		signals <- os.Interrupt
+		Eventually(done).Should(Receive())
+	})
+})
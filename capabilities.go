@@ -0,0 +1,128 @@
+package consuladapter
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type Feature string
+
+const (
+	FeatureTxn           Feature = "transactions"
+	FeatureTokenACLs     Feature = "token and policy ACLs"
+	FeatureConfigEntries Feature = "config entries"
+	FeatureConnect       Feature = "Connect"
+	FeatureNamespaces    Feature = "namespaces"
+	FeaturePartitions    Feature = "admin partitions"
+)
+
+// Capabilities describes what the agent a client talks to supports, so that
+// features can degrade gracefully or fail fast on older servers.
+type Capabilities struct {
+	Version    string
+	Enterprise bool
+
+	Txn           bool
+	TokenACLs     bool
+	ConfigEntries bool
+	Connect       bool
+	Namespaces    bool
+	Partitions    bool
+}
+
+type UnsupportedFeatureError struct {
+	Feature Feature
+	Version string
+}
+
+func (e UnsupportedFeatureError) Error() string {
+	return fmt.Sprintf("consul %s does not support %s", e.Version, e.Feature)
+}
+
+func (c Capabilities) Has(feature Feature) bool {
+	switch feature {
+	case FeatureTxn:
+		return c.Txn
+	case FeatureTokenACLs:
+		return c.TokenACLs
+	case FeatureConfigEntries:
+		return c.ConfigEntries
+	case FeatureConnect:
+		return c.Connect
+	case FeatureNamespaces:
+		return c.Namespaces
+	case FeaturePartitions:
+		return c.Partitions
+	default:
+		return false
+	}
+}
+
+// Require returns an UnsupportedFeatureError unless feature is supported.
+func (c Capabilities) Require(feature Feature) error {
+	if c.Has(feature) {
+		return nil
+	}
+	return UnsupportedFeatureError{Feature: feature, Version: c.Version}
+}
+
+// DetectCapabilities derives capabilities from the agent's version, and from
+// its configuration for Connect, which can be disabled.
+func DetectCapabilities(agent Agent) (Capabilities, error) {
+	self, err := agent.Self()
+	if err != nil {
+		return Capabilities{}, err
+	}
+
+	version, _ := self["Config"]["Version"].(string)
+	if version == "" {
+		return Capabilities{}, errors.New("agent did not report its version")
+	}
+	metadata, _ := self["Config"]["VersionMetadata"].(string)
+
+	major, minor, err := parseMajorMinor(version)
+	if err != nil {
+		return Capabilities{}, err
+	}
+	atLeast := func(wantMajor, wantMinor int) bool {
+		return major > wantMajor || major == wantMajor && minor >= wantMinor
+	}
+
+	c := Capabilities{
+		Version:       version,
+		Enterprise:    strings.Contains(version, "+ent") || strings.Contains(metadata, "ent"),
+		Txn:           atLeast(0, 7),
+		TokenACLs:     atLeast(1, 4),
+		ConfigEntries: atLeast(1, 5),
+		Connect:       atLeast(1, 2),
+	}
+	c.Namespaces = c.Enterprise && atLeast(1, 7)
+	c.Partitions = c.Enterprise && atLeast(1, 11)
+
+	// the debug config is only visible with operator:read
+	if enabled, ok := self["DebugConfig"]["ConnectEnabled"].(bool); ok {
+		c.Connect = c.Connect && enabled
+	}
+
+	return c, nil
+}
+
+func parseMajorMinor(version string) (int, int, error) {
+	parts := strings.SplitN(strings.TrimPrefix(version, "v"), ".", 3)
+	if len(parts) < 2 {
+		return 0, 0, fmt.Errorf("unrecognised consul version '%s'", version)
+	}
+
+	major, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, 0, fmt.Errorf("unrecognised consul version '%s'", version)
+	}
+	minor, err := strconv.Atoi(strings.TrimRightFunc(parts[1], func(r rune) bool { return r < '0' || r > '9' }))
+	if err != nil {
+		return 0, 0, fmt.Errorf("unrecognised consul version '%s'", version)
+	}
+
+	return major, minor, nil
+}
@@ -0,0 +1,73 @@
+package consuladapter_test
+
+import (
+	"errors"
+
+	"code.cloudfoundry.org/consuladapter"
+	"code.cloudfoundry.org/consuladapter/fakes"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("DetectCapabilities", func() {
+	var agent *fakes.FakeAgent
+
+	BeforeEach(func() {
+		agent = &fakes.FakeAgent{}
+	})
+
+	self := func(version string, debugConfig map[string]interface{}) {
+		agent.SelfReturns(map[string]map[string]interface{}{
+			"Config":      {"Version": version},
+			"DebugConfig": debugConfig,
+		}, nil)
+	}
+
+	It("enables features by version", func() {
+		self("1.4.0", nil)
+		capabilities, err := consuladapter.DetectCapabilities(agent)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(capabilities).To(Equal(consuladapter.Capabilities{
+			Version:   "1.4.0",
+			Txn:       true,
+			TokenACLs: true,
+			Connect:   true,
+		}))
+		Expect(capabilities.Require(consuladapter.FeatureConfigEntries)).To(Equal(consuladapter.UnsupportedFeatureError{
+			Feature: consuladapter.FeatureConfigEntries,
+			Version: "1.4.0",
+		}))
+	})
+
+	It("only reports namespaces and partitions for enterprise servers", func() {
+		self("1.15.2", nil)
+		capabilities, err := consuladapter.DetectCapabilities(agent)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(capabilities.Has(consuladapter.FeatureNamespaces)).To(BeFalse())
+
+		self("1.15.2+ent", nil)
+		capabilities, err = consuladapter.DetectCapabilities(agent)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(capabilities.Enterprise).To(BeTrue())
+		Expect(capabilities.Has(consuladapter.FeatureNamespaces)).To(BeTrue())
+		Expect(capabilities.Has(consuladapter.FeaturePartitions)).To(BeTrue())
+	})
+
+	It("reports Connect as unsupported when it is disabled", func() {
+		self("1.15.2", map[string]interface{}{"ConnectEnabled": false})
+		capabilities, err := consuladapter.DetectCapabilities(agent)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(capabilities.Connect).To(BeFalse())
+	})
+
+	It("fails on unrecognised versions", func() {
+		self("dev", nil)
+		_, err := consuladapter.DetectCapabilities(agent)
+		Expect(err).To(MatchError("unrecognised consul version 'dev'"))
+
+		agent.SelfReturns(nil, errors.New("boom"))
+		_, err = consuladapter.DetectCapabilities(agent)
+		Expect(err).To(MatchError("boom"))
+	})
+})
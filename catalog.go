@@ -6,6 +6,8 @@ import "github.com/hashicorp/consul/api"
 
 type Catalog interface {
 	Nodes(q *api.QueryOptions) ([]*api.Node, *api.QueryMeta, error)
+	Services(q *api.QueryOptions) (map[string][]string, *api.QueryMeta, error)
+	Service(service, tag string, q *api.QueryOptions) ([]*api.CatalogService, *api.QueryMeta, error)
 }
 
 type catalog struct {
@@ -19,3 +21,11 @@ func NewConsulCatalog(c *api.Catalog) Catalog {
 func (c *catalog) Nodes(q *api.QueryOptions) ([]*api.Node, *api.QueryMeta, error) {
 	return c.catalog.Nodes(q)
 }
+
+func (c *catalog) Services(q *api.QueryOptions) (map[string][]string, *api.QueryMeta, error) {
+	return c.catalog.Services(q)
+}
+
+func (c *catalog) Service(service, tag string, q *api.QueryOptions) ([]*api.CatalogService, *api.QueryMeta, error) {
+	return c.catalog.Service(service, tag, q)
+}
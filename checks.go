@@ -0,0 +1,290 @@
+package consuladapter
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+)
+
+// InvalidCheckError is returned for a check definition that consul would
+// reject. Check is the check's name, which is empty if it has none.
+type InvalidCheckError struct {
+	Check  string
+	Reason string
+}
+
+func (e InvalidCheckError) Error() string {
+	return fmt.Sprintf("invalid check '%s': %s", e.Check, e.Reason)
+}
+
+// CheckDefinition is a typed health check that can be attached to a service
+// registration.
+type CheckDefinition interface {
+	ServiceCheck() (*api.AgentServiceCheck, error)
+}
+
+type CheckOptions struct {
+	ID       string
+	Name     string
+	Notes    string
+	Interval time.Duration
+	Timeout  time.Duration
+
+	DeregisterCriticalServiceAfter time.Duration
+}
+
+func (o CheckOptions) serviceCheck(interval bool) (*api.AgentServiceCheck, error) {
+	if o.Name == "" {
+		return nil, o.invalid("name is required")
+	}
+	if interval && o.Interval <= 0 {
+		return nil, o.invalid("interval must be positive")
+	}
+	if o.Timeout < 0 {
+		return nil, o.invalid("timeout must not be negative")
+	}
+	if o.DeregisterCriticalServiceAfter < 0 {
+		return nil, o.invalid("deregister critical service after must not be negative")
+	}
+
+	return &api.AgentServiceCheck{
+		CheckID:                        o.ID,
+		Name:                           o.Name,
+		Notes:                          o.Notes,
+		Interval:                       durationString(o.Interval),
+		Timeout:                        durationString(o.Timeout),
+		DeregisterCriticalServiceAfter: durationString(o.DeregisterCriticalServiceAfter),
+	}, nil
+}
+
+func (o CheckOptions) invalid(reason string) error {
+	return InvalidCheckError{Check: o.Name, Reason: reason}
+}
+
+func durationString(d time.Duration) string {
+	if d <= 0 {
+		return ""
+	}
+	return d.String()
+}
+
+// ScriptCheck runs Args on the agent's host. The agent must have script
+// checks enabled.
+type ScriptCheck struct {
+	CheckOptions
+	Args []string
+}
+
+func (c ScriptCheck) ServiceCheck() (*api.AgentServiceCheck, error) {
+	check, err := c.serviceCheck(true)
+	if err != nil {
+		return nil, err
+	}
+	if len(c.Args) == 0 {
+		return nil, c.invalid("args are required")
+	}
+
+	check.Args = c.Args
+	return check, nil
+}
+
+type HTTPCheck struct {
+	CheckOptions
+	URL           string
+	Method        string
+	Header        map[string][]string
+	TLSSkipVerify bool
+}
+
+func (c HTTPCheck) ServiceCheck() (*api.AgentServiceCheck, error) {
+	check, err := c.serviceCheck(true)
+	if err != nil {
+		return nil, err
+	}
+	u, err := url.Parse(c.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, c.invalid(fmt.Sprintf("url '%s' must be an absolute http or https URL", c.URL))
+	}
+
+	check.HTTP = c.URL
+	check.Method = c.Method
+	check.Header = c.Header
+	check.TLSSkipVerify = c.TLSSkipVerify
+	return check, nil
+}
+
+type TCPCheck struct {
+	CheckOptions
+	Address string
+}
+
+func (c TCPCheck) ServiceCheck() (*api.AgentServiceCheck, error) {
+	check, err := c.serviceCheck(true)
+	if err != nil {
+		return nil, err
+	}
+	if _, _, err := net.SplitHostPort(c.Address); err != nil {
+		return nil, c.invalid(fmt.Sprintf("address '%s' must be host:port", c.Address))
+	}
+
+	check.TCP = c.Address
+	return check, nil
+}
+
+// GRPCCheck uses the standard gRPC health checking protocol. Address is
+// host:port, optionally followed by /service to check a single service.
+type GRPCCheck struct {
+	CheckOptions
+	Address string
+	UseTLS  bool
+}
+
+func (c GRPCCheck) ServiceCheck() (*api.AgentServiceCheck, error) {
+	check, err := c.serviceCheck(true)
+	if err != nil {
+		return nil, err
+	}
+	hostPort := strings.SplitN(c.Address, "/", 2)[0]
+	if _, _, err := net.SplitHostPort(hostPort); err != nil {
+		return nil, c.invalid(fmt.Sprintf("address '%s' must be host:port[/service]", c.Address))
+	}
+
+	check.GRPC = c.Address
+	check.GRPCUseTLS = c.UseTLS
+	return check, nil
+}
+
+type TTLCheck struct {
+	CheckOptions
+	TTL time.Duration
+}
+
+func (c TTLCheck) ServiceCheck() (*api.AgentServiceCheck, error) {
+	check, err := c.serviceCheck(false)
+	if err != nil {
+		return nil, err
+	}
+	if c.TTL <= 0 {
+		return nil, c.invalid("ttl must be positive")
+	}
+
+	check.TTL = c.TTL.String()
+	return check, nil
+}
+
+// AliasCheck mirrors the health of another service, or of a node if Service
+// is empty.
+type AliasCheck struct {
+	CheckOptions
+	Node    string
+	Service string
+}
+
+func (c AliasCheck) ServiceCheck() (*api.AgentServiceCheck, error) {
+	check, err := c.serviceCheck(false)
+	if err != nil {
+		return nil, err
+	}
+	if c.Node == "" && c.Service == "" {
+		return nil, c.invalid("node or service is required")
+	}
+
+	check.AliasNode = c.Node
+	check.AliasService = c.Service
+	return check, nil
+}
+
+// CompositeCheck is a TTL check whose status is derived from other checks on
+// the same agent. It passes while at least MinPassing of CheckIDs pass, or
+// all of them if MinPassing is 0. Consul does not evaluate composite checks
+// itself; call Update periodically to refresh the status.
+type CompositeCheck struct {
+	CheckOptions
+	TTL        time.Duration
+	CheckIDs   []string
+	MinPassing int
+}
+
+func (c CompositeCheck) ServiceCheck() (*api.AgentServiceCheck, error) {
+	if c.ID == "" {
+		return nil, c.invalid("id is required")
+	}
+	if len(c.CheckIDs) == 0 {
+		return nil, c.invalid("check ids are required")
+	}
+	if c.MinPassing < 0 || c.MinPassing > len(c.CheckIDs) {
+		return nil, c.invalid(fmt.Sprintf("min passing must be between 0 and %d", len(c.CheckIDs)))
+	}
+
+	return TTLCheck{CheckOptions: c.CheckOptions, TTL: c.TTL}.ServiceCheck()
+}
+
+// Status derives the composite status from the agent's current checks.
+// Missing checks count as critical.
+func (c CompositeCheck) Status(checks map[string]*api.AgentCheck) (string, string) {
+	required := c.MinPassing
+	if required == 0 {
+		required = len(c.CheckIDs)
+	}
+
+	passing, warning := 0, 0
+	for _, id := range c.CheckIDs {
+		check, ok := checks[id]
+		if !ok {
+			continue
+		}
+		switch check.Status {
+		case api.HealthPassing:
+			passing++
+		case api.HealthWarning:
+			warning++
+		}
+	}
+
+	note := fmt.Sprintf("%d/%d checks passing, %d required", passing, len(c.CheckIDs), required)
+	switch {
+	case passing >= required:
+		return api.HealthPassing, note
+	case passing+warning >= required:
+		return api.HealthWarning, note
+	default:
+		return api.HealthCritical, note
+	}
+}
+
+// Update sets the composite check's TTL status from the agent's checks.
+func (c CompositeCheck) Update(agent Agent) error {
+	checks, err := agent.Checks()
+	if err != nil {
+		return err
+	}
+
+	status, note := c.Status(checks)
+	switch status {
+	case api.HealthPassing:
+		return agent.PassTTL(c.ID, note)
+	case api.HealthWarning:
+		return agent.WarnTTL(c.ID, note)
+	default:
+		return agent.FailTTL(c.ID, note)
+	}
+}
+
+// ServiceChecks validates and converts check definitions for use in an
+// api.AgentServiceRegistration.
+func ServiceChecks(definitions ...CheckDefinition) (api.AgentServiceChecks, error) {
+	checks := make(api.AgentServiceChecks, 0, len(definitions))
+	for _, definition := range definitions {
+		check, err := definition.ServiceCheck()
+		if err != nil {
+			return nil, err
+		}
+		checks = append(checks, check)
+	}
+
+	return checks, nil
+}
@@ -0,0 +1,226 @@
+package consuladapter_test
+
+import (
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"code.cloudfoundry.org/consuladapter"
+	"code.cloudfoundry.org/consuladapter/fakes"
+	"github.com/hashicorp/consul/api"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Checks", func() {
+	options := consuladapter.CheckOptions{ID: "check-id", Name: "check", Interval: time.Second, Timeout: 500 * time.Millisecond}
+
+	Describe("ServiceChecks", func() {
+		It("converts each check definition", func() {
+			checks, err := consuladapter.ServiceChecks(
+				consuladapter.ScriptCheck{CheckOptions: options, Args: []string{"/bin/true"}},
+				consuladapter.HTTPCheck{CheckOptions: options, URL: "http://127.0.0.1:8080/health", Method: "HEAD"},
+				consuladapter.TCPCheck{CheckOptions: options, Address: "127.0.0.1:8080"},
+				consuladapter.GRPCCheck{CheckOptions: options, Address: "127.0.0.1:9090/my.Service", UseTLS: true},
+			)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(checks).To(HaveLen(4))
+
+			Expect(checks[0].Args).To(Equal([]string{"/bin/true"}))
+			Expect(checks[0].Interval).To(Equal("1s"))
+			Expect(checks[0].Timeout).To(Equal("500ms"))
+			Expect(checks[1].HTTP).To(Equal("http://127.0.0.1:8080/health"))
+			Expect(checks[1].Method).To(Equal("HEAD"))
+			Expect(checks[2].TCP).To(Equal("127.0.0.1:8080"))
+			Expect(checks[3].GRPC).To(Equal("127.0.0.1:9090/my.Service"))
+			Expect(checks[3].GRPCUseTLS).To(BeTrue())
+		})
+
+		It("rejects invalid checks", func() {
+			invalid := map[string]consuladapter.CheckDefinition{
+				"missing name":              consuladapter.TCPCheck{CheckOptions: consuladapter.CheckOptions{Interval: time.Second}, Address: "127.0.0.1:1"},
+				"missing interval":          consuladapter.TCPCheck{CheckOptions: consuladapter.CheckOptions{Name: "check"}, Address: "127.0.0.1:1"},
+				"missing args":              consuladapter.ScriptCheck{CheckOptions: options},
+				"relative url":              consuladapter.HTTPCheck{CheckOptions: options, URL: "/health"},
+				"tcp address without port":  consuladapter.TCPCheck{CheckOptions: options, Address: "127.0.0.1"},
+				"grpc address without port": consuladapter.GRPCCheck{CheckOptions: options, Address: "127.0.0.1/my.Service"},
+			}
+
+			for description, definition := range invalid {
+				_, err := consuladapter.ServiceChecks(definition)
+				Expect(err).To(BeAssignableToTypeOf(consuladapter.InvalidCheckError{}), description)
+			}
+		})
+
+		It("identifies invalid checks by name", func() {
+			_, err := consuladapter.ServiceChecks(consuladapter.TCPCheck{CheckOptions: consuladapter.CheckOptions{ID: "check-id", Interval: time.Second}, Address: "127.0.0.1:1"})
+			Expect(err).To(Equal(consuladapter.InvalidCheckError{Check: "", Reason: "name is required"}))
+
+			_, err = consuladapter.ServiceChecks(consuladapter.TCPCheck{CheckOptions: options, Address: "127.0.0.1"})
+			Expect(err).To(Equal(consuladapter.InvalidCheckError{Check: "check", Reason: "address '127.0.0.1' must be host:port"}))
+
+			_, err = consuladapter.ServiceChecks(consuladapter.CompositeCheck{CheckOptions: consuladapter.CheckOptions{Name: "composite"}})
+			Expect(err).To(Equal(consuladapter.InvalidCheckError{Check: "composite", Reason: "id is required"}))
+		})
+	})
+
+	Describe("AliasCheck", func() {
+		It("does not require an interval", func() {
+			check, err := consuladapter.AliasCheck{CheckOptions: consuladapter.CheckOptions{Name: "alias"}, Service: "backend"}.ServiceCheck()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(check.AliasService).To(Equal("backend"))
+			Expect(check.Interval).To(BeEmpty())
+		})
+	})
+
+	Describe("CompositeCheck", func() {
+		var (
+			composite consuladapter.CompositeCheck
+			agent     *fakes.FakeAgent
+		)
+
+		BeforeEach(func() {
+			composite = consuladapter.CompositeCheck{
+				CheckOptions: consuladapter.CheckOptions{ID: "composite", Name: "composite"},
+				TTL:          10 * time.Second,
+				CheckIDs:     []string{"a", "b", "c"},
+				MinPassing:   2,
+			}
+			agent = &fakes.FakeAgent{}
+		})
+
+		It("registers as a TTL check", func() {
+			check, err := composite.ServiceCheck()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(check.CheckID).To(Equal("composite"))
+			Expect(check.TTL).To(Equal("10s"))
+		})
+
+		It("passes when enough sub-checks pass", func() {
+			agent.ChecksReturns(map[string]*api.AgentCheck{
+				"a": {Status: api.HealthPassing},
+				"b": {Status: api.HealthPassing},
+				"c": {Status: api.HealthCritical},
+			}, nil)
+
+			Expect(composite.Update(agent)).To(Succeed())
+			Expect(agent.PassTTLCallCount()).To(Equal(1))
+			checkID, note := agent.PassTTLArgsForCall(0)
+			Expect(checkID).To(Equal("composite"))
+			Expect(note).To(Equal("2/3 checks passing, 2 required"))
+		})
+
+		It("warns when warning sub-checks make up the difference", func() {
+			agent.ChecksReturns(map[string]*api.AgentCheck{
+				"a": {Status: api.HealthPassing},
+				"b": {Status: api.HealthWarning},
+			}, nil)
+
+			Expect(composite.Update(agent)).To(Succeed())
+			Expect(agent.WarnTTLCallCount()).To(Equal(1))
+		})
+
+		It("fails when too few sub-checks pass, counting missing checks as critical", func() {
+			composite.MinPassing = 0
+			agent.ChecksReturns(map[string]*api.AgentCheck{
+				"a": {Status: api.HealthPassing},
+				"b": {Status: api.HealthPassing},
+			}, nil)
+
+			Expect(composite.Update(agent)).To(Succeed())
+			Expect(agent.FailTTLCallCount()).To(Equal(1))
+		})
+	})
+
+	Describe("RegisterService", func() {
+		It("registers the service with the checks", func() {
+			agent := &fakes.FakeAgent{}
+			err := consuladapter.RegisterService(agent, &api.AgentServiceRegistration{Name: "service"},
+				consuladapter.TCPCheck{CheckOptions: options, Address: "127.0.0.1:8080"},
+			)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(agent.ServiceRegisterCallCount()).To(Equal(1))
+			registration := agent.ServiceRegisterArgsForCall(0)
+			Expect(registration.Name).To(Equal("service"))
+			Expect(registration.Checks).To(HaveLen(1))
+		})
+
+		It("passes weights and metadata through", func() {
+			agent := &fakes.FakeAgent{}
+			err := consuladapter.RegisterService(agent, &api.AgentServiceRegistration{
+				Name:    "service",
+				Weights: consuladapter.ServiceWeights(10, 1),
+				Meta:    map[string]string{"version": "1.2.3"},
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			registration := agent.ServiceRegisterArgsForCall(0)
+			Expect(registration.Weights).To(Equal(&api.AgentWeights{Passing: 10, Warning: 1}))
+			Expect(registration.Meta).To(HaveKeyWithValue("version", "1.2.3"))
+		})
+
+		It("rejects invalid weights and metadata", func() {
+			agent := &fakes.FakeAgent{}
+			err := consuladapter.RegisterService(agent, &api.AgentServiceRegistration{Name: "service", Weights: consuladapter.ServiceWeights(0, 1)})
+			Expect(err).To(BeAssignableToTypeOf(consuladapter.InvalidServiceError{}))
+
+			err = consuladapter.RegisterService(agent, &api.AgentServiceRegistration{Name: "service", Meta: map[string]string{"consul-version": "1"}})
+			Expect(err).To(BeAssignableToTypeOf(consuladapter.InvalidServiceError{}))
+
+			Expect(agent.ServiceRegisterCallCount()).To(Equal(0))
+		})
+
+		It("does not register the service when a check is invalid", func() {
+			agent := &fakes.FakeAgent{}
+			err := consuladapter.RegisterService(agent, &api.AgentServiceRegistration{Name: "service"}, consuladapter.ScriptCheck{CheckOptions: options})
+			Expect(err).To(HaveOccurred())
+			Expect(agent.ServiceRegisterCallCount()).To(Equal(0))
+		})
+	})
+
+	Describe("HealthServer", func() {
+		var (
+			server    *consuladapter.HealthServer
+			unhealthy int32
+		)
+
+		BeforeEach(func() {
+			atomic.StoreInt32(&unhealthy, 0)
+
+			var err error
+			server, err = consuladapter.NewHealthServer("127.0.0.1:0", func() error {
+				if atomic.LoadInt32(&unhealthy) == 1 {
+					return errors.New("unhealthy")
+				}
+				return nil
+			})
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			server.Close()
+		})
+
+		It("reflects the health function", func() {
+			resp, err := http.Get(server.URL())
+			Expect(err).NotTo(HaveOccurred())
+			resp.Body.Close()
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+			atomic.StoreInt32(&unhealthy, 1)
+			resp, err = http.Get(server.URL())
+			Expect(err).NotTo(HaveOccurred())
+			resp.Body.Close()
+			Expect(resp.StatusCode).To(Equal(http.StatusServiceUnavailable))
+		})
+
+		It("builds an HTTP check targeting the endpoint", func() {
+			check, err := server.Check(options).ServiceCheck()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(check.HTTP).To(Equal(server.URL()))
+		})
+	})
+})
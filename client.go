@@ -1,6 +1,11 @@
 package consuladapter
 
 import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
 	"code.cloudfoundry.org/cfhttp"
 	"github.com/hashicorp/consul/api"
 )
@@ -11,36 +16,146 @@ type Client interface {
 	Agent() Agent
 	Session() Session
 	Catalog() Catalog
+	Health() Health
 	KV() KV
 	Status() Status
+	Operator() Operator
+	ConfigEntries() ConfigEntries
+	ACL() ACL
 
 	LockOpts(opts *api.LockOptions) (Lock, error)
+	SemaphoreOpts(opts *api.SemaphoreOptions) (Semaphore, error)
+
+	// Capabilities reports what the agent supports. It is detected on first
+	// use and cached once detection succeeds.
+	Capabilities() (Capabilities, error)
+
+	// SelfTest runs RunSelfTest, so that components can fail fast at boot
+	// with a diagnosis rather than later mid-operation.
+	SelfTest(ctx context.Context) SelfTestReport
+
+	// WaitForService blocks until at least minHealthyInstances instances of
+	// the service are passing all of their checks, or ctx is done.
+	WaitForService(ctx context.Context, name string, minHealthyInstances int) error
 }
 
 //go:generate counterfeiter -o fakes/fake_lock.go . Lock
 
 type Lock interface {
 	Lock(stopCh <-chan struct{}) (lostLock <-chan struct{}, err error)
+	Unlock() error
+}
+
+//go:generate counterfeiter -o fakes/fake_semaphore.go . Semaphore
+
+// Semaphore is a lock that up to a limit of holders may hold at once. Each
+// contender writes a key under the semaphore's prefix with its session, and
+// the holders are recorded in the prefix's .lock key along with the limit.
+type Semaphore interface {
+	Acquire(stopCh <-chan struct{}) (lostSlot <-chan struct{}, err error)
+	Release() error
 }
 
 type client struct {
-	client *api.Client
+	client       *api.Client
+	maxValueSize int
+	readOnly     bool
+
+	capabilitiesMutex sync.Mutex
+	capabilities      *Capabilities
 }
 
 func NewConsulClient(c *api.Client) Client {
 	return &client{client: c}
 }
 
+// ClientOptions configure clients built by NewClientFromUrlWithOptions.
+// Namespace and Partition scope a client's operations for Consul Enterprise;
+// individual operations can still override them through the corresponding
+// fields of api.QueryOptions and api.WriteOptions.
+type ClientOptions struct {
+	Namespace string
+	Partition string
+
+	// ReresolveInterval, if set and the agent address is a host name,
+	// re-resolves it at most this often so long-lived clients follow the
+	// agent to a new address without a restart.
+	ReresolveInterval time.Duration
+
+	// Discovery, if set, supersedes the URL's address and ReresolveInterval:
+	// requests go to agents it discovers, refreshed at most every
+	// DiscoveryInterval, which defaults to DefaultDiscoveryInterval.
+	Discovery         AgentDiscovery
+	DiscoveryInterval time.Duration
+
+	// RequestRateLimit, if set, caps outbound requests per second with
+	// bursts of up to RequestBurst. Requests over the limit are queued and
+	// sent in RequestPriority order, so session renewals are not starved
+	// by bulk KV traffic when the agent is throttling.
+	RequestRateLimit float64
+	RequestBurst     int
+
+	// Component identifies the process in the User-Agent sent with every
+	// request; see UserAgent. Headers are added to every request that does
+	// not already set them, and may override the User-Agent.
+	Component string
+	Headers   http.Header
+
+	// MaxValueSize, if set, rejects KV writes with larger values with a
+	// ValueTooLargeError before they are sent. MaxResponseSize, if set,
+	// fails reads of larger responses with a ResponseTooLargeError.
+	MaxValueSize    int
+	MaxResponseSize int64
+
+	// ReadOnly, if set, rejects every operation that would change consul's
+	// state with a ReadOnlyError, for components such as dashboards that
+	// must never write, whatever their token allows. That includes session
+	// renewals and locks.
+	ReadOnly bool
+
+	// Token is the ACL token sent with every request that does not set its
+	// own in its QueryOptions or WriteOptions.
+	Token string
+}
+
 func NewClientFromUrl(urlString string) (Client, error) {
+	return NewClientFromUrlWithOptions(urlString, ClientOptions{})
+}
+
+func NewClientFromUrlWithOptions(urlString string, opts ClientOptions) (Client, error) {
 	scheme, address, err := Parse(urlString)
 	if err != nil {
 		return nil, err
 	}
 
+	httpClient := cfhttp.NewStreamingClient()
+	newTransport := func() http.RoundTripper {
+		return newHeaderTransport(cfhttp.NewStreamingClient().Transport, UserAgent(opts.Component), opts.Headers)
+	}
+	if opts.Discovery != nil {
+		httpClient.Transport = newDiscoveringTransport(newTransport(), opts.Discovery, address, opts.DiscoveryInterval)
+	} else if opts.ReresolveInterval > 0 {
+		httpClient.Transport = NewReresolvingTransport(newTransport, address, opts.ReresolveInterval, nil)
+	} else {
+		httpClient.Transport = newTransport()
+	}
+	if opts.MaxResponseSize > 0 {
+		httpClient.Transport = newResponseLimitTransport(httpClient.Transport, opts.MaxResponseSize)
+	}
+	if opts.RequestRateLimit > 0 {
+		httpClient.Transport = NewSchedulingTransport(httpClient.Transport, opts.RequestRateLimit, opts.RequestBurst)
+	}
+	if opts.ReadOnly {
+		httpClient.Transport = newReadOnlyTransport(httpClient.Transport)
+	}
+
 	config := &api.Config{
 		Address:    address,
 		Scheme:     scheme,
-		HttpClient: cfhttp.NewStreamingClient(),
+		HttpClient: httpClient,
+		Namespace:  opts.Namespace,
+		Partition:  opts.Partition,
+		Token:      opts.Token,
 	}
 
 	c, err := api.NewClient(config)
@@ -48,7 +163,7 @@ func NewClientFromUrl(urlString string) (Client, error) {
 		return nil, err
 	}
 
-	return &client{client: c}, nil
+	return &client{client: c, maxValueSize: opts.MaxValueSize, readOnly: opts.ReadOnly}, nil
 }
 
 func (c *client) Agent() Agent {
@@ -56,21 +171,90 @@ func (c *client) Agent() Agent {
 }
 
 func (c *client) KV() KV {
-	return NewConsulKV(c.client.KV())
+	return &keyValue{keyValue: c.client.KV(), maxValueSize: c.maxValueSize, readOnly: c.readOnly}
 }
 
 func (c *client) Catalog() Catalog {
 	return NewConsulCatalog(c.client.Catalog())
 }
 
+func (c *client) Health() Health {
+	return NewConsulHealth(c.client.Health())
+}
+
 func (c *client) Session() Session {
 	return NewConsulSession(c.client.Session())
 }
 
+func (c *client) Capabilities() (Capabilities, error) {
+	c.capabilitiesMutex.Lock()
+	defer c.capabilitiesMutex.Unlock()
+
+	if c.capabilities == nil {
+		capabilities, err := DetectCapabilities(c.Agent())
+		if err != nil {
+			return Capabilities{}, err
+		}
+		c.capabilities = &capabilities
+	}
+	return *c.capabilities, nil
+}
+
+func (c *client) SelfTest(ctx context.Context) SelfTestReport {
+	return RunSelfTest(ctx, c)
+}
+
 func (c *client) LockOpts(opts *api.LockOptions) (Lock, error) {
-	return c.client.LockOpts(opts)
+	if c.readOnly {
+		return nil, ReadOnlyError{Path: opts.Key}
+	}
+
+	tagged := *opts
+	if tagged.SessionName == "" {
+		tagged.SessionName = api.DefaultLockSessionName
+	}
+	tagged.SessionName = TagSessionName(tagged.SessionName)
+	return c.client.LockOpts(&tagged)
+}
+
+func (c *client) SemaphoreOpts(opts *api.SemaphoreOptions) (Semaphore, error) {
+	if c.readOnly {
+		return nil, ReadOnlyError{Path: opts.Prefix}
+	}
+
+	tagged := *opts
+	if tagged.SessionName == "" {
+		tagged.SessionName = api.DefaultSemaphoreSessionName
+	}
+	tagged.SessionName = TagSessionName(tagged.SessionName)
+	return c.client.SemaphoreOpts(&tagged)
 }
 
 func (c *client) Status() Status {
 	return NewConsulStatus(c.client.Status())
 }
+
+func (c *client) Operator() Operator {
+	return NewConsulOperator(c.client.Operator())
+}
+
+func (c *client) ACL() ACL {
+	return NewConsulACL(c.client.ACL())
+}
+
+func (c *client) ConfigEntries() ConfigEntries {
+	return NewConsulConfigEntries(c.client.ConfigEntries())
+}
+
+func (c *client) WaitForService(ctx context.Context, name string, minHealthyInstances int) error {
+	return BlockingQuery(ctx, DefaultWatchBackoff, nil, func(q *api.QueryOptions) (*api.QueryMeta, error) {
+		entries, meta, err := c.Health().Service(name, "", true, q)
+		if err != nil {
+			return nil, err
+		}
+		if len(entries) >= minHealthyInstances {
+			return nil, StopQuerying
+		}
+		return meta, nil
+	})
+}
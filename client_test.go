@@ -0,0 +1,85 @@
+package consuladapter_test
+
+import (
+	"context"
+	"time"
+
+	"code.cloudfoundry.org/consuladapter"
+	"github.com/hashicorp/consul/api"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Client", func() {
+	var client consuladapter.Client
+
+	BeforeEach(func() {
+		clusterRunner.Start()
+		clusterRunner.WaitUntilReady()
+		client = clusterRunner.NewClient()
+	})
+
+	AfterEach(func() {
+		clusterRunner.Stop()
+	})
+
+	Describe("WaitForService", func() {
+		BeforeEach(func() {
+			err := client.Agent().ServiceRegister(&api.AgentServiceRegistration{
+				ID:    "service-1",
+				Name:  "service",
+				Check: &api.AgentServiceCheck{CheckID: "service-1-ttl", TTL: "1m"},
+			})
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("returns once enough instances are healthy", func() {
+			errCh := make(chan error, 1)
+			go func() {
+				errCh <- client.WaitForService(context.Background(), "service", 1)
+			}()
+
+			Consistently(errCh, 500*time.Millisecond).ShouldNot(Receive())
+			Expect(client.Agent().PassTTL("service-1-ttl", "")).To(Succeed())
+			Eventually(errCh, 5*time.Second).Should(Receive(BeNil()))
+		})
+
+		It("returns the context error when the deadline passes first", func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+			defer cancel()
+
+			err := client.WaitForService(ctx, "service", 2)
+			Expect(err).To(Equal(context.DeadlineExceeded))
+		})
+	})
+
+	Describe("Session", func() {
+		It("returns a TTLTooShortError for TTLs below the server minimum", func() {
+			_, _, err := client.Session().CreateNoChecks(&api.SessionEntry{TTL: "1s"}, nil)
+			Expect(err).To(Equal(consuladapter.TTLTooShortError{
+				TTL:     "1s",
+				Minimum: clusterRunner.SessionTTL(),
+				Maximum: 24 * time.Hour,
+			}))
+		})
+
+		It("refuses to create a session without checks or a TTL", func() {
+			_, _, err := client.Session().CreateNoChecks(&api.SessionEntry{}, nil)
+			Expect(err).To(Equal(consuladapter.ErrNoChecksSessionWithoutTTL))
+		})
+
+		It("keeps a TTL session without checks alive until done", func() {
+			doneCh := make(chan struct{})
+			id, renewErr, err := consuladapter.CreateTTLSession(client.Session(), &api.SessionEntry{TTL: "10s"}, doneCh)
+			Expect(err).NotTo(HaveOccurred())
+
+			entry, _, err := client.Session().Info(id, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(entry.Checks).To(BeEmpty())
+
+			close(doneCh)
+			Eventually(renewErr).Should(Receive(BeNil()))
+		})
+	})
+})
@@ -0,0 +1,137 @@
+// Package locks implements the subcommands of consul-locks.
+package locks
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"code.cloudfoundry.org/consuladapter"
+	"github.com/hashicorp/consul/api"
+)
+
+type lockInfo struct {
+	Key     string
+	Value   string
+	Session *api.SessionEntry
+
+	pair *api.KVPair
+}
+
+// List writes a table of the keys under prefix and the sessions holding
+// them to out.
+func List(client consuladapter.Client, prefix string, out io.Writer) error {
+	pairs, _, err := client.KV().List(prefix, nil)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "KEY\tSESSION\tNODE\tNAME")
+	for _, pair := range pairs {
+		info, err := lookup(client, pair)
+		if err != nil {
+			return err
+		}
+
+		if info.Session == nil {
+			fmt.Fprintf(w, "%s\t-\t-\t-\n", info.Key)
+			continue
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", info.Key, info.Session.ID, info.Session.Node, info.Session.Name)
+	}
+	return w.Flush()
+}
+
+// Show writes the value of the lock at key and the details of the session
+// holding it to out.
+func Show(client consuladapter.Client, key string, out io.Writer) error {
+	info, err := get(client, key)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "Key:     %s\n", info.Key)
+	fmt.Fprintf(out, "Value:   %s\n", info.Value)
+	if info.Session == nil {
+		fmt.Fprintln(out, "Holder:  none")
+		return nil
+	}
+
+	fmt.Fprintf(out, "Session: %s\n", info.Session.ID)
+	fmt.Fprintf(out, "Name:    %s\n", info.Session.Name)
+	fmt.Fprintf(out, "Node:    %s\n", info.Session.Node)
+	fmt.Fprintf(out, "TTL:     %s\n", info.Session.TTL)
+	fmt.Fprintf(out, "Checks:  %s\n", strings.Join(info.Session.Checks, ", "))
+	return nil
+}
+
+// Release releases the lock at key on behalf of its holder, leaving the
+// holder's session and the lock's value alone. Unless yes is set, it asks on out for
+// confirmation, read from in.
+func Release(client consuladapter.Client, key string, yes bool, in io.Reader, out io.Writer) error {
+	info, err := get(client, key)
+	if err != nil {
+		return err
+	}
+	if info.Session == nil {
+		return fmt.Errorf("lock '%s' is not held", key)
+	}
+
+	if !yes {
+		fmt.Fprintf(out, "Release lock '%s' held by session %s (%s) on node %s? [y/N] ", key, info.Session.ID, info.Session.Name, info.Session.Node)
+		answer, _ := bufio.NewReader(in).ReadString('\n')
+		answer = strings.ToLower(strings.TrimSpace(answer))
+		if answer != "y" && answer != "yes" {
+			return errors.New("aborted")
+		}
+	}
+
+	// consul stores the body of a release, so keep the holder's value
+	released, _, err := client.KV().Release(&api.KVPair{
+		Key:     key,
+		Value:   info.pair.Value,
+		Flags:   info.pair.Flags,
+		Session: info.Session.ID,
+	}, nil)
+	if err != nil {
+		return err
+	}
+	if !released {
+		return fmt.Errorf("lock '%s' changed holder before it could be released", key)
+	}
+
+	fmt.Fprintf(out, "released '%s'\n", key)
+	return nil
+}
+
+func get(client consuladapter.Client, key string) (lockInfo, error) {
+	pair, _, err := client.KV().Get(key, nil)
+	if err != nil {
+		return lockInfo{}, err
+	}
+	if pair == nil {
+		return lockInfo{}, consuladapter.NewKeyNotFoundError(key)
+	}
+	return lookup(client, pair)
+}
+
+func lookup(client consuladapter.Client, pair *api.KVPair) (lockInfo, error) {
+	info := lockInfo{Key: pair.Key, Value: string(pair.Value), pair: pair}
+	if pair.Session == "" {
+		return info, nil
+	}
+
+	session, _, err := client.Session().Info(pair.Session, nil)
+	if err != nil {
+		return lockInfo{}, err
+	}
+	if session == nil {
+		session = &api.SessionEntry{ID: pair.Session}
+	}
+	info.Session = session
+	return info, nil
+}
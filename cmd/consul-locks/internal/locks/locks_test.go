@@ -0,0 +1,115 @@
+package locks_test
+
+import (
+	"bytes"
+	"strings"
+
+	"code.cloudfoundry.org/consuladapter"
+	"code.cloudfoundry.org/consuladapter/cmd/consul-locks/internal/locks"
+	"code.cloudfoundry.org/consuladapter/fakes"
+	"github.com/hashicorp/consul/api"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("consul-locks", func() {
+	var (
+		backend *fakes.FakeBackend
+		client  *fakes.FakeClient
+		session string
+		out     *bytes.Buffer
+	)
+
+	BeforeEach(func() {
+		backend = fakes.NewFakeBackend()
+		client, _ = backend.Client()
+		out = &bytes.Buffer{}
+
+		var err error
+		session, _, err = backend.Session().Create(&api.SessionEntry{
+			Name:   "bbs",
+			Node:   "database-0",
+			TTL:    "15s",
+			Checks: []string{"serfHealth"},
+		}, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		acquired, _, err := backend.KV().Acquire(&api.KVPair{Key: "v1/locks/bbs", Value: []byte("bbs-0"), Session: session}, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(acquired).To(BeTrue())
+
+		_, err = backend.KV().Put(&api.KVPair{Key: "v1/locks/auctioneer", Value: []byte("auctioneer-0")}, nil)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = backend.KV().Put(&api.KVPair{Key: "v1/presence/cell", Value: []byte("cell-0")}, nil)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	Describe("List", func() {
+		It("lists the locks under the prefix and their holders", func() {
+			Expect(locks.List(client, "v1/locks/", out)).To(Succeed())
+
+			lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+			Expect(lines).To(HaveLen(3))
+			Expect(strings.Fields(lines[0])).To(Equal([]string{"KEY", "SESSION", "NODE", "NAME"}))
+			Expect(strings.Fields(lines[1])).To(Equal([]string{"v1/locks/auctioneer", "-", "-", "-"}))
+			Expect(strings.Fields(lines[2])).To(Equal([]string{"v1/locks/bbs", session, "database-0", "bbs"}))
+		})
+	})
+
+	Describe("Show", func() {
+		It("shows the lock's value and holder", func() {
+			Expect(locks.Show(client, "v1/locks/bbs", out)).To(Succeed())
+			Expect(out.String()).To(Equal("Key:     v1/locks/bbs\n" +
+				"Value:   bbs-0\n" +
+				"Session: " + session + "\n" +
+				"Name:    bbs\n" +
+				"Node:    database-0\n" +
+				"TTL:     15s\n" +
+				"Checks:  serfHealth\n"))
+		})
+
+		It("shows a free lock as having no holder", func() {
+			Expect(locks.Show(client, "v1/locks/auctioneer", out)).To(Succeed())
+			Expect(out.String()).To(ContainSubstring("Holder:  none\n"))
+		})
+
+		It("fails for a missing key", func() {
+			err := locks.Show(client, "v1/locks/missing", out)
+			Expect(err).To(Equal(consuladapter.NewKeyNotFoundError("v1/locks/missing")))
+		})
+	})
+
+	Describe("Release", func() {
+		It("releases the lock once confirmed, keeping the holder's session", func() {
+			Expect(locks.Release(client, "v1/locks/bbs", false, strings.NewReader("y\n"), out)).To(Succeed())
+			Expect(out.String()).To(HavePrefix("Release lock 'v1/locks/bbs' held by session " + session + " (bbs) on node database-0? [y/N] "))
+			Expect(out.String()).To(ContainSubstring("released 'v1/locks/bbs'\n"))
+
+			Expect(backend.Holder("v1/locks/bbs")).To(BeEmpty())
+			pair, _, err := backend.KV().Get("v1/locks/bbs", nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pair.Value).To(Equal([]byte("bbs-0")))
+
+			entry, _, err := backend.Session().Info(session, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(entry).NotTo(BeNil())
+		})
+
+		It("aborts unless confirmed", func() {
+			Expect(locks.Release(client, "v1/locks/bbs", false, strings.NewReader("\n"), out)).To(MatchError("aborted"))
+			Expect(backend.Holder("v1/locks/bbs")).To(Equal(session))
+		})
+
+		It("does not ask with yes", func() {
+			Expect(locks.Release(client, "v1/locks/bbs", true, strings.NewReader(""), out)).To(Succeed())
+			Expect(out.String()).To(Equal("released 'v1/locks/bbs'\n"))
+			Expect(backend.Holder("v1/locks/bbs")).To(BeEmpty())
+		})
+
+		It("fails for a lock that is not held", func() {
+			err := locks.Release(client, "v1/locks/auctioneer", true, strings.NewReader(""), out)
+			Expect(err).To(MatchError("lock 'v1/locks/auctioneer' is not held"))
+		})
+	})
+})
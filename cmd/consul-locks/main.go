@@ -0,0 +1,83 @@
+// Command consul-locks inspects and manages locks held in consul's KV
+// store.
+//
+//	consul-locks [flags] list
+//	consul-locks [flags] show <key>
+//	consul-locks [flags] release [-yes] <key>
+//
+// release asks for confirmation unless -yes is given, and releases the lock
+// on behalf of its holder without destroying the holder's session.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+
+	"code.cloudfoundry.org/consuladapter"
+	"code.cloudfoundry.org/consuladapter/cmd/consul-locks/internal/locks"
+)
+
+var (
+	consulURL = flag.String("url", "http://127.0.0.1:8500", "consul agent URL")
+	token     = flag.String("token", os.Getenv("CONSUL_HTTP_TOKEN"), "ACL token; defaults to $CONSUL_HTTP_TOKEN")
+	prefix    = flag.String("prefix", consuladapter.LockKeys.Prefix(), "prefix under which locks are listed")
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: consul-locks [flags] list | show <key> | release [-yes] <key>\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	headers := http.Header{}
+	if *token != "" {
+		headers.Set("X-Consul-Token", *token)
+	}
+	client, err := consuladapter.NewClientFromUrlWithOptions(*consulURL, consuladapter.ClientOptions{
+		Component: "consul-locks",
+		Headers:   headers,
+	})
+	if err != nil {
+		fail(err)
+	}
+
+	args := flag.Args()[1:]
+	switch flag.Arg(0) {
+	case "list":
+		err = locks.List(client, *prefix, os.Stdout)
+	case "show":
+		if len(args) != 1 {
+			flag.Usage()
+			os.Exit(2)
+		}
+		err = locks.Show(client, args[0], os.Stdout)
+	case "release":
+		releaseFlags := flag.NewFlagSet("release", flag.ExitOnError)
+		yes := releaseFlags.Bool("yes", false, "release without asking for confirmation")
+		releaseFlags.Parse(args)
+		if releaseFlags.NArg() != 1 {
+			flag.Usage()
+			os.Exit(2)
+		}
+		err = locks.Release(client, releaseFlags.Arg(0), *yes, os.Stdin, os.Stdout)
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err != nil {
+		fail(err)
+	}
+}
+
+func fail(err error) {
+	fmt.Fprintf(os.Stderr, "consul-locks: %s\n", err)
+	os.Exit(1)
+}
@@ -0,0 +1,118 @@
+// Package check implements the checks run by consuladapter-check.
+package check
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/consuladapter"
+	"github.com/hashicorp/consul/api"
+)
+
+type Config struct {
+	// Token is the ACL token the client uses. The acl check is a no-op
+	// without one.
+	Token string
+
+	// LockKey is the key used to test lock acquisition, and Timeout how
+	// long to wait to acquire it.
+	LockKey string
+	Timeout time.Duration
+}
+
+type Result struct {
+	Name     string `json:"name"`
+	OK       bool   `json:"ok"`
+	Skipped  bool   `json:"skipped,omitempty"`
+	Duration string `json:"duration,omitempty"`
+	Error    string `json:"error,omitempty"`
+	Hint     string `json:"hint,omitempty"`
+}
+
+type Report struct {
+	OK     bool     `json:"ok"`
+	Checks []Result `json:"checks"`
+}
+
+// Run runs the client's self-test, which checks that the cluster has a
+// leader and that keys and sessions can be written, and then checks that
+// the ACL token is valid and that the lock at config.LockKey can be
+// acquired and released. Those two checks are skipped once another check
+// has failed.
+func Run(ctx context.Context, client consuladapter.Client, config Config) Report {
+	r := Report{OK: true}
+	for _, step := range client.SelfTest(ctx).Steps {
+		result := Result{Name: step.Name, OK: step.OK, Skipped: step.Skipped, Error: step.Error, Hint: step.Hint}
+		if !step.Skipped {
+			result.Duration = step.Duration.String()
+		}
+		if !step.OK {
+			r.OK = false
+		}
+		r.Checks = append(r.Checks, result)
+	}
+
+	checks := []struct {
+		name string
+		fn   func(consuladapter.Client, Config) error
+	}{
+		{"acl", checkACL},
+		{"lock", checkLock},
+	}
+
+	for _, check := range checks {
+		if !r.OK {
+			r.Checks = append(r.Checks, Result{Name: check.name, Skipped: true})
+			continue
+		}
+
+		start := time.Now()
+		err := check.fn(client, config)
+		result := Result{Name: check.name, OK: err == nil, Duration: time.Since(start).String()}
+		if err != nil {
+			result.Error = err.Error()
+			r.OK = false
+		}
+		r.Checks = append(r.Checks, result)
+	}
+
+	return r
+}
+
+func checkACL(client consuladapter.Client, config Config) error {
+	if config.Token == "" {
+		return nil
+	}
+
+	_, _, err := client.ACL().TokenReadSelf(nil)
+	if err != nil && strings.Contains(err.Error(), "ACL support disabled") {
+		// any token is accepted
+		return nil
+	}
+	return err
+}
+
+func checkLock(client consuladapter.Client, config Config) error {
+	lock, err := client.LockOpts(&api.LockOptions{
+		Key:          config.LockKey,
+		SessionName:  "consuladapter-check",
+		SessionTTL:   "10s",
+		LockTryOnce:  true,
+		LockWaitTime: config.Timeout,
+	})
+	if err != nil {
+		return err
+	}
+
+	lostLock, err := lock.Lock(nil)
+	if err != nil {
+		return err
+	}
+	if lostLock == nil {
+		return fmt.Errorf("lock '%s' is held by another session", config.LockKey)
+	}
+
+	return lock.Unlock()
+}
@@ -0,0 +1,119 @@
+package check_test
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"code.cloudfoundry.org/consuladapter"
+	"code.cloudfoundry.org/consuladapter/cmd/consuladapter-check/internal/check"
+	"code.cloudfoundry.org/consuladapter/fakes"
+	"github.com/hashicorp/consul/api"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Run", func() {
+	var (
+		backend *fakes.FakeBackend
+		client  *fakes.FakeClient
+		status  *fakes.FakeStatus
+		acl     *fakes.FakeACL
+		config  check.Config
+	)
+
+	BeforeEach(func() {
+		backend = fakes.NewFakeBackend()
+		client, _ = backend.Client()
+		client.SelfTestStub = func(ctx context.Context) consuladapter.SelfTestReport {
+			return consuladapter.RunSelfTest(ctx, client)
+		}
+
+		status = &fakes.FakeStatus{}
+		status.LeaderReturns("10.0.0.1:8300", nil)
+		client.StatusReturns(status)
+
+		acl = &fakes.FakeACL{}
+		client.ACLReturns(acl)
+
+		config = check.Config{LockKey: "consuladapter-check/lock", Timeout: time.Second}
+	})
+
+	names := func(report check.Report) []string {
+		names := []string{}
+		for _, result := range report.Checks {
+			names = append(names, result.Name)
+		}
+		return names
+	}
+
+	sessions := func() []*api.SessionEntry {
+		sessions, _, err := backend.Session().List(nil)
+		Expect(err).NotTo(HaveOccurred())
+		return sessions
+	}
+
+	It("passes every check against a working cluster, cleaning up after itself", func() {
+		report := check.Run(context.Background(), client, config)
+		Expect(report.OK).To(BeTrue())
+		Expect(names(report)).To(Equal([]string{"leader", "kv", "session", "acl", "lock"}))
+		for _, result := range report.Checks {
+			Expect(result.OK).To(BeTrue(), result.Name)
+			Expect(result.Duration).NotTo(BeEmpty())
+		}
+
+		Expect(acl.TokenReadSelfCallCount()).To(Equal(0))
+		Expect(sessions()).To(BeEmpty())
+		Expect(backend.Holder(config.LockKey)).To(BeEmpty())
+	})
+
+	It("skips the checks after the first failure", func() {
+		status.LeaderReturns("", nil)
+
+		report := check.Run(context.Background(), client, config)
+		Expect(report.OK).To(BeFalse())
+		Expect(report.Checks[0].Name).To(Equal("leader"))
+		Expect(report.Checks[0].Error).To(Equal("cluster has no leader"))
+		Expect(report.Checks[0].Hint).NotTo(BeEmpty())
+		for _, result := range report.Checks[1:] {
+			Expect(result.Skipped).To(BeTrue(), result.Name)
+		}
+	})
+
+	Describe("with an ACL token", func() {
+		BeforeEach(func() {
+			config.Token = "secret"
+		})
+
+		It("checks that the token is valid", func() {
+			acl.TokenReadSelfReturns(nil, nil, errors.New("Unexpected response code: 403 (ACL not found)"))
+
+			report := check.Run(context.Background(), client, config)
+			Expect(report.OK).To(BeFalse())
+			Expect(report.Checks[3].Name).To(Equal("acl"))
+			Expect(report.Checks[3].Error).To(Equal("Unexpected response code: 403 (ACL not found)"))
+		})
+
+		It("accepts any token when ACLs are disabled", func() {
+			acl.TokenReadSelfReturns(nil, nil, errors.New("Unexpected response code: 401 (ACL support disabled)"))
+
+			Expect(check.Run(context.Background(), client, config).OK).To(BeTrue())
+			Expect(acl.TokenReadSelfCallCount()).To(Equal(1))
+		})
+	})
+
+	It("fails the lock check when another session holds the lock", func() {
+		session, _, err := backend.Session().Create(&api.SessionEntry{Name: "holder"}, nil)
+		Expect(err).NotTo(HaveOccurred())
+		acquired, _, err := backend.KV().Acquire(&api.KVPair{Key: config.LockKey, Session: session}, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(acquired).To(BeTrue())
+
+		report := check.Run(context.Background(), client, config)
+		Expect(report.OK).To(BeFalse())
+		Expect(report.Checks[4].Name).To(Equal("lock"))
+		Expect(report.Checks[4].Error).To(Equal("lock 'consuladapter-check/lock' is held by another session"))
+		Expect(backend.Holder(config.LockKey)).To(Equal(session))
+	})
+})
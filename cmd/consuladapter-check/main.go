@@ -0,0 +1,60 @@
+// Command consuladapter-check verifies that a process using consuladapter
+// can work against a consul cluster: that it passes the client's self-test,
+// the ACL token is valid, and locks can be acquired. It prints the
+// results as JSON and exits non-zero if any check fails, for use in BOSH
+// pre-start and drain scripts.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"net/http"
+	"os"
+	"time"
+
+	"code.cloudfoundry.org/consuladapter"
+	"code.cloudfoundry.org/consuladapter/cmd/consuladapter-check/internal/check"
+)
+
+var (
+	consulURL = flag.String("url", "http://127.0.0.1:8500", "consul agent URL")
+	token     = flag.String("token", os.Getenv("CONSUL_HTTP_TOKEN"), "ACL token; defaults to $CONSUL_HTTP_TOKEN")
+	lockKey   = flag.String("lock-key", "consuladapter-check/lock", "key used to test lock acquisition")
+	timeout   = flag.Duration("timeout", 10*time.Second, "how long to wait to acquire the lock")
+)
+
+func main() {
+	flag.Parse()
+
+	r := run()
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(r)
+
+	if !r.OK {
+		os.Exit(1)
+	}
+}
+
+func run() check.Report {
+	headers := http.Header{}
+	if *token != "" {
+		headers.Set("X-Consul-Token", *token)
+	}
+
+	client, err := consuladapter.NewClientFromUrlWithOptions(*consulURL, consuladapter.ClientOptions{
+		Component: "consuladapter-check",
+		Headers:   headers,
+	})
+	if err != nil {
+		return check.Report{Checks: []check.Result{{Name: "client", Error: err.Error()}}}
+	}
+
+	return check.Run(context.Background(), client, check.Config{
+		Token:   *token,
+		LockKey: *lockKey,
+		Timeout: *timeout,
+	})
+}
@@ -0,0 +1,192 @@
+package consuladapter
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/consul/api"
+)
+
+//go:generate counterfeiter -o fakes/fake_config_entries.go . ConfigEntries
+
+type ConfigEntries interface {
+	Get(kind string, name string, q *api.QueryOptions) (api.ConfigEntry, *api.QueryMeta, error)
+	List(kind string, q *api.QueryOptions) ([]api.ConfigEntry, *api.QueryMeta, error)
+	Set(entry api.ConfigEntry, w *api.WriteOptions) (bool, *api.WriteMeta, error)
+	CAS(entry api.ConfigEntry, index uint64, w *api.WriteOptions) (bool, *api.WriteMeta, error)
+	Delete(kind string, name string, w *api.WriteOptions) (*api.WriteMeta, error)
+}
+
+type configEntries struct {
+	configEntries *api.ConfigEntries
+}
+
+func NewConsulConfigEntries(c *api.ConfigEntries) ConfigEntries {
+	return &configEntries{configEntries: c}
+}
+
+func (c *configEntries) Get(kind string, name string, q *api.QueryOptions) (api.ConfigEntry, *api.QueryMeta, error) {
+	return c.configEntries.Get(kind, name, q)
+}
+
+func (c *configEntries) List(kind string, q *api.QueryOptions) ([]api.ConfigEntry, *api.QueryMeta, error) {
+	return c.configEntries.List(kind, q)
+}
+
+func (c *configEntries) Set(entry api.ConfigEntry, w *api.WriteOptions) (bool, *api.WriteMeta, error) {
+	return c.configEntries.Set(entry, w)
+}
+
+func (c *configEntries) CAS(entry api.ConfigEntry, index uint64, w *api.WriteOptions) (bool, *api.WriteMeta, error) {
+	return c.configEntries.CAS(entry, index, w)
+}
+
+func (c *configEntries) Delete(kind string, name string, w *api.WriteOptions) (*api.WriteMeta, error) {
+	return c.configEntries.Delete(kind, name, w)
+}
+
+type ConfigEntryNotFoundError struct {
+	Kind string
+	Name string
+}
+
+func (e ConfigEntryNotFoundError) Error() string {
+	return fmt.Sprintf("config entry not found: %s '%s'", e.Kind, e.Name)
+}
+
+func GetServiceDefaults(entries ConfigEntries, name string, q *api.QueryOptions) (*api.ServiceConfigEntry, error) {
+	entry, _, err := GetServiceDefaultsWithMeta(entries, name, q)
+	return entry, err
+}
+
+// GetServiceDefaultsWithMeta is like GetServiceDefaults, but also returns the
+// query metadata, e.g. the LastIndex to use for a following blocking query.
+// The other WithMeta helpers behave likewise.
+func GetServiceDefaultsWithMeta(entries ConfigEntries, name string, q *api.QueryOptions) (*api.ServiceConfigEntry, *api.QueryMeta, error) {
+	entry, meta, err := getTypedConfigEntry(entries, api.ServiceDefaults, name, q)
+	if err != nil {
+		return nil, meta, err
+	}
+	return entry.(*api.ServiceConfigEntry), meta, nil
+}
+
+func ListServiceDefaults(entries ConfigEntries, q *api.QueryOptions) ([]*api.ServiceConfigEntry, error) {
+	typed, _, err := ListServiceDefaultsWithMeta(entries, q)
+	return typed, err
+}
+
+func ListServiceDefaultsWithMeta(entries ConfigEntries, q *api.QueryOptions) ([]*api.ServiceConfigEntry, *api.QueryMeta, error) {
+	list, meta, err := entries.List(api.ServiceDefaults, q)
+	if err != nil {
+		return nil, meta, err
+	}
+
+	typed := make([]*api.ServiceConfigEntry, 0, len(list))
+	for _, entry := range list {
+		if serviceDefaults, ok := entry.(*api.ServiceConfigEntry); ok {
+			typed = append(typed, serviceDefaults)
+		}
+	}
+	return typed, meta, nil
+}
+
+// GetProxyDefaults returns the global proxy-defaults entry, the only one
+// consul allows.
+func GetProxyDefaults(entries ConfigEntries, q *api.QueryOptions) (*api.ProxyConfigEntry, error) {
+	entry, _, err := GetProxyDefaultsWithMeta(entries, q)
+	return entry, err
+}
+
+func GetProxyDefaultsWithMeta(entries ConfigEntries, q *api.QueryOptions) (*api.ProxyConfigEntry, *api.QueryMeta, error) {
+	entry, meta, err := getTypedConfigEntry(entries, api.ProxyDefaults, api.ProxyConfigGlobal, q)
+	if err != nil {
+		return nil, meta, err
+	}
+	return entry.(*api.ProxyConfigEntry), meta, nil
+}
+
+func GetServiceRouter(entries ConfigEntries, name string, q *api.QueryOptions) (*api.ServiceRouterConfigEntry, error) {
+	entry, _, err := GetServiceRouterWithMeta(entries, name, q)
+	return entry, err
+}
+
+func GetServiceRouterWithMeta(entries ConfigEntries, name string, q *api.QueryOptions) (*api.ServiceRouterConfigEntry, *api.QueryMeta, error) {
+	entry, meta, err := getTypedConfigEntry(entries, api.ServiceRouter, name, q)
+	if err != nil {
+		return nil, meta, err
+	}
+	return entry.(*api.ServiceRouterConfigEntry), meta, nil
+}
+
+func ListServiceRouters(entries ConfigEntries, q *api.QueryOptions) ([]*api.ServiceRouterConfigEntry, error) {
+	typed, _, err := ListServiceRoutersWithMeta(entries, q)
+	return typed, err
+}
+
+func ListServiceRoutersWithMeta(entries ConfigEntries, q *api.QueryOptions) ([]*api.ServiceRouterConfigEntry, *api.QueryMeta, error) {
+	list, meta, err := entries.List(api.ServiceRouter, q)
+	if err != nil {
+		return nil, meta, err
+	}
+
+	typed := make([]*api.ServiceRouterConfigEntry, 0, len(list))
+	for _, entry := range list {
+		if router, ok := entry.(*api.ServiceRouterConfigEntry); ok {
+			typed = append(typed, router)
+		}
+	}
+	return typed, meta, nil
+}
+
+// SetConfigEntry writes entry, filling in its Kind from its type if unset.
+func SetConfigEntry(entries ConfigEntries, entry api.ConfigEntry, w *api.WriteOptions) error {
+	switch e := entry.(type) {
+	case *api.ServiceConfigEntry:
+		if e.Kind == "" {
+			e.Kind = api.ServiceDefaults
+		}
+	case *api.ProxyConfigEntry:
+		if e.Kind == "" {
+			e.Kind = api.ProxyDefaults
+		}
+		if e.Name == "" {
+			e.Name = api.ProxyConfigGlobal
+		}
+	case *api.ServiceRouterConfigEntry:
+		if e.Kind == "" {
+			e.Kind = api.ServiceRouter
+		}
+	}
+
+	_, _, err := entries.Set(entry, w)
+	return err
+}
+
+func DeleteConfigEntry(entries ConfigEntries, kind, name string, w *api.WriteOptions) error {
+	_, err := entries.Delete(kind, name, w)
+	return err
+}
+
+func getTypedConfigEntry(entries ConfigEntries, kind, name string, q *api.QueryOptions) (api.ConfigEntry, *api.QueryMeta, error) {
+	entry, meta, err := getConfigEntry(entries, kind, name, q)
+	if err != nil {
+		return nil, meta, err
+	}
+	if entry == nil {
+		return nil, meta, ConfigEntryNotFoundError{Kind: kind, Name: name}
+	}
+
+	var ok bool
+	switch kind {
+	case api.ServiceDefaults:
+		_, ok = entry.(*api.ServiceConfigEntry)
+	case api.ProxyDefaults:
+		_, ok = entry.(*api.ProxyConfigEntry)
+	case api.ServiceRouter:
+		_, ok = entry.(*api.ServiceRouterConfigEntry)
+	}
+	if !ok {
+		return nil, meta, fmt.Errorf("unexpected config entry type %T for kind %s", entry, kind)
+	}
+
+	return entry, meta, nil
+}
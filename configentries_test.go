@@ -0,0 +1,184 @@
+package consuladapter_test
+
+import (
+	"errors"
+	"net/http"
+
+	"code.cloudfoundry.org/consuladapter"
+	"code.cloudfoundry.org/consuladapter/fakes"
+	"github.com/hashicorp/consul/api"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("GetServiceDefaultsWithMeta", func() {
+	var entries *fakes.FakeConfigEntries
+
+	BeforeEach(func() {
+		entries = &fakes.FakeConfigEntries{}
+	})
+
+	It("returns the entry with its query metadata", func() {
+		entries.GetReturns(
+			&api.ServiceConfigEntry{Kind: api.ServiceDefaults, Name: "web", Protocol: "http"},
+			&api.QueryMeta{LastIndex: 42, KnownLeader: true},
+			nil,
+		)
+
+		entry, meta, err := consuladapter.GetServiceDefaultsWithMeta(entries, "web", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entry.Protocol).To(Equal("http"))
+		Expect(meta.LastIndex).To(BeEquivalentTo(42))
+		Expect(meta.KnownLeader).To(BeTrue())
+	})
+
+	It("returns a ConfigEntryNotFoundError when the entry does not exist", func() {
+		entries.GetReturns(nil, nil, api.StatusError{Code: http.StatusNotFound})
+
+		_, _, err := consuladapter.GetServiceDefaultsWithMeta(entries, "web", nil)
+		Expect(err).To(Equal(consuladapter.ConfigEntryNotFoundError{Kind: api.ServiceDefaults, Name: "web"}))
+	})
+})
+
+var _ = Describe("Config entry helpers", func() {
+	var entries *fakes.FakeConfigEntries
+
+	BeforeEach(func() {
+		entries = &fakes.FakeConfigEntries{}
+	})
+
+	Describe("Get", func() {
+		It("gets service defaults by name", func() {
+			entries.GetReturns(&api.ServiceConfigEntry{Kind: api.ServiceDefaults, Name: "web", Protocol: "grpc"}, nil, nil)
+
+			entry, err := consuladapter.GetServiceDefaults(entries, "web", nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(entry.Protocol).To(Equal("grpc"))
+
+			kind, name, _ := entries.GetArgsForCall(0)
+			Expect(kind).To(Equal(api.ServiceDefaults))
+			Expect(name).To(Equal("web"))
+		})
+
+		It("gets the global proxy defaults", func() {
+			entries.GetReturns(&api.ProxyConfigEntry{Kind: api.ProxyDefaults, Name: api.ProxyConfigGlobal}, nil, nil)
+
+			entry, err := consuladapter.GetProxyDefaults(entries, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(entry.Name).To(Equal(api.ProxyConfigGlobal))
+
+			kind, name, _ := entries.GetArgsForCall(0)
+			Expect(kind).To(Equal(api.ProxyDefaults))
+			Expect(name).To(Equal(api.ProxyConfigGlobal))
+		})
+
+		It("gets a service router", func() {
+			entries.GetReturns(&api.ServiceRouterConfigEntry{Kind: api.ServiceRouter, Name: "web"}, nil, nil)
+
+			entry, err := consuladapter.GetServiceRouter(entries, "web", nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(entry.Name).To(Equal("web"))
+		})
+
+		It("fails when the entry is not of the kind's type", func() {
+			entries.GetReturns(&api.ServiceConfigEntry{Kind: api.ServiceDefaults, Name: "web"}, nil, nil)
+
+			_, err := consuladapter.GetServiceRouter(entries, "web", nil)
+			Expect(err).To(MatchError("unexpected config entry type *api.ServiceConfigEntry for kind service-router"))
+		})
+
+		It("returns other errors as they are", func() {
+			entries.GetReturns(nil, nil, errors.New("boom"))
+
+			_, err := consuladapter.GetProxyDefaults(entries, nil)
+			Expect(err).To(MatchError("boom"))
+		})
+	})
+
+	Describe("List", func() {
+		It("lists the entries of the kind, skipping entries of other types", func() {
+			entries.ListReturns([]api.ConfigEntry{
+				&api.ServiceConfigEntry{Kind: api.ServiceDefaults, Name: "web"},
+				&api.ServiceRouterConfigEntry{Kind: api.ServiceRouter, Name: "web"},
+				&api.ServiceConfigEntry{Kind: api.ServiceDefaults, Name: "api"},
+			}, &api.QueryMeta{LastIndex: 7}, nil)
+
+			list, meta, err := consuladapter.ListServiceDefaultsWithMeta(entries, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(list).To(HaveLen(2))
+			Expect(list[0].Name).To(Equal("web"))
+			Expect(list[1].Name).To(Equal("api"))
+			Expect(meta.LastIndex).To(BeEquivalentTo(7))
+
+			kind, _ := entries.ListArgsForCall(0)
+			Expect(kind).To(Equal(api.ServiceDefaults))
+		})
+
+		It("lists service routers", func() {
+			entries.ListReturns([]api.ConfigEntry{&api.ServiceRouterConfigEntry{Kind: api.ServiceRouter, Name: "web"}}, nil, nil)
+
+			routers, err := consuladapter.ListServiceRouters(entries, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(routers).To(HaveLen(1))
+
+			kind, _ := entries.ListArgsForCall(0)
+			Expect(kind).To(Equal(api.ServiceRouter))
+		})
+
+		It("returns an empty list when there are none, and errors as they are", func() {
+			routers, err := consuladapter.ListServiceRouters(entries, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(routers).To(BeEmpty())
+
+			entries.ListReturns(nil, nil, errors.New("boom"))
+			_, err = consuladapter.ListServiceDefaults(entries, nil)
+			Expect(err).To(MatchError("boom"))
+		})
+	})
+
+	Describe("SetConfigEntry", func() {
+		set := func(entry api.ConfigEntry) api.ConfigEntry {
+			Expect(consuladapter.SetConfigEntry(entries, entry, nil)).To(Succeed())
+			written, _ := entries.SetArgsForCall(entries.SetCallCount() - 1)
+			return written
+		}
+
+		It("fills in the kind from the entry's type", func() {
+			Expect(set(&api.ServiceConfigEntry{Name: "web"}).GetKind()).To(Equal(api.ServiceDefaults))
+			Expect(set(&api.ServiceRouterConfigEntry{Name: "web"}).GetKind()).To(Equal(api.ServiceRouter))
+		})
+
+		It("names proxy defaults global by default", func() {
+			written := set(&api.ProxyConfigEntry{})
+			Expect(written.GetKind()).To(Equal(api.ProxyDefaults))
+			Expect(written.GetName()).To(Equal(api.ProxyConfigGlobal))
+		})
+
+		It("keeps a kind and name that are already set", func() {
+			written := set(&api.ProxyConfigEntry{Kind: "custom", Name: "other"})
+			Expect(written.GetKind()).To(Equal("custom"))
+			Expect(written.GetName()).To(Equal("other"))
+		})
+
+		It("returns the error from writing the entry", func() {
+			entries.SetReturns(false, nil, errors.New("boom"))
+			Expect(consuladapter.SetConfigEntry(entries, &api.ServiceConfigEntry{Name: "web"}, nil)).To(MatchError("boom"))
+		})
+	})
+
+	Describe("DeleteConfigEntry", func() {
+		It("deletes the entry of the kind and name", func() {
+			Expect(consuladapter.DeleteConfigEntry(entries, api.ServiceRouter, "web", nil)).To(Succeed())
+
+			kind, name, _ := entries.DeleteArgsForCall(0)
+			Expect(kind).To(Equal(api.ServiceRouter))
+			Expect(name).To(Equal("web"))
+		})
+
+		It("returns the error from deleting the entry", func() {
+			entries.DeleteReturns(nil, errors.New("boom"))
+			Expect(consuladapter.DeleteConfigEntry(entries, api.ServiceDefaults, "web", nil)).To(MatchError("boom"))
+		})
+	})
+})
@@ -0,0 +1,185 @@
+package consuladapter
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+type ConnectionState int
+
+const (
+	Disconnected ConnectionState = iota
+	Connected
+	// Degraded follows Disconnected once the agent has been unreachable for
+	// ConnectionMonitorConfig.DegradedAfter.
+	Degraded
+)
+
+func (s ConnectionState) String() string {
+	switch s {
+	case Connected:
+		return "connected"
+	case Degraded:
+		return "degraded"
+	default:
+		return "disconnected"
+	}
+}
+
+type ConnectionEvent struct {
+	State ConnectionState
+	// Err is the latest probe failure when State is Disconnected or Degraded.
+	Err error
+}
+
+type DisconnectedError struct {
+	Err error
+}
+
+func (e *DisconnectedError) Error() string {
+	return fmt.Sprintf("consul agent unreachable: %s", e.Err)
+}
+
+const (
+	defaultConnectionPollInterval = 5 * time.Second
+	defaultReconnectInitial       = 100 * time.Millisecond
+	defaultReconnectMax           = 30 * time.Second
+	defaultConnectionEventBuffer  = 16
+)
+
+type ConnectionMonitorConfig struct {
+	// PollInterval is how often the agent is probed while connected.
+	PollInterval time.Duration
+	// Backoff paces probes while disconnected.
+	Backoff BackoffRetry
+	// DegradedAfter, if set, is how long the agent must stay unreachable
+	// before the monitor reports Degraded.
+	DegradedAfter time.Duration
+	// EventBuffer is how many events Events holds for a slow receiver
+	// before dropping them. Zero means 16.
+	EventBuffer int
+}
+
+// ConnectionMonitor is an ifrit.Runner that probes the agent, backing off
+// exponentially while it is unreachable. Callers can check Err before
+// talking to consul rather than each hitting the same connection error.
+type ConnectionMonitor struct {
+	client Client
+	config ConnectionMonitorConfig
+	events chan ConnectionEvent
+
+	mu                sync.RWMutex
+	probed            bool
+	state             ConnectionState
+	lastErr           error
+	disconnectedSince time.Time
+}
+
+func NewConnectionMonitor(client Client, config ConnectionMonitorConfig) *ConnectionMonitor {
+	if config.PollInterval <= 0 {
+		config.PollInterval = defaultConnectionPollInterval
+	}
+	if config.Backoff.Initial <= 0 {
+		config.Backoff.Initial = defaultReconnectInitial
+	}
+	if config.Backoff.Max <= 0 {
+		config.Backoff.Max = defaultReconnectMax
+	}
+	if config.EventBuffer <= 0 {
+		config.EventBuffer = defaultConnectionEventBuffer
+	}
+
+	return &ConnectionMonitor{
+		client:  client,
+		config:  config,
+		events:  make(chan ConnectionEvent, config.EventBuffer),
+		lastErr: fmt.Errorf("not yet probed"),
+	}
+}
+
+// Events delivers state transitions. Events are dropped, and counted by
+// DroppedEvents, if the receiver falls behind; State always reflects the
+// latest probe.
+func (m *ConnectionMonitor) Events() <-chan ConnectionEvent {
+	return m.events
+}
+
+func (m *ConnectionMonitor) State() ConnectionState {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.state
+}
+
+// Err returns a *DisconnectedError while the agent is unreachable, whether
+// Disconnected or Degraded, and nil otherwise.
+func (m *ConnectionMonitor) Err() error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.state == Connected {
+		return nil
+	}
+	return &DisconnectedError{Err: m.lastErr}
+}
+
+func (m *ConnectionMonitor) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
+	attempt := 0
+	if m.probe() != nil {
+		attempt++
+	}
+	close(ready)
+
+	for {
+		delay := m.config.PollInterval
+		if attempt > 0 {
+			delay = m.config.Backoff.Delay(attempt)
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-signals:
+			timer.Stop()
+			return nil
+		case <-timer.C:
+		}
+
+		if m.probe() != nil {
+			attempt++
+		} else {
+			attempt = 0
+		}
+	}
+}
+
+func (m *ConnectionMonitor) probe() error {
+	_, err := m.client.Status().Leader()
+
+	m.mu.Lock()
+	state := Connected
+	if err != nil {
+		if !m.probed || m.state == Connected {
+			m.disconnectedSince = time.Now()
+		}
+		state = Disconnected
+		if m.config.DegradedAfter > 0 && time.Since(m.disconnectedSince) >= m.config.DegradedAfter {
+			state = Degraded
+		}
+	}
+
+	changed := !m.probed || state != m.state
+	m.probed = true
+	m.state = state
+	m.lastErr = err
+	m.mu.Unlock()
+
+	if changed {
+		select {
+		case m.events <- ConnectionEvent{State: state, Err: err}:
+		default:
+			countDroppedEvent(ConnectionEventsChannel)
+		}
+	}
+
+	return err
+}
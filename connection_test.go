@@ -0,0 +1,175 @@
+package consuladapter_test
+
+import (
+	"errors"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"code.cloudfoundry.org/consuladapter"
+	"code.cloudfoundry.org/consuladapter/fakes"
+	"github.com/hashicorp/consul/api"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ConnectionMonitor", func() {
+	var (
+		client  *fakes.FakeClient
+		status  *fakes.FakeStatus
+		monitor *consuladapter.ConnectionMonitor
+		signals chan os.Signal
+		done    chan error
+
+		probeErr    error
+		unreachable int32
+	)
+
+	BeforeEach(func() {
+		client, _ = fakes.NewFakeClient()
+		status = &fakes.FakeStatus{}
+		client.StatusReturns(status)
+
+		probeErr = errors.New("connection refused")
+		atomic.StoreInt32(&unreachable, 0)
+		status.LeaderStub = func() (string, error) {
+			if atomic.LoadInt32(&unreachable) == 1 {
+				return "", probeErr
+			}
+			return "127.0.0.1:8300", nil
+		}
+
+		monitor = consuladapter.NewConnectionMonitor(client, consuladapter.ConnectionMonitorConfig{
+			PollInterval:  time.Millisecond,
+			Backoff:       consuladapter.BackoffRetry{Initial: time.Millisecond, Max: 4 * time.Millisecond},
+			DegradedAfter: 50 * time.Millisecond,
+		})
+		signals = make(chan os.Signal)
+		done = make(chan error)
+	})
+
+	JustBeforeEach(func() {
+		ready := make(chan struct{})
+		go func() {
+			done <- monitor.Run(signals, ready)
+		}()
+		Eventually(ready).Should(BeClosed())
+	})
+
+	AfterEach(func() {
+		signals <- os.Interrupt
+		Eventually(done).Should(Receive(BeNil()))
+	})
+
+	Context("when the agent is reachable", func() {
+		It("reports connected", func() {
+			Expect(monitor.State()).To(Equal(consuladapter.Connected))
+			Expect(monitor.Err()).NotTo(HaveOccurred())
+			Expect(monitor.Events()).To(Receive(Equal(consuladapter.ConnectionEvent{State: consuladapter.Connected})))
+		})
+	})
+
+	Context("when the agent is unreachable", func() {
+		BeforeEach(func() {
+			atomic.StoreInt32(&unreachable, 1)
+		})
+
+		It("reports disconnected until a probe succeeds", func() {
+			Expect(monitor.State()).To(Equal(consuladapter.Disconnected))
+			Expect(monitor.Err()).To(Equal(&consuladapter.DisconnectedError{Err: probeErr}))
+			Expect(monitor.Events()).To(Receive(Equal(consuladapter.ConnectionEvent{State: consuladapter.Disconnected, Err: probeErr})))
+
+			atomic.StoreInt32(&unreachable, 0)
+			Eventually(monitor.Events()).Should(Receive(Equal(consuladapter.ConnectionEvent{State: consuladapter.Connected})))
+			Expect(monitor.Err()).NotTo(HaveOccurred())
+		})
+
+		It("reports degraded once the agent stays unreachable", func() {
+			Eventually(monitor.Events()).Should(Receive(Equal(consuladapter.ConnectionEvent{State: consuladapter.Degraded, Err: probeErr})))
+			Expect(monitor.Err()).To(Equal(&consuladapter.DisconnectedError{Err: probeErr}))
+		})
+
+		Describe("NewDegradableKV", func() {
+			var kv *fakes.FakeKV
+
+			BeforeEach(func() {
+				kv = &fakes.FakeKV{}
+			})
+
+			It("rejects writes and allows stale reads while degraded", func() {
+				degradable := consuladapter.NewDegradableKV(kv, monitor)
+				Eventually(monitor.State).Should(Equal(consuladapter.Degraded))
+
+				_, err := degradable.Put(&api.KVPair{Key: "key"}, nil)
+				Expect(err).To(Equal(consuladapter.ErrReadOnly))
+				Expect(kv.PutCallCount()).To(Equal(0))
+
+				_, _, err = degradable.Get("key", &api.QueryOptions{WaitIndex: 3})
+				Expect(err).NotTo(HaveOccurred())
+				_, q := kv.GetArgsForCall(0)
+				Expect(q.AllowStale).To(BeTrue())
+				Expect(q.WaitIndex).To(BeEquivalentTo(3))
+			})
+
+			It("serves the last good reads from its cache while degraded", func() {
+				atomic.StoreInt32(&unreachable, 0)
+				Eventually(monitor.State).Should(Equal(consuladapter.Connected))
+
+				degradable := consuladapter.NewCachingDegradableKV(kv, monitor)
+				kv.GetReturns(&api.KVPair{Key: "key", Value: []byte("value")}, &api.QueryMeta{LastIndex: 7}, nil)
+				kv.ListReturns(api.KVPairs{{Key: "prefix/a"}}, &api.QueryMeta{LastIndex: 8}, nil)
+				_, _, err := degradable.Get("key", nil)
+				Expect(err).NotTo(HaveOccurred())
+				_, _, err = degradable.List("prefix/", nil)
+				Expect(err).NotTo(HaveOccurred())
+
+				kv.GetReturns(nil, nil, errors.New("connection refused"))
+				kv.ListReturns(nil, nil, errors.New("connection refused"))
+				_, _, err = degradable.Get("key", nil)
+				Expect(err).To(MatchError("connection refused"))
+
+				atomic.StoreInt32(&unreachable, 1)
+				Eventually(monitor.State).Should(Equal(consuladapter.Degraded))
+
+				pair, meta, err := degradable.Get("key", nil)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(pair.Value).To(Equal([]byte("value")))
+				Expect(meta.LastIndex).To(BeEquivalentTo(7))
+
+				pairs, _, err := degradable.List("prefix/", nil)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(pairs).To(HaveLen(1))
+
+				_, _, err = degradable.Get("other", nil)
+				Expect(err).To(MatchError("connection refused"))
+			})
+		})
+	})
+
+	Context("when events are not received", func() {
+		var dropped uint64
+
+		BeforeEach(func() {
+			dropped = consuladapter.DroppedEvents()[consuladapter.ConnectionEventsChannel]
+			monitor = consuladapter.NewConnectionMonitor(client, consuladapter.ConnectionMonitorConfig{
+				PollInterval: time.Millisecond,
+				Backoff:      consuladapter.BackoffRetry{Initial: time.Millisecond, Max: time.Millisecond},
+				EventBuffer:  1,
+			})
+		})
+
+		It("drops and counts them rather than blocking probes", func() {
+			atomic.StoreInt32(&unreachable, 1)
+			Eventually(monitor.State).Should(Equal(consuladapter.Disconnected))
+
+			Eventually(func() uint64 {
+				return consuladapter.DroppedEvents()[consuladapter.ConnectionEventsChannel]
+			}).Should(BeNumerically(">", dropped))
+
+			atomic.StoreInt32(&unreachable, 0)
+			Eventually(monitor.State).Should(Equal(consuladapter.Connected))
+			Expect(monitor.Events()).To(Receive(Equal(consuladapter.ConnectionEvent{State: consuladapter.Connected})))
+		})
+	})
+})
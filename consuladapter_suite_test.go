@@ -1,6 +1,8 @@
 package consuladapter_test
 
 import (
+	"os"
+
 	"code.cloudfoundry.org/consuladapter/consulrunner"
 
 	. "github.com/onsi/ginkgo"
@@ -20,6 +22,11 @@ const clusterSize = 1
 var clusterRunner *consulrunner.ClusterRunner
 
 var _ = BeforeSuite(func() {
-	clusterStartingPort := 5001 + config.GinkgoConfig.ParallelNode*consulrunner.PortOffsetLength*clusterSize
+	if os.Getenv(consulrunner.ReleaseKeyEnv) != "" {
+		_, err := consulrunner.EnsureConsulBinary("")
+		Expect(err).NotTo(HaveOccurred())
+	}
+
+	clusterStartingPort := 5001 + config.GinkgoConfig.ParallelNode*consulrunner.PortsPerNode*clusterSize
 	clusterRunner = consulrunner.NewClusterRunner(clusterStartingPort, clusterSize, "http")
 })
@@ -0,0 +1,132 @@
+package agentlog
+
+import (
+	"bufio"
+	"bytes"
+	"regexp"
+	"strings"
+	"time"
+)
+
+type EventType string
+
+const (
+	ElectionWon        EventType = "election-won"
+	LeaderElected      EventType = "leader-elected"
+	LeadershipAcquired EventType = "leadership-acquired"
+	LeadershipLost     EventType = "leadership-lost"
+	MemberJoin         EventType = "member-join"
+	MemberLeave        EventType = "member-leave"
+	MemberFailed       EventType = "member-failed"
+	MemberUpdate       EventType = "member-update"
+	MemberReap         EventType = "member-reap"
+)
+
+type Event struct {
+	Node    int
+	Time    time.Time
+	Level   string
+	Type    EventType
+	Subject string
+	Line    string
+}
+
+type Events []Event
+
+func (events Events) OfType(t EventType) Events {
+	filtered := Events{}
+	for _, event := range events {
+		if event.Type == t {
+			filtered = append(filtered, event)
+		}
+	}
+
+	return filtered
+}
+
+func (events Events) ForNode(node int) Events {
+	filtered := Events{}
+	for _, event := range events {
+		if event.Node == node {
+			filtered = append(filtered, event)
+		}
+	}
+
+	return filtered
+}
+
+func (events Events) Count(t EventType) int {
+	return len(events.OfType(t))
+}
+
+var (
+	levelRegexp  = regexp.MustCompile(`\[(TRACE|DEBUG|INFO|WARN|ERR|ERROR)\]`)
+	leaderRegexp = regexp.MustCompile(`(?i)new leader elected: (?:payload=)?(\S+)`)
+	memberRegexp = regexp.MustCompile(`EventMember(Join|Leave|Failed|Update|Reap): (\S+)`)
+	memberTypes  = map[string]EventType{"Join": MemberJoin, "Leave": MemberLeave, "Failed": MemberFailed, "Update": MemberUpdate, "Reap": MemberReap}
+	timeLayouts  = []string{"2006/01/02 15:04:05", "2006-01-02T15:04:05.000Z0700", "2006-01-02T15:04:05.000Z07:00"}
+)
+
+// ParseLine converts a single consul agent log line into an Event. It
+// returns false for lines that do not describe a recognized event.
+func ParseLine(node int, line string) (Event, bool) {
+	event := Event{Node: node, Line: line}
+	lower := strings.ToLower(line)
+
+	if match := memberRegexp.FindStringSubmatch(line); match != nil {
+		event.Type = memberTypes[match[1]]
+		event.Subject = match[2]
+	} else if match := leaderRegexp.FindStringSubmatch(line); match != nil {
+		event.Type = LeaderElected
+		event.Subject = match[1]
+	} else if strings.Contains(lower, "election won") {
+		event.Type = ElectionWon
+	} else if strings.Contains(lower, "cluster leadership acquired") {
+		event.Type = LeadershipAcquired
+	} else if strings.Contains(lower, "cluster leadership lost") {
+		event.Type = LeadershipLost
+	} else {
+		return Event{}, false
+	}
+
+	if match := levelRegexp.FindStringSubmatch(line); match != nil {
+		event.Level = match[1]
+	}
+	event.Time = parseTime(line)
+
+	return event, true
+}
+
+// Parse extracts all recognized events from the output of a single agent.
+func Parse(node int, output []byte) Events {
+	events := Events{}
+
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		event, ok := ParseLine(node, scanner.Text())
+		if ok {
+			events = append(events, event)
+		}
+	}
+
+	return events
+}
+
+func parseTime(line string) time.Time {
+	fields := strings.Fields(line)
+	for _, layout := range timeLayouts {
+		candidate := ""
+		if strings.Contains(layout, " ") && len(fields) >= 2 {
+			candidate = fields[0] + " " + fields[1]
+		} else if len(fields) >= 1 {
+			candidate = fields[0]
+		}
+
+		t, err := time.Parse(layout, candidate)
+		if err == nil {
+			return t
+		}
+	}
+
+	return time.Time{}
+}
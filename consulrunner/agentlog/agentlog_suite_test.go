@@ -0,0 +1,13 @@
+package agentlog_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestAgentlog(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Agentlog Suite")
+}
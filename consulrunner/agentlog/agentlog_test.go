@@ -0,0 +1,64 @@
+package agentlog_test
+
+import (
+	"time"
+
+	"code.cloudfoundry.org/consuladapter/consulrunner/agentlog"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Agentlog", func() {
+	Describe("ParseLine", func() {
+		It("ignores unrecognized lines", func() {
+			_, ok := agentlog.ParseLine(0, "    2016/10/10 12:00:00 [INFO] agent: Synced service 'consul'")
+			Expect(ok).To(BeFalse())
+		})
+
+		It("parses election wins", func() {
+			event, ok := agentlog.ParseLine(1, "    2016/10/10 12:00:01 [INFO] raft: Election won. Tally: 2")
+			Expect(ok).To(BeTrue())
+			Expect(event.Node).To(Equal(1))
+			Expect(event.Type).To(Equal(agentlog.ElectionWon))
+			Expect(event.Level).To(Equal("INFO"))
+			Expect(event.Time).To(Equal(time.Date(2016, 10, 10, 12, 0, 1, 0, time.UTC)))
+		})
+
+		It("parses the elected leader in both log formats", func() {
+			event, ok := agentlog.ParseLine(0, "    2016/10/10 12:00:01 [INFO] consul: New leader elected: 2")
+			Expect(ok).To(BeTrue())
+			Expect(event.Type).To(Equal(agentlog.LeaderElected))
+			Expect(event.Subject).To(Equal("2"))
+
+			event, ok = agentlog.ParseLine(0, "2020-01-02T03:04:05.678Z [INFO]  agent.server: New leader elected: payload=node-2")
+			Expect(ok).To(BeTrue())
+			Expect(event.Type).To(Equal(agentlog.LeaderElected))
+			Expect(event.Subject).To(Equal("node-2"))
+			Expect(event.Time.IsZero()).To(BeFalse())
+		})
+
+		It("parses membership changes", func() {
+			event, ok := agentlog.ParseLine(0, "    2016/10/10 12:00:01 [INFO] serf: EventMemberFailed: 1 127.0.0.1")
+			Expect(ok).To(BeTrue())
+			Expect(event.Type).To(Equal(agentlog.MemberFailed))
+			Expect(event.Subject).To(Equal("1"))
+		})
+	})
+
+	Describe("Parse", func() {
+		It("returns the events found in the output", func() {
+			output := []byte(`    2016/10/10 12:00:00 [INFO] serf: EventMemberJoin: 0 127.0.0.1
+    2016/10/10 12:00:01 [DEBUG] raft: Votes needed: 1
+    2016/10/10 12:00:01 [INFO] raft: Election won. Tally: 1
+    2016/10/10 12:00:01 [INFO] consul: cluster leadership acquired
+`)
+
+			events := agentlog.Parse(0, output)
+			Expect(events).To(HaveLen(3))
+			Expect(events.Count(agentlog.ElectionWon)).To(Equal(1))
+			Expect(events.OfType(agentlog.MemberJoin)[0].Subject).To(Equal("0"))
+			Expect(events.ForNode(1)).To(BeEmpty())
+		})
+	})
+})
@@ -0,0 +1,43 @@
+package agentmetrics
+
+import (
+	"strings"
+
+	"github.com/hashicorp/consul/api"
+)
+
+// metricMatches matches either the full metric name or its name without the
+// hostname prefix consul adds unless telemetry.disable_hostname is set.
+func metricMatches(metricName, name string) bool {
+	return metricName == name || strings.HasSuffix(metricName, "."+name)
+}
+
+func FindSample(metrics *api.MetricsInfo, name string) (api.SampledValue, bool) {
+	for _, sample := range metrics.Samples {
+		if metricMatches(sample.Name, name) {
+			return sample, true
+		}
+	}
+
+	return api.SampledValue{}, false
+}
+
+func FindCounter(metrics *api.MetricsInfo, name string) (api.SampledValue, bool) {
+	for _, counter := range metrics.Counters {
+		if metricMatches(counter.Name, name) {
+			return counter, true
+		}
+	}
+
+	return api.SampledValue{}, false
+}
+
+func FindGauge(metrics *api.MetricsInfo, name string) (api.GaugeValue, bool) {
+	for _, gauge := range metrics.Gauges {
+		if metricMatches(gauge.Name, name) {
+			return gauge, true
+		}
+	}
+
+	return api.GaugeValue{}, false
+}
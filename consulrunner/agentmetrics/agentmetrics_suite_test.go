@@ -0,0 +1,13 @@
+package agentmetrics_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestAgentmetrics(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Agentmetrics Suite")
+}
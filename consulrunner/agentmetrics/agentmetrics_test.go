@@ -0,0 +1,41 @@
+package agentmetrics_test
+
+import (
+	"code.cloudfoundry.org/consuladapter/consulrunner/agentmetrics"
+	"github.com/hashicorp/consul/api"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Agentmetrics", func() {
+	metrics := &api.MetricsInfo{
+		Samples:  []api.SampledValue{{Name: "consul.node-0.consul.kvs.apply", Count: 3}},
+		Counters: []api.SampledValue{{Name: "consul.rpc.request", Count: 7}},
+		Gauges:   []api.GaugeValue{{Name: "consul.node-0.runtime.num_goroutines", Value: 42}},
+	}
+
+	It("finds metrics by their full name", func() {
+		counter, ok := agentmetrics.FindCounter(metrics, "consul.rpc.request")
+		Expect(ok).To(BeTrue())
+		Expect(counter.Count).To(Equal(7))
+	})
+
+	It("finds metrics by their name without the hostname prefix", func() {
+		sample, ok := agentmetrics.FindSample(metrics, "consul.kvs.apply")
+		Expect(ok).To(BeTrue())
+		Expect(sample.Count).To(Equal(3))
+
+		gauge, ok := agentmetrics.FindGauge(metrics, "runtime.num_goroutines")
+		Expect(ok).To(BeTrue())
+		Expect(gauge.Value).To(BeEquivalentTo(42))
+	})
+
+	It("only matches whole parts of names, and only metrics of the kind asked for", func() {
+		_, ok := agentmetrics.FindCounter(metrics, "pc.request")
+		Expect(ok).To(BeFalse())
+
+		_, ok = agentmetrics.FindSample(metrics, "consul.rpc.request")
+		Expect(ok).To(BeFalse())
+	})
+})
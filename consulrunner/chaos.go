@@ -0,0 +1,80 @@
+package consulrunner
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"time"
+
+	"github.com/tedsuo/ifrit"
+)
+
+const (
+	DefaultChaosInterval = 5 * time.Second
+	DefaultChaosDowntime = 2 * time.Second
+)
+
+type ChaosConfig struct {
+	// Interval is how long all nodes stay up between faults. Defaults to
+	// DefaultChaosInterval.
+	Interval time.Duration
+
+	// Downtime is how long each faulted node stays down. Defaults to
+	// DefaultChaosDowntime.
+	Downtime time.Duration
+
+	// Seed picks which node each fault stops, so that runs can be repeated.
+	Seed int64
+}
+
+// ChaosRunner returns an ifrit.Runner that injects faults into the running
+// cluster: after every Interval it stops a node picked at random and starts
+// it again after Downtime, keeping its data. Only one node is down at a
+// time, so a cluster of three or more servers keeps its quorum. It is ready
+// straight away and exits when signalled, first restarting any node it has
+// stopped, or with the error of a node that fails to stop or start.
+//
+// Run it alongside clients under test, e.g. with ifrit.Invoke, to check that
+// they keep their guarantees while agents restart and leaders change.
+func (cr *ClusterRunner) ChaosRunner(config ChaosConfig) ifrit.Runner {
+	interval := config.Interval
+	if interval <= 0 {
+		interval = DefaultChaosInterval
+	}
+	downtime := config.Downtime
+	if downtime <= 0 {
+		downtime = DefaultChaosDowntime
+	}
+
+	return ifrit.RunFunc(func(signals <-chan os.Signal, ready chan<- struct{}) error {
+		random := rand.New(rand.NewSource(config.Seed))
+		close(ready)
+
+		for {
+			select {
+			case <-signals:
+				return nil
+			case <-time.After(interval):
+			}
+
+			index := random.Intn(cr.NodeCount())
+			err := cr.TryStopNode(index)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(cr.output, "chaos: stopped consul agent %d for %s\n", index, downtime)
+
+			select {
+			case <-signals:
+				return cr.TryStartNode(index)
+			case <-time.After(downtime):
+			}
+
+			err = cr.TryStartNode(index)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(cr.output, "chaos: restarted consul agent %d\n", index)
+		}
+	})
+}
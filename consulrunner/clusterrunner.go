@@ -1,57 +1,366 @@
 package consulrunner
 
 import (
+	"context"
+	"crypto/tls"
 	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"net/http"
+	"net/url"
 	"os"
 	"os/exec"
-	"path"
+	"path/filepath"
+	"regexp"
 	"strings"
 	"sync"
 	"time"
 
 	"code.cloudfoundry.org/cfhttp"
 	"code.cloudfoundry.org/consuladapter"
+	"code.cloudfoundry.org/consuladapter/agentconfig"
+	"code.cloudfoundry.org/consuladapter/consulrunner/agentlog"
+	"code.cloudfoundry.org/consuladapter/consulrunner/internal/cluster"
 	"github.com/hashicorp/consul/api"
 	"github.com/tedsuo/ifrit"
-	"github.com/tedsuo/ifrit/ginkgomon"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
-	"github.com/onsi/gomega/gbytes"
-	"github.com/onsi/gomega/gexec"
+)
+
+const PortsPerNode = agentconfig.PortsPerNode
+
+// Deprecated: use Ports. Each node now uses PortsPerNode ports, 8 rather
+// than 6.
+const (
+	PortOffsetHTTP   = agentconfig.PortOffsetHTTP
+	PortOffsetLength = agentconfig.PortOffsetLength
 )
 
 type ClusterRunner struct {
-	startingPort    int
+	// startingPort moves when TryStart retries on the next port block. It
+	// has its own mutex so that Ports and NodeAddress can read it from
+	// methods that already hold mutex.
+	startingPort int
+	portMutex    sync.RWMutex
+
 	numNodes        int
+	startAttempts   int
+	portStride      int
 	consulProcesses []ifrit.Process
+	consulRunners   []*cluster.AgentRunner
 	running         bool
 	dataDir         string
 	configDir       string
 	scheme          string
 	sessionTTL      time.Duration
+	resourceLimits  ResourceLimits
+	logLevel        string
+	telemetry       *TelemetryConfig
+	tuning          *TuningConfig
+	bindAddress     string
+	advertiseAddr   string
+	artifactsDir    string
+	fixturePath     string
+	tempDir         string
+	minFreeDisk     uint64
+	namespaces      []string
+	segments        []string
+	configFilePaths []string
+	cleanups        []func() error
+	tls             *agentconfig.TLSFiles
+	verifyTLS       bool
+	sessionPrefix   string
+	enableUI        bool
+	agentArgs       []string
+	agentEnv        []string
+	aclToken        string
+	tokenCount      int
+	output          io.Writer
+
+	// externalAddress is the address of a cluster the runner is attached
+	// to rather than running; see TryAttachClusterRunner.
+	externalAddress string
 
 	mutex *sync.RWMutex
 }
 
+type ResourceLimits = cluster.ResourceLimits
+type PortLayout = agentconfig.PortLayout
+type TelemetryConfig = agentconfig.TelemetryConfig
+type TuningConfig = agentconfig.TuningConfig
+
+type Fixture = cluster.Fixture
+type Topology = cluster.Topology
+type NodeTopology = cluster.NodeTopology
+
+type ConfigFile = agentconfig.ConfigFile
+type CheckDefinition = agentconfig.CheckDefinition
+
+type ResetOptions = cluster.ResetOptions
+
+var ScopedResetOptions = cluster.ScopedResetOptions
+
+var ErrEmptyResetPrefix = cluster.ErrEmptyResetPrefix
+
+type ResetError = cluster.ResetError
+type ResetFailure = cluster.ResetFailure
+
+// PortInUseError is returned by TryStart when a port the cluster needs is
+// taken on its last start attempt.
+type PortInUseError = cluster.PortInUseError
+
+var FastConvergence = agentconfig.FastConvergence
+
+type ClusterRunnerConfig struct {
+	StartingPort int
+	NumNodes     int
+	Scheme       string
+
+	// SessionTTL is the minimum session TTL (session_ttl_min) accepted by the
+	// agents. Defaults to DefaultSessionTTL.
+	SessionTTL time.Duration
+
+	ResourceLimits ResourceLimits
+
+	// LogLevel is the agents' log_level. Defaults to "info".
+	LogLevel string
+
+	Telemetry *TelemetryConfig
+
+	// Tuning adjusts raft, autopilot and gossip timings; see FastConvergence.
+	Tuning *TuningConfig
+
+	// BindAddress is the address agents bind to, including their HTTP API.
+	// Defaults to 127.0.0.1; use ::1 for IPv6-only environments.
+	BindAddress      string
+	AdvertiseAddress string
+
+	// ArtifactsDir enables the agents' debug endpoints and is where
+	// CaptureDiagnosticsOnFailure writes its output.
+	ArtifactsDir string
+
+	// FixturePath, when set, is loaded into the cluster by Start once it has
+	// elected a leader. See ExportFixture.
+	FixturePath string
+
+	// TempDir is where the agents' data and config directories are created,
+	// e.g. a tmpfs. Defaults to the system temporary directory. Start fails
+	// unless it has at least MinFreeDisk bytes free, which defaults to
+	// 256 MiB.
+	TempDir     string
+	MinFreeDisk uint64
+
+	// Namespaces are created by Start once the cluster has elected a leader.
+	// They require a Consul Enterprise binary; Start fails otherwise.
+	Namespaces []string
+
+	// Segments are network segments configured on every server. Each
+	// segment takes one extra port per node, allocated after the ports of
+	// all nodes, so leave room for them when running clusters in parallel.
+	// They require a Consul Enterprise binary; Start fails otherwise.
+	Segments []string
+
+	// VerifyTLS configures the agents of an https cluster, which use a
+	// generated CA and agent certificate, to require certificates signed by
+	// that CA from clients and from each other. Clients from NewClient and
+	// APIConfig present the agent certificate.
+	VerifyTLS bool
+
+	// EnableUI serves consul's web UI from every agent, for inspecting the
+	// cluster in a browser while debugging a test; see UIURL.
+	EnableUI bool
+
+	// ACLs enables ACLs with a default policy of deny, bootstrapped with a
+	// generated management token; see ManagementToken. Clients from
+	// NewClient use that token, and CreateToken mints scoped ones.
+	ACLs bool
+
+	// AgentArgs are appended to every agent's command line, e.g. "-ui" or
+	// "-raft-protocol=3", to test against feature flags. AgentEnv adds
+	// "NAME=value" variables to the agents' environment.
+	AgentArgs []string
+	AgentEnv  []string
+
+	// Reset destroys every session on a cluster the runner started. On one
+	// attached with TryAttachClusterRunner, and so possibly shared with
+	// other users, it destroys only sessions the adapter created, whose
+	// names start with consuladapter.SessionNamePrefix. SessionNamePrefix,
+	// if set, limits Reset to sessions named with it instead on either.
+	SessionNamePrefix string
+
+	// StartAttempts is how many times Start tries to bring the cluster up
+	// when a port it needs is taken, moving to the next port block after
+	// each failure. Defaults to DefaultStartAttempts.
+	StartAttempts int
+
+	// Output receives the agents' output, each line prefixed with its node,
+	// and the runner's own progress messages. Defaults to GinkgoWriter in a
+	// test binary and to ioutil.Discard elsewhere.
+	Output io.Writer
+
+	// PortRetryStride is how far each retry moves StartingPort. Defaults to
+	// the ports the cluster takes, including segment ports; when running in
+	// parallel, make it a multiple of the spacing between clusters so
+	// retries do not land on another cluster's ports.
+	PortRetryStride int
+}
+
+var artifactNameRegexp = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+
 const defaultDataDirPrefix = "consul_data"
 const defaultConfigDirPrefix = "consul_config"
+const defaultStartTimeout = 10 * time.Second
+const defaultStopTimeout = 5 * time.Second
+
+const DefaultSessionTTL = 5 * time.Second
+const DefaultStartAttempts = 3
 
 func NewClusterRunner(startingPort int, numNodes int, scheme string) *ClusterRunner {
-	Expect(startingPort).To(BeNumerically(">", 0))
-	Expect(startingPort).To(BeNumerically("<", 1<<16))
-	Expect(numNodes).To(BeNumerically(">", 0))
+	return NewClusterRunnerWithConfig(ClusterRunnerConfig{
+		StartingPort: startingPort,
+		NumNodes:     numNodes,
+		Scheme:       scheme,
+	})
+}
+
+func NewClusterRunnerWithConfig(config ClusterRunnerConfig) *ClusterRunner {
+	cr, err := TryNewClusterRunner(config)
+	Expect(err).NotTo(HaveOccurred())
+	return cr
+}
+
+// TryNewClusterRunner is NewClusterRunnerWithConfig for use outside Ginkgo:
+// it and the other Try methods return errors instead of failing the current
+// spec. Agents are run with os/exec; set Output to see their output outside
+// a Ginkgo suite. See execrunner for a runner without Ginkgo at all.
+func TryNewClusterRunner(config ClusterRunnerConfig) (*ClusterRunner, error) {
+	if config.StartingPort <= 0 || config.StartingPort >= 1<<16 {
+		return nil, fmt.Errorf("invalid starting port %d", config.StartingPort)
+	}
+	if config.NumNodes <= 0 {
+		return nil, fmt.Errorf("invalid number of nodes %d", config.NumNodes)
+	}
+	if config.SessionTTL < 0 {
+		return nil, fmt.Errorf("invalid session TTL %s", config.SessionTTL)
+	}
+	if config.VerifyTLS && config.Scheme != "https" {
+		return nil, errors.New("VerifyTLS requires the https scheme")
+	}
+	if config.StartAttempts < 0 || config.PortRetryStride < 0 {
+		return nil, fmt.Errorf("invalid start attempts %d or port retry stride %d", config.StartAttempts, config.PortRetryStride)
+	}
+	if last := config.StartingPort + portSpan(config.NumNodes, len(config.Segments)) - 1; last >= 1<<16 {
+		return nil, fmt.Errorf("a cluster starting at port %d needs ports up to %d", config.StartingPort, last)
+	}
+
+	startAttempts := config.StartAttempts
+	if startAttempts == 0 {
+		startAttempts = DefaultStartAttempts
+	}
+
+	var aclToken string
+	if config.ACLs {
+		var err error
+		aclToken, err = cluster.NewToken()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	portStride := config.PortRetryStride
+	if portStride == 0 {
+		portStride = portSpan(config.NumNodes, len(config.Segments))
+	}
+
+	sessionTTL := config.SessionTTL
+	if sessionTTL == 0 {
+		sessionTTL = DefaultSessionTTL
+	}
+
+	logLevel := config.LogLevel
+	if logLevel == "" {
+		logLevel = agentconfig.DefaultLogLevel
+	}
+
+	minFreeDisk := config.MinFreeDisk
+	if minFreeDisk == 0 {
+		minFreeDisk = cluster.DefaultMinFreeDisk
+	}
+
+	output := config.Output
+	if output == nil {
+		output = defaultOutput()
+	}
 
 	return &ClusterRunner{
-		startingPort: startingPort,
-		numNodes:     numNodes,
-		scheme:       scheme,
-		sessionTTL:   5 * time.Second,
+		startingPort:   config.StartingPort,
+		numNodes:       config.NumNodes,
+		startAttempts:  startAttempts,
+		portStride:     portStride,
+		scheme:         config.Scheme,
+		sessionTTL:     sessionTTL,
+		resourceLimits: config.ResourceLimits,
+		logLevel:       logLevel,
+		telemetry:      config.Telemetry,
+		tuning:         config.Tuning,
+		bindAddress:    config.BindAddress,
+		advertiseAddr:  config.AdvertiseAddress,
+		artifactsDir:   config.ArtifactsDir,
+		fixturePath:    config.FixturePath,
+		tempDir:        config.TempDir,
+		minFreeDisk:    minFreeDisk,
+		namespaces:     config.Namespaces,
+		segments:       config.Segments,
+		verifyTLS:      config.VerifyTLS,
+		sessionPrefix:  config.SessionNamePrefix,
+		enableUI:       config.EnableUI,
+		agentArgs:      config.AgentArgs,
+		agentEnv:       config.AgentEnv,
+		aclToken:       aclToken,
+		output:         output,
 
 		mutex: &sync.RWMutex{},
+	}, nil
+}
+
+// TryAttachClusterRunner returns a runner for an already running cluster
+// whose HTTP API is at rawURL, e.g. "http://127.0.0.1:8500", rather than
+// one it runs itself. It is running from the start, and Start and Stop
+// leave the cluster as it is. Only methods that talk to the cluster
+// through its API, such as NewClient, URL and Reset, can be used; those
+// that manage nodes cannot.
+func TryAttachClusterRunner(rawURL string) (*ClusterRunner, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cluster URL '%s': %s", rawURL, err)
 	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Port() == "" {
+		return nil, fmt.Errorf("invalid cluster URL '%s': expected http(s)://host:port", rawURL)
+	}
+
+	return &ClusterRunner{
+		numNodes:        1,
+		scheme:          u.Scheme,
+		sessionTTL:      DefaultSessionTTL,
+		running:         true,
+		output:          defaultOutput(),
+		externalAddress: u.Host,
+
+		mutex: &sync.RWMutex{},
+	}, nil
+}
+
+func (cr *ClusterRunner) Running() bool {
+	cr.mutex.RLock()
+	defer cr.mutex.RUnlock()
+	return cr.running
+}
+
+func (cr *ClusterRunner) NodeCount() int {
+	return cr.numNodes
 }
 
 func (cr *ClusterRunner) SessionTTL() time.Duration {
@@ -59,186 +368,1077 @@ func (cr *ClusterRunner) SessionTTL() time.Duration {
 }
 
 func (cr *ClusterRunner) ConsulVersion() string {
-	cmd := exec.Command("consul", "-v")
-	session, err := gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+	version, err := cr.TryConsulVersion()
 	Expect(err).NotTo(HaveOccurred())
-	Eventually(session).Should(gexec.Exit(0))
-	Expect(session.Out).To(gbytes.Say("Consul v"))
-	lines := strings.Split(string(session.Out.Contents()), "\n")
-	versionLine := lines[0]
-	return strings.TrimPrefix(versionLine, "Consul v")
+	return version
+}
+
+func (cr *ClusterRunner) TryConsulVersion() (string, error) {
+	output, err := exec.Command(cluster.ConsulBinary(), "-v").Output()
+	if err != nil {
+		return "", err
+	}
+
+	return cluster.ParseVersion(string(output))
 }
 
 func (cr *ClusterRunner) HasPerformanceFlag() bool {
-	return !strings.HasPrefix(cr.ConsulVersion(), "0.6")
+	return cluster.HasPerformanceFlag(cr.ConsulVersion())
+}
+
+// IsEnterprise reports whether the consul binary on the PATH is a Consul
+// Enterprise build, which supports namespaces and admin partitions.
+func (cr *ClusterRunner) IsEnterprise() bool {
+	return cluster.IsEnterprise(cr.ConsulVersion())
 }
 
 func (cr *ClusterRunner) Start() {
+	cr.StartWithContext(context.Background())
+}
+
+// StartWithContext starts the cluster like Start, but waits for each agent
+// until ctx is done instead of for a fixed 10 seconds, or until ctx's
+// deadline if it has one. If an agent fails to start, the agents already
+// started are stopped before failing, or before retrying on the next port
+// block if a port was taken.
+func (cr *ClusterRunner) StartWithContext(ctx context.Context) {
+	Expect(cr.TryStart(ctx)).To(Succeed())
+}
+
+// TryStart is StartWithContext returning an error. On error, nothing is
+// left running and the agents' directories are removed.
+func (cr *ClusterRunner) TryStart(ctx context.Context) error {
 	cr.mutex.Lock()
 	defer cr.mutex.Unlock()
 
 	if cr.running {
-		return
+		return nil
 	}
 
-	tmpDir, err := ioutil.TempDir("", defaultDataDirPrefix)
-	Expect(err).NotTo(HaveOccurred())
-	cr.dataDir = tmpDir
+	version, err := cr.TryConsulVersion()
+	if err != nil {
+		return err
+	}
 
-	tmpDir, err = ioutil.TempDir("", defaultConfigDirPrefix)
-	Expect(err).NotTo(HaveOccurred())
-	cr.configDir = tmpDir
+	if len(cr.segments) > 0 && !cluster.IsEnterprise(version) {
+		return errors.New("network segments require a Consul Enterprise binary")
+	}
+
+	tempDirBase, err := cluster.TempDirBase(cr.tempDir, cr.minFreeDisk)
+	if err != nil {
+		return err
+	}
+
+	for attempt := 1; ; attempt++ {
+		err = cr.startCluster(ctx, tempDirBase, version)
+		if err == nil {
+			break
+		}
+
+		var portErr cluster.PortInUseError
+		startingPort := cr.firstPort()
+		nextPort := startingPort + cr.portStride
+		if attempt >= cr.startAttempts || !errors.As(err, &portErr) || ctx.Err() != nil || nextPort+portSpan(cr.numNodes, len(cr.segments)) > 1<<16 {
+			return err
+		}
+
+		fmt.Fprintf(cr.output, "consul cluster could not start on ports from %d (%v), retrying from %d\n", startingPort, err, nextPort)
+		cr.portMutex.Lock()
+		cr.startingPort = nextPort
+		cr.portMutex.Unlock()
+	}
+
+	cr.running = true
+
+	if len(cr.namespaces) > 0 || cr.fixturePath != "" || cr.aclToken != "" {
+		err = cr.TryWaitUntilReady(ctx)
+	}
+
+	if err == nil && len(cr.namespaces) > 0 {
+		err = cr.createNamespaces(cluster.IsEnterprise(version))
+	}
+
+	if err == nil && cr.fixturePath != "" {
+		err = cr.TryLoadFixture(cr.fixturePath)
+	}
+
+	if err != nil {
+		cr.teardown(ctx)
+		return err
+	}
+
+	return nil
+}
+
+// Runner returns an ifrit.Runner for the whole cluster, so it can run in a
+// group with the processes under test. It starts the cluster, is ready once
+// the cluster has elected a leader, and stops the cluster when signalled,
+// killing the agents on os.Kill. Nodes stopped with StopNode do not stop
+// the runner.
+func (cr *ClusterRunner) Runner() ifrit.Runner {
+	return ifrit.RunFunc(func(signals <-chan os.Signal, ready chan<- struct{}) error {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		started := make(chan error, 1)
+		go func() {
+			err := cr.TryStart(ctx)
+			if err == nil {
+				err = cr.TryWaitUntilReady(ctx)
+			}
+			started <- err
+		}()
+
+		select {
+		case err := <-started:
+			if err != nil {
+				cr.TryStop(ctx)
+				return err
+			}
+		case <-signals:
+			cancel()
+			<-started
+			return cr.TryStop(ctx)
+		}
+
+		close(ready)
+
+		if signal := <-signals; signal == os.Kill {
+			cancel()
+		}
+		return cr.TryStop(ctx)
+	})
+}
+
+// startCluster starts every agent on the current port block, or stops
+// them and removes their directories on error.
+func (cr *ClusterRunner) startCluster(ctx context.Context, tempDirBase, version string) error {
+	var err error
+	cr.dataDir, err = ioutil.TempDir(tempDirBase, defaultDataDirPrefix)
+	if err != nil {
+		return err
+	}
+
+	cr.configDir, err = ioutil.TempDir(tempDirBase, defaultConfigDirPrefix)
+	if err != nil {
+		os.RemoveAll(cr.dataDir)
+		return err
+	}
 
 	cr.consulProcesses = make([]ifrit.Process, cr.numNodes)
+	cr.consulRunners = make([]*cluster.AgentRunner, cr.numNodes)
+	cr.configFilePaths = make([]string, cr.numNodes)
+	cr.cleanups = make([]func() error, cr.numNodes)
+
+	err = cr.startNodes(ctx, version)
+	if err != nil {
+		cr.teardown(ctx)
+		return err
+	}
+
+	return nil
+}
+
+func (cr *ClusterRunner) startNodes(ctx context.Context, version string) error {
+	var err error
+	if cr.scheme == "https" {
+		cr.tls, err = cluster.GenerateTLS(cr.configDir, []string{cr.clientHost()})
+		if err != nil {
+			return err
+		}
+		cr.tls.Verify = cr.verifyTLS
+	}
 
 	for i := 0; i < cr.numNodes; i++ {
 		iStr := fmt.Sprintf("%d", i)
-		nodeDataDir := path.Join(cr.dataDir, iStr)
+		nodeDataDir := cr.nodeDataDir(i)
 		os.MkdirAll(nodeDataDir, 0700)
 
-		configFilePath := writeConfigFile(
-			cr.HasPerformanceFlag(),
-			cr.configDir,
-			nodeDataDir,
-			iStr,
-			cr.startingPort,
-			i,
-			cr.numNodes,
-			cr.sessionTTL,
-		)
-
-		process := ginkgomon.Invoke(ginkgomon.New(ginkgomon.Config{
-			Name:              fmt.Sprintf("consul_cluster[%d]", i),
-			AnsiColorCode:     "35m",
-			StartCheck:        "agent: Join completed.",
-			StartCheckTimeout: 10 * time.Second,
-			Command: exec.Command(
-				"consul",
-				"agent",
-				"--log-level", "trace",
-				"--config-file", configFilePath,
-			),
-		}))
-		cr.consulProcesses[i] = process
-
-		ready := process.Ready()
-		Eventually(ready, 10, 0.05).Should(BeClosed(), "Expected consul to be up and running")
+		configFilePath, err := agentconfig.WriteConfigFile(cr.configDir, agentconfig.ConfigOptions{
+			IncludePerformanceConfig: cluster.HasPerformanceFlag(version),
+			DataDir:                  nodeDataDir,
+			NodeName:                 iStr,
+			ClusterStartingPort:      cr.firstPort(),
+			Index:                    i,
+			NumNodes:                 cr.numNodes,
+			SessionTTL:               cr.sessionTTL,
+			LogLevel:                 cr.logLevel,
+			Telemetry:                cr.telemetry,
+			Tuning:                   cr.tuning,
+			BindAddress:              cr.bindAddress,
+			AdvertiseAddress:         cr.advertiseAddr,
+			EnableDebug:              cr.artifactsDir != "",
+			EnableUI:                 cr.enableUI,
+			Segments:                 cr.segments,
+			TLS:                      cr.tls,
+			ACLMasterToken:           cr.aclToken,
+		})
+		if err != nil {
+			return err
+		}
+
+		cr.configFilePaths[i] = configFilePath
+
+		err = cr.startNode(ctx, i)
+		if err != nil {
+			return err
+		}
 	}
 
-	cr.running = true
+	return nil
+}
+
+// NodeProcess returns the ifrit process of the agent at index, for tests
+// that monitor agents alongside their own processes, e.g. in a grouper. It
+// is nil while the agent is stopped, and is replaced when StartNode or
+// WipeNode starts the agent again.
+func (cr *ClusterRunner) NodeProcess(index int) ifrit.Process {
+	cr.mutex.RLock()
+	defer cr.mutex.RUnlock()
+
+	if index < 0 || index >= len(cr.consulProcesses) {
+		return nil
+	}
+	return cr.consulProcesses[index]
+}
+
+// NodeProcesses returns the processes of every agent, as NodeProcess does.
+func (cr *ClusterRunner) NodeProcesses() []ifrit.Process {
+	cr.mutex.RLock()
+	defer cr.mutex.RUnlock()
+
+	processes := make([]ifrit.Process, len(cr.consulProcesses))
+	copy(processes, cr.consulProcesses)
+	return processes
+}
+
+// LogEvents parses the output of every agent started by the most recent
+// Start into structured events. Output remains available after Stop.
+func (cr *ClusterRunner) LogEvents() agentlog.Events {
+	cr.mutex.RLock()
+	defer cr.mutex.RUnlock()
+
+	events := agentlog.Events{}
+	for i, runner := range cr.consulRunners {
+		if runner != nil {
+			events = append(events, agentlog.Parse(i, runner.Output().Contents())...)
+		}
+	}
+
+	return events
+}
+
+// CaptureDiagnostics writes agent logs, state, goroutine dumps and the test
+// process' own profiles into dir.
+func (cr *ClusterRunner) CaptureDiagnostics(dir string) {
+	Expect(cr.TryCaptureDiagnostics(dir)).To(Succeed())
+}
+
+func (cr *ClusterRunner) TryCaptureDiagnostics(dir string) error {
+	cr.mutex.RLock()
+	defer cr.mutex.RUnlock()
+
+	sources := make([]cluster.DiagnosticsSource, 0, len(cr.consulRunners))
+	for i, runner := range cr.consulRunners {
+		if runner == nil {
+			continue
+		}
+		sources = append(sources, cluster.DiagnosticsSource{
+			Name:    fmt.Sprintf("%d", i),
+			Scheme:  cr.scheme,
+			Address: cr.NodeAddress(i),
+			Log:     runner.Output().Contents(),
+		})
+	}
+
+	httpClient := cfhttp.NewClient()
+	if cr.tls != nil {
+		httpClient = cr.httpClient()
+	}
+
+	return cluster.CaptureDiagnostics(dir, httpClient, sources)
+}
+
+// CaptureDiagnosticsOnFailure is meant to be called from an AfterEach. When
+// the current spec has failed it captures diagnostics into a directory under
+// ArtifactsDir named after the spec.
+func (cr *ClusterRunner) CaptureDiagnosticsOnFailure() {
+	description := CurrentGinkgoTestDescription()
+	if !description.Failed || cr.artifactsDir == "" {
+		return
+	}
+
+	name := artifactNameRegexp.ReplaceAllString(description.FullTestText, "_")
+	if len(name) > 100 {
+		name = name[:100]
+	}
+	dir := filepath.Join(cr.artifactsDir, fmt.Sprintf("%s-%d", name, time.Now().UnixNano()))
+	cr.CaptureDiagnostics(dir)
+	fmt.Fprintf(cr.output, "consul cluster diagnostics written to %s\n", dir)
 }
 
 func (cr *ClusterRunner) NewClient() consuladapter.Client {
-	client, err := api.NewClient(&api.Config{
-		Address:    cr.Address(),
-		Scheme:     cr.scheme,
-		HttpClient: cfhttp.NewStreamingClient(),
-	})
+	client, err := cr.TryNewClient()
 	Expect(err).NotTo(HaveOccurred())
+	return client
+}
 
-	return consuladapter.NewConsulClient(client)
+func (cr *ClusterRunner) TryNewClient() (consuladapter.Client, error) {
+	client, err := cr.newAPIClient(0)
+	if err != nil {
+		return nil, err
+	}
+
+	return consuladapter.NewConsulClient(client), nil
 }
 
-func (cr *ClusterRunner) WaitUntilReady() {
-	client := cr.NewClient()
-	catalog := client.Catalog()
+func (cr *ClusterRunner) newAPIClient(index int) (*api.Client, error) {
+	httpClient, err := cluster.NewHTTPClient(cr.tls)
+	if err != nil {
+		return nil, err
+	}
 
-	Eventually(func() error {
-		_, qm, err := catalog.Nodes(nil)
-		if err != nil {
-			return err
+	config := cr.NodeAPIConfig(index)
+	config.HttpClient = httpClient
+	return api.NewClient(config)
+}
+
+// APIConfig returns the configuration of clients from NewClient, for
+// building api clients directly. Once an https cluster has started, it
+// trusts the generated CA and presents the agent certificate.
+func (cr *ClusterRunner) APIConfig() *api.Config {
+	return cr.NodeAPIConfig(0)
+}
+
+func (cr *ClusterRunner) NodeAPIConfig(index int) *api.Config {
+	return &api.Config{
+		Address:   cr.NodeAddress(index),
+		Scheme:    cr.scheme,
+		TLSConfig: cluster.APITLSConfig(cr.tls),
+		Token:     cr.aclToken,
+	}
+}
+
+// ManagementToken returns the token ACLs were bootstrapped with, or "" if
+// they are not enabled.
+func (cr *ClusterRunner) ManagementToken() string {
+	return cr.aclToken
+}
+
+// CreateToken returns a new token limited to rules, in consul's ACL rule
+// language, e.g. `key_prefix "v1/locks/" { policy = "write" }`.
+func (cr *ClusterRunner) CreateToken(rules string) string {
+	token, err := cr.TryCreateToken(rules)
+	Expect(err).NotTo(HaveOccurred())
+	return token
+}
+
+func (cr *ClusterRunner) TryCreateToken(rules string) (string, error) {
+	if cr.aclToken == "" {
+		return "", errors.New("ACLs are not enabled")
+	}
+
+	client, err := cr.TryNewClient()
+	if err != nil {
+		return "", err
+	}
+
+	cr.mutex.Lock()
+	cr.tokenCount++
+	name := fmt.Sprintf("consulrunner-%d", cr.tokenCount)
+	cr.mutex.Unlock()
+
+	return cluster.CreateToken(client, name, rules)
+}
+
+// NewClientWithToken returns a client that sends token instead of the
+// management token.
+func (cr *ClusterRunner) NewClientWithToken(token string) consuladapter.Client {
+	client, err := cr.TryNewClientWithToken(token)
+	Expect(err).NotTo(HaveOccurred())
+	return client
+}
+
+func (cr *ClusterRunner) TryNewClientWithToken(token string) (consuladapter.Client, error) {
+	httpClient, err := cluster.NewHTTPClient(cr.tls)
+	if err != nil {
+		return nil, err
+	}
+
+	config := cr.APIConfig()
+	config.HttpClient = httpClient
+	config.Token = token
+	client, err := api.NewClient(config)
+	if err != nil {
+		return nil, err
+	}
+
+	return consuladapter.NewConsulClient(client), nil
+}
+
+// TLSConfig returns the client TLS configuration for an https cluster once
+// it has started, and nil otherwise.
+func (cr *ClusterRunner) TLSConfig() (*tls.Config, error) {
+	if cr.tls == nil {
+		return nil, nil
+	}
+
+	apiConfig := cluster.APITLSConfig(cr.tls)
+	return api.SetupTLSConfig(&apiConfig)
+}
+
+// NewScopedClient returns a client whose operations default to the given
+// namespace and partition.
+func (cr *ClusterRunner) NewScopedClient(opts consuladapter.ClientOptions) consuladapter.Client {
+	if cr.tls != nil {
+		config := cr.APIConfig()
+		config.HttpClient = cr.httpClient()
+		config.Namespace = opts.Namespace
+		config.Partition = opts.Partition
+		if opts.Token != "" {
+			config.Token = opts.Token
 		}
-		if qm.KnownLeader && qm.LastIndex > 0 {
-			return nil
+		client, err := api.NewClient(config)
+		Expect(err).NotTo(HaveOccurred())
+		return consuladapter.NewConsulClient(client)
+	}
+
+	if opts.Token == "" {
+		opts.Token = cr.aclToken
+	}
+	client, err := consuladapter.NewClientFromUrlWithOptions(cr.URL(), opts)
+	Expect(err).NotTo(HaveOccurred())
+	return client
+}
+
+func (cr *ClusterRunner) httpClient() *http.Client {
+	client, err := cluster.NewHTTPClient(cr.tls)
+	Expect(err).NotTo(HaveOccurred())
+	return client
+}
+
+func (cr *ClusterRunner) createNamespaces(enterprise bool) error {
+	if !enterprise {
+		return errors.New("namespaces require a Consul Enterprise binary")
+	}
+
+	client, err := cr.newAPIClient(0)
+	if err != nil {
+		return err
+	}
+
+	for _, namespace := range cr.namespaces {
+		_, _, err := client.Namespaces().Create(&api.Namespace{Name: namespace}, nil)
+		if err != nil {
+			return fmt.Errorf("creating namespace %s: %v", namespace, err)
 		}
-		return errors.New("not ready")
-	}, 10, 100*time.Millisecond).Should(BeNil())
+	}
+	return nil
+}
+
+// Ports returns the port layout of the node at index.
+func (cr *ClusterRunner) Ports(index int) PortLayout {
+	return agentconfig.PortsForNode(cr.firstPort(), index)
+}
+
+func (cr *ClusterRunner) firstPort() int {
+	cr.portMutex.RLock()
+	defer cr.portMutex.RUnlock()
+	return cr.startingPort
+}
+
+// portSpan is how many ports a cluster takes: every node's PortLayout,
+// followed by each node's segment ports.
+func portSpan(numNodes, numSegments int) int {
+	return (PortsPerNode + numSegments) * numNodes
+}
+
+func (cr *ClusterRunner) NodeAddress(index int) string {
+	if cr.externalAddress != "" {
+		return cr.externalAddress
+	}
+
+	ports := cr.Ports(index)
+	if cr.scheme == "https" {
+		return agentconfig.NodeAddress(cr.clientHost(), ports.HTTPS)
+	}
+	return agentconfig.NodeAddress(cr.clientHost(), ports.HTTP)
+}
+
+func (cr *ClusterRunner) NewNodeClient(index int) consuladapter.Client {
+	client, err := cr.TryNewNodeClient(index)
+	Expect(err).NotTo(HaveOccurred())
+	return client
+}
+
+func (cr *ClusterRunner) TryNewNodeClient(index int) (consuladapter.Client, error) {
+	if index < 0 || index >= cr.numNodes {
+		return nil, fmt.Errorf("no node %d in a cluster of %d", index, cr.numNodes)
+	}
+
+	client, err := cr.newAPIClient(index)
+	if err != nil {
+		return nil, err
+	}
+
+	return consuladapter.NewConsulClient(client), nil
+}
+
+// Metrics scrapes the in-memory telemetry of the agent at index.
+func (cr *ClusterRunner) Metrics(index int) *api.MetricsInfo {
+	metrics, err := cr.TryMetrics(index)
+	Expect(err).NotTo(HaveOccurred())
+	return metrics
+}
+
+func (cr *ClusterRunner) TryMetrics(index int) (*api.MetricsInfo, error) {
+	client, err := cr.TryNewNodeClient(index)
+	if err != nil {
+		return nil, err
+	}
+
+	return client.Agent().Metrics()
+}
+
+func (cr *ClusterRunner) Topology() Topology {
+	topology, err := cr.TryTopology()
+	Expect(err).NotTo(HaveOccurred())
+	return topology
+}
+
+func (cr *ClusterRunner) TryTopology() (Topology, error) {
+	client, err := cr.TryNewClient()
+	if err != nil {
+		return Topology{}, err
+	}
+
+	return cluster.GetTopology(client)
+}
+
+func (cr *ClusterRunner) EventuallyHaveLeader(intervals ...interface{}) {
+	if len(intervals) == 0 {
+		intervals = []interface{}{10, 100 * time.Millisecond}
+	}
+
+	EventuallyWithOffset(1, func() (string, error) {
+		topology, err := cluster.GetTopology(cr.NewClient())
+		return topology.Leader, err
+	}, intervals...).ShouldNot(BeEmpty(), "Expected the cluster to elect a leader")
+}
+
+func (cr *ClusterRunner) EventuallyHaveVoterCount(count int, intervals ...interface{}) {
+	if len(intervals) == 0 {
+		intervals = []interface{}{10, 100 * time.Millisecond}
+	}
+
+	EventuallyWithOffset(1, func() (int, error) {
+		topology, err := cluster.GetTopology(cr.NewClient())
+		return topology.VoterCount(), err
+	}, intervals...).Should(Equal(count), "Expected the cluster to have %d voters", count)
+}
+
+func (cr *ClusterRunner) WaitUntilReady() {
+	Expect(cr.TryWaitUntilReady(context.Background())).To(Succeed())
+}
+
+// TryWaitUntilReady waits for the cluster to elect a leader, for at most
+// 10 seconds or until ctx is done.
+func (cr *ClusterRunner) TryWaitUntilReady(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, defaultStartTimeout)
+	defer cancel()
+
+	client, err := cr.TryNewClient()
+	if err != nil {
+		return err
+	}
+
+	_, err = consuladapter.WaitForLeader(ctx, client.Status())
+	return err
 }
 
 func (cr *ClusterRunner) Stop() {
+	cr.StopWithContext(context.Background())
+}
+
+// StopWithContext stops the cluster like Stop, giving the agents until ctx's
+// deadline to exit instead of 5 seconds each. Once ctx is done, remaining
+// agents are killed rather than interrupted.
+func (cr *ClusterRunner) StopWithContext(ctx context.Context) {
+	Expect(cr.TryStop(ctx)).To(Succeed())
+}
+
+// TryStop is StopWithContext returning an error. Agents that do not exit
+// in time are killed, and the cluster is stopped even if TryStop returns
+// an error.
+func (cr *ClusterRunner) TryStop(ctx context.Context) error {
 	cr.mutex.Lock()
 	defer cr.mutex.Unlock()
 
-	if !cr.running {
-		return
+	if !cr.running || cr.externalAddress != "" {
+		return nil
 	}
 
-	for i := 0; i < cr.numNodes; i++ {
-		stopSignal(cr.consulProcesses[i], 5*time.Second)
+	return cr.teardown(ctx)
+}
+
+func (cr *ClusterRunner) teardown(ctx context.Context) error {
+	var errs []string
+	for i := range cr.consulProcesses {
+		if err := cr.stopNode(i, timeoutFromContext(ctx, defaultStopTimeout)); err != nil {
+			errs = append(errs, err.Error())
+		}
 	}
+	cr.cleanups = nil
 
-	os.RemoveAll(cr.dataDir)
-	os.RemoveAll(cr.configDir)
+	for _, dir := range []string{cr.dataDir, cr.configDir} {
+		if err := os.RemoveAll(dir); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
 	cr.consulProcesses = nil
+	cr.tls = nil
 	cr.running = false
+
+	if len(errs) > 0 {
+		return fmt.Errorf("stopping consul cluster: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+func (cr *ClusterRunner) nodeDataDir(index int) string {
+	return filepath.Join(cr.dataDir, fmt.Sprintf("%d", index))
+}
+
+func (cr *ClusterRunner) startNode(ctx context.Context, i int) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("consul agent %d did not start: %v", i, err)
+	}
+
+	err := cluster.CheckPortsFree(cr.bindAddress, cr.Ports(i), cr.tls != nil)
+	if err != nil {
+		return err
+	}
+
+	args := append([]string{
+		"agent",
+		"--config-file", cr.configFilePaths[i],
+	}, cr.agentArgs...)
+	cmd, cleanup, err := cluster.NewAgentCommand(
+		cr.resourceLimits,
+		fmt.Sprintf("consul_cluster_%d_%d", os.Getpid(), i),
+		args...,
+	)
+	if err != nil {
+		return err
+	}
+	cluster.AddEnv(cmd, cr.agentEnv)
+	cr.cleanups[i] = cleanup
+
+	output := cluster.NewAgentOutput(cr.output, fmt.Sprintf("\x1b[35m[consul_cluster[%d]]\x1b[0m ", i))
+	runner := cluster.NewAgentRunner(cmd, output)
+	cr.consulRunners[i] = runner
+
+	process := ifrit.Background(runner)
+	cr.consulProcesses[i] = process
+
+	timeout := timeoutFromContext(ctx, defaultStartTimeout)
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-process.Ready():
+		return nil
+	case err := <-process.Wait():
+		cr.consulProcesses[i] = nil
+		cr.cleanupNode(i)
+		if bindErr := cluster.BindFailure(output.Contents()); bindErr != nil {
+			return fmt.Errorf("consul agent %d exited before becoming ready: %w", i, bindErr)
+		}
+		return fmt.Errorf("consul agent %d exited before becoming ready: %v", i, err)
+	case <-timer.C:
+		stopProcess(process, 0)
+		cr.consulProcesses[i] = nil
+		cr.cleanupNode(i)
+		return fmt.Errorf("timed out after %s waiting for consul agent %d to start", timeout, i)
+	case <-ctx.Done():
+		stopProcess(process, 0)
+		cr.consulProcesses[i] = nil
+		cr.cleanupNode(i)
+		return fmt.Errorf("consul agent %d did not start: %v", i, ctx.Err())
+	}
+}
+
+// defaultOutput is GinkgoWriter in a test binary. Elsewhere nothing flushes
+// GinkgoWriter, so output is discarded unless Output is set.
+func defaultOutput() io.Writer {
+	if flag.Lookup("test.v") == nil {
+		return ioutil.Discard
+	}
+	return GinkgoWriter
+}
+
+// timeoutFromContext returns the time left until ctx's deadline, or
+// fallback if it has none. It is zero once ctx is done or its deadline has
+// passed, never negative.
+func timeoutFromContext(ctx context.Context, fallback time.Duration) time.Duration {
+	if ctx.Err() != nil {
+		return 0
+	}
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return fallback
+	}
+
+	remaining := time.Until(deadline)
+	if remaining < 0 {
+		return 0
+	}
+	return remaining
+}
+
+func (cr *ClusterRunner) stopNode(i int, timeout time.Duration) error {
+	var err error
+	if cr.consulProcesses[i] != nil {
+		err = stopProcess(cr.consulProcesses[i], timeout)
+		cr.consulProcesses[i] = nil
+	}
+
+	cr.cleanupNode(i)
+
+	if err != nil {
+		return fmt.Errorf("consul agent %d: %v", i, err)
+	}
+	return nil
+}
+
+// cleanupNode removes what was set up for the agent at index once it has
+// exited, such as its cgroup.
+func (cr *ClusterRunner) cleanupNode(i int) {
+	if cr.cleanups[i] != nil {
+		cr.cleanups[i]()
+		cr.cleanups[i] = nil
+	}
+}
+
+// stopProcess sends stopSignal and waits up to timeout for the process to
+// exit before killing it.
+func stopProcess(process ifrit.Process, timeout time.Duration) error {
+	if timeout > 0 {
+		process.Signal(stopSignal)
+		select {
+		case <-process.Wait():
+			return nil
+		case <-time.After(timeout):
+		}
+	}
+
+	process.Signal(os.Kill)
+	select {
+	case <-process.Wait():
+		return nil
+	case <-time.After(defaultStopTimeout):
+		return fmt.Errorf("did not exit within %s of being killed", defaultStopTimeout)
+	}
+}
+
+func (cr *ClusterRunner) StopNode(index int) {
+	Expect(cr.TryStopNode(index)).To(Succeed())
+}
+
+// TryStopNode is StopNode returning an error.
+func (cr *ClusterRunner) TryStopNode(index int) error {
+	cr.mutex.Lock()
+	defer cr.mutex.Unlock()
+
+	err := cr.checkNode(index)
+	if err != nil {
+		return err
+	}
+	return cr.stopNode(index, defaultStopTimeout)
+}
+
+func (cr *ClusterRunner) StartNode(index int) {
+	Expect(cr.TryStartNode(index)).To(Succeed())
+}
+
+// TryStartNode is StartNode returning an error.
+func (cr *ClusterRunner) TryStartNode(index int) error {
+	cr.mutex.Lock()
+	defer cr.mutex.Unlock()
+
+	err := cr.checkNode(index)
+	if err != nil {
+		return err
+	}
+	if cr.consulProcesses[index] != nil {
+		return fmt.Errorf("consul agent %d is already running", index)
+	}
+	return cr.startNode(context.Background(), index)
+}
+
+// checkNode returns an error unless the cluster is running and index is
+// one of its nodes.
+func (cr *ClusterRunner) checkNode(index int) error {
+	if !cr.running {
+		return errors.New("the consul cluster is not running")
+	}
+	if cr.externalAddress != "" {
+		return errors.New("the nodes of an attached consul cluster cannot be managed")
+	}
+	if index < 0 || index >= cr.numNodes {
+		return fmt.Errorf("no consul agent %d in a cluster of %d", index, cr.numNodes)
+	}
+	return nil
+}
+
+// WipeNode stops the agent at index, deletes its data directory and starts
+// it again, simulating a server coming back after losing its disk.
+func (cr *ClusterRunner) WipeNode(index int) {
+	Expect(cr.TryWipeNode(index)).To(Succeed())
+}
+
+// TryWipeNode is WipeNode returning an error.
+func (cr *ClusterRunner) TryWipeNode(index int) error {
+	cr.mutex.Lock()
+	defer cr.mutex.Unlock()
+
+	err := cr.checkNode(index)
+	if err != nil {
+		return err
+	}
+
+	err = cr.stopNode(index, defaultStopTimeout)
+	if err != nil {
+		return err
+	}
+
+	nodeDataDir := cr.nodeDataDir(index)
+	err = os.RemoveAll(nodeDataDir)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(nodeDataDir, 0700)
+	if err != nil {
+		return err
+	}
+
+	return cr.startNode(context.Background(), index)
+}
+
+// ReconfigureNode applies update to the config file of the agent at index
+// and reloads the agent, e.g. to change its log level or add checks without
+// restarting it. Settings consul cannot reload need StopNode and StartNode.
+func (cr *ClusterRunner) ReconfigureNode(index int, update func(*ConfigFile)) {
+	Expect(cr.TryReconfigureNode(index, update)).To(Succeed())
+}
+
+func (cr *ClusterRunner) TryReconfigureNode(index int, update func(*ConfigFile)) error {
+	client, err := cr.TryNewNodeClient(index)
+	if err != nil {
+		return err
+	}
+
+	cr.mutex.RLock()
+	defer cr.mutex.RUnlock()
+
+	err = cr.checkNode(index)
+	if err != nil {
+		return err
+	}
+	if cr.consulProcesses[index] == nil {
+		return fmt.Errorf("node %d is not running", index)
+	}
+	return cluster.Reconfigure(client, cr.configFilePaths[index], update)
+}
+
+// EventuallyResync waits for the agent at index to rejoin the cluster, see a
+// leader, and catch up with the raft index the rest of the cluster had when
+// it was called.
+func (cr *ClusterRunner) EventuallyResync(index int, intervals ...interface{}) {
+	if len(intervals) == 0 {
+		intervals = []interface{}{10, 100 * time.Millisecond}
+	}
+
+	peer, targetIndex, err := cr.resyncTarget(index)
+	Expect(err).NotTo(HaveOccurred())
+
+	EventuallyWithOffset(1, func() error {
+		return cr.checkRejoined(index, peer)
+	}, intervals...).Should(Succeed(), "Expected node %d to rejoin the cluster", index)
+
+	EventuallyWithOffset(1, func() error {
+		return cr.checkCaughtUp(index, targetIndex)
+	}, intervals...).Should(Succeed(), "Expected node %d to resync", index)
+}
+
+// TryEventuallyResync is EventuallyResync for use outside a Ginkgo suite. It
+// polls until the agent at index has resynced or ctx is done, and then
+// returns the last error.
+func (cr *ClusterRunner) TryEventuallyResync(ctx context.Context, index int) error {
+	peer, targetIndex, err := cr.resyncTarget(index)
+	if err != nil {
+		return err
+	}
+
+	for {
+		err = cr.checkRejoined(index, peer)
+		if err == nil {
+			err = cr.checkCaughtUp(index, targetIndex)
+		}
+		if err == nil {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("node %d did not resync: %v", index, err)
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
+// resyncTarget picks a peer of the agent at index and returns it with the
+// raft index the agent has to catch up with.
+func (cr *ClusterRunner) resyncTarget(index int) (int, uint64, error) {
+	if cr.numNodes < 2 {
+		return 0, 0, errors.New("resyncing needs a cluster with more than one node")
+	}
+	peer := (index + 1) % cr.numNodes
+
+	client, err := cr.TryNewNodeClient(peer)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	_, meta, err := client.Catalog().Nodes(nil)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return peer, meta.LastIndex, nil
+}
+
+func (cr *ClusterRunner) checkRejoined(index, peer int) error {
+	client, err := cr.TryNewNodeClient(peer)
+	if err != nil {
+		return err
+	}
+
+	topology, err := cluster.GetTopology(client)
+	if err != nil {
+		return err
+	}
+
+	status := topology.Nodes[fmt.Sprintf("%d", index)].Status
+	if status != cluster.MemberStatusAlive {
+		return fmt.Errorf("node %d is %q", index, status)
+	}
+	return nil
+}
+
+func (cr *ClusterRunner) checkCaughtUp(index int, targetIndex uint64) error {
+	client, err := cr.TryNewNodeClient(index)
+	if err != nil {
+		return err
+	}
+
+	_, meta, err := client.Catalog().Nodes(nil)
+	if err != nil {
+		return err
+	}
+	if !meta.KnownLeader {
+		return errors.New("no known leader")
+	}
+	if meta.LastIndex < targetIndex {
+		return fmt.Errorf("index %d behind %d", meta.LastIndex, targetIndex)
+	}
+	return nil
 }
 
 func (cr *ClusterRunner) ConsulCluster() string {
 	urls := make([]string, cr.numNodes)
 	for i := 0; i < cr.numNodes; i++ {
-		urls[i] = fmt.Sprintf("%s://127.0.0.1:%d", cr.scheme, cr.startingPort+i*PortOffsetLength+PortOffsetHTTP)
+		urls[i] = fmt.Sprintf("%s://%s", cr.scheme, cr.NodeAddress(i))
 	}
 
 	return strings.Join(urls, ",")
 }
 
+func (cr *ClusterRunner) clientHost() string {
+	return agentconfig.ClientHost(cr.bindAddress, cr.advertiseAddr)
+}
+
 func (cr *ClusterRunner) Address() string {
-	return fmt.Sprintf("127.0.0.1:%d", cr.startingPort+PortOffsetHTTP)
+	return cr.NodeAddress(0)
 }
 
 func (cr *ClusterRunner) URL() string {
 	return fmt.Sprintf("%s://%s", cr.scheme, cr.Address())
 }
 
-func (cr *ClusterRunner) Reset() error {
-	client := cr.NewClient()
-
-	sessions, _, err := client.Session().List(nil)
-	if err == nil {
-		for _, session := range sessions {
-			_, err1 := client.Session().Destroy(session.ID, nil)
-			if err1 != nil {
-				err = err1
-			}
-		}
-	}
+// UIURL returns the address of the first agent's web UI, which is only
+// served with EnableUI. With ACLs, log in with ManagementToken.
+func (cr *ClusterRunner) UIURL() string {
+	return cr.URL() + "/ui/"
+}
 
+// ExportFixture writes the cluster's KV pairs, sessions and the first agent's
+// services to filePath, for loading into another cluster with FixturePath or
+// LoadFixture.
+func (cr *ClusterRunner) ExportFixture(filePath string) {
+	Expect(cr.TryExportFixture(filePath)).To(Succeed())
+}
+
+func (cr *ClusterRunner) TryExportFixture(filePath string) error {
+	client, err := cr.TryNewClient()
 	if err != nil {
 		return err
 	}
 
-	services, err := client.Agent().Services()
-	if err == nil {
-		for _, service := range services {
-			if service.Service == "consul" {
-				continue
-			}
-			err1 := client.Agent().ServiceDeregister(service.ID)
-			if err1 != nil {
-				err = err1
-			}
-		}
+	fixture, err := cluster.ExportFixture(client)
+	if err != nil {
+		return err
 	}
 
+	return cluster.WriteFixture(filePath, fixture)
+}
+
+func (cr *ClusterRunner) LoadFixture(filePath string) {
+	Expect(cr.TryLoadFixture(filePath)).To(Succeed())
+}
+
+func (cr *ClusterRunner) TryLoadFixture(filePath string) error {
+	fixture, err := cluster.ReadFixture(filePath)
 	if err != nil {
 		return err
 	}
 
-	checks, err := client.Agent().Checks()
-	if err == nil {
-		for _, check := range checks {
-			err1 := client.Agent().CheckDeregister(check.CheckID)
-			if err1 != nil {
-				err = err1
-			}
-		}
+	client, err := cr.TryNewClient()
+	if err != nil {
+		return err
 	}
 
+	return cluster.LoadFixture(client, fixture)
+}
+
+func (cr *ClusterRunner) Reset() error {
+	return cr.ResetWithOptions(ResetOptions{
+		SessionNamePrefix: cr.sessionPrefix,
+		Force:             cr.externalAddress == "" && cr.sessionPrefix == "",
+	})
+}
+
+// ResetScoped resets only what a suite created under prefix; see
+// ScopedResetOptions.
+func (cr *ClusterRunner) ResetScoped(prefix string) error {
+	opts, err := ScopedResetOptions(prefix)
 	if err != nil {
 		return err
 	}
+	return cr.ResetWithOptions(opts)
+}
 
-	_, err1 := client.KV().DeleteTree("", nil)
-
-	return err1
+func (cr *ClusterRunner) ResetWithOptions(opts ResetOptions) error {
+	return cluster.Reset(cr.NewClient(), opts)
 }
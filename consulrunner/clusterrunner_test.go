@@ -0,0 +1,380 @@
+// +build !windows
+
+package consulrunner_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"code.cloudfoundry.org/consuladapter/consulrunner"
+	"github.com/tedsuo/ifrit"
+
+	"github.com/onsi/ginkgo/config"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ClusterRunner", func() {
+	Describe("TryNewClusterRunner", func() {
+		It("rejects invalid configurations", func() {
+			configs := []consulrunner.ClusterRunnerConfig{
+				{StartingPort: 0, NumNodes: 1},
+				{StartingPort: 1 << 16, NumNodes: 1},
+				{StartingPort: 5000, NumNodes: 0},
+				{StartingPort: 5000, NumNodes: 1, SessionTTL: -1},
+				{StartingPort: 5000, NumNodes: 1, Scheme: "http", VerifyTLS: true},
+				{StartingPort: 5000, NumNodes: 1, StartAttempts: -1},
+				{StartingPort: 5000, NumNodes: 1, PortRetryStride: -1},
+			}
+			for _, config := range configs {
+				_, err := consulrunner.TryNewClusterRunner(config)
+				Expect(err).To(HaveOccurred(), "%+v", config)
+			}
+		})
+
+		It("addresses agents on their bind or advertise address", func() {
+			runner, err := consulrunner.TryNewClusterRunner(consulrunner.ClusterRunnerConfig{StartingPort: 5000, NumNodes: 2, Scheme: "http", BindAddress: "::1"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(runner.Address()).To(Equal("[::1]:5001"))
+			Expect(runner.ConsulCluster()).To(Equal("http://[::1]:5001,http://[::1]:5009"))
+
+			runner, err = consulrunner.TryNewClusterRunner(consulrunner.ClusterRunnerConfig{StartingPort: 5000, NumNodes: 1, Scheme: "http", BindAddress: "0.0.0.0", AdvertiseAddress: "10.0.0.5"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(runner.URL()).To(Equal("http://10.0.0.5:5001"))
+		})
+	})
+
+	Describe("TryStart", func() {
+		var (
+			dir     string
+			tempDir string
+			pidFile string
+			runner  *consulrunner.ClusterRunner
+		)
+
+		// fakeConsul installs a consul binary running script for its agents
+		fakeConsul := func(version, script string) {
+			path := filepath.Join(dir, "consul")
+			contents := "#!/bin/sh\nif [ \"$1\" = -v ]; then\n" + version + "\nfi\n" + script + "\n"
+			Expect(ioutil.WriteFile(path, []byte(contents), 0755)).To(Succeed())
+			os.Setenv("CONSUL_BINARY", path)
+		}
+
+		newRunner := func(startAttempts int) *consulrunner.ClusterRunner {
+			runner, err := consulrunner.TryNewClusterRunner(consulrunner.ClusterRunnerConfig{
+				StartingPort:  26000 + config.GinkgoConfig.ParallelNode*consulrunner.PortsPerNode*4,
+				NumNodes:      2,
+				Scheme:        "http",
+				TempDir:       tempDir,
+				MinFreeDisk:   1,
+				StartAttempts: startAttempts,
+				Output:        GinkgoWriter,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			return runner
+		}
+
+		BeforeEach(func() {
+			var err error
+			dir, err = ioutil.TempDir("", "consulrunner")
+			Expect(err).NotTo(HaveOccurred())
+			tempDir = filepath.Join(dir, "tmp")
+			Expect(os.Mkdir(tempDir, 0700)).To(Succeed())
+			pidFile = filepath.Join(dir, "pid")
+
+			runner = newRunner(1)
+		})
+
+		AfterEach(func() {
+			os.Unsetenv("CONSUL_BINARY")
+			os.RemoveAll(dir)
+		})
+
+		It("fails without starting anything when the consul version cannot be read", func() {
+			fakeConsul("echo 'not consul'; exit 0", "exit 1")
+
+			err := runner.TryStart(context.Background())
+			Expect(err).To(MatchError(ContainSubstring("unexpected consul version output: not consul")))
+			Expect(runner.Running()).To(BeFalse())
+			Expect(ioutil.ReadDir(tempDir)).To(BeEmpty())
+		})
+
+		It("fails when the consul binary cannot run", func() {
+			fakeConsul("exit 1", "exit 1")
+
+			Expect(runner.TryStart(context.Background())).NotTo(Succeed())
+			Expect(runner.Running()).To(BeFalse())
+		})
+
+		It("stops the agents already started and removes their directories when a later agent fails", func() {
+			fakeConsul("echo 'Consul v1.9.0'; exit 0", `
+case "$3" in
+*/0.json)
+	echo $$ > `+pidFile+`
+	echo '    agent: Join completed. Synced service "consul"'
+	exec sleep 60
+	;;
+*)
+	echo '==> Error starting agent: no good'
+	exit 1
+	;;
+esac`)
+
+			err := runner.TryStart(context.Background())
+			Expect(err).To(MatchError(ContainSubstring("consul agent 1 exited before becoming ready")))
+			Expect(runner.Running()).To(BeFalse())
+			Expect(runner.NodeProcesses()).To(BeEmpty())
+			Expect(ioutil.ReadDir(tempDir)).To(BeEmpty())
+
+			contents, err := ioutil.ReadFile(pidFile)
+			Expect(err).NotTo(HaveOccurred())
+			pid, err := strconv.Atoi(strings.TrimSpace(string(contents)))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(syscall.Kill(pid, 0)).To(Equal(syscall.ESRCH))
+		})
+
+		It("fails without starting an agent when the context is already done", func() {
+			fakeConsul("echo 'Consul v1.9.0'; exit 0", "echo $$ > "+pidFile+"\nexec sleep 60")
+
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			err := runner.TryStart(ctx)
+			Expect(err).To(MatchError("consul agent 0 did not start: context canceled"))
+			Expect(runner.Running()).To(BeFalse())
+			Expect(pidFile).NotTo(BeAnExistingFile())
+		})
+
+		It("kills the agents straight away when stopping past the context's deadline", func() {
+			fakeConsul("echo 'Consul v1.9.0'; exit 0", `
+trap '' INT TERM
+echo $$ >> `+pidFile+`
+echo '    agent: Join completed. Synced service "consul"'
+exec sleep 60`)
+			Expect(runner.TryStart(context.Background())).To(Succeed())
+
+			ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+			defer cancel()
+			start := time.Now()
+			Expect(runner.TryStop(ctx)).To(Succeed())
+			Expect(time.Since(start)).To(BeNumerically("<", 5*time.Second))
+			Expect(runner.Running()).To(BeFalse())
+
+			contents, err := ioutil.ReadFile(pidFile)
+			Expect(err).NotTo(HaveOccurred())
+			pids := strings.Fields(string(contents))
+			Expect(pids).To(HaveLen(2))
+			for _, field := range pids {
+				pid, err := strconv.Atoi(field)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(syscall.Kill(pid, 0)).To(Equal(syscall.ESRCH))
+			}
+		})
+
+		It("passes AgentArgs and AgentEnv to every agent", func() {
+			argsFile := filepath.Join(dir, "args")
+			fakeConsul("echo 'Consul v1.9.0'; exit 0", `
+echo "$4 $5 $FEATURE" >> `+argsFile+`
+echo '    agent: Join completed. Synced service "consul"'
+exec sleep 60`)
+
+			runner, err := consulrunner.TryNewClusterRunner(consulrunner.ClusterRunnerConfig{
+				StartingPort: 26000 + config.GinkgoConfig.ParallelNode*consulrunner.PortsPerNode*4,
+				NumNodes:     2,
+				Scheme:       "http",
+				TempDir:      tempDir,
+				MinFreeDisk:  1,
+				AgentArgs:    []string{"-ui", "-raft-protocol=3"},
+				AgentEnv:     []string{"FEATURE=on"},
+				Output:       GinkgoWriter,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(runner.TryStart(context.Background())).To(Succeed())
+			defer runner.TryStop(context.Background())
+
+			contents, err := ioutil.ReadFile(argsFile)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(contents)).To(Equal("-ui -raft-protocol=3 on\n-ui -raft-protocol=3 on\n"))
+		})
+
+		Context("managing nodes", func() {
+			var logFile string
+
+			// the agents log whether they find the data of a previous run
+			BeforeEach(func() {
+				logFile = filepath.Join(dir, "log")
+				fakeConsul("echo 'Consul v1.9.0'; exit 0", `
+data_dir=$(sed -n 's/.*"data_dir":"\([^"]*\)".*/\1/p' "$3")
+if [ -e "$data_dir/marker" ]; then
+	echo "$(basename "$3") restored" >> `+logFile+`
+else
+	touch "$data_dir/marker"
+	echo "$(basename "$3") fresh" >> `+logFile+`
+fi
+echo '    agent: Join completed. Synced service "consul"'
+exec sleep 60`)
+				Expect(runner.TryStart(context.Background())).To(Succeed())
+			})
+
+			AfterEach(func() {
+				Expect(runner.TryStop(context.Background())).To(Succeed())
+			})
+
+			log := func() []string {
+				contents, err := ioutil.ReadFile(logFile)
+				Expect(err).NotTo(HaveOccurred())
+				return strings.Split(strings.TrimSpace(string(contents)), "\n")
+			}
+
+			It("stops and starts a node, keeping its data", func() {
+				runner.StopNode(1)
+				Expect(runner.NodeProcess(1)).To(BeNil())
+				Expect(runner.NodeProcess(0)).NotTo(BeNil())
+
+				runner.StartNode(1)
+				Expect(runner.NodeProcess(1)).NotTo(BeNil())
+				Expect(log()).To(Equal([]string{"0.json fresh", "1.json fresh", "1.json restored"}))
+			})
+
+			It("exposes each agent's process until its node stops", func() {
+				processes := runner.NodeProcesses()
+				Expect(processes).To(HaveLen(2))
+				Expect(runner.NodeProcess(0)).To(Equal(processes[0]))
+				Expect(runner.NodeProcess(2)).To(BeNil())
+
+				runner.StopNode(1)
+				Eventually(processes[1].Wait()).Should(Receive())
+				Expect(runner.NodeProcess(1)).To(BeNil())
+				Expect(runner.NodeProcesses()[1]).To(BeNil())
+			})
+
+			It("wipes a node's data before starting it again", func() {
+				runner.WipeNode(0)
+				Expect(runner.NodeProcess(0)).NotTo(BeNil())
+				Expect(log()).To(Equal([]string{"0.json fresh", "1.json fresh", "0.json fresh"}))
+			})
+
+			It("refuses to start a running node or to manage nodes that do not exist", func() {
+				Expect(runner.TryStartNode(0)).To(MatchError("consul agent 0 is already running"))
+				Expect(runner.TryStopNode(2)).To(MatchError("no consul agent 2 in a cluster of 2"))
+				Expect(runner.TryWipeNode(-1)).To(MatchError("no consul agent -1 in a cluster of 2"))
+			})
+		})
+
+		Context("as an ifrit runner", func() {
+			It("fails when the cluster cannot start", func() {
+				fakeConsul("echo 'Consul v1.9.0'; exit 0", "exit 1")
+
+				process := ifrit.Background(runner.Runner())
+				Eventually(process.Wait()).Should(Receive(MatchError(ContainSubstring("consul agent 0 exited before becoming ready"))))
+				Expect(runner.Running()).To(BeFalse())
+			})
+
+			It("stops the cluster when signalled before it has elected a leader", func() {
+				fakeConsul("echo 'Consul v1.9.0'; exit 0", `
+echo '    agent: Join completed. Synced service "consul"'
+exec sleep 60`)
+
+				process := ifrit.Background(runner.Runner())
+				Eventually(runner.Running).Should(BeTrue())
+
+				process.Signal(os.Interrupt)
+				Eventually(process.Wait()).Should(Receive(BeNil()))
+				Expect(process.Ready()).NotTo(BeClosed())
+				Expect(runner.Running()).To(BeFalse())
+				Expect(ioutil.ReadDir(tempDir)).To(BeEmpty())
+			})
+		})
+
+		Context("with a ChaosRunner", func() {
+			var startsFile string
+
+			BeforeEach(func() {
+				startsFile = filepath.Join(dir, "starts")
+				fakeConsul("echo 'Consul v1.9.0'; exit 0", `
+echo "$3" >> `+startsFile+`
+echo '    agent: Join completed. Synced service "consul"'
+exec sleep 60`)
+				Expect(runner.TryStart(context.Background())).To(Succeed())
+			})
+
+			AfterEach(func() {
+				Expect(runner.TryStop(context.Background())).To(Succeed())
+			})
+
+			starts := func() int {
+				contents, err := ioutil.ReadFile(startsFile)
+				Expect(err).NotTo(HaveOccurred())
+				return len(strings.Fields(string(contents)))
+			}
+
+			It("stops and restarts nodes until signalled, leaving them all running", func() {
+				process := ifrit.Invoke(runner.ChaosRunner(consulrunner.ChaosConfig{
+					Interval: 20 * time.Millisecond,
+					Downtime: 20 * time.Millisecond,
+					Seed:     1,
+				}))
+				Eventually(starts).Should(BeNumerically(">=", 5))
+
+				process.Signal(os.Interrupt)
+				Eventually(process.Wait()).Should(Receive(BeNil()))
+				for _, node := range runner.NodeProcesses() {
+					Expect(node).NotTo(BeNil())
+				}
+			})
+
+			It("fails once the cluster has stopped", func() {
+				process := ifrit.Invoke(runner.ChaosRunner(consulrunner.ChaosConfig{Interval: 20 * time.Millisecond, Downtime: 20 * time.Millisecond}))
+				Expect(runner.TryStop(context.Background())).To(Succeed())
+
+				Eventually(process.Wait()).Should(Receive(MatchError("the consul cluster is not running")))
+			})
+		})
+
+		Context("when a port is taken", func() {
+			var listener net.Listener
+
+			BeforeEach(func() {
+				fakeConsul("echo 'Consul v1.9.0'; exit 0", `
+echo '    agent: Join completed. Synced service "consul"'
+exec sleep 60`)
+
+				var err error
+				listener, err = net.Listen("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(runner.Ports(1).Server)))
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			AfterEach(func() {
+				listener.Close()
+			})
+
+			It("retries on the next port block", func() {
+				runner = newRunner(2)
+				firstHTTP := runner.Ports(0).HTTP
+
+				Expect(runner.TryStart(context.Background())).To(Succeed())
+				defer runner.TryStop(context.Background())
+
+				Expect(runner.Ports(0).HTTP).To(Equal(firstHTTP + 2*consulrunner.PortsPerNode))
+				Expect(runner.Address()).To(Equal(net.JoinHostPort("127.0.0.1", strconv.Itoa(firstHTTP+2*consulrunner.PortsPerNode))))
+			})
+
+			It("fails with the taken port once out of attempts", func() {
+				err := runner.TryStart(context.Background())
+
+				var portErr consulrunner.PortInUseError
+				Expect(errors.As(err, &portErr)).To(BeTrue(), "%v", err)
+				Expect(runner.Running()).To(BeFalse())
+				Expect(ioutil.ReadDir(tempDir)).To(BeEmpty())
+			})
+		})
+	})
+})
@@ -0,0 +1,13 @@
+package consulrunner_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestConsulrunner(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Consulrunner Suite")
+}
@@ -0,0 +1,20 @@
+package consulrunner
+
+import "code.cloudfoundry.org/consuladapter/consulrunner/internal/cluster"
+
+// PinnedConsulVersion is the consul release EnsureConsulBinary downloads.
+const PinnedConsulVersion = cluster.PinnedConsulVersion
+
+// ReleaseKeyEnv names a file holding HashiCorp's armored public release
+// key. Setting it opts in to EnsureConsulBinary downloading consul.
+const ReleaseKeyEnv = cluster.ReleaseKeyEnv
+
+// EnsureConsulBinary makes sure the runners have a consul binary to start,
+// downloading and caching PinnedConsulVersion for the current platform into
+// cacheDir when none is set in $CONSUL_BINARY or found on the PATH. The
+// download needs $CONSUL_RELEASE_KEY, and is verified against the release's
+// signed SHA256SUMS. An empty cacheDir uses the user cache directory. It
+// returns the binary that will be used.
+func EnsureConsulBinary(cacheDir string) (string, error) {
+	return cluster.EnsureConsulBinary(cacheDir)
+}
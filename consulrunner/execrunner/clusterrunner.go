@@ -0,0 +1,535 @@
+package execrunner
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/consuladapter"
+	"code.cloudfoundry.org/consuladapter/agentconfig"
+	"code.cloudfoundry.org/consuladapter/consulrunner/agentlog"
+	"code.cloudfoundry.org/consuladapter/consulrunner/internal/cluster"
+	"github.com/hashicorp/consul/api"
+)
+
+const PortsPerNode = agentconfig.PortsPerNode
+
+// Deprecated: use Ports. Each node now uses PortsPerNode ports, 8 rather
+// than 6.
+const (
+	PortOffsetHTTP   = agentconfig.PortOffsetHTTP
+	PortOffsetLength = agentconfig.PortOffsetLength
+)
+
+const DefaultSessionTTL = 5 * time.Second
+const DefaultStartTimeout = 10 * time.Second
+const DefaultStopTimeout = 5 * time.Second
+
+const defaultDataDirPrefix = "consul_data"
+const defaultConfigDirPrefix = "consul_config"
+
+type ResourceLimits = cluster.ResourceLimits
+type PortLayout = agentconfig.PortLayout
+type TelemetryConfig = agentconfig.TelemetryConfig
+type TuningConfig = agentconfig.TuningConfig
+
+type Topology = cluster.Topology
+type NodeTopology = cluster.NodeTopology
+
+type ConfigFile = agentconfig.ConfigFile
+type CheckDefinition = agentconfig.CheckDefinition
+
+type ResetOptions = cluster.ResetOptions
+
+var ScopedResetOptions = cluster.ScopedResetOptions
+
+var ErrEmptyResetPrefix = cluster.ErrEmptyResetPrefix
+
+type ResetError = cluster.ResetError
+type ResetFailure = cluster.ResetFailure
+
+var FastConvergence = agentconfig.FastConvergence
+
+type ClusterRunnerConfig struct {
+	StartingPort int
+	NumNodes     int
+	Scheme       string
+
+	// SessionTTL is the minimum session TTL (session_ttl_min) accepted by the
+	// agents. Defaults to DefaultSessionTTL.
+	SessionTTL time.Duration
+
+	ResourceLimits ResourceLimits
+
+	// LogLevel is the agents' log_level. Defaults to "info".
+	LogLevel string
+
+	Telemetry *TelemetryConfig
+
+	// Tuning adjusts raft, autopilot and gossip timings; see FastConvergence.
+	Tuning *TuningConfig
+
+	// BindAddress is the address agents bind to, including their HTTP API.
+	// Defaults to 127.0.0.1; use ::1 for IPv6-only environments.
+	BindAddress      string
+	AdvertiseAddress string
+
+	StartTimeout time.Duration
+	StopTimeout  time.Duration
+
+	// TempDir is where the agents' data and config directories are created,
+	// e.g. a tmpfs. Defaults to the system temporary directory. Start fails
+	// unless it has at least MinFreeDisk bytes free, which defaults to
+	// 256 MiB.
+	TempDir     string
+	MinFreeDisk uint64
+
+	// Output receives the combined output of all agents. Defaults to
+	// ioutil.Discard.
+	Output io.Writer
+
+	// AgentArgs are appended to every agent's command line, e.g. "-ui" or
+	// "-raft-protocol=3", to test against feature flags. AgentEnv adds
+	// "NAME=value" variables to the agents' environment.
+	AgentArgs []string
+	AgentEnv  []string
+
+	// Reset destroys every session on the cluster. SessionNamePrefix, if
+	// set, limits it to sessions named with it, whether or not the adapter
+	// tagged them with consuladapter.SessionNamePrefix.
+	SessionNamePrefix string
+}
+
+// ClusterRunner runs a local consul cluster using os/exec directly, without
+// depending on ginkgo, gomega or ifrit, so it can be used from TestMain and
+// benchmarks.
+type ClusterRunner struct {
+	config ClusterRunnerConfig
+
+	agents          []*exec.Cmd
+	exited          []chan error
+	cleanups        []func() error
+	outputs         []*cluster.AgentOutput
+	configFilePaths []string
+	running         bool
+	dataDir         string
+	configDir       string
+	tls             *agentconfig.TLSFiles
+
+	mutex *sync.RWMutex
+}
+
+func NewClusterRunner(config ClusterRunnerConfig) (*ClusterRunner, error) {
+	if config.StartingPort <= 0 || config.StartingPort >= 1<<16 {
+		return nil, fmt.Errorf("invalid starting port: %d", config.StartingPort)
+	}
+	if config.NumNodes <= 0 {
+		return nil, fmt.Errorf("invalid number of nodes: %d", config.NumNodes)
+	}
+	if config.Scheme == "" {
+		config.Scheme = "http"
+	}
+	if config.SessionTTL == 0 {
+		config.SessionTTL = DefaultSessionTTL
+	}
+	if config.StartTimeout == 0 {
+		config.StartTimeout = DefaultStartTimeout
+	}
+	if config.StopTimeout == 0 {
+		config.StopTimeout = DefaultStopTimeout
+	}
+	if config.LogLevel == "" {
+		config.LogLevel = agentconfig.DefaultLogLevel
+	}
+	if config.MinFreeDisk == 0 {
+		config.MinFreeDisk = cluster.DefaultMinFreeDisk
+	}
+	if config.Output == nil {
+		config.Output = ioutil.Discard
+	}
+
+	return &ClusterRunner{
+		config: config,
+		mutex:  &sync.RWMutex{},
+	}, nil
+}
+
+func (cr *ClusterRunner) Running() bool {
+	cr.mutex.RLock()
+	defer cr.mutex.RUnlock()
+	return cr.running
+}
+
+func (cr *ClusterRunner) NodeCount() int {
+	return cr.config.NumNodes
+}
+
+func (cr *ClusterRunner) SessionTTL() time.Duration {
+	return cr.config.SessionTTL
+}
+
+func (cr *ClusterRunner) ConsulVersion() (string, error) {
+	output, err := exec.Command(cluster.ConsulBinary(), "-v").Output()
+	if err != nil {
+		return "", err
+	}
+
+	return cluster.ParseVersion(string(output))
+}
+
+func (cr *ClusterRunner) Start() error {
+	cr.mutex.Lock()
+	defer cr.mutex.Unlock()
+
+	if cr.running {
+		return nil
+	}
+
+	version, err := cr.ConsulVersion()
+	if err != nil {
+		return err
+	}
+
+	tempDirBase, err := cluster.TempDirBase(cr.config.TempDir, cr.config.MinFreeDisk)
+	if err != nil {
+		return err
+	}
+
+	cr.dataDir, err = ioutil.TempDir(tempDirBase, defaultDataDirPrefix)
+	if err != nil {
+		return err
+	}
+
+	cr.configDir, err = ioutil.TempDir(tempDirBase, defaultConfigDirPrefix)
+	if err != nil {
+		os.RemoveAll(cr.dataDir)
+		return err
+	}
+
+	if cr.config.Scheme == "https" {
+		cr.tls, err = cluster.GenerateTLS(cr.configDir, []string{cr.clientHost()})
+		if err != nil {
+			os.RemoveAll(cr.dataDir)
+			os.RemoveAll(cr.configDir)
+			return err
+		}
+	}
+
+	cr.outputs = nil
+	cr.configFilePaths = nil
+	cr.agents = make([]*exec.Cmd, 0, cr.config.NumNodes)
+	cr.exited = make([]chan error, 0, cr.config.NumNodes)
+
+	for i := 0; i < cr.config.NumNodes; i++ {
+		err = cr.startAgent(i, cluster.HasPerformanceFlag(version))
+		if err != nil {
+			cr.stop()
+			return err
+		}
+	}
+
+	cr.running = true
+	return nil
+}
+
+func (cr *ClusterRunner) startAgent(index int, includePerformanceConfig bool) error {
+	err := cluster.CheckPortsFree(cr.config.BindAddress, cr.Ports(index), cr.tls != nil)
+	if err != nil {
+		return err
+	}
+
+	iStr := fmt.Sprintf("%d", index)
+	nodeDataDir := filepath.Join(cr.dataDir, iStr)
+	err = os.MkdirAll(nodeDataDir, 0700)
+	if err != nil {
+		return err
+	}
+
+	configFilePath, err := agentconfig.WriteConfigFile(cr.configDir, agentconfig.ConfigOptions{
+		IncludePerformanceConfig: includePerformanceConfig,
+		DataDir:                  nodeDataDir,
+		NodeName:                 iStr,
+		ClusterStartingPort:      cr.config.StartingPort,
+		Index:                    index,
+		NumNodes:                 cr.config.NumNodes,
+		SessionTTL:               cr.config.SessionTTL,
+		LogLevel:                 cr.config.LogLevel,
+		Telemetry:                cr.config.Telemetry,
+		Tuning:                   cr.config.Tuning,
+		BindAddress:              cr.config.BindAddress,
+		AdvertiseAddress:         cr.config.AdvertiseAddress,
+		TLS:                      cr.tls,
+	})
+	if err != nil {
+		return err
+	}
+	cr.configFilePaths = append(cr.configFilePaths, configFilePath)
+
+	output := cluster.NewAgentOutput(cr.config.Output, fmt.Sprintf("[consul_cluster[%d]] ", index))
+	cr.outputs = append(cr.outputs, output)
+
+	args := append([]string{
+		"agent",
+		"--config-file", configFilePath,
+	}, cr.config.AgentArgs...)
+	cmd, cleanup, err := cluster.NewAgentCommand(
+		cr.config.ResourceLimits,
+		fmt.Sprintf("consul_cluster_%d_%d", os.Getpid(), index),
+		args...,
+	)
+	if err != nil {
+		return err
+	}
+	cluster.AddEnv(cmd, cr.config.AgentEnv)
+	cr.cleanups = append(cr.cleanups, cleanup)
+
+	cmd.Stdout = output
+	cmd.Stderr = output
+
+	err = cmd.Start()
+	if err != nil {
+		return err
+	}
+
+	exited := make(chan error, 1)
+	go func() {
+		exited <- cmd.Wait()
+	}()
+
+	cr.agents = append(cr.agents, cmd)
+	cr.exited = append(cr.exited, exited)
+
+	select {
+	case <-output.Ready():
+		return nil
+	case err := <-exited:
+		exited <- err
+		return fmt.Errorf("consul agent %d exited before becoming ready: %v", index, err)
+	case <-time.After(cr.config.StartTimeout):
+		return fmt.Errorf("timed out after %s waiting for consul agent %d to start", cr.config.StartTimeout, index)
+	}
+}
+
+// LogEvents parses the output of every agent started by the most recent
+// Start into structured events. Output remains available after Stop.
+func (cr *ClusterRunner) LogEvents() agentlog.Events {
+	cr.mutex.RLock()
+	defer cr.mutex.RUnlock()
+
+	events := agentlog.Events{}
+	for i, w := range cr.outputs {
+		events = append(events, agentlog.Parse(i, w.Contents())...)
+	}
+
+	return events
+}
+
+func (cr *ClusterRunner) NewClient() (consuladapter.Client, error) {
+	httpClient, err := cluster.NewHTTPClient(cr.tls)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := api.NewClient(&api.Config{
+		Address:    cr.Address(),
+		Scheme:     cr.config.Scheme,
+		HttpClient: httpClient,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return consuladapter.NewConsulClient(client), nil
+}
+
+// Ports returns the port layout of the node at index.
+func (cr *ClusterRunner) Ports(index int) PortLayout {
+	return agentconfig.PortsForNode(cr.config.StartingPort, index)
+}
+
+func (cr *ClusterRunner) NodeAddress(index int) string {
+	ports := cr.Ports(index)
+	if cr.config.Scheme == "https" {
+		return agentconfig.NodeAddress(cr.clientHost(), ports.HTTPS)
+	}
+	return agentconfig.NodeAddress(cr.clientHost(), ports.HTTP)
+}
+
+func (cr *ClusterRunner) NewNodeClient(index int) (consuladapter.Client, error) {
+	if index < 0 || index >= cr.config.NumNodes {
+		return nil, fmt.Errorf("invalid node index: %d", index)
+	}
+
+	httpClient, err := cluster.NewHTTPClient(cr.tls)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := api.NewClient(&api.Config{
+		Address:    cr.NodeAddress(index),
+		Scheme:     cr.config.Scheme,
+		HttpClient: httpClient,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return consuladapter.NewConsulClient(client), nil
+}
+
+// ReconfigureNode applies update to the config file of the agent at index
+// and reloads the agent, e.g. to change its log level or add checks without
+// restarting it.
+func (cr *ClusterRunner) ReconfigureNode(index int, update func(*ConfigFile)) error {
+	client, err := cr.NewNodeClient(index)
+	if err != nil {
+		return err
+	}
+
+	cr.mutex.RLock()
+	defer cr.mutex.RUnlock()
+
+	if !cr.running {
+		return fmt.Errorf("cluster is not running")
+	}
+	return cluster.Reconfigure(client, cr.configFilePaths[index], update)
+}
+
+// Metrics scrapes the in-memory telemetry of the agent at index.
+func (cr *ClusterRunner) Metrics(index int) (*api.MetricsInfo, error) {
+	client, err := cr.NewNodeClient(index)
+	if err != nil {
+		return nil, err
+	}
+
+	return client.Agent().Metrics()
+}
+
+func (cr *ClusterRunner) Topology() (Topology, error) {
+	client, err := cr.NewClient()
+	if err != nil {
+		return Topology{}, err
+	}
+
+	return cluster.GetTopology(client)
+}
+
+func (cr *ClusterRunner) WaitUntilReady(timeout time.Duration) error {
+	client, err := cr.NewClient()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	_, err = consuladapter.WaitForLeader(ctx, client.Status())
+	if err != nil {
+		return fmt.Errorf("consul cluster not ready after %s: %v", timeout, err)
+	}
+	return nil
+}
+
+func (cr *ClusterRunner) Stop() error {
+	cr.mutex.Lock()
+	defer cr.mutex.Unlock()
+
+	if !cr.running {
+		return nil
+	}
+
+	return cr.stop()
+}
+
+func (cr *ClusterRunner) stop() error {
+	var stopErr error
+	for i, cmd := range cr.agents {
+		err := stopAgent(cmd, cr.exited[i], cr.config.StopTimeout)
+		if err != nil {
+			stopErr = err
+		}
+	}
+
+	for _, cleanup := range cr.cleanups {
+		cleanup()
+	}
+
+	os.RemoveAll(cr.dataDir)
+	os.RemoveAll(cr.configDir)
+	cr.tls = nil
+	cr.cleanups = nil
+	cr.agents = nil
+	cr.exited = nil
+	cr.running = false
+
+	return stopErr
+}
+
+func (cr *ClusterRunner) ConsulCluster() string {
+	urls := make([]string, cr.config.NumNodes)
+	for i := 0; i < cr.config.NumNodes; i++ {
+		urls[i] = fmt.Sprintf("%s://%s", cr.config.Scheme, cr.NodeAddress(i))
+	}
+
+	return strings.Join(urls, ",")
+}
+
+func (cr *ClusterRunner) clientHost() string {
+	return agentconfig.ClientHost(cr.config.BindAddress, cr.config.AdvertiseAddress)
+}
+
+func (cr *ClusterRunner) Address() string {
+	return cr.NodeAddress(0)
+}
+
+func (cr *ClusterRunner) URL() string {
+	return fmt.Sprintf("%s://%s", cr.config.Scheme, cr.Address())
+}
+
+func (cr *ClusterRunner) Reset() error {
+	return cr.ResetWithOptions(ResetOptions{
+		SessionNamePrefix: cr.config.SessionNamePrefix,
+		Force:             cr.config.SessionNamePrefix == "",
+	})
+}
+
+// ResetScoped resets only what a suite created under prefix; see
+// ScopedResetOptions.
+func (cr *ClusterRunner) ResetScoped(prefix string) error {
+	opts, err := ScopedResetOptions(prefix)
+	if err != nil {
+		return err
+	}
+	return cr.ResetWithOptions(opts)
+}
+
+func (cr *ClusterRunner) ResetWithOptions(opts ResetOptions) error {
+	client, err := cr.NewClient()
+	if err != nil {
+		return err
+	}
+
+	return cluster.Reset(client, opts)
+}
+
+func stopAgent(cmd *exec.Cmd, exited chan error, timeout time.Duration) error {
+	err := stopSignal(cmd)
+	if err == nil {
+		select {
+		case <-exited:
+			return nil
+		case <-time.After(timeout):
+		}
+	}
+
+	err = cmd.Process.Kill()
+	<-exited
+	return err
+}
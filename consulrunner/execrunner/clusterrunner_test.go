@@ -0,0 +1,120 @@
+// +build !windows
+
+package execrunner_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"code.cloudfoundry.org/consuladapter/consulrunner/execrunner"
+
+	"github.com/onsi/ginkgo/config"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ClusterRunner", func() {
+	Describe("NewClusterRunner", func() {
+		It("rejects invalid starting ports and node counts", func() {
+			for _, config := range []execrunner.ClusterRunnerConfig{
+				{StartingPort: 0, NumNodes: 1},
+				{StartingPort: 1 << 16, NumNodes: 1},
+				{StartingPort: 5000, NumNodes: 0},
+			} {
+				_, err := execrunner.NewClusterRunner(config)
+				Expect(err).To(HaveOccurred(), "%+v", config)
+			}
+		})
+
+		It("defaults to an http cluster with the default session TTL", func() {
+			runner, err := execrunner.NewClusterRunner(execrunner.ClusterRunnerConfig{StartingPort: 5000, NumNodes: 2})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(runner.NodeCount()).To(Equal(2))
+			Expect(runner.SessionTTL()).To(Equal(execrunner.DefaultSessionTTL))
+			Expect(runner.Running()).To(BeFalse())
+			Expect(runner.URL()).To(Equal("http://127.0.0.1:5001"))
+			Expect(runner.ConsulCluster()).To(Equal("http://127.0.0.1:5001,http://127.0.0.1:5009"))
+		})
+	})
+
+	Describe("Start", func() {
+		var (
+			dir    string
+			runner *execrunner.ClusterRunner
+		)
+
+		// fakeConsul installs a consul binary running script for its agents
+		fakeConsul := func(version, script string) {
+			path := filepath.Join(dir, "consul")
+			contents := "#!/bin/sh\nif [ \"$1\" = -v ]; then\n" + version + "\nfi\n" + script + "\n"
+			Expect(ioutil.WriteFile(path, []byte(contents), 0755)).To(Succeed())
+			os.Setenv("CONSUL_BINARY", path)
+		}
+
+		BeforeEach(func() {
+			var err error
+			dir, err = ioutil.TempDir("", "execrunner")
+			Expect(err).NotTo(HaveOccurred())
+			tempDir := filepath.Join(dir, "tmp")
+			Expect(os.Mkdir(tempDir, 0700)).To(Succeed())
+
+			runner, err = execrunner.NewClusterRunner(execrunner.ClusterRunnerConfig{
+				StartingPort: 27000 + config.GinkgoConfig.ParallelNode*execrunner.PortsPerNode*2,
+				NumNodes:     2,
+				TempDir:      tempDir,
+				MinFreeDisk:  1,
+				Output:       GinkgoWriter,
+			})
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			os.Unsetenv("CONSUL_BINARY")
+			os.RemoveAll(dir)
+		})
+
+		It("starts an agent per node with its own config file, and removes their directories when stopped", func() {
+			fakeConsul("echo 'Consul v1.9.0'; exit 0", `
+cp "$3" `+dir+`/
+echo '    agent: Join completed. Synced service "consul"'
+exec sleep 60`)
+
+			Expect(runner.Start()).To(Succeed())
+			Expect(runner.Running()).To(BeTrue())
+			Expect(filepath.Join(dir, "0.json")).To(BeAnExistingFile())
+			Expect(filepath.Join(dir, "1.json")).To(BeAnExistingFile())
+
+			Expect(runner.Stop()).To(Succeed())
+			Expect(runner.Running()).To(BeFalse())
+			Expect(ioutil.ReadDir(filepath.Join(dir, "tmp"))).To(BeEmpty())
+		})
+
+		It("fails without starting anything when the consul version cannot be read", func() {
+			fakeConsul("echo 'not consul'; exit 0", "exit 1")
+
+			Expect(runner.Start()).To(MatchError("unexpected consul version output: not consul"))
+			Expect(runner.Running()).To(BeFalse())
+			Expect(ioutil.ReadDir(filepath.Join(dir, "tmp"))).To(BeEmpty())
+		})
+
+		It("stops the agents already started when a later agent fails", func() {
+			fakeConsul("echo 'Consul v1.9.0'; exit 0", `
+case "$3" in
+*/0.json)
+	echo '    agent: Join completed. Synced service "consul"'
+	exec sleep 60
+	;;
+*)
+	exit 1
+	;;
+esac`)
+
+			Expect(runner.Start()).To(MatchError(ContainSubstring("consul agent 1 exited before becoming ready")))
+			Expect(runner.Running()).To(BeFalse())
+			Expect(ioutil.ReadDir(filepath.Join(dir, "tmp"))).To(BeEmpty())
+		})
+	})
+})
@@ -0,0 +1,12 @@
+// +build !windows
+
+package execrunner
+
+import (
+	"os"
+	"os/exec"
+)
+
+func stopSignal(cmd *exec.Cmd) error {
+	return cmd.Process.Signal(os.Interrupt)
+}
@@ -0,0 +1,12 @@
+// +build windows
+
+package execrunner
+
+import (
+	"errors"
+	"os/exec"
+)
+
+func stopSignal(cmd *exec.Cmd) error {
+	return errors.New("interrupt is not supported on windows")
+}
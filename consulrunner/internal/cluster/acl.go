@@ -0,0 +1,40 @@
+package cluster
+
+import (
+	"crypto/rand"
+	"fmt"
+
+	"code.cloudfoundry.org/consuladapter"
+	"github.com/hashicorp/consul/api"
+)
+
+// NewToken returns a random token in the UUID format consul expects.
+func NewToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
+
+// CreateToken creates a policy named name with rules, and a token with that
+// policy, and returns the token's secret. client must have a management
+// token.
+func CreateToken(client consuladapter.Client, name, rules string) (string, error) {
+	policy, _, err := client.ACL().PolicyCreate(&api.ACLPolicy{Name: name, Rules: rules}, nil)
+	if err != nil {
+		return "", fmt.Errorf("creating policy %s: %v", name, err)
+	}
+
+	token, _, err := client.ACL().TokenCreate(&api.ACLToken{
+		Description: name,
+		Policies:    []*api.ACLTokenPolicyLink{{ID: policy.ID}},
+	}, nil)
+	if err != nil {
+		return "", fmt.Errorf("creating token for policy %s: %v", name, err)
+	}
+
+	return token.SecretID, nil
+}
@@ -0,0 +1,152 @@
+package cluster
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+// StartCheck is logged by an agent once it has joined the cluster.
+const StartCheck = "agent: Join completed."
+
+// MaxAgentLogSize bounds how much of an agent's output AgentOutput records.
+// Beyond it, the oldest lines are dropped, down to half of it.
+const MaxAgentLogSize = 4 << 20
+
+// AgentOutput records an agent's output and forwards it to another writer
+// with each line prefixed. Ready is closed once the agent logs StartCheck.
+type AgentOutput struct {
+	out   io.Writer
+	ready chan struct{}
+
+	mutex   sync.Mutex
+	log     bytes.Buffer
+	buffer  bytes.Buffer
+	matched bool
+}
+
+func NewAgentOutput(out io.Writer, prefix string) *AgentOutput {
+	return &AgentOutput{
+		out:   &linePrefixWriter{out: out, prefix: prefix, atLineStart: true},
+		ready: make(chan struct{}),
+	}
+}
+
+func (w *AgentOutput) Ready() <-chan struct{} {
+	return w.ready
+}
+
+// Contents returns a copy of what the agent has written, or of its most
+// recent lines once it has written more than MaxAgentLogSize.
+func (w *AgentOutput) Contents() []byte {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	contents := make([]byte, w.log.Len())
+	copy(contents, w.log.Bytes())
+	return contents
+}
+
+func (w *AgentOutput) Write(p []byte) (int, error) {
+	w.mutex.Lock()
+	w.log.Write(p)
+	if w.log.Len() > MaxAgentLogSize {
+		w.trimLog()
+	}
+	if !w.matched {
+		w.buffer.Write(p)
+		if strings.Contains(w.buffer.String(), StartCheck) {
+			w.matched = true
+			w.buffer.Reset()
+			close(w.ready)
+		} else if tail := len(StartCheck) - 1; w.buffer.Len() > tail {
+			// only a partial StartCheck at the end can still match
+			w.buffer.Next(w.buffer.Len() - tail)
+		}
+	}
+	w.mutex.Unlock()
+
+	return w.out.Write(p)
+}
+
+func (w *AgentOutput) trimLog() {
+	kept := w.log.Bytes()[w.log.Len()-MaxAgentLogSize/2:]
+	if i := bytes.IndexByte(kept, '\n'); i >= 0 {
+		kept = kept[i+1:]
+	}
+	kept = append([]byte{}, kept...)
+
+	w.log.Reset()
+	w.log.Write(kept)
+}
+
+type linePrefixWriter struct {
+	out         io.Writer
+	prefix      string
+	atLineStart bool
+}
+
+func (w *linePrefixWriter) Write(p []byte) (int, error) {
+	var buf bytes.Buffer
+	for _, b := range p {
+		if w.atLineStart {
+			buf.WriteString(w.prefix)
+		}
+		buf.WriteByte(b)
+		w.atLineStart = b == '\n'
+	}
+
+	_, err := w.out.Write(buf.Bytes())
+	if err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+// AgentRunner runs an agent command with os/exec, writing its output to
+// an AgentOutput. Its Run method makes it an ifrit.Runner: it is ready once
+// the agent logs StartCheck, forwards signals to the agent, and returns
+// the agent's exit error.
+type AgentRunner struct {
+	cmd    *exec.Cmd
+	output *AgentOutput
+}
+
+func NewAgentRunner(cmd *exec.Cmd, output *AgentOutput) *AgentRunner {
+	return &AgentRunner{cmd: cmd, output: output}
+}
+
+func (r *AgentRunner) Output() *AgentOutput {
+	return r.output
+}
+
+func (r *AgentRunner) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
+	r.cmd.Stdout = r.output
+	r.cmd.Stderr = r.output
+	err := r.cmd.Start()
+	if err != nil {
+		return err
+	}
+
+	exited := make(chan error, 1)
+	go func() {
+		exited <- r.cmd.Wait()
+	}()
+
+	started := r.output.Ready()
+	for {
+		select {
+		case <-started:
+			close(ready)
+			started = nil
+		case signal := <-signals:
+			r.cmd.Process.Signal(signal)
+		case err := <-exited:
+			return err
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package cluster_test
+
+import (
+	"bytes"
+	"strings"
+
+	"code.cloudfoundry.org/consuladapter/consulrunner/internal/cluster"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("AgentOutput", func() {
+	var (
+		forwarded *bytes.Buffer
+		output    *cluster.AgentOutput
+	)
+
+	BeforeEach(func() {
+		forwarded = &bytes.Buffer{}
+		output = cluster.NewAgentOutput(forwarded, "[agent] ")
+	})
+
+	It("forwards each line prefixed, and records it as written", func() {
+		output.Write([]byte("first\nsec"))
+		output.Write([]byte("ond\n"))
+
+		Expect(forwarded.String()).To(Equal("[agent] first\n[agent] second\n"))
+		Expect(output.Contents()).To(Equal([]byte("first\nsecond\n")))
+	})
+
+	It("becomes ready once the agent logs the start check, even split across writes", func() {
+		output.Write([]byte("starting\n" + cluster.StartCheck[:10]))
+		Expect(output.Ready()).NotTo(BeClosed())
+
+		output.Write([]byte(cluster.StartCheck[10:] + "\n"))
+		Expect(output.Ready()).To(BeClosed())
+	})
+
+	It("keeps only the most recent lines once it has recorded too much", func() {
+		line := strings.Repeat("x", 1023) + "\n"
+		for written := 0; written <= cluster.MaxAgentLogSize; written += len(line) {
+			output.Write([]byte(line))
+		}
+		output.Write([]byte("last\n"))
+
+		contents := output.Contents()
+		Expect(len(contents)).To(BeNumerically("<=", cluster.MaxAgentLogSize/2+len("last\n")))
+		Expect(bytes.HasPrefix(contents, []byte(line))).To(BeTrue())
+		Expect(bytes.HasSuffix(contents, []byte("\nlast\n"))).To(BeTrue())
+	})
+})
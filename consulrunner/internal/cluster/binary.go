@@ -0,0 +1,24 @@
+package cluster
+
+import (
+	"os"
+	"os/exec"
+)
+
+// ConsulBinaryEnv overrides the consul binary the runners start.
+const ConsulBinaryEnv = "CONSUL_BINARY"
+
+// ConsulBinary returns the consul binary to run: $CONSUL_BINARY if set,
+// otherwise consul, or consul.exe on Windows, from the PATH.
+func ConsulBinary() string {
+	if binary := os.Getenv(ConsulBinaryEnv); binary != "" {
+		return binary
+	}
+
+	binary, err := exec.LookPath("consul")
+	if err != nil {
+		// let the caller's exec report the missing binary
+		return "consul"
+	}
+	return binary
+}
@@ -0,0 +1,44 @@
+// +build linux
+
+package cluster
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+)
+
+const cpuPeriod = 100000
+
+func createCgroup(root, name string, limits ResourceLimits) (string, error) {
+	_, err := os.Stat(path.Join(root, "cgroup.controllers"))
+	if err != nil {
+		return "", fmt.Errorf("cgroup v2 hierarchy not found at %s: %s", root, err)
+	}
+
+	dir := path.Join(root, name)
+	err = os.Mkdir(dir, 0755)
+	if err != nil {
+		return "", err
+	}
+
+	if limits.CPUQuota > 0 {
+		quota := int(limits.CPUQuota * cpuPeriod)
+		err = ioutil.WriteFile(path.Join(dir, "cpu.max"), []byte(fmt.Sprintf("%d %d", quota, cpuPeriod)), 0644)
+		if err != nil {
+			os.Remove(dir)
+			return "", err
+		}
+	}
+
+	if limits.MemoryLimit > 0 {
+		err = ioutil.WriteFile(path.Join(dir, "memory.max"), []byte(fmt.Sprintf("%d", limits.MemoryLimit)), 0644)
+		if err != nil {
+			os.Remove(dir)
+			return "", err
+		}
+	}
+
+	return dir, nil
+}
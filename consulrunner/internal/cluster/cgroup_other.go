@@ -0,0 +1,9 @@
+// +build !linux
+
+package cluster
+
+import "errors"
+
+func createCgroup(root, name string, limits ResourceLimits) (string, error) {
+	return "", errors.New("cpu and memory limits are only supported on linux")
+}
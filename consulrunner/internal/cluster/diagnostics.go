@@ -0,0 +1,114 @@
+package cluster
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime/pprof"
+	"strings"
+)
+
+type DiagnosticsSource struct {
+	Name    string
+	Scheme  string
+	Address string
+	Log     []byte
+}
+
+var diagnosticsEndpoints = map[string]string{
+	"self.json":      "v1/agent/self",
+	"members.json":   "v1/agent/members",
+	"raft.json":      "v1/operator/raft/configuration",
+	"metrics.json":   "v1/agent/metrics",
+	"goroutines.txt": "debug/pprof/goroutine?debug=2",
+	"heap.prof":      "debug/pprof/heap",
+}
+
+// CaptureDiagnostics writes each agent's log, state endpoints and pprof data
+// (available when enable_debug is set) along with the current process'
+// goroutine and heap profiles into dir. Failures to fetch individual
+// artifacts are recorded in errors.txt rather than aborting the capture.
+func CaptureDiagnostics(dir string, httpClient *http.Client, sources []DiagnosticsSource) error {
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return err
+	}
+
+	errs := []string{}
+
+	for _, source := range sources {
+		nodeDir := filepath.Join(dir, "node-"+source.Name)
+		err := os.MkdirAll(nodeDir, 0755)
+		if err != nil {
+			return err
+		}
+
+		err = ioutil.WriteFile(filepath.Join(nodeDir, "agent.log"), source.Log, 0644)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("node %s: agent.log: %s", source.Name, err))
+		}
+
+		for file, endpoint := range diagnosticsEndpoints {
+			url := fmt.Sprintf("%s://%s/%s", source.Scheme, source.Address, endpoint)
+			err := fetch(httpClient, url, filepath.Join(nodeDir, file))
+			if err != nil {
+				errs = append(errs, fmt.Sprintf("node %s: %s: %s", source.Name, file, err))
+			}
+		}
+	}
+
+	processDir := filepath.Join(dir, "process")
+	err = os.MkdirAll(processDir, 0755)
+	if err != nil {
+		return err
+	}
+
+	for file, profile := range map[string]string{"goroutines.txt": "goroutine", "heap.prof": "heap"} {
+		err := writeProfile(profile, filepath.Join(processDir, file))
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("process: %s: %s", file, err))
+		}
+	}
+
+	if len(errs) > 0 {
+		return ioutil.WriteFile(filepath.Join(dir, "errors.txt"), []byte(strings.Join(errs, "\n")+"\n"), 0644)
+	}
+
+	return nil
+}
+
+func fetch(httpClient *http.Client, url, filePath string) error {
+	resp, err := httpClient.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	return ioutil.WriteFile(filePath, body, 0644)
+}
+
+func writeProfile(name, filePath string) error {
+	file, err := os.Create(filePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	debug := 0
+	if name == "goroutine" {
+		debug = 2
+	}
+
+	return pprof.Lookup(name).WriteTo(file, debug)
+}
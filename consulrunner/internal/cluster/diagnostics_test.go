@@ -0,0 +1,88 @@
+package cluster_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"code.cloudfoundry.org/consuladapter/consulrunner/internal/cluster"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("CaptureDiagnostics", func() {
+	var (
+		server *httptest.Server
+		dir    string
+		source cluster.DiagnosticsSource
+	)
+
+	BeforeEach(func() {
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/v1/agent/self":
+				w.Write([]byte(`{"Config":{}}`))
+			case "/v1/agent/members":
+				w.Write([]byte(`[]`))
+			default:
+				http.Error(w, "not enabled", http.StatusNotFound)
+			}
+		}))
+
+		var err error
+		dir, err = ioutil.TempDir("", "diagnostics")
+		Expect(err).NotTo(HaveOccurred())
+
+		source = cluster.DiagnosticsSource{
+			Name:    "0",
+			Scheme:  "http",
+			Address: strings.TrimPrefix(server.URL, "http://"),
+			Log:     []byte("agent: Join completed.\n"),
+		}
+	})
+
+	AfterEach(func() {
+		server.Close()
+		os.RemoveAll(dir)
+	})
+
+	readFile := func(path ...string) string {
+		contents, err := ioutil.ReadFile(filepath.Join(append([]string{dir}, path...)...))
+		Expect(err).NotTo(HaveOccurred())
+		return string(contents)
+	}
+
+	It("writes each agent's log and the endpoints it serves", func() {
+		Expect(cluster.CaptureDiagnostics(dir, http.DefaultClient, []cluster.DiagnosticsSource{source})).To(Succeed())
+
+		Expect(readFile("node-0", "agent.log")).To(Equal("agent: Join completed.\n"))
+		Expect(readFile("node-0", "self.json")).To(Equal(`{"Config":{}}`))
+		Expect(readFile("node-0", "members.json")).To(Equal(`[]`))
+	})
+
+	It("records the endpoints it failed to fetch in errors.txt", func() {
+		Expect(cluster.CaptureDiagnostics(dir, http.DefaultClient, []cluster.DiagnosticsSource{source})).To(Succeed())
+
+		_, err := os.Stat(filepath.Join(dir, "node-0", "raft.json"))
+		Expect(os.IsNotExist(err)).To(BeTrue())
+
+		errors := readFile("errors.txt")
+		Expect(errors).To(ContainSubstring("node 0: raft.json: unexpected status 404: not enabled\n"))
+		Expect(errors).To(ContainSubstring("node 0: goroutines.txt: unexpected status 404: not enabled\n"))
+		Expect(errors).NotTo(ContainSubstring("self.json"))
+	})
+
+	It("writes the current process' profiles", func() {
+		Expect(cluster.CaptureDiagnostics(dir, http.DefaultClient, nil)).To(Succeed())
+
+		Expect(readFile("process", "goroutines.txt")).To(ContainSubstring("goroutine"))
+		Expect(readFile("process", "heap.prof")).NotTo(BeEmpty())
+
+		_, err := os.Stat(filepath.Join(dir, "errors.txt"))
+		Expect(os.IsNotExist(err)).To(BeTrue())
+	})
+})
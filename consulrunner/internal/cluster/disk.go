@@ -0,0 +1,38 @@
+package cluster
+
+import (
+	"fmt"
+	"os"
+)
+
+// DefaultMinFreeDisk leaves room for raft logs and snapshots of a test
+// cluster.
+const DefaultMinFreeDisk = 256 << 20
+
+type InsufficientDiskError struct {
+	Dir       string
+	Available uint64
+	Required  uint64
+}
+
+func (e InsufficientDiskError) Error() string {
+	return fmt.Sprintf("%s has %d MiB free, but consul agents need at least %d MiB for raft data", e.Dir, e.Available>>20, e.Required>>20)
+}
+
+// TempDirBase returns dir, or the system temporary directory if dir is
+// empty, after checking that it has at least required bytes free.
+func TempDirBase(dir string, required uint64) (string, error) {
+	if dir == "" {
+		dir = os.TempDir()
+	}
+
+	available, err := freeDiskSpace(dir)
+	if err != nil {
+		return "", fmt.Errorf("checking free space in %s: %s", dir, err)
+	}
+	if available < required {
+		return "", InsufficientDiskError{Dir: dir, Available: available, Required: required}
+	}
+
+	return dir, nil
+}
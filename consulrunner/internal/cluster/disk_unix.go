@@ -0,0 +1,14 @@
+// +build !windows
+
+package cluster
+
+import "syscall"
+
+func freeDiskSpace(dir string) (uint64, error) {
+	var stat syscall.Statfs_t
+	err := syscall.Statfs(dir, &stat)
+	if err != nil {
+		return 0, err
+	}
+	return stat.Bavail * uint64(stat.Bsize), nil
+}
@@ -0,0 +1,24 @@
+// +build windows
+
+package cluster
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+var getDiskFreeSpaceEx = syscall.NewLazyDLL("kernel32.dll").NewProc("GetDiskFreeSpaceExW")
+
+func freeDiskSpace(dir string) (uint64, error) {
+	path, err := syscall.UTF16PtrFromString(dir)
+	if err != nil {
+		return 0, err
+	}
+
+	var available uint64
+	ret, _, err := getDiskFreeSpaceEx.Call(uintptr(unsafe.Pointer(path)), uintptr(unsafe.Pointer(&available)), 0, 0)
+	if ret == 0 {
+		return 0, err
+	}
+	return available, nil
+}
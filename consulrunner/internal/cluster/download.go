@@ -0,0 +1,217 @@
+package cluster
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"golang.org/x/crypto/openpgp"
+)
+
+// PinnedConsulVersion is the release EnsureConsulBinary downloads when no
+// consul binary is available.
+const PinnedConsulVersion = "1.16.2"
+
+const releasesURL = "https://releases.hashicorp.com/consul"
+
+// ReleaseKeyEnv names a file holding HashiCorp's armored public release
+// key, which EnsureConsulBinary needs to verify a download. Downloading is
+// opt-in: without it, EnsureConsulBinary fails when no binary is installed.
+const ReleaseKeyEnv = "CONSUL_RELEASE_KEY"
+
+type ChecksumMismatchError struct {
+	Archive  string
+	Expected string
+	Actual   string
+}
+
+func (e ChecksumMismatchError) Error() string {
+	return fmt.Sprintf("checksum mismatch for %s: expected %s, got %s", e.Archive, e.Expected, e.Actual)
+}
+
+// EnsureConsulBinary returns the consul binary the runners will start. If
+// neither $CONSUL_BINARY nor the PATH provide one, and $CONSUL_RELEASE_KEY is
+// set, it downloads PinnedConsulVersion for the current GOOS/GOARCH into
+// cacheDir, verifies it, and points $CONSUL_BINARY at it. An empty cacheDir
+// uses the user cache directory.
+func EnsureConsulBinary(cacheDir string) (string, error) {
+	if binary := os.Getenv(ConsulBinaryEnv); binary != "" {
+		return binary, nil
+	}
+	if binary, err := exec.LookPath("consul"); err == nil {
+		return binary, nil
+	}
+
+	keyFile := os.Getenv(ReleaseKeyEnv)
+	if keyFile == "" {
+		return "", fmt.Errorf("no consul binary: set $%s, add consul to the PATH, or set $%s to download it", ConsulBinaryEnv, ReleaseKeyEnv)
+	}
+	releaseKey, err := ioutil.ReadFile(keyFile)
+	if err != nil {
+		return "", err
+	}
+
+	if cacheDir == "" {
+		userCacheDir, err := os.UserCacheDir()
+		if err != nil {
+			return "", err
+		}
+		cacheDir = filepath.Join(userCacheDir, "consuladapter")
+	}
+
+	binary, err := DownloadConsul(cacheDir, PinnedConsulVersion, runtime.GOOS, runtime.GOARCH, releaseKey)
+	if err != nil {
+		return "", err
+	}
+
+	return binary, os.Setenv(ConsulBinaryEnv, binary)
+}
+
+// DownloadConsul fetches a consul release for goos and goarch into cacheDir,
+// returning the cached binary without downloading if it is already present.
+// The release's SHA256SUMS must be signed by releaseKey, an armored public
+// key, and the archive must match its checksum.
+func DownloadConsul(cacheDir, version, goos, goarch string, releaseKey []byte) (string, error) {
+	name := "consul"
+	if goos == "windows" {
+		name = "consul.exe"
+	}
+
+	binary := filepath.Join(cacheDir, fmt.Sprintf("consul_%s_%s_%s", version, goos, goarch), name)
+	if _, err := os.Stat(binary); err == nil {
+		return binary, nil
+	}
+
+	archiveName := fmt.Sprintf("consul_%s_%s_%s.zip", version, goos, goarch)
+	sumsURL := fmt.Sprintf("%s/%s/consul_%s_SHA256SUMS", releasesURL, version, version)
+	sums, err := fetchRelease(sumsURL)
+	if err != nil {
+		return "", err
+	}
+	signature, err := fetchRelease(sumsURL + ".sig")
+	if err != nil {
+		return "", err
+	}
+	err = VerifySignature(sums, signature, releaseKey)
+	if err != nil {
+		return "", err
+	}
+
+	expected, err := FindChecksum(sums, archiveName)
+	if err != nil {
+		return "", err
+	}
+
+	archive, err := fetchRelease(fmt.Sprintf("%s/%s/%s", releasesURL, version, archiveName))
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(archive)
+	if actual := hex.EncodeToString(sum[:]); actual != expected {
+		return "", ChecksumMismatchError{Archive: archiveName, Expected: expected, Actual: actual}
+	}
+
+	contents, err := Extract(archive, name)
+	if err != nil {
+		return "", fmt.Errorf("extracting %s: %s", archiveName, err)
+	}
+
+	err = os.MkdirAll(filepath.Dir(binary), 0755)
+	if err != nil {
+		return "", err
+	}
+
+	// write then rename, so that parallel suites never run a partial binary
+	tmp, err := ioutil.TempFile(filepath.Dir(binary), name)
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(contents)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", err
+	}
+	err = os.Chmod(tmp.Name(), 0755)
+	if err != nil {
+		return "", err
+	}
+
+	return binary, os.Rename(tmp.Name(), binary)
+}
+
+func fetchRelease(url string) ([]byte, error) {
+	resp, err := http.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("downloading %s: %s", url, resp.Status)
+	}
+	return ioutil.ReadAll(resp.Body)
+}
+
+// VerifySignature checks that signature is a detached signature of signed
+// by releaseKey, an armored public key.
+func VerifySignature(signed, signature, releaseKey []byte) error {
+	keyring, err := openpgp.ReadArmoredKeyRing(bytes.NewReader(releaseKey))
+	if err != nil {
+		return fmt.Errorf("reading release key: %s", err)
+	}
+
+	_, err = openpgp.CheckDetachedSignature(keyring, bytes.NewReader(signed), bytes.NewReader(signature))
+	if err != nil {
+		return fmt.Errorf("verifying SHA256SUMS signature: %s", err)
+	}
+	return nil
+}
+
+// FindChecksum returns the checksum of archiveName in a SHA256SUMS file.
+func FindChecksum(sums []byte, archiveName string) (string, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(sums))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && fields[1] == archiveName {
+			return fields[0], nil
+		}
+	}
+	return "", fmt.Errorf("no checksum for %s; is there a release for this platform?", archiveName)
+}
+
+// Extract returns the contents of the file called name in a zip archive.
+func Extract(archive []byte, name string) ([]byte, error) {
+	reader, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, file := range reader.File {
+		if file.Name != name {
+			continue
+		}
+		rc, err := file.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+		return ioutil.ReadAll(io.LimitReader(rc, int64(file.UncompressedSize64)))
+	}
+
+	return nil, fmt.Errorf("%s not found in archive", name)
+}
@@ -0,0 +1,98 @@
+package cluster_test
+
+import (
+	"archive/zip"
+	"bytes"
+
+	"code.cloudfoundry.org/consuladapter/consulrunner/internal/cluster"
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/armor"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("FindChecksum", func() {
+	sums := []byte("0123abcd  consul_1.16.2_darwin_arm64.zip\n4567ef01  consul_1.16.2_linux_amd64.zip\n")
+
+	It("finds the archive's checksum", func() {
+		Expect(cluster.FindChecksum(sums, "consul_1.16.2_linux_amd64.zip")).To(Equal("4567ef01"))
+	})
+
+	It("fails for a platform without a release", func() {
+		_, err := cluster.FindChecksum(sums, "consul_1.16.2_plan9_386.zip")
+		Expect(err).To(MatchError(ContainSubstring("no checksum for consul_1.16.2_plan9_386.zip")))
+	})
+})
+
+var _ = Describe("Extract", func() {
+	var archive []byte
+
+	BeforeEach(func() {
+		buffer := &bytes.Buffer{}
+		writer := zip.NewWriter(buffer)
+		file, err := writer.Create("consul")
+		Expect(err).NotTo(HaveOccurred())
+		_, err = file.Write([]byte("binary"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(writer.Close()).To(Succeed())
+		archive = buffer.Bytes()
+	})
+
+	It("returns the named file", func() {
+		Expect(cluster.Extract(archive, "consul")).To(Equal([]byte("binary")))
+	})
+
+	It("fails if the file is missing", func() {
+		_, err := cluster.Extract(archive, "consul.exe")
+		Expect(err).To(MatchError("consul.exe not found in archive"))
+	})
+
+	It("fails for something other than a zip archive", func() {
+		_, err := cluster.Extract([]byte("not a zip"), "consul")
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("VerifySignature", func() {
+	var (
+		signer     *openpgp.Entity
+		releaseKey []byte
+		sums       = []byte("4567ef01  consul_1.16.2_linux_amd64.zip\n")
+	)
+
+	BeforeEach(func() {
+		var err error
+		signer, err = openpgp.NewEntity("releases", "", "releases@example.com", nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		key := &bytes.Buffer{}
+		writer, err := armor.Encode(key, openpgp.PublicKeyType, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(signer.Serialize(writer)).To(Succeed())
+		Expect(writer.Close()).To(Succeed())
+		releaseKey = key.Bytes()
+	})
+
+	sign := func(entity *openpgp.Entity, signed []byte) []byte {
+		signature := &bytes.Buffer{}
+		Expect(openpgp.DetachSign(signature, entity, bytes.NewReader(signed), nil)).To(Succeed())
+		return signature.Bytes()
+	}
+
+	It("accepts sums signed by the release key", func() {
+		Expect(cluster.VerifySignature(sums, sign(signer, sums), releaseKey)).To(Succeed())
+	})
+
+	It("rejects sums that were changed after signing", func() {
+		signature := sign(signer, sums)
+		tampered := []byte("00000000  consul_1.16.2_linux_amd64.zip\n")
+		Expect(cluster.VerifySignature(tampered, signature, releaseKey)).To(MatchError(ContainSubstring("verifying SHA256SUMS signature")))
+	})
+
+	It("rejects sums signed by another key", func() {
+		other, err := openpgp.NewEntity("other", "", "other@example.com", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cluster.VerifySignature(sums, sign(other, sums), releaseKey)).To(MatchError(ContainSubstring("verifying SHA256SUMS signature")))
+	})
+})
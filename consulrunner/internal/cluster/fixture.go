@@ -0,0 +1,216 @@
+package cluster
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"time"
+
+	"code.cloudfoundry.org/consuladapter"
+	"github.com/hashicorp/consul/api"
+)
+
+type Fixture struct {
+	KV       []FixtureKV      `json:"kv"`
+	Sessions []FixtureSession `json:"sessions"`
+	Services []FixtureService `json:"services"`
+}
+
+type FixtureKV struct {
+	Key     string `json:"key"`
+	Value   []byte `json:"value"`
+	Flags   uint64 `json:"flags,omitempty"`
+	Session string `json:"session,omitempty"`
+}
+
+// FixtureSession records session metadata. Sessions are recreated with new
+// IDs when loaded; ID is only used to re-acquire the keys they held. TTL is
+// recorded but not restored, see LoadFixture.
+type FixtureSession struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Node      string `json:"node"`
+	Behavior  string `json:"behavior"`
+	TTL       string `json:"ttl"`
+	LockDelay string `json:"lock_delay"`
+}
+
+type FixtureService struct {
+	ID      string            `json:"id"`
+	Name    string            `json:"name"`
+	Tags    []string          `json:"tags,omitempty"`
+	Address string            `json:"address,omitempty"`
+	Port    int               `json:"port,omitempty"`
+	Meta    map[string]string `json:"meta,omitempty"`
+}
+
+func ExportFixture(client consuladapter.Client) (Fixture, error) {
+	fixture := Fixture{
+		KV:       []FixtureKV{},
+		Sessions: []FixtureSession{},
+		Services: []FixtureService{},
+	}
+
+	pairs, _, err := client.KV().List("", nil)
+	if err != nil {
+		return Fixture{}, err
+	}
+	for _, pair := range pairs {
+		fixture.KV = append(fixture.KV, FixtureKV{
+			Key:     pair.Key,
+			Value:   pair.Value,
+			Flags:   pair.Flags,
+			Session: pair.Session,
+		})
+	}
+	sort.Sort(fixtureKVsByKey(fixture.KV))
+
+	sessions, _, err := client.Session().List(nil)
+	if err != nil {
+		return Fixture{}, err
+	}
+	for _, session := range sessions {
+		fixture.Sessions = append(fixture.Sessions, FixtureSession{
+			ID:        session.ID,
+			Name:      session.Name,
+			Node:      session.Node,
+			Behavior:  session.Behavior,
+			TTL:       session.TTL,
+			LockDelay: session.LockDelay.String(),
+		})
+	}
+	sort.Sort(fixtureSessionsByID(fixture.Sessions))
+
+	services, err := client.Agent().Services()
+	if err != nil {
+		return Fixture{}, err
+	}
+	for _, service := range services {
+		if service.Service == "consul" {
+			continue
+		}
+		fixture.Services = append(fixture.Services, FixtureService{
+			ID:      service.ID,
+			Name:    service.Service,
+			Tags:    service.Tags,
+			Address: service.Address,
+			Port:    service.Port,
+			Meta:    service.Meta,
+		})
+	}
+	sort.Sort(fixtureServicesByID(fixture.Services))
+
+	return fixture, nil
+}
+
+// LoadFixture registers the fixture's services, recreates its sessions and
+// writes its keys, re-acquiring keys that were held by a session.
+//
+// Sessions are recreated without their TTL: nothing renews them once
+// loaded, so they would otherwise expire, releasing or deleting their keys,
+// partway through the specs using the fixture. They last until destroyed
+// or their node fails its health checks.
+func LoadFixture(client consuladapter.Client, fixture Fixture) error {
+	for _, service := range fixture.Services {
+		err := client.Agent().ServiceRegister(&api.AgentServiceRegistration{
+			ID:      service.ID,
+			Name:    service.Name,
+			Tags:    service.Tags,
+			Address: service.Address,
+			Port:    service.Port,
+			Meta:    service.Meta,
+		})
+		if err != nil {
+			return fmt.Errorf("registering service %s: %s", service.ID, err)
+		}
+	}
+
+	sessionIDs := map[string]string{}
+	for _, session := range fixture.Sessions {
+		entry := &api.SessionEntry{
+			Name:     consuladapter.TagSessionName(session.Name),
+			Node:     session.Node,
+			Behavior: session.Behavior,
+		}
+		if session.LockDelay != "" {
+			lockDelay, err := time.ParseDuration(session.LockDelay)
+			if err != nil {
+				return err
+			}
+			entry.LockDelay = lockDelay
+		}
+
+		id, _, err := client.Session().Create(entry, nil)
+		if err != nil {
+			return fmt.Errorf("creating session %s: %s", session.ID, err)
+		}
+		sessionIDs[session.ID] = id
+	}
+
+	for _, kv := range fixture.KV {
+		pair := &api.KVPair{Key: kv.Key, Value: kv.Value, Flags: kv.Flags}
+
+		if kv.Session == "" {
+			_, err := client.KV().Put(pair, nil)
+			if err != nil {
+				return fmt.Errorf("writing key %s: %s", kv.Key, err)
+			}
+			continue
+		}
+
+		sessionID, ok := sessionIDs[kv.Session]
+		if !ok {
+			return fmt.Errorf("key %s is held by unknown session %s", kv.Key, kv.Session)
+		}
+
+		pair.Session = sessionID
+		acquired, _, err := client.KV().Acquire(pair, nil)
+		if err != nil {
+			return fmt.Errorf("acquiring key %s: %s", kv.Key, err)
+		}
+		if !acquired {
+			return fmt.Errorf("failed to acquire key %s", kv.Key)
+		}
+	}
+
+	return nil
+}
+
+func WriteFixture(filePath string, fixture Fixture) error {
+	fixtureJSON, err := json.MarshalIndent(fixture, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(filePath, fixtureJSON, 0644)
+}
+
+func ReadFixture(filePath string) (Fixture, error) {
+	fixtureJSON, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return Fixture{}, err
+	}
+
+	var fixture Fixture
+	err = json.Unmarshal(fixtureJSON, &fixture)
+	return fixture, err
+}
+
+type fixtureKVsByKey []FixtureKV
+
+func (f fixtureKVsByKey) Len() int           { return len(f) }
+func (f fixtureKVsByKey) Less(i, j int) bool { return f[i].Key < f[j].Key }
+func (f fixtureKVsByKey) Swap(i, j int)      { f[i], f[j] = f[j], f[i] }
+
+type fixtureSessionsByID []FixtureSession
+
+func (f fixtureSessionsByID) Len() int           { return len(f) }
+func (f fixtureSessionsByID) Less(i, j int) bool { return f[i].ID < f[j].ID }
+func (f fixtureSessionsByID) Swap(i, j int)      { f[i], f[j] = f[j], f[i] }
+
+type fixtureServicesByID []FixtureService
+
+func (f fixtureServicesByID) Len() int           { return len(f) }
+func (f fixtureServicesByID) Less(i, j int) bool { return f[i].ID < f[j].ID }
+func (f fixtureServicesByID) Swap(i, j int)      { f[i], f[j] = f[j], f[i] }
@@ -0,0 +1,113 @@
+package cluster_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"code.cloudfoundry.org/consuladapter"
+	"code.cloudfoundry.org/consuladapter/consulrunner/internal/cluster"
+	"code.cloudfoundry.org/consuladapter/fakes"
+	"github.com/hashicorp/consul/api"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Fixtures", func() {
+	var fixture cluster.Fixture
+
+	BeforeEach(func() {
+		fixture = cluster.Fixture{
+			KV: []cluster.FixtureKV{
+				{Key: "v1/config", Value: []byte("config"), Flags: 7},
+				{Key: "v1/locks/bbs", Value: []byte("bbs-1"), Session: "old-session"},
+			},
+			Sessions: []cluster.FixtureSession{
+				{ID: "old-session", Name: "bbs", Behavior: api.SessionBehaviorDelete, TTL: "15s", LockDelay: "1s"},
+			},
+			Services: []cluster.FixtureService{
+				{ID: "bbs-1", Name: "bbs", Tags: []string{"a"}, Port: 8889},
+			},
+		}
+	})
+
+	Describe("LoadFixture", func() {
+		var (
+			backend *fakes.FakeBackend
+			client  *fakes.FakeClient
+			agent   *fakes.FakeAgent
+		)
+
+		BeforeEach(func() {
+			backend = fakes.NewFakeBackend()
+			var components *fakes.FakeClientComponents
+			client, components = backend.Client()
+			agent = components.Agent
+		})
+
+		It("registers services, recreates sessions and writes keys", func() {
+			Expect(cluster.LoadFixture(client, fixture)).To(Succeed())
+
+			Expect(agent.ServiceRegisterCallCount()).To(Equal(1))
+			registration := agent.ServiceRegisterArgsForCall(0)
+			Expect(registration.ID).To(Equal("bbs-1"))
+			Expect(registration.Port).To(Equal(8889))
+
+			pair, _, err := backend.KV().Get("v1/config", nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pair.Value).To(Equal([]byte("config")))
+			Expect(pair.Flags).To(BeEquivalentTo(7))
+			Expect(pair.Session).To(BeEmpty())
+
+			sessions, _, err := backend.Session().List(nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(sessions).To(HaveLen(1))
+			Expect(sessions[0].Name).To(Equal(consuladapter.TagSessionName("bbs")))
+			Expect(sessions[0].Behavior).To(Equal(api.SessionBehaviorDelete))
+			Expect(backend.Holder("v1/locks/bbs")).To(Equal(sessions[0].ID))
+		})
+
+		It("recreates sessions without a TTL, so they do not expire unrenewed", func() {
+			Expect(cluster.LoadFixture(client, fixture)).To(Succeed())
+
+			sessions, _, err := backend.Session().List(nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(sessions[0].TTL).To(BeEmpty())
+		})
+
+		It("fails for keys held by a session the fixture does not record", func() {
+			fixture.Sessions = nil
+			err := cluster.LoadFixture(client, fixture)
+			Expect(err).To(MatchError("key v1/locks/bbs is held by unknown session old-session"))
+		})
+
+		It("fails for invalid lock delays", func() {
+			fixture.Sessions[0].LockDelay = "soon"
+			Expect(cluster.LoadFixture(client, fixture)).NotTo(Succeed())
+		})
+	})
+
+	Describe("WriteFixture and ReadFixture", func() {
+		var dir string
+
+		BeforeEach(func() {
+			var err error
+			dir, err = ioutil.TempDir("", "fixture")
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			os.RemoveAll(dir)
+		})
+
+		It("round-trip fixtures through a file", func() {
+			path := filepath.Join(dir, "fixture.json")
+			Expect(cluster.WriteFixture(path, fixture)).To(Succeed())
+
+			read, err := cluster.ReadFixture(path)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(read).To(Equal(fixture))
+		})
+	})
+})
@@ -0,0 +1,83 @@
+package cluster
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+const defaultCgroupRoot = "/sys/fs/cgroup"
+
+type ResourceLimits struct {
+	// GOMAXPROCS is passed to the agent's environment.
+	GOMAXPROCS int
+
+	// MaxOpenFiles sets the agent's open file descriptor ulimit.
+	MaxOpenFiles uint64
+
+	// CPUQuota (in CPUs, e.g. 0.5) and MemoryLimit (in bytes) are enforced
+	// through a cgroup v2 group created under CgroupRoot per agent. Linux only.
+	CPUQuota    float64
+	MemoryLimit uint64
+	CgroupRoot  string
+}
+
+func (l ResourceLimits) usesCgroup() bool {
+	return l.CPUQuota > 0 || l.MemoryLimit > 0
+}
+
+func (l ResourceLimits) needsWrapper() bool {
+	return l.MaxOpenFiles > 0 || l.usesCgroup()
+}
+
+// NewAgentCommand builds the command for a consul agent with the given
+// limits applied. The returned cleanup function must be called once the
+// agent has exited.
+func NewAgentCommand(limits ResourceLimits, name string, args ...string) (*exec.Cmd, func() error, error) {
+	cleanup := func() error { return nil }
+
+	var cmd *exec.Cmd
+	if limits.needsWrapper() {
+		cgroupDir := ""
+		if limits.usesCgroup() {
+			root := limits.CgroupRoot
+			if root == "" {
+				root = defaultCgroupRoot
+			}
+
+			var err error
+			cgroupDir, err = createCgroup(root, name, limits)
+			if err != nil {
+				return nil, nil, err
+			}
+			cleanup = func() error { return os.Remove(cgroupDir) }
+		}
+
+		var err error
+		cmd, err = wrapCommand(limits, cgroupDir, ConsulBinary(), args...)
+		if err != nil {
+			cleanup()
+			return nil, nil, err
+		}
+	} else {
+		cmd = exec.Command(ConsulBinary(), args...)
+	}
+
+	if limits.GOMAXPROCS > 0 {
+		cmd.Env = append(os.Environ(), fmt.Sprintf("GOMAXPROCS=%d", limits.GOMAXPROCS))
+	}
+
+	return cmd, cleanup, nil
+}
+
+// AddEnv adds env, as "NAME=value" pairs, to cmd's environment. Later
+// values override earlier ones, including the test process' own.
+func AddEnv(cmd *exec.Cmd, env []string) {
+	if len(env) == 0 {
+		return
+	}
+	if cmd.Env == nil {
+		cmd.Env = os.Environ()
+	}
+	cmd.Env = append(cmd.Env, env...)
+}
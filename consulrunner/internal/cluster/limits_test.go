@@ -0,0 +1,36 @@
+package cluster_test
+
+import (
+	"os"
+	"os/exec"
+
+	"code.cloudfoundry.org/consuladapter/consulrunner/internal/cluster"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("AddEnv", func() {
+	It("adds the variables to the test process' environment", func() {
+		cmd := exec.Command("consul")
+		cluster.AddEnv(cmd, []string{"CONSUL_LICENSE=abc"})
+
+		Expect(cmd.Env).To(HaveLen(len(os.Environ()) + 1))
+		Expect(cmd.Env[len(cmd.Env)-1]).To(Equal("CONSUL_LICENSE=abc"))
+	})
+
+	It("keeps an environment the command already has, overriding its values", func() {
+		cmd := exec.Command("consul")
+		cmd.Env = []string{"GOMAXPROCS=2"}
+		cluster.AddEnv(cmd, []string{"GOMAXPROCS=4", "CONSUL_LICENSE=abc"})
+
+		Expect(cmd.Env).To(Equal([]string{"GOMAXPROCS=2", "GOMAXPROCS=4", "CONSUL_LICENSE=abc"}))
+	})
+
+	It("leaves the command alone when there is nothing to add", func() {
+		cmd := exec.Command("consul")
+		cluster.AddEnv(cmd, nil)
+
+		Expect(cmd.Env).To(BeNil())
+	})
+})
@@ -0,0 +1,22 @@
+// +build !windows
+
+package cluster
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+func wrapCommand(limits ResourceLimits, cgroupDir string, name string, args ...string) (*exec.Cmd, error) {
+	var script []string
+	if cgroupDir != "" {
+		script = append(script, fmt.Sprintf("echo $$ > '%s/cgroup.procs'", cgroupDir))
+	}
+	if limits.MaxOpenFiles > 0 {
+		script = append(script, fmt.Sprintf("ulimit -n %d", limits.MaxOpenFiles))
+	}
+	script = append(script, `exec "$0" "$@"`)
+
+	return exec.Command("/bin/sh", append([]string{"-c", strings.Join(script, " && "), name}, args...)...), nil
+}
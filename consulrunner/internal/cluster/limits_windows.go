@@ -0,0 +1,12 @@
+// +build windows
+
+package cluster
+
+import (
+	"errors"
+	"os/exec"
+)
+
+func wrapCommand(limits ResourceLimits, cgroupDir string, name string, args ...string) (*exec.Cmd, error) {
+	return nil, errors.New("open file and cgroup limits are not supported on windows")
+}
@@ -0,0 +1,57 @@
+package cluster
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+
+	"code.cloudfoundry.org/consuladapter/agentconfig"
+)
+
+type PortInUseError struct {
+	Address string
+	Err     error
+}
+
+func (e PortInUseError) Error() string {
+	return fmt.Sprintf("port %s is unavailable: %s", e.Address, e.Err)
+}
+
+// CheckPortsFree probes the TCP ports an agent will listen on, so that a
+// port held by another process, or reserved as on Windows, is reported
+// clearly rather than as an agent that fails to start.
+func CheckPortsFree(bindAddress string, ports agentconfig.PortLayout, https bool) error {
+	if bindAddress == "" {
+		bindAddress = agentconfig.DefaultBindAddress
+	}
+
+	probe := []int{ports.DNS, ports.HTTP, ports.SerfLAN, ports.SerfWAN, ports.Server}
+	if https {
+		probe = append(probe, ports.HTTPS)
+	}
+
+	for _, port := range probe {
+		address := net.JoinHostPort(bindAddress, strconv.Itoa(port))
+		listener, err := net.Listen("tcp", address)
+		if err != nil {
+			return PortInUseError{Address: address, Err: err}
+		}
+		listener.Close()
+	}
+
+	return nil
+}
+
+var bindFailureRegexp = regexp.MustCompile(`listen (?:tcp|udp)\S* (\S+): bind: ([^\n"]+)`)
+
+// BindFailure returns a PortInUseError if an agent's log shows it exited
+// because a port was taken after CheckPortsFree probed it.
+func BindFailure(log []byte) error {
+	match := bindFailureRegexp.FindSubmatch(log)
+	if match == nil {
+		return nil
+	}
+	return PortInUseError{Address: string(match[1]), Err: errors.New(string(match[2]))}
+}
@@ -0,0 +1,115 @@
+package cluster_test
+
+import (
+	"errors"
+	"net"
+	"strconv"
+
+	"code.cloudfoundry.org/consuladapter/agentconfig"
+	"code.cloudfoundry.org/consuladapter/consulrunner/internal/cluster"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("BindFailure", func() {
+	It("finds a taken port in an agent's startup error", func() {
+		log := []byte(`==> Starting Consul agent...
+==> Error starting agent: Failed to start Consul server: Failed to start RPC layer: listen tcp 127.0.0.1:5005: bind: address already in use
+`)
+		Expect(cluster.BindFailure(log)).To(Equal(cluster.PortInUseError{
+			Address: "127.0.0.1:5005",
+			Err:     errors.New("address already in use"),
+		}))
+	})
+
+	It("finds a taken port in a structured log line", func() {
+		log := []byte(`2023-10-16T09:12:01.482Z [ERROR] agent: startup error: error="error starting listeners: listen tcp 127.0.0.1:5001: bind: address already in use"
+`)
+		Expect(cluster.BindFailure(log)).To(Equal(cluster.PortInUseError{
+			Address: "127.0.0.1:5001",
+			Err:     errors.New("address already in use"),
+		}))
+	})
+
+	It("finds taken UDP and IPv6 ports", func() {
+		log := []byte(`==> Error starting agent: error starting agent: Failed to start DNS server: listen udp [::1]:5000: bind: address already in use
+`)
+		Expect(cluster.BindFailure(log)).To(Equal(cluster.PortInUseError{
+			Address: "[::1]:5000",
+			Err:     errors.New("address already in use"),
+		}))
+	})
+
+	It("finds ports Windows reserves", func() {
+		log := []byte(`==> Error starting agent: error starting listeners: listen tcp 127.0.0.1:5001: bind: An attempt was made to access a socket in a way forbidden by its access permissions.
+`)
+		Expect(cluster.BindFailure(log)).To(Equal(cluster.PortInUseError{
+			Address: "127.0.0.1:5001",
+			Err:     errors.New("An attempt was made to access a socket in a way forbidden by its access permissions."),
+		}))
+	})
+
+	It("ignores other startup errors", func() {
+		log := []byte(`==> Error starting agent: Failed to start Consul server: Failed to start Raft: open /tmp/consul_data/raft/raft.db: permission denied
+`)
+		Expect(cluster.BindFailure(log)).To(BeNil())
+	})
+})
+
+var _ = Describe("CheckPortsFree", func() {
+	var (
+		listener net.Listener
+		taken    int
+		ports    agentconfig.PortLayout
+	)
+
+	// freePort returns a port that was free when it was probed
+	freePort := func() int {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		Expect(err).NotTo(HaveOccurred())
+		defer l.Close()
+		return l.Addr().(*net.TCPAddr).Port
+	}
+
+	BeforeEach(func() {
+		var err error
+		listener, err = net.Listen("tcp", "127.0.0.1:0")
+		Expect(err).NotTo(HaveOccurred())
+		taken = listener.Addr().(*net.TCPAddr).Port
+
+		ports = agentconfig.PortLayout{
+			DNS:     freePort(),
+			HTTP:    freePort(),
+			SerfLAN: freePort(),
+			SerfWAN: freePort(),
+			Server:  freePort(),
+			HTTPS:   freePort(),
+		}
+	})
+
+	AfterEach(func() {
+		listener.Close()
+	})
+
+	It("succeeds when the agent's ports are free", func() {
+		Expect(cluster.CheckPortsFree("127.0.0.1", ports, true)).To(Succeed())
+	})
+
+	It("returns a PortInUseError for a port that is taken", func() {
+		ports.Server = taken
+
+		err := cluster.CheckPortsFree("", ports, false)
+		var portErr cluster.PortInUseError
+		Expect(errors.As(err, &portErr)).To(BeTrue())
+		Expect(portErr.Address).To(Equal(net.JoinHostPort("127.0.0.1", strconv.Itoa(taken))))
+		Expect(portErr.Err).To(HaveOccurred())
+	})
+
+	It("only probes the HTTPS port for https clusters", func() {
+		ports.HTTPS = taken
+
+		Expect(cluster.CheckPortsFree("127.0.0.1", ports, false)).To(Succeed())
+		Expect(cluster.CheckPortsFree("127.0.0.1", ports, true)).To(BeAssignableToTypeOf(cluster.PortInUseError{}))
+	})
+})
@@ -0,0 +1,31 @@
+package cluster
+
+import (
+	"fmt"
+
+	"code.cloudfoundry.org/consuladapter"
+	"code.cloudfoundry.org/consuladapter/agentconfig"
+)
+
+// Reconfigure applies update to the agent config file at filePath and has
+// the agent reload it through client. Only settings consul can reload take
+// effect, e.g. log_level and checks; others need the agent restarted.
+func Reconfigure(client consuladapter.Client, filePath string, update func(*agentconfig.ConfigFile)) error {
+	config, err := agentconfig.ReadConfigFile(filePath)
+	if err != nil {
+		return fmt.Errorf("reading agent config: %v", err)
+	}
+
+	update(&config)
+
+	err = config.WriteFile(filePath)
+	if err != nil {
+		return fmt.Errorf("writing agent config: %v", err)
+	}
+
+	err = client.Agent().Reload()
+	if err != nil {
+		return fmt.Errorf("reloading agent: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package cluster_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"code.cloudfoundry.org/consuladapter/agentconfig"
+	"code.cloudfoundry.org/consuladapter/consulrunner/internal/cluster"
+	"code.cloudfoundry.org/consuladapter/fakes"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Reconfigure", func() {
+	var (
+		configDir string
+		filePath  string
+		opts      agentconfig.ConfigOptions
+		client    *fakes.FakeClient
+		agent     *fakes.FakeAgent
+	)
+
+	BeforeEach(func() {
+		var err error
+		configDir, err = ioutil.TempDir("", "reload")
+		Expect(err).NotTo(HaveOccurred())
+
+		opts = agentconfig.ConfigOptions{NodeName: "0", ClusterStartingPort: 5000, NumNodes: 1, LogLevel: "info"}
+		filePath, err = agentconfig.WriteConfigFile(configDir, opts)
+		Expect(err).NotTo(HaveOccurred())
+
+		var components *fakes.FakeClientComponents
+		client, components = fakes.NewFakeBackend().Client()
+		agent = components.Agent
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(configDir)
+	})
+
+	It("rewrites the config file with the update applied, then reloads the agent", func() {
+		err := cluster.Reconfigure(client, filePath, func(config *agentconfig.ConfigFile) {
+			Expect(agent.ReloadCallCount()).To(Equal(0))
+			config.LogLevel = "debug"
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(agent.ReloadCallCount()).To(Equal(1))
+
+		config, err := agentconfig.ReadConfigFile(filePath)
+		Expect(err).NotTo(HaveOccurred())
+
+		expected := agentconfig.NewConfigFile(opts)
+		expected.LogLevel = "debug"
+		Expect(config).To(Equal(expected))
+	})
+
+	It("fails without reloading when the config file cannot be read", func() {
+		err := cluster.Reconfigure(client, filepath.Join(configDir, "missing.json"), func(*agentconfig.ConfigFile) {
+			Fail("update called without a config")
+		})
+		Expect(err).To(MatchError(ContainSubstring("reading agent config")))
+		Expect(agent.ReloadCallCount()).To(Equal(0))
+	})
+
+	It("fails when the agent does not reload, leaving the config file updated", func() {
+		agent.ReloadReturns(errors.New("boom"))
+
+		err := cluster.Reconfigure(client, filePath, func(config *agentconfig.ConfigFile) {
+			config.LogLevel = "debug"
+		})
+		Expect(err).To(MatchError("reloading agent: boom"))
+
+		config, err := agentconfig.ReadConfigFile(filePath)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(config.LogLevel).To(Equal("debug"))
+	})
+})
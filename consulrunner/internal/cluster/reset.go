@@ -0,0 +1,152 @@
+package cluster
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"code.cloudfoundry.org/consuladapter"
+)
+
+type ResetFailure struct {
+	// Operation is the cleanup step that failed, e.g. "destroy-session".
+	Operation string
+	// ID identifies the session, service, check or key involved, if any.
+	ID  string
+	Err error
+}
+
+func (f ResetFailure) Error() string {
+	if f.ID == "" {
+		return fmt.Sprintf("%s: %s", f.Operation, f.Err)
+	}
+	return fmt.Sprintf("%s %s: %s", f.Operation, f.ID, f.Err)
+}
+
+// ResetError lists every cleanup operation that failed during Reset.
+type ResetError struct {
+	Failures []ResetFailure
+}
+
+func (e *ResetError) Error() string {
+	messages := make([]string, len(e.Failures))
+	for i, failure := range e.Failures {
+		messages[i] = failure.Error()
+	}
+	return fmt.Sprintf("reset failed: %s", strings.Join(messages, "; "))
+}
+
+func (e *ResetError) add(operation, id string, err error) {
+	e.Failures = append(e.Failures, ResetFailure{Operation: operation, ID: id, Err: err})
+}
+
+type ResetOptions struct {
+	// Reset destroys only the sessions the adapter created, named with
+	// consuladapter.SessionNamePrefix, leaving other users' sessions on a
+	// shared cluster alone. SessionNamePrefix, if set, selects sessions
+	// whose names start with it instead, whether or not the adapter tagged
+	// them. Force destroys every session regardless.
+	SessionNamePrefix string
+	Force             bool
+
+	// KeyPrefix, if set, limits the keys Reset deletes to those under it.
+	KeyPrefix string
+
+	// ServiceNamePrefix, if set, limits the services Reset deregisters to
+	// those whose names start with it, along with their checks. Checks of
+	// the node itself are then left registered.
+	ServiceNamePrefix string
+}
+
+// ErrEmptyResetPrefix is returned by ScopedResetOptions for an empty
+// prefix, which would reset everything rather than one suite's state.
+var ErrEmptyResetPrefix = errors.New("scoped reset needs a non-empty prefix")
+
+// ScopedResetOptions returns options resetting only what a test suite
+// created under prefix: keys under it, and sessions and services whose
+// names start with it. Suites sharing a cluster can each use their own.
+func ScopedResetOptions(prefix string) (ResetOptions, error) {
+	if prefix == "" {
+		return ResetOptions{}, ErrEmptyResetPrefix
+	}
+
+	return ResetOptions{
+		SessionNamePrefix: prefix,
+		KeyPrefix:         prefix,
+		ServiceNamePrefix: prefix,
+	}, nil
+}
+
+// Reset destroys the sessions, deregisters the services and checks, and
+// deletes the keys selected by opts, by default all of them, continuing
+// past individual failures. Any failures are returned together as a
+// *ResetError.
+func Reset(client consuladapter.Client, opts ResetOptions) error {
+	resetErr := &ResetError{}
+
+	for _, prefix := range sessionNamePrefixes(opts) {
+		_, err := consuladapter.DestroySessions(client.Session(), consuladapter.SessionFilter{NamePrefix: prefix})
+		if failures, ok := err.(consuladapter.DestroySessionsError); ok {
+			for _, failure := range failures {
+				resetErr.add("destroy-session", failure.ID, failure.Err)
+			}
+		} else if err != nil {
+			resetErr.add("list-sessions", "", err)
+		}
+	}
+
+	services, err := client.Agent().Services()
+	if err != nil {
+		resetErr.add("list-services", "", err)
+	}
+	for _, service := range services {
+		if service.Service == "consul" || !strings.HasPrefix(service.Service, opts.ServiceNamePrefix) {
+			continue
+		}
+		err := client.Agent().ServiceDeregister(service.ID)
+		if err != nil {
+			resetErr.add("deregister-service", service.ID, err)
+		}
+	}
+
+	checks, err := client.Agent().Checks()
+	if err != nil {
+		resetErr.add("list-checks", "", err)
+	}
+	for _, check := range checks {
+		if opts.ServiceNamePrefix != "" && (check.ServiceName == "" || !strings.HasPrefix(check.ServiceName, opts.ServiceNamePrefix)) {
+			continue
+		}
+		err := client.Agent().CheckDeregister(check.CheckID)
+		if err != nil {
+			resetErr.add("deregister-check", check.CheckID, err)
+		}
+	}
+
+	_, err = client.KV().DeleteTree(opts.KeyPrefix, nil)
+	if err != nil {
+		resetErr.add("delete-keys", "", err)
+	}
+
+	if len(resetErr.Failures) > 0 {
+		return resetErr
+	}
+	return nil
+}
+
+// sessionNamePrefixes returns the name prefixes of the sessions Reset
+// destroys with opts.
+func sessionNamePrefixes(opts ResetOptions) []string {
+	switch {
+	case opts.Force:
+		return []string{""}
+	case opts.SessionNamePrefix == "":
+		return []string{consuladapter.SessionNamePrefix}
+	}
+
+	tagged := consuladapter.TagSessionName(opts.SessionNamePrefix)
+	if tagged == opts.SessionNamePrefix {
+		return []string{tagged}
+	}
+	return []string{opts.SessionNamePrefix, tagged}
+}
@@ -0,0 +1,184 @@
+package cluster_test
+
+import (
+	"errors"
+	"sort"
+
+	"code.cloudfoundry.org/consuladapter"
+	"code.cloudfoundry.org/consuladapter/consulrunner/internal/cluster"
+	"code.cloudfoundry.org/consuladapter/fakes"
+	"github.com/hashicorp/consul/api"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Reset", func() {
+	var (
+		backend    *fakes.FakeBackend
+		client     *fakes.FakeClient
+		agent      *fakes.FakeAgent
+		ttlSession *consuladapter.TTLSession
+	)
+
+	createSession := func(name string) {
+		_, _, err := backend.Session().Create(&api.SessionEntry{Name: name}, nil)
+		Expect(err).NotTo(HaveOccurred())
+	}
+
+	sessionNames := func() []string {
+		sessions, _, err := backend.Session().List(nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		names := []string{}
+		for _, session := range sessions {
+			names = append(names, session.Name)
+		}
+		sort.Strings(names)
+		return names
+	}
+
+	BeforeEach(func() {
+		backend = fakes.NewFakeBackend()
+		var components *fakes.FakeClientComponents
+		client, components = backend.Client()
+		agent = components.Agent
+
+		var err error
+		ttlSession, err = consuladapter.NewTTLSession(backend.Session(), &api.SessionEntry{Name: "suite-a-presence", TTL: "10s"})
+		Expect(err).NotTo(HaveOccurred())
+
+		createSession(consuladapter.TagSessionName("Consul API Lock"))
+		createSession("suite-a-raw")
+		createSession("other-user")
+
+		for _, key := range []string{"suite-a/locks/bbs", "suite-b/locks/bbs", "v1/locks/bbs"} {
+			_, err := backend.KV().Put(&api.KVPair{Key: key}, nil)
+			Expect(err).NotTo(HaveOccurred())
+		}
+
+		agent.ServicesReturns(map[string]*api.AgentService{
+			"consul":        {ID: "consul", Service: "consul"},
+			"suite-a-cell":  {ID: "suite-a-cell", Service: "suite-a-cell"},
+			"other-service": {ID: "other-service", Service: "other-service"},
+		}, nil)
+		agent.ChecksReturns(map[string]*api.AgentCheck{
+			"service:suite-a-cell":  {CheckID: "service:suite-a-cell", ServiceName: "suite-a-cell"},
+			"service:other-service": {CheckID: "service:other-service", ServiceName: "other-service"},
+			"node-health":           {CheckID: "node-health"},
+		}, nil)
+	})
+
+	keys := func() []string {
+		pairs, _, err := backend.KV().List("", nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		keys := []string{}
+		for _, pair := range pairs {
+			keys = append(keys, pair.Key)
+		}
+		return keys
+	}
+
+	deregistered := func() ([]string, []string) {
+		services := []string{}
+		for i := 0; i < agent.ServiceDeregisterCallCount(); i++ {
+			services = append(services, agent.ServiceDeregisterArgsForCall(i))
+		}
+		checks := []string{}
+		for i := 0; i < agent.CheckDeregisterCallCount(); i++ {
+			checks = append(checks, agent.CheckDeregisterArgsForCall(i))
+		}
+		sort.Strings(services)
+		sort.Strings(checks)
+		return services, checks
+	}
+
+	AfterEach(func() {
+		ttlSession.Destroy()
+	})
+
+	It("destroys only the sessions the adapter created by default", func() {
+		Expect(cluster.Reset(client, cluster.ResetOptions{})).To(Succeed())
+		Expect(sessionNames()).To(Equal([]string{"other-user", "suite-a-raw"}))
+	})
+
+	It("destroys the sessions named with SessionNamePrefix, whether or not the adapter tagged them", func() {
+		Expect(cluster.Reset(client, cluster.ResetOptions{SessionNamePrefix: "suite-a-"})).To(Succeed())
+		Expect(sessionNames()).To(Equal([]string{consuladapter.SessionNamePrefix + "Consul API Lock", "other-user"}))
+	})
+
+	It("destroys every session when forced", func() {
+		Expect(cluster.Reset(client, cluster.ResetOptions{SessionNamePrefix: "suite-a-", Force: true})).To(Succeed())
+		Expect(sessionNames()).To(BeEmpty())
+	})
+	It("deletes every key and deregisters every service and check but consul's by default", func() {
+		Expect(cluster.Reset(client, cluster.ResetOptions{})).To(Succeed())
+		Expect(keys()).To(BeEmpty())
+
+		services, checks := deregistered()
+		Expect(services).To(Equal([]string{"other-service", "suite-a-cell"}))
+		Expect(checks).To(Equal([]string{"node-health", "service:other-service", "service:suite-a-cell"}))
+	})
+
+	Describe("scoped to a prefix", func() {
+		var opts cluster.ResetOptions
+
+		BeforeEach(func() {
+			var err error
+			opts, err = cluster.ScopedResetOptions("suite-a")
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(cluster.Reset(client, opts)).To(Succeed())
+		})
+
+		It("deletes only the keys under the prefix", func() {
+			Expect(keys()).To(Equal([]string{"suite-b/locks/bbs", "v1/locks/bbs"}))
+		})
+
+		It("destroys only the sessions named with the prefix", func() {
+			Expect(sessionNames()).To(Equal([]string{consuladapter.SessionNamePrefix + "Consul API Lock", "other-user"}))
+		})
+
+		It("deregisters only the services named with the prefix, and their checks", func() {
+			services, checks := deregistered()
+			Expect(services).To(Equal([]string{"suite-a-cell"}))
+			Expect(checks).To(Equal([]string{"service:suite-a-cell"}))
+		})
+	})
+
+	Describe("when cleanup operations fail", func() {
+		var (
+			listErr       error
+			deregisterErr error
+		)
+
+		BeforeEach(func() {
+			listErr = errors.New("boom")
+			deregisterErr = errors.New("check is gone")
+			agent.ServicesReturns(nil, listErr)
+			agent.ChecksReturns(map[string]*api.AgentCheck{
+				"node-health": {CheckID: "node-health"},
+			}, nil)
+			agent.CheckDeregisterReturns(deregisterErr)
+		})
+
+		It("carries on, and returns every failure in a ResetError", func() {
+			err := cluster.Reset(client, cluster.ResetOptions{})
+			Expect(err).To(BeAssignableToTypeOf(&cluster.ResetError{}))
+			Expect(err.(*cluster.ResetError).Failures).To(Equal([]cluster.ResetFailure{
+				{Operation: "list-services", Err: listErr},
+				{Operation: "deregister-check", ID: "node-health", Err: deregisterErr},
+			}))
+			Expect(err).To(MatchError("reset failed: list-services: boom; deregister-check node-health: check is gone"))
+
+			Expect(keys()).To(BeEmpty())
+			Expect(sessionNames()).To(Equal([]string{"other-user", "suite-a-raw"}))
+		})
+	})
+
+	It("refuses to scope to an empty prefix", func() {
+		_, err := cluster.ScopedResetOptions("")
+		Expect(err).To(Equal(cluster.ErrEmptyResetPrefix))
+	})
+})
@@ -0,0 +1,113 @@
+package cluster
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"path/filepath"
+	"time"
+
+	"code.cloudfoundry.org/cfhttp"
+	"code.cloudfoundry.org/consuladapter/agentconfig"
+	"github.com/hashicorp/consul/api"
+)
+
+const tlsValidity = 24 * time.Hour
+
+// GenerateTLS writes a self-signed CA and an agent certificate for hosts
+// into dir.
+func GenerateTLS(dir string, hosts []string) (*agentconfig.TLSFiles, error) {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "consulrunner CA"},
+		NotBefore:             time.Now().Add(-time.Minute),
+		NotAfter:              time.Now().Add(tlsValidity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "server.dc1.consul"},
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(tlsValidity),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		DNSNames:     []string{"server.dc1.consul", "localhost"},
+	}
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, host)
+		}
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, template, caTemplate, &key.PublicKey, caKey)
+	if err != nil {
+		return nil, err
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	files := &agentconfig.TLSFiles{
+		CAFile:   filepath.Join(dir, "ca.pem"),
+		CertFile: filepath.Join(dir, "agent.pem"),
+		KeyFile:  filepath.Join(dir, "agent-key.pem"),
+	}
+	for file, block := range map[string]*pem.Block{
+		files.CAFile:   {Type: "CERTIFICATE", Bytes: caDER},
+		files.CertFile: {Type: "CERTIFICATE", Bytes: certDER},
+		files.KeyFile:  {Type: "EC PRIVATE KEY", Bytes: keyDER},
+	} {
+		err := ioutil.WriteFile(file, pem.EncodeToMemory(block), 0600)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return files, nil
+}
+
+// NewHTTPClient returns a streaming client for talking to the agents. When
+// tlsFiles is set it trusts their CA and presents the agent certificate,
+// which agents verifying incoming connections require.
+func NewHTTPClient(tlsFiles *agentconfig.TLSFiles) (*http.Client, error) {
+	if tlsFiles == nil {
+		return cfhttp.NewStreamingClient(), nil
+	}
+	return api.NewHttpClient(api.DefaultConfig().Transport, APITLSConfig(tlsFiles))
+}
+
+func APITLSConfig(tlsFiles *agentconfig.TLSFiles) api.TLSConfig {
+	if tlsFiles == nil {
+		return api.TLSConfig{}
+	}
+	return api.TLSConfig{
+		CAFile:   tlsFiles.CAFile,
+		CertFile: tlsFiles.CertFile,
+		KeyFile:  tlsFiles.KeyFile,
+	}
+}
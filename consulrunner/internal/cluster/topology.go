@@ -0,0 +1,96 @@
+package cluster
+
+import "code.cloudfoundry.org/consuladapter"
+
+const (
+	MemberStatusNone    = "none"
+	MemberStatusAlive   = "alive"
+	MemberStatusLeaving = "leaving"
+	MemberStatusLeft    = "left"
+	MemberStatusFailed  = "failed"
+)
+
+var memberStatuses = []string{MemberStatusNone, MemberStatusAlive, MemberStatusLeaving, MemberStatusLeft, MemberStatusFailed}
+
+type NodeTopology struct {
+	Name    string
+	Address string
+	Leader  bool
+	Voter   bool
+	Status  string
+}
+
+type Topology struct {
+	Leader    string
+	Voters    []string
+	NonVoters []string
+	Nodes     map[string]NodeTopology
+}
+
+func (t Topology) HasLeader() bool {
+	return t.Leader != ""
+}
+
+func (t Topology) VoterCount() int {
+	return len(t.Voters)
+}
+
+func (t Topology) AliveCount() int {
+	alive := 0
+	for _, node := range t.Nodes {
+		if node.Status == MemberStatusAlive {
+			alive++
+		}
+	}
+	return alive
+}
+
+func GetTopology(client consuladapter.Client) (Topology, error) {
+	raftConfig, err := client.Operator().RaftGetConfiguration(nil)
+	if err != nil {
+		return Topology{}, err
+	}
+
+	members, err := client.Agent().Members(false)
+	if err != nil {
+		return Topology{}, err
+	}
+
+	topology := Topology{
+		Voters:    []string{},
+		NonVoters: []string{},
+		Nodes:     map[string]NodeTopology{},
+	}
+
+	for _, member := range members {
+		status := MemberStatusNone
+		if member.Status >= 0 && member.Status < len(memberStatuses) {
+			status = memberStatuses[member.Status]
+		}
+
+		topology.Nodes[member.Name] = NodeTopology{
+			Name:   member.Name,
+			Status: status,
+		}
+	}
+
+	for _, server := range raftConfig.Servers {
+		node := topology.Nodes[server.Node]
+		node.Name = server.Node
+		node.Address = server.Address
+		node.Leader = server.Leader
+		node.Voter = server.Voter
+		topology.Nodes[server.Node] = node
+
+		if server.Leader {
+			topology.Leader = server.Node
+		}
+		if server.Voter {
+			topology.Voters = append(topology.Voters, server.Node)
+		} else {
+			topology.NonVoters = append(topology.NonVoters, server.Node)
+		}
+	}
+
+	return topology, nil
+}
@@ -0,0 +1,75 @@
+package cluster_test
+
+import (
+	"errors"
+
+	"code.cloudfoundry.org/consuladapter/consulrunner/internal/cluster"
+	"code.cloudfoundry.org/consuladapter/fakes"
+	"github.com/hashicorp/consul/api"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("GetTopology", func() {
+	var (
+		client     *fakes.FakeClient
+		components *fakes.FakeClientComponents
+		operator   *fakes.FakeOperator
+	)
+
+	BeforeEach(func() {
+		client, components = fakes.NewFakeClient()
+		operator = &fakes.FakeOperator{}
+		client.OperatorReturns(operator)
+
+		operator.RaftGetConfigurationReturns(&api.RaftConfiguration{Servers: []*api.RaftServer{
+			{Node: "0", Address: "127.0.0.1:5005", Leader: true, Voter: true},
+			{Node: "1", Address: "127.0.0.1:5013", Voter: true},
+			{Node: "2", Address: "127.0.0.1:5021"},
+		}}, nil)
+		components.Agent.MembersReturns([]*api.AgentMember{
+			{Name: "0", Status: 1},
+			{Name: "1", Status: 1},
+			{Name: "2", Status: 4},
+			{Name: "client", Status: 1},
+		}, nil)
+	})
+
+	It("combines the raft configuration with the agents' view of the members", func() {
+		topology, err := cluster.GetTopology(client)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(topology.Leader).To(Equal("0"))
+		Expect(topology.HasLeader()).To(BeTrue())
+		Expect(topology.Voters).To(Equal([]string{"0", "1"}))
+		Expect(topology.VoterCount()).To(Equal(2))
+		Expect(topology.NonVoters).To(Equal([]string{"2"}))
+		Expect(topology.AliveCount()).To(Equal(3))
+
+		Expect(topology.Nodes["0"]).To(Equal(cluster.NodeTopology{Name: "0", Address: "127.0.0.1:5005", Leader: true, Voter: true, Status: cluster.MemberStatusAlive}))
+		Expect(topology.Nodes["2"].Status).To(Equal(cluster.MemberStatusFailed))
+		Expect(topology.Nodes["client"]).To(Equal(cluster.NodeTopology{Name: "client", Status: cluster.MemberStatusAlive}))
+	})
+
+	It("has no leader while raft has none, and maps unknown member statuses to none", func() {
+		operator.RaftGetConfigurationReturns(&api.RaftConfiguration{Servers: []*api.RaftServer{{Node: "0", Voter: true}}}, nil)
+		components.Agent.MembersReturns([]*api.AgentMember{{Name: "0", Status: 9}}, nil)
+
+		topology, err := cluster.GetTopology(client)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(topology.HasLeader()).To(BeFalse())
+		Expect(topology.Nodes["0"].Status).To(Equal(cluster.MemberStatusNone))
+	})
+
+	It("returns errors from reading the raft configuration or members", func() {
+		operator.RaftGetConfigurationReturns(nil, errors.New("no raft"))
+		_, err := cluster.GetTopology(client)
+		Expect(err).To(MatchError("no raft"))
+
+		operator.RaftGetConfigurationReturns(&api.RaftConfiguration{}, nil)
+		components.Agent.MembersReturns(nil, errors.New("no members"))
+		_, err = cluster.GetTopology(client)
+		Expect(err).To(MatchError("no members"))
+	})
+})
@@ -0,0 +1,26 @@
+package cluster
+
+import (
+	"errors"
+	"strings"
+)
+
+func ParseVersion(output string) (string, error) {
+	lines := strings.Split(output, "\n")
+	versionLine := lines[0]
+	if !strings.HasPrefix(versionLine, "Consul v") {
+		return "", errors.New("unexpected consul version output: " + versionLine)
+	}
+
+	return strings.TrimPrefix(versionLine, "Consul v"), nil
+}
+
+func HasPerformanceFlag(version string) bool {
+	return !strings.HasPrefix(version, "0.6")
+}
+
+// IsEnterprise reports whether version, as returned by ParseVersion, is a
+// Consul Enterprise build, e.g. "1.15.2+ent".
+func IsEnterprise(version string) bool {
+	return strings.Contains(version, "+ent")
+}
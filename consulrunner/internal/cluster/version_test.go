@@ -0,0 +1,29 @@
+package cluster_test
+
+import (
+	"code.cloudfoundry.org/consuladapter/consulrunner/internal/cluster"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ParseVersion", func() {
+	It("parses the version from the first line of consul -v", func() {
+		version, err := cluster.ParseVersion("Consul v1.9.0\nRevision 10bb6cb3b\nProtocol 2 spoken by default\n")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(version).To(Equal("1.9.0"))
+	})
+
+	It("fails on other output", func() {
+		_, err := cluster.ParseVersion("consul: command not found\n")
+		Expect(err).To(MatchError("unexpected consul version output: consul: command not found"))
+	})
+
+	It("knows which versions take performance config and which are enterprise builds", func() {
+		Expect(cluster.HasPerformanceFlag("0.6.4")).To(BeFalse())
+		Expect(cluster.HasPerformanceFlag("1.9.0")).To(BeTrue())
+
+		Expect(cluster.IsEnterprise("1.15.2+ent")).To(BeTrue())
+		Expect(cluster.IsEnterprise("1.15.2")).To(BeFalse())
+	})
+})
@@ -0,0 +1,195 @@
+// Package scenarios sets up the consul state of common Diego topologies,
+// such as cell presences, a held BBS lock and route registrations, for
+// integration tests against a ClusterRunner:
+//
+//	scenario, err := scenarios.Diego{Cells: 3, BBS: true}.Apply(clusterRunner.NewClient())
+//	defer scenario.Teardown()
+package scenarios
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"code.cloudfoundry.org/consuladapter"
+	"github.com/hashicorp/consul/api"
+)
+
+const (
+	BBSLockName  = "bbs"
+	DefaultZone  = "z1"
+	DefaultTTL   = 5 * time.Second
+	repPort      = 1800
+	bbsPort      = 8889
+	cellIDFormat = "cell-%d"
+)
+
+type CellCapacity struct {
+	MemoryMB   int `json:"memory_mb"`
+	DiskMB     int `json:"disk_mb"`
+	Containers int `json:"containers"`
+}
+
+// CellPresence is the value a cell writes to its presence key.
+type CellPresence struct {
+	CellID     string       `json:"cell_id"`
+	RepAddress string       `json:"rep_address"`
+	RepURL     string       `json:"rep_url"`
+	Zone       string       `json:"zone"`
+	Capacity   CellCapacity `json:"capacity"`
+}
+
+// BBSPresence is the value the active BBS writes to its lock.
+type BBSPresence struct {
+	ID  string `json:"id"`
+	URL string `json:"url"`
+}
+
+// Route is a service registered on the agent for each of its hostnames,
+// which are set as tags.
+type Route struct {
+	Service   string
+	Port      int
+	Hostnames []string
+}
+
+// Diego describes a topology. Cells are named cell-0 to cell-<Cells-1> and
+// spread across Zones, which default to DefaultZone. Presences and the BBS
+// lock are held by sessions with TTL, which default to DefaultTTL and are
+// renewed until Teardown.
+type Diego struct {
+	Cells  int
+	Zones  []string
+	BBS    bool
+	Routes []Route
+	TTL    time.Duration
+
+	// Keys defaults to consuladapter.DefaultKeyConventions.
+	Keys *consuladapter.KeyConventions
+}
+
+// Scenario is the state set up by Diego.Apply.
+type Scenario struct {
+	CellIDs []string
+	// BBSSession is the session holding the BBS lock, if any.
+	BBSSession string
+
+	client   consuladapter.Client
+	sessions []*consuladapter.TTLSession
+	services []string
+}
+
+// Apply sets up the topology through client. On failure, whatever was set
+// up is torn down again.
+func (d Diego) Apply(client consuladapter.Client) (*Scenario, error) {
+	keys := consuladapter.DefaultKeyConventions
+	if d.Keys != nil {
+		keys = *d.Keys
+	}
+	zones := d.Zones
+	if len(zones) == 0 {
+		zones = []string{DefaultZone}
+	}
+	ttl := d.TTL
+	if ttl == 0 {
+		ttl = DefaultTTL
+	}
+
+	s := &Scenario{client: client}
+	err := s.apply(d, keys, zones, ttl)
+	if err != nil {
+		s.Teardown()
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *Scenario) apply(d Diego, keys consuladapter.KeyConventions, zones []string, ttl time.Duration) error {
+	for i := 0; i < d.Cells; i++ {
+		cellID := fmt.Sprintf(cellIDFormat, i)
+		presence := CellPresence{
+			CellID:     cellID,
+			RepAddress: fmt.Sprintf("http://%s:%d", cellID, repPort),
+			RepURL:     fmt.Sprintf("https://%s.cell.service.cf.internal:%d", cellID, repPort),
+			Zone:       zones[i%len(zones)],
+			Capacity:   CellCapacity{MemoryMB: 16384, DiskMB: 65536, Containers: 250},
+		}
+
+		if _, err := s.hold(keys.Presence().Key(cellID), cellID, ttl, presence); err != nil {
+			return fmt.Errorf("cell %s presence: %s", cellID, err)
+		}
+		s.CellIDs = append(s.CellIDs, cellID)
+	}
+
+	if d.BBS {
+		presence := BBSPresence{ID: "bbs-0", URL: fmt.Sprintf("https://bbs.service.cf.internal:%d", bbsPort)}
+		id, err := s.hold(keys.Locks().Key(BBSLockName), BBSLockName, ttl, presence)
+		if err != nil {
+			return fmt.Errorf("bbs lock: %s", err)
+		}
+		s.BBSSession = id
+	}
+
+	for _, route := range d.Routes {
+		id := fmt.Sprintf("%s-%d", route.Service, route.Port)
+		err := s.client.Agent().ServiceRegister(&api.AgentServiceRegistration{
+			ID:   id,
+			Name: route.Service,
+			Port: route.Port,
+			Tags: route.Hostnames,
+		})
+		if err != nil {
+			return fmt.Errorf("route %s: %s", id, err)
+		}
+		s.services = append(s.services, id)
+	}
+
+	return nil
+}
+
+// hold acquires key with value under a new session named name, and keeps
+// the session renewed.
+func (s *Scenario) hold(key, name string, ttl time.Duration, value interface{}) (string, error) {
+	payload, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+
+	session, err := consuladapter.NewTTLSession(s.client.Session(), &api.SessionEntry{
+		Name:     name,
+		TTL:      ttl.String(),
+		Behavior: api.SessionBehaviorDelete,
+	})
+	if err != nil {
+		return "", err
+	}
+	s.sessions = append(s.sessions, session)
+
+	acquired, _, err := s.client.KV().Acquire(&api.KVPair{Key: key, Value: payload, Session: session.ID()}, nil)
+	if err != nil {
+		return "", err
+	}
+	if !acquired {
+		return "", fmt.Errorf("'%s' is already held", key)
+	}
+	return session.ID(), nil
+}
+
+// Teardown destroys the scenario's sessions, which deletes the keys they
+// hold, and deregisters its routes. It returns the first error.
+func (s *Scenario) Teardown() error {
+	var firstErr error
+	for _, session := range s.sessions {
+		if err := session.Destroy(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	for _, id := range s.services {
+		if err := s.client.Agent().ServiceDeregister(id); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	s.sessions = nil
+	s.services = nil
+	return firstErr
+}
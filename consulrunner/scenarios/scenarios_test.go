@@ -0,0 +1,92 @@
+package scenarios_test
+
+import (
+	"encoding/json"
+
+	"code.cloudfoundry.org/consuladapter"
+	"code.cloudfoundry.org/consuladapter/consulrunner/scenarios"
+	"code.cloudfoundry.org/consuladapter/fakes"
+	"code.cloudfoundry.org/consuladapter/matchers"
+	"github.com/hashicorp/consul/api"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Diego", func() {
+	var (
+		backend    *fakes.FakeBackend
+		client     *fakes.FakeClient
+		components *fakes.FakeClientComponents
+	)
+
+	BeforeEach(func() {
+		backend = fakes.NewFakeBackend()
+		client, components = backend.Client()
+	})
+
+	It("sets up cell presences, the BBS lock and routes", func() {
+		scenario, err := scenarios.Diego{
+			Cells:  3,
+			Zones:  []string{"z1", "z2"},
+			BBS:    true,
+			Routes: []scenarios.Route{{Service: "router", Port: 8080, Hostnames: []string{"app.example.com"}}},
+		}.Apply(client)
+		Expect(err).NotTo(HaveOccurred())
+		defer scenario.Teardown()
+
+		Expect(scenario.CellIDs).To(Equal([]string{"cell-0", "cell-1", "cell-2"}))
+		for _, cellID := range scenario.CellIDs {
+			Expect(client).To(matchers.BePresentUnder("v1/presence", cellID))
+		}
+		Expect(client).To(matchers.HoldLock(scenarios.BBSLockName, "v1/locks/bbs"))
+
+		pair, _, err := backend.KV().Get("v1/presence/cell-1", nil)
+		Expect(err).NotTo(HaveOccurred())
+		var presence scenarios.CellPresence
+		Expect(json.Unmarshal(pair.Value, &presence)).To(Succeed())
+		Expect(presence.CellID).To(Equal("cell-1"))
+		Expect(presence.Zone).To(Equal("z2"))
+
+		Expect(components.Agent.ServiceRegisterCallCount()).To(Equal(1))
+		registration := components.Agent.ServiceRegisterArgsForCall(0)
+		Expect(registration.ID).To(Equal("router-8080"))
+		Expect(registration.Tags).To(Equal([]string{"app.example.com"}))
+	})
+
+	It("removes everything it set up on Teardown", func() {
+		scenario, err := scenarios.Diego{
+			Cells:  1,
+			BBS:    true,
+			Routes: []scenarios.Route{{Service: "router", Port: 8080}},
+		}.Apply(client)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(scenario.Teardown()).To(Succeed())
+
+		pairs, _, err := backend.KV().List("v1/", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pairs).To(BeEmpty())
+		Expect(components.Agent.ServiceDeregisterArgsForCall(0)).To(Equal("router-8080"))
+	})
+
+	It("fails, cleaning up, if the BBS lock is already held", func() {
+		id, _, err := backend.Session().Create(&api.SessionEntry{}, nil)
+		Expect(err).NotTo(HaveOccurred())
+		_, _, err = backend.KV().Acquire(&api.KVPair{Key: "v1/locks/bbs", Session: id}, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = scenarios.Diego{Cells: 1, BBS: true}.Apply(client)
+		Expect(err).To(MatchError(ContainSubstring("'v1/locks/bbs' is already held")))
+		Expect(client).NotTo(matchers.BePresentUnder("v1/presence", "cell-0"))
+	})
+
+	It("follows other key conventions", func() {
+		keys := consuladapter.KeyConventions{Root: "v2"}
+		scenario, err := scenarios.Diego{Cells: 1, Keys: &keys}.Apply(client)
+		Expect(err).NotTo(HaveOccurred())
+		defer scenario.Teardown()
+
+		Expect(client).To(matchers.BePresentUnder("v2/presence", "cell-0"))
+	})
+})
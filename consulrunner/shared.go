@@ -0,0 +1,73 @@
+package consulrunner
+
+import (
+	"os"
+	"sync"
+
+	. "github.com/onsi/gomega"
+)
+
+// SharedClusterURLEnv names the environment variable that points StartOnce
+// at an already running cluster, e.g. "http://127.0.0.1:8500".
+const SharedClusterURLEnv = "CONSULRUNNER_SHARED_CLUSTER_URL"
+
+var shared struct {
+	mutex  sync.Mutex
+	runner *ClusterRunner
+	config ClusterRunnerConfig
+	refs   int
+}
+
+// StartOnce returns a cluster shared by every caller in the test process,
+// creating and starting it with config on first use. Later callers must
+// pass the same starting port, node count and scheme. Each call must be
+// balanced by a call to StopAtSuiteEnd, typically from an AfterSuite.
+//
+// go test runs each package in its own process, so StartOnce alone cannot
+// share a cluster between packages. To do that, start one cluster outside
+// the tests and set SharedClusterURLEnv to its URL: StartOnce then attaches
+// to it with TryAttachClusterRunner, ignoring config, and neither starts
+// nor stops it.
+func StartOnce(config ClusterRunnerConfig) *ClusterRunner {
+	shared.mutex.Lock()
+	defer shared.mutex.Unlock()
+
+	if shared.runner == nil {
+		if rawURL := os.Getenv(SharedClusterURLEnv); rawURL != "" {
+			runner, err := TryAttachClusterRunner(rawURL)
+			Expect(err).NotTo(HaveOccurred())
+			shared.runner = runner
+		} else {
+			shared.runner = NewClusterRunnerWithConfig(config)
+		}
+		shared.config = config
+	} else if shared.runner.externalAddress == "" {
+		Expect(config.StartingPort).To(Equal(shared.config.StartingPort), "shared cluster already uses a different starting port")
+		Expect(config.NumNodes).To(Equal(shared.config.NumNodes), "shared cluster already has a different number of nodes")
+		Expect(config.Scheme).To(Equal(shared.config.Scheme), "shared cluster already uses a different scheme")
+	}
+
+	if !shared.runner.Running() {
+		shared.runner.Start()
+	}
+	shared.refs++
+
+	return shared.runner
+}
+
+// StopAtSuiteEnd releases a reference taken by StartOnce, stopping the
+// shared cluster once no caller is using it.
+func StopAtSuiteEnd() {
+	shared.mutex.Lock()
+	defer shared.mutex.Unlock()
+
+	if shared.refs == 0 {
+		return
+	}
+
+	shared.refs--
+	if shared.refs == 0 {
+		shared.runner.Stop()
+		shared.runner = nil
+	}
+}
@@ -0,0 +1,152 @@
+package consulrunner_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+
+	"code.cloudfoundry.org/consuladapter/consulrunner"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Shared clusters", func() {
+	var server *httptest.Server
+
+	BeforeEach(func() {
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/v1/status/leader" {
+				w.Write([]byte(`"10.0.0.1:8300"`))
+				return
+			}
+			w.WriteHeader(http.StatusNotFound)
+		}))
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	Describe("TryAttachClusterRunner", func() {
+		It("talks to the cluster at the URL, and leaves it running when stopped", func() {
+			runner, err := consulrunner.TryAttachClusterRunner(server.URL)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(runner.Running()).To(BeTrue())
+			Expect(runner.URL()).To(Equal(server.URL))
+			Expect(runner.ConsulCluster()).To(Equal(server.URL))
+
+			leader, err := runner.NewClient().Status().Leader()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(leader).To(Equal("10.0.0.1:8300"))
+
+			runner.Stop()
+			Expect(runner.Running()).To(BeTrue())
+		})
+
+		It("refuses to manage the cluster's nodes", func() {
+			runner, err := consulrunner.TryAttachClusterRunner(server.URL)
+			Expect(err).NotTo(HaveOccurred())
+
+			err = runner.TryReconfigureNode(0, func(*consulrunner.ConfigFile) {})
+			Expect(err).To(MatchError("the nodes of an attached consul cluster cannot be managed"))
+			Expect(runner.TryWipeNode(0)).To(MatchError("the nodes of an attached consul cluster cannot be managed"))
+		})
+
+		It("returns errors from the Try variants instead of failing the spec", func() {
+			runner, err := consulrunner.TryAttachClusterRunner(server.URL)
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = runner.TryMetrics(1)
+			Expect(err).To(MatchError("no node 1 in a cluster of 1"))
+			err = runner.TryEventuallyResync(context.Background(), 0)
+			Expect(err).To(MatchError("resyncing needs a cluster with more than one node"))
+		})
+
+		It("rejects URLs without an http(s) scheme or a port", func() {
+			for _, rawURL := range []string{"127.0.0.1:8500", "tcp://127.0.0.1:8500", "http://127.0.0.1", "http://%zz"} {
+				_, err := consulrunner.TryAttachClusterRunner(rawURL)
+				Expect(err).To(HaveOccurred(), rawURL)
+			}
+		})
+	})
+
+	Describe("Reset on an attached cluster", func() {
+		var (
+			mutex     sync.Mutex
+			destroyed []string
+		)
+
+		BeforeEach(func() {
+			destroyed = nil
+			server.Close()
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch {
+				case r.URL.Path == "/v1/session/list":
+					w.Write([]byte(`[{"ID":"adapter","Name":"consuladapter: bbs"},{"ID":"other","Name":"other-user"}]`))
+				case strings.HasPrefix(r.URL.Path, "/v1/session/destroy/"):
+					mutex.Lock()
+					destroyed = append(destroyed, strings.TrimPrefix(r.URL.Path, "/v1/session/destroy/"))
+					mutex.Unlock()
+					w.Write([]byte(`true`))
+				case r.URL.Path == "/v1/agent/services", r.URL.Path == "/v1/agent/checks":
+					w.Write([]byte(`{}`))
+				default:
+					w.Write([]byte(`true`))
+				}
+			}))
+		})
+
+		It("destroys only the sessions the adapter created", func() {
+			runner, err := consulrunner.TryAttachClusterRunner(server.URL)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(runner.Reset()).To(Succeed())
+			mutex.Lock()
+			defer mutex.Unlock()
+			Expect(destroyed).To(Equal([]string{"adapter"}))
+		})
+	})
+
+	Describe("StartOnce", func() {
+		BeforeEach(func() {
+			os.Setenv(consulrunner.SharedClusterURLEnv, server.URL)
+		})
+
+		AfterEach(func() {
+			os.Unsetenv(consulrunner.SharedClusterURLEnv)
+		})
+
+		It("attaches to the cluster in the environment, sharing it until every caller has stopped", func() {
+			config := consulrunner.ClusterRunnerConfig{StartingPort: 5000, NumNodes: 1}
+			first := consulrunner.StartOnce(config)
+			Expect(first.URL()).To(Equal(server.URL))
+
+			second := consulrunner.StartOnce(consulrunner.ClusterRunnerConfig{StartingPort: 6000, NumNodes: 3})
+			Expect(second).To(BeIdenticalTo(first))
+
+			consulrunner.StopAtSuiteEnd()
+			Expect(consulrunner.StartOnce(config)).To(BeIdenticalTo(first))
+
+			consulrunner.StopAtSuiteEnd()
+			consulrunner.StopAtSuiteEnd()
+			Expect(first.Running()).To(BeTrue())
+
+			third := consulrunner.StartOnce(config)
+			Expect(third).NotTo(BeIdenticalTo(first))
+			consulrunner.StopAtSuiteEnd()
+		})
+
+		It("ignores unbalanced calls to StopAtSuiteEnd", func() {
+			consulrunner.StopAtSuiteEnd()
+
+			runner := consulrunner.StartOnce(consulrunner.ClusterRunnerConfig{StartingPort: 5000, NumNodes: 1})
+			consulrunner.StopAtSuiteEnd()
+			consulrunner.StopAtSuiteEnd()
+			Expect(runner.Running()).To(BeTrue())
+		})
+	})
+})
@@ -2,13 +2,6 @@
 
 package consulrunner
 
-import (
-	"time"
+import "os"
 
-	"github.com/tedsuo/ifrit"
-	"github.com/tedsuo/ifrit/ginkgomon"
-)
-
-func stopSignal(process ifrit.Process, interval time.Duration) {
-	ginkgomon.Interrupt(process, interval)
-}
+var stopSignal os.Signal = os.Interrupt
@@ -0,0 +1,180 @@
+package consuladapter
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/hashicorp/consul/api"
+)
+
+var ErrReadOnly = errors.New("consul is unavailable: writes are disabled while degraded")
+
+// NewDegradableKV wraps kv so that, while monitor reports Degraded, writes
+// fail fast with ErrReadOnly and reads allow stale results, letting any
+// reachable server answer from its local state.
+func NewDegradableKV(kv KV, monitor *ConnectionMonitor) KV {
+	return &degradableKV{kv: kv, monitor: monitor}
+}
+
+// NewCachingDegradableKV is like NewDegradableKV, but also keeps the last
+// result of each Get and List, and serves it while degraded if the read
+// fails, e.g. because the agent is unreachable. The cache grows with the
+// keys and prefixes read.
+func NewCachingDegradableKV(kv KV, monitor *ConnectionMonitor) KV {
+	return &degradableKV{kv: kv, monitor: monitor, cache: &readCache{reads: map[string]cachedRead{}}}
+}
+
+type degradableKV struct {
+	kv      KV
+	monitor *ConnectionMonitor
+	cache   *readCache
+}
+
+type cachedRead struct {
+	pairs api.KVPairs
+	meta  api.QueryMeta
+}
+
+// readCache holds the last successful read of each key and prefix.
+type readCache struct {
+	mu    sync.Mutex
+	reads map[string]cachedRead
+}
+
+func (c *readCache) store(name string, pairs api.KVPairs, meta *api.QueryMeta) {
+	if c == nil || meta == nil {
+		return
+	}
+	read := cachedRead{meta: *meta}
+	for _, pair := range pairs {
+		read.pairs = append(read.pairs, copyKVPair(pair))
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.reads[name] = read
+}
+
+func (c *readCache) load(name string) (api.KVPairs, *api.QueryMeta, bool) {
+	if c == nil {
+		return nil, nil, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	read, ok := c.reads[name]
+	if !ok {
+		return nil, nil, false
+	}
+
+	var pairs api.KVPairs
+	for _, pair := range read.pairs {
+		pairs = append(pairs, copyKVPair(pair))
+	}
+	meta := read.meta
+	return pairs, &meta, true
+}
+
+func copyKVPair(pair *api.KVPair) *api.KVPair {
+	copied := *pair
+	copied.Value = append([]byte(nil), pair.Value...)
+	return &copied
+}
+
+func (d *degradableKV) degraded() bool {
+	return d.monitor.State() == Degraded
+}
+
+func (d *degradableKV) readOptions(q *api.QueryOptions) *api.QueryOptions {
+	if !d.degraded() {
+		return q
+	}
+
+	stale := api.QueryOptions{}
+	if q != nil {
+		stale = *q
+	}
+	stale.AllowStale = true
+	return &stale
+}
+
+func (d *degradableKV) Get(key string, q *api.QueryOptions) (*api.KVPair, *api.QueryMeta, error) {
+	name := "get:" + key
+	pair, meta, err := d.kv.Get(key, d.readOptions(q))
+	if err == nil {
+		var pairs api.KVPairs
+		if pair != nil {
+			pairs = api.KVPairs{pair}
+		}
+		d.cache.store(name, pairs, meta)
+		return pair, meta, nil
+	}
+
+	if d.degraded() {
+		if pairs, cachedMeta, ok := d.cache.load(name); ok {
+			if len(pairs) == 0 {
+				return nil, cachedMeta, nil
+			}
+			return pairs[0], cachedMeta, nil
+		}
+	}
+	return nil, nil, err
+}
+
+func (d *degradableKV) List(prefix string, q *api.QueryOptions) (api.KVPairs, *api.QueryMeta, error) {
+	name := "list:" + prefix
+	pairs, meta, err := d.kv.List(prefix, d.readOptions(q))
+	if err == nil {
+		d.cache.store(name, pairs, meta)
+		return pairs, meta, nil
+	}
+
+	if d.degraded() {
+		if cached, cachedMeta, ok := d.cache.load(name); ok {
+			return cached, cachedMeta, nil
+		}
+	}
+	return nil, nil, err
+}
+
+func (d *degradableKV) Put(p *api.KVPair, q *api.WriteOptions) (*api.WriteMeta, error) {
+	if d.degraded() {
+		return nil, ErrReadOnly
+	}
+	return d.kv.Put(p, q)
+}
+
+func (d *degradableKV) CAS(p *api.KVPair, q *api.WriteOptions) (bool, *api.WriteMeta, error) {
+	if d.degraded() {
+		return false, nil, ErrReadOnly
+	}
+	return d.kv.CAS(p, q)
+}
+
+func (d *degradableKV) Acquire(p *api.KVPair, q *api.WriteOptions) (bool, *api.WriteMeta, error) {
+	if d.degraded() {
+		return false, nil, ErrReadOnly
+	}
+	return d.kv.Acquire(p, q)
+}
+
+func (d *degradableKV) Release(p *api.KVPair, q *api.WriteOptions) (bool, *api.WriteMeta, error) {
+	if d.degraded() {
+		return false, nil, ErrReadOnly
+	}
+	return d.kv.Release(p, q)
+}
+
+func (d *degradableKV) DeleteCAS(p *api.KVPair, w *api.WriteOptions) (bool, *api.WriteMeta, error) {
+	if d.degraded() {
+		return false, nil, ErrReadOnly
+	}
+	return d.kv.DeleteCAS(p, w)
+}
+
+func (d *degradableKV) DeleteTree(prefix string, w *api.WriteOptions) (*api.WriteMeta, error) {
+	if d.degraded() {
+		return nil, ErrReadOnly
+	}
+	return d.kv.DeleteTree(prefix, w)
+}
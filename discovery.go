@@ -0,0 +1,221 @@
+package consuladapter
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const DefaultDiscoveryInterval = 30 * time.Second
+
+// discoveryTimeout bounds each agent discovery, which runs independently of
+// the requests that trigger it.
+const discoveryTimeout = 10 * time.Second
+
+// AgentDiscovery finds the addresses, as host:port, of the consul agents a
+// client may use, for environments where agents are scheduled dynamically.
+type AgentDiscovery interface {
+	Discover(ctx context.Context) ([]string, error)
+}
+
+type DiscoveryFunc func(ctx context.Context) ([]string, error)
+
+func (f DiscoveryFunc) Discover(ctx context.Context) ([]string, error) {
+	return f(ctx)
+}
+
+// SRVDiscovery looks up agents from DNS SRV records, e.g. Service "consul",
+// Proto "tcp" and Name "service.example.com", in priority and weight order.
+type SRVDiscovery struct {
+	Service string
+	Proto   string
+	Name    string
+}
+
+func (d SRVDiscovery) Discover(ctx context.Context) ([]string, error) {
+	_, records, err := net.DefaultResolver.LookupSRV(ctx, d.Service, d.Proto, d.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	addresses := make([]string, 0, len(records))
+	for _, record := range records {
+		host := strings.TrimSuffix(record.Target, ".")
+		addresses = append(addresses, net.JoinHostPort(host, strconv.Itoa(int(record.Port))))
+	}
+	return addresses, nil
+}
+
+// FileDiscovery reads agent addresses from a file with one host:port per
+// line. Blank lines and lines starting with # are ignored.
+type FileDiscovery struct {
+	Path string
+}
+
+func (d FileDiscovery) Discover(ctx context.Context) ([]string, error) {
+	file, err := os.Open(d.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var addresses []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if _, _, err := net.SplitHostPort(line); err != nil {
+			return nil, fmt.Errorf("%s: invalid agent address '%s': %s", d.Path, line, err)
+		}
+		addresses = append(addresses, line)
+	}
+	return addresses, scanner.Err()
+}
+
+var errNoAgentsDiscovered = errors.New("no consul agents discovered")
+
+// discoveringTransport sends each request to a discovered agent, refreshing
+// the list at most once per interval. It sticks with one agent while that
+// agent stays in the list, moving to the next after a connection error, and
+// closes idle connections whenever it moves. Until the first successful
+// discovery it uses the address the client was created with.
+//
+// Discovery runs outside the mutex, one at a time, with its own context, so
+// a slow or cancelled request cannot stall or fail it for the others.
+// Requests arriving while it runs wait for it, or for their own context.
+type discoveringTransport struct {
+	transport http.RoundTripper
+	discovery AgentDiscovery
+	interval  time.Duration
+
+	mu            sync.Mutex
+	addresses     []string
+	current       int
+	lastDiscovery time.Time
+	discovering   chan struct{}
+}
+
+func newDiscoveringTransport(transport http.RoundTripper, discovery AgentDiscovery, address string, interval time.Duration) *discoveringTransport {
+	if interval <= 0 {
+		interval = DefaultDiscoveryInterval
+	}
+	return &discoveringTransport{
+		transport: transport,
+		discovery: discovery,
+		interval:  interval,
+		addresses: []string{address},
+	}
+}
+
+func (t *discoveringTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	address, err := t.address(req.Context())
+	if err != nil {
+		return nil, err
+	}
+
+	req = req.Clone(req.Context())
+	req.URL.Host = address
+	req.Host = ""
+
+	resp, err := t.transport.RoundTrip(req)
+	if err != nil {
+		var opErr *net.OpError
+		if errors.As(err, &opErr) {
+			t.failed(address)
+		}
+	}
+	return resp, err
+}
+
+func (t *discoveringTransport) address(ctx context.Context) (string, error) {
+	t.mu.Lock()
+	if t.discovering == nil && time.Since(t.lastDiscovery) >= t.interval {
+		t.lastDiscovery = time.Now()
+		discovering := make(chan struct{})
+		t.discovering = discovering
+		goBackground(nil, func() { t.discover(discovering) }, nil)
+	}
+	discovering := t.discovering
+	t.mu.Unlock()
+
+	if discovering != nil {
+		select {
+		case <-discovering:
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.addresses) == 0 {
+		return "", errNoAgentsDiscovered
+	}
+	return t.addresses[t.current], nil
+}
+
+func (t *discoveringTransport) discover(done chan struct{}) {
+	defer func() {
+		t.mu.Lock()
+		t.discovering = nil
+		t.mu.Unlock()
+		close(done)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), discoveryTimeout)
+	defer cancel()
+	addresses, err := t.discovery.Discover(ctx)
+	if err != nil {
+		// keep the previous agents
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.update(addresses)
+}
+
+// must be called with the mutex held
+func (t *discoveringTransport) update(addresses []string) {
+	current := ""
+	if len(t.addresses) > 0 {
+		current = t.addresses[t.current]
+	}
+
+	t.addresses = addresses
+	t.current = 0
+	for i, address := range addresses {
+		if address == current {
+			t.current = i
+			return
+		}
+	}
+	t.closeIdleConnections()
+}
+
+func (t *discoveringTransport) failed(address string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if len(t.addresses) < 2 || t.addresses[t.current] != address {
+		return
+	}
+	t.current = (t.current + 1) % len(t.addresses)
+	t.closeIdleConnections()
+}
+
+func (t *discoveringTransport) closeIdleConnections() {
+	if closer, ok := t.transport.(interface{ CloseIdleConnections() }); ok {
+		closer.CloseIdleConnections()
+	}
+}
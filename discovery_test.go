@@ -0,0 +1,152 @@
+package consuladapter_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/consuladapter"
+	"github.com/hashicorp/consul/api"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("agent discovery", func() {
+	Describe("FileDiscovery", func() {
+		var dir string
+
+		BeforeEach(func() {
+			var err error
+			dir, err = ioutil.TempDir("", "discovery")
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			os.RemoveAll(dir)
+		})
+
+		It("reads one address per line, skipping comments", func() {
+			path := filepath.Join(dir, "agents")
+			Expect(ioutil.WriteFile(path, []byte("# agents\n10.0.0.1:8500\n\n10.0.0.2:8500\n"), 0644)).To(Succeed())
+
+			addresses, err := consuladapter.FileDiscovery{Path: path}.Discover(context.Background())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(addresses).To(Equal([]string{"10.0.0.1:8500", "10.0.0.2:8500"}))
+		})
+
+		It("rejects addresses without ports", func() {
+			path := filepath.Join(dir, "agents")
+			Expect(ioutil.WriteFile(path, []byte("10.0.0.1\n"), 0644)).To(Succeed())
+
+			_, err := consuladapter.FileDiscovery{Path: path}.Discover(context.Background())
+			Expect(err).To(MatchError(ContainSubstring("invalid agent address '10.0.0.1'")))
+		})
+	})
+
+	Describe("clients with discovery", func() {
+		var (
+			first, second *httptest.Server
+
+			mutex     sync.Mutex
+			addresses []string
+		)
+
+		newAgent := func(leader string) *httptest.Server {
+			return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(`"` + leader + `"`))
+			}))
+		}
+
+		discovery := consuladapter.DiscoveryFunc(func(ctx context.Context) ([]string, error) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			return addresses, nil
+		})
+
+		setAddresses := func(servers ...*httptest.Server) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			addresses = nil
+			for _, server := range servers {
+				addresses = append(addresses, strings.TrimPrefix(server.URL, "http://"))
+			}
+		}
+
+		BeforeEach(func() {
+			first = newAgent("10.0.0.1:8300")
+			second = newAgent("10.0.0.2:8300")
+		})
+
+		AfterEach(func() {
+			first.Close()
+			second.Close()
+		})
+
+		It("sends requests to discovered agents, following changes", func() {
+			setAddresses(first)
+			client, err := consuladapter.NewClientFromUrlWithOptions("http://consul.invalid:8500", consuladapter.ClientOptions{
+				Discovery:         discovery,
+				DiscoveryInterval: time.Millisecond,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(client.Status().Leader()).To(Equal("10.0.0.1:8300"))
+
+			setAddresses(second)
+			time.Sleep(2 * time.Millisecond)
+			Expect(client.Status().Leader()).To(Equal("10.0.0.2:8300"))
+		})
+
+		It("discovers with its own context, so a cancelled request does not fail discovery", func() {
+			setAddresses(first)
+			release := make(chan struct{})
+			discoveryErrs := make(chan error, 1)
+			client, err := consuladapter.NewClientFromUrlWithOptions("http://consul.invalid:8500", consuladapter.ClientOptions{
+				Discovery: consuladapter.DiscoveryFunc(func(ctx context.Context) ([]string, error) {
+					<-release
+					discoveryErrs <- ctx.Err()
+					return discovery(ctx)
+				}),
+				DiscoveryInterval: time.Hour,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			ctx, cancel := context.WithCancel(context.Background())
+			getErrs := make(chan error, 1)
+			go func() {
+				_, _, err := client.KV().Get("key", (&api.QueryOptions{}).WithContext(ctx))
+				getErrs <- err
+			}()
+			Consistently(getErrs).ShouldNot(Receive())
+			cancel()
+			Eventually(getErrs).Should(Receive(HaveOccurred()))
+
+			close(release)
+			Eventually(discoveryErrs).Should(Receive(BeNil()))
+			Expect(client.Status().Leader()).To(Equal("10.0.0.1:8300"))
+		})
+
+		It("moves to the next agent after a connection error", func() {
+			setAddresses(first, second)
+			client, err := consuladapter.NewClientFromUrlWithOptions("http://consul.invalid:8500", consuladapter.ClientOptions{
+				Discovery:         discovery,
+				DiscoveryInterval: time.Hour,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(client.Status().Leader()).To(Equal("10.0.0.1:8300"))
+
+			first.Close()
+			_, err = client.Status().Leader()
+			Expect(err).To(HaveOccurred())
+			Expect(client.Status().Leader()).To(Equal("10.0.0.2:8300"))
+		})
+	})
+})
@@ -0,0 +1,111 @@
+package consuladapter
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+)
+
+// Draining coordinates graceful rolling deploys: an instance marks itself
+// draining before it deregisters, and consumers stop routing to it as soon
+// as they see the mark rather than when its health checks fail. Marks live
+// under DrainingKeys, one per instance under the service's name, e.g.
+// v1/draining/worker/worker-1, holding the time the instance started
+// draining in RFC 3339 format.
+type Draining struct {
+	kv KV
+}
+
+func NewDraining(kv KV) *Draining {
+	return &Draining{kv: kv}
+}
+
+func drainingKey(service, instanceID string) string {
+	return DrainingKeys.Key(service, instanceID)
+}
+
+func (d *Draining) MarkDraining(service, instanceID string) error {
+	value := []byte(time.Now().UTC().Format(time.RFC3339))
+	_, err := d.kv.Put(&api.KVPair{Key: drainingKey(service, instanceID), Value: value}, nil)
+	return err
+}
+
+// ClearDraining removes an instance's mark. Call it once the instance has
+// deregistered, or to return it to service.
+func (d *Draining) ClearDraining(service, instanceID string) error {
+	// DeleteTree would also clear instances whose IDs share this prefix
+	key := drainingKey(service, instanceID)
+	for {
+		pair, _, err := d.kv.Get(key, nil)
+		if err != nil || pair == nil {
+			return err
+		}
+
+		deleted, err := DeleteIfIndex(d.kv, key, pair.ModifyIndex)
+		if err != nil || deleted {
+			return err
+		}
+	}
+}
+
+// DrainingInstances returns the sorted IDs of the instances of service that
+// are draining.
+func (d *Draining) DrainingInstances(service string, q *api.QueryOptions) ([]string, *api.QueryMeta, error) {
+	prefix := DrainingKeys.Sub(service).Prefix()
+	pairs, meta, err := d.kv.List(prefix, q)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ids := make([]string, 0, len(pairs))
+	for _, pair := range pairs {
+		id := strings.TrimPrefix(pair.Key, prefix)
+		if id != "" && !strings.Contains(id, "/") {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids, meta, nil
+}
+
+// WatchDraining calls onChange with the draining instances of service, once
+// at the start and then after every change, until ctx is done.
+func (d *Draining) WatchDraining(ctx context.Context, service string, onChange func(instanceIDs []string)) error {
+	var (
+		previous []string
+		notified bool
+	)
+	return BlockingQuery(ctx, DefaultWatchBackoff, nil, func(q *api.QueryOptions) (*api.QueryMeta, error) {
+		ids, meta, err := d.DrainingInstances(service, q)
+		if err != nil {
+			return nil, err
+		}
+
+		if !notified || !equalStrings(previous, ids) {
+			notified = true
+			previous = ids
+			onChange(ids)
+		}
+		return meta, nil
+	})
+}
+
+// WithoutDraining returns instanceIDs minus those that are draining, e.g.
+// to filter HealthyInstances before updating a ShardRing.
+func WithoutDraining(instanceIDs, draining []string) []string {
+	skip := map[string]bool{}
+	for _, id := range draining {
+		skip[id] = true
+	}
+
+	routable := make([]string, 0, len(instanceIDs))
+	for _, id := range instanceIDs {
+		if !skip[id] {
+			routable = append(routable, id)
+		}
+	}
+	return routable
+}
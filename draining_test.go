@@ -0,0 +1,77 @@
+package consuladapter_test
+
+import (
+	"context"
+
+	"code.cloudfoundry.org/consuladapter"
+	"code.cloudfoundry.org/consuladapter/fakes"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Draining", func() {
+	var (
+		kv       consuladapter.KV
+		draining *consuladapter.Draining
+	)
+
+	BeforeEach(func() {
+		kv = fakes.NewFakeBackend().KV()
+		draining = consuladapter.NewDraining(kv)
+	})
+
+	It("lists the instances marked draining for a service", func() {
+		Expect(draining.MarkDraining("worker", "worker-2")).To(Succeed())
+		Expect(draining.MarkDraining("worker", "worker-1")).To(Succeed())
+		Expect(draining.MarkDraining("other", "other-1")).To(Succeed())
+
+		pair, _, err := kv.Get("v1/draining/worker/worker-1", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pair).NotTo(BeNil())
+
+		ids, _, err := draining.DrainingInstances("worker", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ids).To(Equal([]string{"worker-1", "worker-2"}))
+	})
+
+	It("clears only the given instance", func() {
+		Expect(draining.MarkDraining("worker", "worker-1")).To(Succeed())
+		Expect(draining.MarkDraining("worker", "worker-10")).To(Succeed())
+
+		Expect(draining.ClearDraining("worker", "worker-1")).To(Succeed())
+		Expect(draining.ClearDraining("worker", "worker-2")).To(Succeed())
+
+		ids, _, err := draining.DrainingInstances("worker", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ids).To(Equal([]string{"worker-10"}))
+	})
+
+	It("notifies watchers of the initial state and each change", func() {
+		Expect(draining.MarkDraining("worker", "worker-1")).To(Succeed())
+
+		ctx, cancel := context.WithCancel(context.Background())
+		changes := make(chan []string, 10)
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- draining.WatchDraining(ctx, "worker", func(ids []string) {
+				changes <- ids
+			})
+		}()
+
+		Eventually(changes).Should(Receive(Equal([]string{"worker-1"})))
+
+		Expect(draining.MarkDraining("worker", "worker-2")).To(Succeed())
+		Eventually(changes).Should(Receive(Equal([]string{"worker-1", "worker-2"})))
+
+		Expect(draining.ClearDraining("worker", "worker-1")).To(Succeed())
+		Eventually(changes).Should(Receive(Equal([]string{"worker-2"})))
+
+		cancel()
+		Eventually(errCh).Should(Receive(Equal(context.Canceled)))
+	})
+
+	It("filters draining instances out of routable ones", func() {
+		Expect(consuladapter.WithoutDraining([]string{"a", "b", "c"}, []string{"b"})).To(Equal([]string{"a", "c"}))
+	})
+})
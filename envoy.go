@@ -0,0 +1,105 @@
+package consuladapter
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/hashicorp/consul/api"
+)
+
+const (
+	DefaultEnvoyAdminBindAddress = "127.0.0.1:19000"
+	DefaultAgentGRPCAddress      = "127.0.0.1:8502"
+	defaultProxyProtocol         = "tcp"
+)
+
+type EnvoyBootstrapOptions struct {
+	// ServiceID is the ID of the service instance the sidecar proxies, and
+	// ServiceName its name.
+	ServiceID   string
+	ServiceName string
+
+	Namespace string
+	Partition string
+
+	// AgentGRPCAddress is the local agent's xDS address. Defaults to
+	// DefaultAgentGRPCAddress.
+	AgentGRPCAddress string
+	// AdminBindAddress defaults to DefaultEnvoyAdminBindAddress.
+	AdminBindAddress string
+}
+
+// EnvoyBootstrap is the data needed to render a bootstrap config for an
+// Envoy sidecar, equivalent to what `consul connect envoy` resolves.
+type EnvoyBootstrap struct {
+	ProxyID          string
+	Cluster          string
+	Namespace        string
+	Partition        string
+	AgentGRPCAddress string
+	AdminBindAddress string
+
+	// Protocol comes from the service's service-defaults, falling back to
+	// the protocol in proxy-defaults and then to "tcp".
+	Protocol string
+
+	// Config is the global proxy-defaults config, e.g. envoy_* overrides.
+	Config map[string]interface{}
+}
+
+// FetchEnvoyBootstrap reads the proxy-defaults and service-defaults config
+// entries that apply to a service's sidecar. Missing entries are not an
+// error.
+func FetchEnvoyBootstrap(entries ConfigEntries, opts EnvoyBootstrapOptions) (EnvoyBootstrap, error) {
+	if opts.ServiceID == "" || opts.ServiceName == "" {
+		return EnvoyBootstrap{}, fmt.Errorf("service id and name are required")
+	}
+
+	bootstrap := EnvoyBootstrap{
+		ProxyID:          opts.ServiceID + "-sidecar-proxy",
+		Cluster:          opts.ServiceName,
+		Namespace:        opts.Namespace,
+		Partition:        opts.Partition,
+		AgentGRPCAddress: opts.AgentGRPCAddress,
+		AdminBindAddress: opts.AdminBindAddress,
+		Protocol:         defaultProxyProtocol,
+		Config:           map[string]interface{}{},
+	}
+	if bootstrap.AgentGRPCAddress == "" {
+		bootstrap.AgentGRPCAddress = DefaultAgentGRPCAddress
+	}
+	if bootstrap.AdminBindAddress == "" {
+		bootstrap.AdminBindAddress = DefaultEnvoyAdminBindAddress
+	}
+
+	entry, _, err := getConfigEntry(entries, api.ProxyDefaults, api.ProxyConfigGlobal, &api.QueryOptions{Partition: opts.Partition})
+	if err != nil {
+		return EnvoyBootstrap{}, err
+	}
+	if proxyDefaults, ok := entry.(*api.ProxyConfigEntry); ok {
+		for key, value := range proxyDefaults.Config {
+			bootstrap.Config[key] = value
+		}
+		if protocol, ok := proxyDefaults.Config["protocol"].(string); ok && protocol != "" {
+			bootstrap.Protocol = protocol
+		}
+	}
+
+	entry, _, err = getConfigEntry(entries, api.ServiceDefaults, opts.ServiceName, &api.QueryOptions{Namespace: opts.Namespace, Partition: opts.Partition})
+	if err != nil {
+		return EnvoyBootstrap{}, err
+	}
+	if serviceDefaults, ok := entry.(*api.ServiceConfigEntry); ok && serviceDefaults.Protocol != "" {
+		bootstrap.Protocol = serviceDefaults.Protocol
+	}
+
+	return bootstrap, nil
+}
+
+func getConfigEntry(entries ConfigEntries, kind, name string, q *api.QueryOptions) (api.ConfigEntry, *api.QueryMeta, error) {
+	entry, meta, err := entries.Get(kind, name, q)
+	if statusErr, ok := err.(api.StatusError); ok && statusErr.Code == http.StatusNotFound {
+		return nil, meta, nil
+	}
+	return entry, meta, err
+}
@@ -0,0 +1,68 @@
+package consuladapter_test
+
+import (
+	"errors"
+	"net/http"
+
+	"code.cloudfoundry.org/consuladapter"
+	"code.cloudfoundry.org/consuladapter/fakes"
+	"github.com/hashicorp/consul/api"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("FetchEnvoyBootstrap", func() {
+	var (
+		entries *fakes.FakeConfigEntries
+		opts    consuladapter.EnvoyBootstrapOptions
+	)
+
+	BeforeEach(func() {
+		entries = &fakes.FakeConfigEntries{}
+		entries.GetStub = func(kind, name string, q *api.QueryOptions) (api.ConfigEntry, *api.QueryMeta, error) {
+			switch kind {
+			case api.ProxyDefaults:
+				return &api.ProxyConfigEntry{
+					Kind:   api.ProxyDefaults,
+					Name:   api.ProxyConfigGlobal,
+					Config: map[string]interface{}{"protocol": "http", "envoy_stats_bind_addr": "0.0.0.0:9102"},
+				}, nil, nil
+			case api.ServiceDefaults:
+				return &api.ServiceConfigEntry{Kind: api.ServiceDefaults, Name: name, Protocol: "grpc"}, nil, nil
+			}
+			return nil, nil, errors.New("unexpected kind")
+		}
+
+		opts = consuladapter.EnvoyBootstrapOptions{ServiceID: "web-1", ServiceName: "web"}
+	})
+
+	It("resolves the bootstrap data from the config entries", func() {
+		bootstrap, err := consuladapter.FetchEnvoyBootstrap(entries, opts)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(bootstrap.ProxyID).To(Equal("web-1-sidecar-proxy"))
+		Expect(bootstrap.Cluster).To(Equal("web"))
+		Expect(bootstrap.AgentGRPCAddress).To(Equal(consuladapter.DefaultAgentGRPCAddress))
+		Expect(bootstrap.AdminBindAddress).To(Equal(consuladapter.DefaultEnvoyAdminBindAddress))
+		Expect(bootstrap.Protocol).To(Equal("grpc"))
+		Expect(bootstrap.Config).To(HaveKeyWithValue("envoy_stats_bind_addr", "0.0.0.0:9102"))
+	})
+
+	It("falls back to defaults when the config entries do not exist", func() {
+		entries.GetStub = nil
+		entries.GetReturns(nil, nil, api.StatusError{Code: http.StatusNotFound})
+
+		bootstrap, err := consuladapter.FetchEnvoyBootstrap(entries, opts)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(bootstrap.Protocol).To(Equal("tcp"))
+	})
+
+	It("returns other errors", func() {
+		entries.GetStub = nil
+		entries.GetReturns(nil, nil, errors.New("boom"))
+
+		_, err := consuladapter.FetchEnvoyBootstrap(entries, opts)
+		Expect(err).To(MatchError("boom"))
+	})
+})
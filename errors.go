@@ -1,6 +1,14 @@
 package consuladapter
 
-import "fmt"
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+)
 
 func NewKeyNotFoundError(key string) error {
 	return KeyNotFoundError(key)
@@ -21,3 +29,119 @@ type PrefixNotFoundError string
 func (e PrefixNotFoundError) Error() string {
 	return fmt.Sprintf("prefix not found: '%s'", string(e))
 }
+
+// PermissionDeniedError is returned by KV and Session operations that consul
+// rejected because the token lacks a permission. Resource, Verb and Target
+// are only set when consul reports them (1.12 and later), e.g. "key",
+// "write" and "locks/my-lock".
+type PermissionDeniedError struct {
+	AccessorID string
+	Resource   string
+	Verb       string
+	Target     string
+	Message    string
+}
+
+func (e PermissionDeniedError) Error() string {
+	if e.Resource == "" {
+		return fmt.Sprintf("permission denied: %s", e.Message)
+	}
+
+	permission := fmt.Sprintf("%s:%s", e.Resource, e.Verb)
+	if e.Target != "" {
+		return fmt.Sprintf("permission denied: token lacks permission '%s' on '%s'", permission, e.Target)
+	}
+	return fmt.Sprintf("permission denied: token lacks permission '%s'", permission)
+}
+
+var permissionDeniedRegexp = regexp.MustCompile(`token with AccessorID '([^']*)' lacks permission '([^:']+):([^']+)'(?: on "([^"]*)")?`)
+
+// AsPermissionDeniedError converts an ACL denial from consul into a
+// PermissionDeniedError. Any other error, including nil, is returned
+// unchanged.
+func AsPermissionDeniedError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := err.(PermissionDeniedError); ok {
+		return err
+	}
+
+	message := err.Error()
+	statusErr, isStatusErr := err.(api.StatusError)
+	if isStatusErr {
+		if statusErr.Code != http.StatusForbidden {
+			return err
+		}
+		message = statusErr.Body
+	} else if !strings.Contains(strings.ToLower(message), "permission denied") {
+		return err
+	}
+
+	denied := PermissionDeniedError{Message: message}
+	if match := permissionDeniedRegexp.FindStringSubmatch(message); match != nil {
+		denied.AccessorID = match[1]
+		denied.Resource = match[2]
+		denied.Verb = match[3]
+		denied.Target = match[4]
+	}
+
+	return denied
+}
+
+// TTLTooShortError is returned when creating a session whose TTL is below
+// the servers' session_ttl_min.
+type TTLTooShortError struct {
+	TTL     string
+	Minimum time.Duration
+	Maximum time.Duration
+}
+
+func (e TTLTooShortError) Error() string {
+	return fmt.Sprintf("session TTL '%s' is shorter than the server minimum of %s", e.TTL, e.Minimum)
+}
+
+var invalidSessionTTLRegexp = regexp.MustCompile(`Session TTL '[^']*',? must be between \[([^=\]]+)=([^\]]+)\]`)
+
+func asTTLTooShortError(se *api.SessionEntry, err error) error {
+	if err == nil || se == nil {
+		return err
+	}
+
+	match := invalidSessionTTLRegexp.FindStringSubmatch(err.Error())
+	if match == nil {
+		return err
+	}
+
+	minimum, minErr := time.ParseDuration(match[1])
+	maximum, maxErr := time.ParseDuration(match[2])
+	ttl, ttlErr := time.ParseDuration(se.TTL)
+	if minErr != nil || maxErr != nil || ttlErr != nil || ttl >= minimum {
+		return err
+	}
+
+	return TTLTooShortError{TTL: se.TTL, Minimum: minimum, Maximum: maximum}
+}
+
+// ValueTooLargeError is returned by KV writes whose value exceeds
+// ClientOptions.MaxValueSize; the write is not sent to consul.
+type ValueTooLargeError struct {
+	Key   string
+	Size  int
+	Limit int
+}
+
+func (e ValueTooLargeError) Error() string {
+	return fmt.Sprintf("value for key '%s' is %d bytes, exceeding the limit of %d", e.Key, e.Size, e.Limit)
+}
+
+// ResponseTooLargeError is returned when a response body exceeds
+// ClientOptions.MaxResponseSize.
+type ResponseTooLargeError struct {
+	Path  string
+	Limit int64
+}
+
+func (e ResponseTooLargeError) Error() string {
+	return fmt.Sprintf("response from '%s' exceeds the limit of %d bytes", e.Path, e.Limit)
+}
@@ -0,0 +1,45 @@
+package consuladapter_test
+
+import (
+	"errors"
+	"net/http"
+
+	"code.cloudfoundry.org/consuladapter"
+	"github.com/hashicorp/consul/api"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("AsPermissionDeniedError", func() {
+	It("parses the required permission from the response", func() {
+		err := consuladapter.AsPermissionDeniedError(api.StatusError{
+			Code: http.StatusForbidden,
+			Body: `Permission denied: token with AccessorID 'accessor-id' lacks permission 'key:write' on "locks/my-lock"`,
+		})
+
+		Expect(err).To(Equal(consuladapter.PermissionDeniedError{
+			AccessorID: "accessor-id",
+			Resource:   "key",
+			Verb:       "write",
+			Target:     "locks/my-lock",
+			Message:    `Permission denied: token with AccessorID 'accessor-id' lacks permission 'key:write' on "locks/my-lock"`,
+		}))
+		Expect(err.Error()).To(Equal("permission denied: token lacks permission 'key:write' on 'locks/my-lock'"))
+	})
+
+	It("handles denials without details from older servers", func() {
+		err := consuladapter.AsPermissionDeniedError(errors.New("Unexpected response code: 403 (Permission denied)"))
+		Expect(err).To(BeAssignableToTypeOf(consuladapter.PermissionDeniedError{}))
+		Expect(err.(consuladapter.PermissionDeniedError).Resource).To(BeEmpty())
+	})
+
+	It("returns other errors unchanged", func() {
+		notFound := api.StatusError{Code: http.StatusNotFound, Body: "not found"}
+		Expect(consuladapter.AsPermissionDeniedError(notFound)).To(Equal(notFound))
+
+		other := errors.New("boom")
+		Expect(consuladapter.AsPermissionDeniedError(other)).To(Equal(other))
+		Expect(consuladapter.AsPermissionDeniedError(nil)).To(BeNil())
+	})
+})
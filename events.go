@@ -0,0 +1,55 @@
+package consuladapter
+
+import "sync"
+
+// Channels whose dropped events are counted by DroppedEvents.
+const (
+	ConnectionEventsChannel        = "connection_events"
+	SessionRenewalErrorsChannel    = "session_renewal_errors"
+	LockReleaseErrorsChannel       = "lock_release_errors"
+	ServiceDeregisterErrorsChannel = "service_deregister_errors"
+	ErrorFanOutChannel             = "error_fan_out"
+	LeadershipTransitionsChannel   = "leadership_transitions"
+	KeyWatchErrorsChannel          = "key_watch_errors"
+	PrefixWatchErrorsChannel       = "prefix_watch_errors"
+)
+
+var droppedEvents struct {
+	mutex  sync.Mutex
+	counts map[string]uint64
+}
+
+// DroppedEvents returns how many events and errors have been dropped on each
+// channel because its buffer was full. Background goroutines never block on
+// a send, so a slow consumer misses events rather than stalling session
+// renewal or lock monitoring.
+func DroppedEvents() map[string]uint64 {
+	droppedEvents.mutex.Lock()
+	defer droppedEvents.mutex.Unlock()
+
+	counts := make(map[string]uint64, len(droppedEvents.counts))
+	for channel, count := range droppedEvents.counts {
+		counts[channel] = count
+	}
+	return counts
+}
+
+func countDroppedEvent(channel string) {
+	droppedEvents.mutex.Lock()
+	defer droppedEvents.mutex.Unlock()
+
+	if droppedEvents.counts == nil {
+		droppedEvents.counts = map[string]uint64{}
+	}
+	droppedEvents.counts[channel]++
+}
+
+// sendError sends err on ch without blocking, counting it as dropped on
+// channel if ch is full.
+func sendError(ch chan<- error, err error, channel string) {
+	select {
+	case ch <- err:
+	default:
+		countDroppedEvent(channel)
+	}
+}
@@ -0,0 +1,770 @@
+package fakes
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/consuladapter"
+	"github.com/hashicorp/consul/api"
+)
+
+var (
+	ErrInvalidSession = errors.New("invalid session")
+	ErrSessionExpired = errors.New("session expired")
+)
+
+const defaultBlockingWaitTime = 5 * time.Minute
+
+// FakeBackend is an in-memory stand-in for the consul KV store and session
+// state. Unlike the recording fakes, the sessions, KV stores and locks it
+// hands out behave like consul's: a key has at most one holding session,
+// invalidating a session releases or deletes the keys it holds, and locks
+// built on it block, contend, and report loss. Share one backend between
+// several simulated processes to exercise their coordination.
+type FakeBackend struct {
+	mutex    sync.Mutex
+	index    uint64
+	nextID   int
+	sessions map[string]*api.SessionEntry
+	pairs    map[string]*api.KVPair
+	changed  chan struct{}
+}
+
+func NewFakeBackend() *FakeBackend {
+	return &FakeBackend{
+		sessions: map[string]*api.SessionEntry{},
+		pairs:    map[string]*api.KVPair{},
+		changed:  make(chan struct{}),
+		// consul's index is never 0 once it has a leader
+		index: 1,
+	}
+}
+
+func (b *FakeBackend) Session() consuladapter.Session {
+	return &backendSession{backend: b}
+}
+
+func (b *FakeBackend) KV() consuladapter.KV {
+	return &backendKV{backend: b}
+}
+
+func (b *FakeBackend) LockOpts(opts *api.LockOptions) (consuladapter.Lock, error) {
+	if opts.Key == "" {
+		return nil, errors.New("missing key")
+	}
+	return &backendLock{backend: b, opts: *opts}, nil
+}
+
+// Client returns a FakeClient whose Session, KV, LockOpts and SemaphoreOpts
+// are backed by b. The remaining components are recording fakes, as from
+// NewFakeClient.
+func (b *FakeBackend) Client() (*FakeClient, *FakeClientComponents) {
+	client, components := NewFakeClient()
+	client.SessionReturns(b.Session())
+	client.KVReturns(b.KV())
+	client.LockOptsStub = b.LockOpts
+	client.SemaphoreOptsStub = b.SemaphoreOpts
+	return client, components
+}
+
+// Expire invalidates a session as if its TTL had elapsed or one of its
+// checks had failed.
+func (b *FakeBackend) Expire(id string) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.invalidate(id)
+}
+
+// Holder returns the session holding key, or "" if it is not held.
+func (b *FakeBackend) Holder(key string) string {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if pair, ok := b.pairs[key]; ok {
+		return pair.Session
+	}
+	return ""
+}
+
+// must be called with the mutex held
+func (b *FakeBackend) bump() {
+	b.index++
+	close(b.changed)
+	b.changed = make(chan struct{})
+}
+
+// must be called with the mutex held
+func (b *FakeBackend) invalidate(id string) bool {
+	entry, ok := b.sessions[id]
+	if !ok {
+		return false
+	}
+	delete(b.sessions, id)
+
+	for key, pair := range b.pairs {
+		if pair.Session != id {
+			continue
+		}
+		if entry.Behavior == api.SessionBehaviorDelete {
+			delete(b.pairs, key)
+		} else {
+			pair.Session = ""
+			pair.ModifyIndex = b.index + 1
+		}
+	}
+	b.bump()
+	return true
+}
+
+// waitForIndex emulates a blocking query, returning once the backend index
+// passes q.WaitIndex, q.WaitTime elapses or q's context is done. It must be
+// called with the mutex held, which it releases while waiting.
+func (b *FakeBackend) waitForIndex(q *api.QueryOptions) {
+	if q == nil || q.WaitIndex == 0 {
+		return
+	}
+
+	waitTime := q.WaitTime
+	if waitTime == 0 {
+		waitTime = defaultBlockingWaitTime
+	}
+	timer := time.NewTimer(waitTime)
+	defer timer.Stop()
+
+	for b.index <= q.WaitIndex {
+		changed := b.changed
+		b.mutex.Unlock()
+		select {
+		case <-changed:
+			b.mutex.Lock()
+		case <-timer.C:
+			b.mutex.Lock()
+			return
+		case <-q.Context().Done():
+			b.mutex.Lock()
+			return
+		}
+	}
+}
+
+func (b *FakeBackend) queryMeta() *api.QueryMeta {
+	return &api.QueryMeta{LastIndex: b.index, KnownLeader: true}
+}
+
+type backendSession struct {
+	backend *FakeBackend
+}
+
+func (s *backendSession) Create(se *api.SessionEntry, q *api.WriteOptions) (string, *api.WriteMeta, error) {
+	b := s.backend
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	entry := api.SessionEntry{}
+	if se != nil {
+		entry = *se
+	}
+	if entry.Behavior == "" {
+		entry.Behavior = api.SessionBehaviorRelease
+	}
+	if entry.TTL != "" {
+		if _, err := time.ParseDuration(entry.TTL); err != nil {
+			return "", nil, fmt.Errorf("invalid session TTL '%s': %s", entry.TTL, err)
+		}
+	}
+
+	b.nextID++
+	entry.ID = fmt.Sprintf("session-%d", b.nextID)
+	entry.CreateIndex = b.index + 1
+	b.sessions[entry.ID] = &entry
+	b.bump()
+
+	return entry.ID, &api.WriteMeta{}, nil
+}
+
+func (s *backendSession) CreateNoChecks(se *api.SessionEntry, q *api.WriteOptions) (string, *api.WriteMeta, error) {
+	if se == nil || se.TTL == "" {
+		return "", nil, consuladapter.ErrNoChecksSessionWithoutTTL
+	}
+	return s.Create(se, q)
+}
+
+func (s *backendSession) Destroy(id string, q *api.WriteOptions) (*api.WriteMeta, error) {
+	b := s.backend
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.invalidate(id)
+	return &api.WriteMeta{}, nil
+}
+
+func (s *backendSession) Info(id string, q *api.QueryOptions) (*api.SessionEntry, *api.QueryMeta, error) {
+	b := s.backend
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.waitForIndex(q)
+
+	entry, ok := b.sessions[id]
+	if !ok {
+		return nil, b.queryMeta(), nil
+	}
+	copied := *entry
+	return &copied, b.queryMeta(), nil
+}
+
+func (s *backendSession) List(q *api.QueryOptions) ([]*api.SessionEntry, *api.QueryMeta, error) {
+	return s.list("", q)
+}
+
+func (s *backendSession) Node(node string, q *api.QueryOptions) ([]*api.SessionEntry, *api.QueryMeta, error) {
+	return s.list(node, q)
+}
+
+func (s *backendSession) list(node string, q *api.QueryOptions) ([]*api.SessionEntry, *api.QueryMeta, error) {
+	b := s.backend
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.waitForIndex(q)
+
+	entries := []*api.SessionEntry{}
+	for _, entry := range b.sessions {
+		if node != "" && entry.Node != node {
+			continue
+		}
+		copied := *entry
+		entries = append(entries, &copied)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].CreateIndex < entries[j].CreateIndex })
+
+	return entries, b.queryMeta(), nil
+}
+
+// Renew returns a nil entry for sessions that no longer exist, as consul
+// does.
+func (s *backendSession) Renew(id string, q *api.WriteOptions) (*api.SessionEntry, *api.WriteMeta, error) {
+	b := s.backend
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	entry, ok := b.sessions[id]
+	if !ok {
+		return nil, &api.WriteMeta{}, nil
+	}
+	copied := *entry
+	return &copied, &api.WriteMeta{}, nil
+}
+
+// RenewPeriodic sessions never expire on their own in the backend, so it
+// only waits for doneCh, returning ErrSessionExpired early if the session is
+// invalidated. The session is destroyed once doneCh is closed.
+func (s *backendSession) RenewPeriodic(initialTTL string, id string, q *api.WriteOptions, doneCh chan struct{}) error {
+	b := s.backend
+	for {
+		b.mutex.Lock()
+		_, ok := b.sessions[id]
+		changed := b.changed
+		b.mutex.Unlock()
+
+		if !ok {
+			return ErrSessionExpired
+		}
+
+		select {
+		case <-changed:
+		case <-doneCh:
+			_, err := s.Destroy(id, q)
+			return err
+		}
+	}
+}
+
+type backendKV struct {
+	backend *FakeBackend
+}
+
+func (kv *backendKV) Get(key string, q *api.QueryOptions) (*api.KVPair, *api.QueryMeta, error) {
+	b := kv.backend
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.waitForIndex(q)
+
+	pair, ok := b.pairs[key]
+	if !ok {
+		return nil, b.queryMeta(), nil
+	}
+	return copyPair(pair), b.queryMeta(), nil
+}
+
+func (kv *backendKV) List(prefix string, q *api.QueryOptions) (api.KVPairs, *api.QueryMeta, error) {
+	b := kv.backend
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.waitForIndex(q)
+
+	pairs := api.KVPairs{}
+	for key, pair := range b.pairs {
+		if strings.HasPrefix(key, prefix) {
+			pairs = append(pairs, copyPair(pair))
+		}
+	}
+	sort.Slice(pairs, func(i, j int) bool { return pairs[i].Key < pairs[j].Key })
+
+	return pairs, b.queryMeta(), nil
+}
+
+func (kv *backendKV) Put(p *api.KVPair, q *api.WriteOptions) (*api.WriteMeta, error) {
+	b := kv.backend
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.put(p, b.pairs[p.Key])
+	return &api.WriteMeta{}, nil
+}
+
+func (kv *backendKV) CAS(p *api.KVPair, q *api.WriteOptions) (bool, *api.WriteMeta, error) {
+	b := kv.backend
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	existing, ok := b.pairs[p.Key]
+	if p.ModifyIndex == 0 && ok || p.ModifyIndex != 0 && (!ok || existing.ModifyIndex != p.ModifyIndex) {
+		return false, &api.WriteMeta{}, nil
+	}
+	b.put(p, existing)
+	return true, &api.WriteMeta{}, nil
+}
+
+func (kv *backendKV) Acquire(p *api.KVPair, q *api.WriteOptions) (bool, *api.WriteMeta, error) {
+	b := kv.backend
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	acquired, err := b.acquire(p)
+	return acquired, &api.WriteMeta{}, err
+}
+
+func (kv *backendKV) Release(p *api.KVPair, q *api.WriteOptions) (bool, *api.WriteMeta, error) {
+	b := kv.backend
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	return b.release(p), &api.WriteMeta{}, nil
+}
+
+func (kv *backendKV) DeleteCAS(p *api.KVPair, w *api.WriteOptions) (bool, *api.WriteMeta, error) {
+	b := kv.backend
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	existing, ok := b.pairs[p.Key]
+	if !ok || existing.ModifyIndex != p.ModifyIndex {
+		return false, &api.WriteMeta{}, nil
+	}
+	delete(b.pairs, p.Key)
+	b.bump()
+	return true, &api.WriteMeta{}, nil
+}
+
+func (kv *backendKV) DeleteTree(prefix string, w *api.WriteOptions) (*api.WriteMeta, error) {
+	b := kv.backend
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	for key := range b.pairs {
+		if strings.HasPrefix(key, prefix) {
+			delete(b.pairs, key)
+		}
+	}
+	b.bump()
+	return &api.WriteMeta{}, nil
+}
+
+// must be called with the mutex held
+func (b *FakeBackend) put(p *api.KVPair, existing *api.KVPair) *api.KVPair {
+	pair := &api.KVPair{
+		Key:         p.Key,
+		Flags:       p.Flags,
+		Value:       append([]byte(nil), p.Value...),
+		CreateIndex: b.index + 1,
+		ModifyIndex: b.index + 1,
+	}
+	if existing != nil {
+		pair.CreateIndex = existing.CreateIndex
+		pair.LockIndex = existing.LockIndex
+		pair.Session = existing.Session
+	}
+	b.pairs[p.Key] = pair
+	b.bump()
+	return pair
+}
+
+// must be called with the mutex held
+func (b *FakeBackend) acquire(p *api.KVPair) (bool, error) {
+	if _, ok := b.sessions[p.Session]; !ok {
+		return false, ErrInvalidSession
+	}
+
+	existing := b.pairs[p.Key]
+	if existing != nil && existing.Session != "" && existing.Session != p.Session {
+		return false, nil
+	}
+
+	pair := b.put(p, existing)
+	if pair.Session != p.Session {
+		pair.Session = p.Session
+		pair.LockIndex++
+	}
+	return true, nil
+}
+
+// must be called with the mutex held
+func (b *FakeBackend) release(p *api.KVPair) bool {
+	existing, ok := b.pairs[p.Key]
+	if !ok || existing.Session != p.Session {
+		return false
+	}
+
+	pair := b.put(p, existing)
+	pair.Session = ""
+	return true
+}
+
+func copyPair(pair *api.KVPair) *api.KVPair {
+	copied := *pair
+	copied.Value = append([]byte(nil), pair.Value...)
+	return &copied
+}
+
+type backendLock struct {
+	backend *FakeBackend
+	opts    api.LockOptions
+
+	mutex      sync.Mutex
+	session    string
+	ownSession bool
+	unlocked   chan struct{}
+}
+
+func (l *backendLock) Lock(stopCh <-chan struct{}) (<-chan struct{}, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.unlocked != nil {
+		return nil, api.ErrLockHeld
+	}
+
+	session := l.opts.Session
+	if session == "" {
+		entry := &api.SessionEntry{Name: l.opts.SessionName, TTL: l.opts.SessionTTL}
+		if l.opts.SessionOpts != nil {
+			copied := *l.opts.SessionOpts
+			entry = &copied
+		}
+		if entry.Name == "" {
+			entry.Name = api.DefaultLockSessionName
+		}
+		if entry.TTL == "" {
+			entry.TTL = api.DefaultLockSessionTTL
+		}
+
+		var err error
+		session, _, err = l.backend.Session().Create(entry, nil)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	b := l.backend
+	pair := &api.KVPair{Key: l.opts.Key, Value: l.opts.Value, Session: session, Flags: api.LockFlagValue}
+	for {
+		b.mutex.Lock()
+		acquired, err := b.acquire(pair)
+		changed := b.changed
+		b.mutex.Unlock()
+
+		if err != nil || !acquired && l.opts.LockTryOnce {
+			l.cleanupSession(session)
+			return nil, err
+		}
+		if acquired {
+			break
+		}
+
+		select {
+		case <-changed:
+		case <-stopCh:
+			l.cleanupSession(session)
+			return nil, nil
+		}
+	}
+
+	l.session = session
+	l.ownSession = l.opts.Session == ""
+	l.unlocked = make(chan struct{})
+
+	lost := make(chan struct{})
+	go l.monitor(session, l.unlocked, lost)
+	return lost, nil
+}
+
+func (l *backendLock) Unlock() error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.unlocked == nil {
+		return api.ErrLockNotHeld
+	}
+	close(l.unlocked)
+	l.unlocked = nil
+
+	b := l.backend
+	b.mutex.Lock()
+	b.release(&api.KVPair{Key: l.opts.Key, Session: l.session})
+	b.mutex.Unlock()
+
+	if l.ownSession {
+		l.cleanupSession(l.session)
+	}
+	return nil
+}
+
+func (l *backendLock) cleanupSession(session string) {
+	if l.opts.Session == "" {
+		l.backend.Session().Destroy(session, nil)
+	}
+}
+
+func (l *backendLock) monitor(session string, unlocked, lost chan struct{}) {
+	defer close(lost)
+
+	b := l.backend
+	for {
+		b.mutex.Lock()
+		pair, ok := b.pairs[l.opts.Key]
+		held := ok && pair.Session == session
+		changed := b.changed
+		b.mutex.Unlock()
+
+		if !held {
+			return
+		}
+
+		select {
+		case <-changed:
+		case <-unlocked:
+			return
+		}
+	}
+}
+
+func (b *FakeBackend) SemaphoreOpts(opts *api.SemaphoreOptions) (consuladapter.Semaphore, error) {
+	if opts.Prefix == "" {
+		return nil, errors.New("missing prefix")
+	}
+	if opts.Limit <= 0 {
+		return nil, errors.New("semaphore limit must be positive")
+	}
+	return &backendSemaphore{backend: b, opts: *opts}, nil
+}
+
+// semaphoreLock is the value of a semaphore's .lock key, as written by the
+// consul api.
+type semaphoreLock struct {
+	Limit   int
+	Holders map[string]bool
+}
+
+type backendSemaphore struct {
+	backend *FakeBackend
+	opts    api.SemaphoreOptions
+
+	mutex    sync.Mutex
+	session  string
+	released chan struct{}
+}
+
+func (s *backendSemaphore) contenderKey(session string) string {
+	return strings.TrimSuffix(s.opts.Prefix, "/") + "/" + session
+}
+
+func (s *backendSemaphore) lockKey() string {
+	return strings.TrimSuffix(s.opts.Prefix, "/") + "/" + api.DefaultSemaphoreKey
+}
+
+func (s *backendSemaphore) Acquire(stopCh <-chan struct{}) (<-chan struct{}, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.released != nil {
+		return nil, api.ErrSemaphoreHeld
+	}
+
+	session := s.opts.Session
+	if session == "" {
+		name := s.opts.SessionName
+		if name == "" {
+			name = api.DefaultSemaphoreSessionName
+		}
+		ttl := s.opts.SessionTTL
+		if ttl == "" {
+			ttl = api.DefaultSemaphoreSessionTTL
+		}
+
+		var err error
+		session, _, err = s.backend.Session().Create(&api.SessionEntry{Name: name, TTL: ttl}, nil)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	b := s.backend
+	contender := &api.KVPair{Key: s.contenderKey(session), Value: s.opts.Value, Session: session, Flags: api.SemaphoreFlagValue}
+	b.mutex.Lock()
+	acquired, err := b.acquire(contender)
+	b.mutex.Unlock()
+	if err != nil || !acquired {
+		s.cleanup(session)
+		if err == nil {
+			err = errors.New("failed to make contender entry")
+		}
+		return nil, err
+	}
+
+	for {
+		b.mutex.Lock()
+		acquired, err := b.takeSlot(s, session)
+		changed := b.changed
+		b.mutex.Unlock()
+
+		if err != nil || !acquired && s.opts.SemaphoreTryOnce {
+			s.cleanup(session)
+			return nil, err
+		}
+		if acquired {
+			break
+		}
+
+		select {
+		case <-changed:
+		case <-stopCh:
+			s.cleanup(session)
+			return nil, nil
+		}
+	}
+
+	s.session = session
+	s.released = make(chan struct{})
+
+	lost := make(chan struct{})
+	go s.monitor(session, s.released, lost)
+	return lost, nil
+}
+
+// takeSlot adds session to the semaphore's holders if there is room,
+// pruning holders whose contender keys are no longer held. It must be
+// called with the mutex held.
+func (b *FakeBackend) takeSlot(s *backendSemaphore, session string) (bool, error) {
+	lock, err := b.semaphoreLock(s)
+	if err != nil {
+		return false, err
+	}
+	if lock.Limit != s.opts.Limit {
+		return false, api.ErrSemaphoreConflict
+	}
+
+	for holder := range lock.Holders {
+		pair, ok := b.pairs[s.contenderKey(holder)]
+		if !ok || pair.Session == "" {
+			delete(lock.Holders, holder)
+		}
+	}
+	if len(lock.Holders) >= lock.Limit {
+		return false, nil
+	}
+
+	lock.Holders[session] = true
+	b.putSemaphoreLock(s, lock)
+	return true, nil
+}
+
+func (b *FakeBackend) semaphoreLock(s *backendSemaphore) (semaphoreLock, error) {
+	lock := semaphoreLock{Limit: s.opts.Limit, Holders: map[string]bool{}}
+	pair, ok := b.pairs[s.lockKey()]
+	if !ok || len(pair.Value) == 0 {
+		return lock, nil
+	}
+	if err := json.Unmarshal(pair.Value, &lock); err != nil {
+		return lock, fmt.Errorf("failed to decode semaphore lock: %v", err)
+	}
+	if lock.Holders == nil {
+		lock.Holders = map[string]bool{}
+	}
+	return lock, nil
+}
+
+func (b *FakeBackend) putSemaphoreLock(s *backendSemaphore, lock semaphoreLock) {
+	value, _ := json.Marshal(lock)
+	b.put(&api.KVPair{Key: s.lockKey(), Value: value, Flags: api.SemaphoreFlagValue}, b.pairs[s.lockKey()])
+}
+
+func (s *backendSemaphore) Release() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.released == nil {
+		return api.ErrSemaphoreNotHeld
+	}
+	close(s.released)
+	s.released = nil
+
+	b := s.backend
+	b.mutex.Lock()
+	if lock, err := b.semaphoreLock(s); err == nil && lock.Holders[s.session] {
+		delete(lock.Holders, s.session)
+		b.putSemaphoreLock(s, lock)
+	}
+	b.mutex.Unlock()
+
+	s.cleanup(s.session)
+	return nil
+}
+
+// cleanup deletes the session's contender key, and the session itself if
+// the semaphore created it.
+func (s *backendSemaphore) cleanup(session string) {
+	b := s.backend
+	b.mutex.Lock()
+	delete(b.pairs, s.contenderKey(session))
+	b.bump()
+	b.mutex.Unlock()
+
+	if s.opts.Session == "" {
+		s.backend.Session().Destroy(session, nil)
+	}
+}
+
+func (s *backendSemaphore) monitor(session string, released, lost chan struct{}) {
+	defer close(lost)
+
+	b := s.backend
+	for {
+		b.mutex.Lock()
+		pair, ok := b.pairs[s.contenderKey(session)]
+		lock, err := b.semaphoreLock(s)
+		held := ok && pair.Session == session && err == nil && lock.Holders[session]
+		changed := b.changed
+		b.mutex.Unlock()
+
+		if !held {
+			return
+		}
+
+		select {
+		case <-changed:
+		case <-released:
+			return
+		}
+	}
+}
@@ -0,0 +1,216 @@
+package fakes_test
+
+import (
+	"code.cloudfoundry.org/consuladapter"
+	"code.cloudfoundry.org/consuladapter/fakes"
+	"github.com/hashicorp/consul/api"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("FakeBackend", func() {
+	var (
+		backend *fakes.FakeBackend
+		session consuladapter.Session
+		kv      consuladapter.KV
+	)
+
+	BeforeEach(func() {
+		backend = fakes.NewFakeBackend()
+		session = backend.Session()
+		kv = backend.KV()
+	})
+
+	createSession := func(behavior string) string {
+		id, _, err := session.Create(&api.SessionEntry{TTL: "10s", Behavior: behavior}, nil)
+		Expect(err).NotTo(HaveOccurred())
+		return id
+	}
+
+	Describe("Acquire", func() {
+		It("lets only one session hold a key", func() {
+			first := createSession("")
+			second := createSession("")
+
+			acquired, _, err := kv.Acquire(&api.KVPair{Key: "lock", Session: first}, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(acquired).To(BeTrue())
+
+			acquired, _, err = kv.Acquire(&api.KVPair{Key: "lock", Session: second}, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(acquired).To(BeFalse())
+			Expect(backend.Holder("lock")).To(Equal(first))
+
+			released, _, err := kv.Release(&api.KVPair{Key: "lock", Session: second}, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(released).To(BeFalse())
+
+			released, _, err = kv.Release(&api.KVPair{Key: "lock", Session: first}, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(released).To(BeTrue())
+
+			acquired, _, err = kv.Acquire(&api.KVPair{Key: "lock", Session: second}, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(acquired).To(BeTrue())
+
+			pair, _, err := kv.Get("lock", nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pair.LockIndex).To(BeEquivalentTo(2))
+		})
+
+		It("rejects unknown sessions", func() {
+			_, _, err := kv.Acquire(&api.KVPair{Key: "lock", Session: "missing"}, nil)
+			Expect(err).To(Equal(fakes.ErrInvalidSession))
+		})
+	})
+
+	Describe("session invalidation", func() {
+		It("releases held keys for the release behavior", func() {
+			id := createSession(api.SessionBehaviorRelease)
+			_, _, err := kv.Acquire(&api.KVPair{Key: "lock", Value: []byte("v"), Session: id}, nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			backend.Expire(id)
+
+			pair, _, err := kv.Get("lock", nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pair.Session).To(BeEmpty())
+			Expect(pair.Value).To(Equal([]byte("v")))
+
+			entry, _, err := session.Renew(id, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(entry).To(BeNil())
+		})
+
+		It("deletes held keys for the delete behavior", func() {
+			id := createSession(api.SessionBehaviorDelete)
+			_, _, err := kv.Acquire(&api.KVPair{Key: "lock", Session: id}, nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = session.Destroy(id, nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			pair, _, err := kv.Get("lock", nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pair).To(BeNil())
+		})
+	})
+
+	Describe("LockOpts", func() {
+		It("blocks a second lock until the first is unlocked", func() {
+			first, err := backend.LockOpts(&api.LockOptions{Key: "lock"})
+			Expect(err).NotTo(HaveOccurred())
+			second, err := backend.LockOpts(&api.LockOptions{Key: "lock"})
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = first.Lock(nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			acquired := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				_, err := second.Lock(nil)
+				Expect(err).NotTo(HaveOccurred())
+				close(acquired)
+			}()
+
+			Consistently(acquired).ShouldNot(BeClosed())
+			Expect(first.Unlock()).To(Succeed())
+			Eventually(acquired).Should(BeClosed())
+		})
+
+		It("reports the lock lost when its session is invalidated", func() {
+			lock, err := backend.LockOpts(&api.LockOptions{Key: "lock"})
+			Expect(err).NotTo(HaveOccurred())
+
+			lost, err := lock.Lock(nil)
+			Expect(err).NotTo(HaveOccurred())
+			Consistently(lost).ShouldNot(BeClosed())
+
+			backend.Expire(backend.Holder("lock"))
+			Eventually(lost).Should(BeClosed())
+		})
+
+		It("gives up when stopped", func() {
+			first, err := backend.LockOpts(&api.LockOptions{Key: "lock"})
+			Expect(err).NotTo(HaveOccurred())
+			_, err = first.Lock(nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			second, err := backend.LockOpts(&api.LockOptions{Key: "lock"})
+			Expect(err).NotTo(HaveOccurred())
+			stop := make(chan struct{})
+			close(stop)
+
+			lost, err := second.Lock(stop)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(lost).To(BeNil())
+
+			sessions, _, err := backend.Session().List(nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(sessions).To(HaveLen(1))
+		})
+	})
+
+	Describe("SemaphoreOpts", func() {
+		newSemaphore := func(opts api.SemaphoreOptions) consuladapter.Semaphore {
+			opts.Prefix = "service/workers"
+			opts.Limit = 2
+			semaphore, err := backend.SemaphoreOpts(&opts)
+			Expect(err).NotTo(HaveOccurred())
+			return semaphore
+		}
+
+		It("lets up to the limit hold a slot and blocks the next until one is released", func() {
+			first := newSemaphore(api.SemaphoreOptions{})
+			second := newSemaphore(api.SemaphoreOptions{})
+			third := newSemaphore(api.SemaphoreOptions{})
+
+			_, err := first.Acquire(nil)
+			Expect(err).NotTo(HaveOccurred())
+			_, err = second.Acquire(nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			acquired := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				_, err := third.Acquire(nil)
+				Expect(err).NotTo(HaveOccurred())
+				close(acquired)
+			}()
+
+			Consistently(acquired).ShouldNot(BeClosed())
+			Expect(first.Release()).To(Succeed())
+			Eventually(acquired).Should(BeClosed())
+		})
+
+		It("reports the slot lost when its session is invalidated", func() {
+			session, _, err := backend.Session().Create(nil, nil)
+			Expect(err).NotTo(HaveOccurred())
+			semaphore := newSemaphore(api.SemaphoreOptions{Session: session})
+
+			lost, err := semaphore.Acquire(nil)
+			Expect(err).NotTo(HaveOccurred())
+			Consistently(lost).ShouldNot(BeClosed())
+
+			backend.Expire(session)
+			Eventually(lost).Should(BeClosed())
+		})
+
+		It("gives up after one attempt when asked to", func() {
+			for i := 0; i < 2; i++ {
+				_, err := newSemaphore(api.SemaphoreOptions{}).Acquire(nil)
+				Expect(err).NotTo(HaveOccurred())
+			}
+
+			lost, err := newSemaphore(api.SemaphoreOptions{SemaphoreTryOnce: true}).Acquire(nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(lost).To(BeNil())
+
+			sessions, _, err := backend.Session().List(nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(sessions).To(HaveLen(2))
+		})
+	})
+})
@@ -5,6 +5,7 @@ type FakeClientComponents struct {
 	KV      *FakeKV
 	Session *FakeSession
 	Catalog *FakeCatalog
+	Health  *FakeHealth
 }
 
 func NewFakeClient() (*FakeClient, *FakeClientComponents) {
@@ -14,15 +15,18 @@ func NewFakeClient() (*FakeClient, *FakeClientComponents) {
 	kv := &FakeKV{}
 	session := &FakeSession{}
 	catalog := &FakeCatalog{}
+	health := &FakeHealth{}
 
 	client.AgentReturns(agent)
 	client.KVReturns(kv)
 	client.SessionReturns(session)
 	client.CatalogReturns(catalog)
+	client.HealthReturns(health)
 	return client, &FakeClientComponents{
 		Agent:   agent,
 		KV:      kv,
 		Session: session,
 		Catalog: catalog,
+		Health:  health,
 	}
 }
@@ -0,0 +1,828 @@
+// This file was generated by counterfeiter
+package fakes
+
+import (
+	"sync"
+
+	"code.cloudfoundry.org/consuladapter"
+	"github.com/hashicorp/consul/api"
+)
+
+type FakeACL struct {
+	PolicyCreateStub        func(policy *api.ACLPolicy, q *api.WriteOptions) (*api.ACLPolicy, *api.WriteMeta, error)
+	policyCreateMutex       sync.RWMutex
+	policyCreateArgsForCall []struct {
+		policy *api.ACLPolicy
+		q      *api.WriteOptions
+	}
+	policyCreateReturns struct {
+		result1 *api.ACLPolicy
+		result2 *api.WriteMeta
+		result3 error
+	}
+	PolicyReadStub        func(policyID string, q *api.QueryOptions) (*api.ACLPolicy, *api.QueryMeta, error)
+	policyReadMutex       sync.RWMutex
+	policyReadArgsForCall []struct {
+		policyID string
+		q        *api.QueryOptions
+	}
+	policyReadReturns struct {
+		result1 *api.ACLPolicy
+		result2 *api.QueryMeta
+		result3 error
+	}
+	PolicyReadByNameStub        func(policyName string, q *api.QueryOptions) (*api.ACLPolicy, *api.QueryMeta, error)
+	policyReadByNameMutex       sync.RWMutex
+	policyReadByNameArgsForCall []struct {
+		policyName string
+		q          *api.QueryOptions
+	}
+	policyReadByNameReturns struct {
+		result1 *api.ACLPolicy
+		result2 *api.QueryMeta
+		result3 error
+	}
+	PolicyUpdateStub        func(policy *api.ACLPolicy, q *api.WriteOptions) (*api.ACLPolicy, *api.WriteMeta, error)
+	policyUpdateMutex       sync.RWMutex
+	policyUpdateArgsForCall []struct {
+		policy *api.ACLPolicy
+		q      *api.WriteOptions
+	}
+	policyUpdateReturns struct {
+		result1 *api.ACLPolicy
+		result2 *api.WriteMeta
+		result3 error
+	}
+	PolicyDeleteStub        func(policyID string, q *api.WriteOptions) (*api.WriteMeta, error)
+	policyDeleteMutex       sync.RWMutex
+	policyDeleteArgsForCall []struct {
+		policyID string
+		q        *api.WriteOptions
+	}
+	policyDeleteReturns struct {
+		result1 *api.WriteMeta
+		result2 error
+	}
+	PolicyListStub        func(q *api.QueryOptions) ([]*api.ACLPolicyListEntry, *api.QueryMeta, error)
+	policyListMutex       sync.RWMutex
+	policyListArgsForCall []struct {
+		q *api.QueryOptions
+	}
+	policyListReturns struct {
+		result1 []*api.ACLPolicyListEntry
+		result2 *api.QueryMeta
+		result3 error
+	}
+	RoleCreateStub        func(role *api.ACLRole, q *api.WriteOptions) (*api.ACLRole, *api.WriteMeta, error)
+	roleCreateMutex       sync.RWMutex
+	roleCreateArgsForCall []struct {
+		role *api.ACLRole
+		q    *api.WriteOptions
+	}
+	roleCreateReturns struct {
+		result1 *api.ACLRole
+		result2 *api.WriteMeta
+		result3 error
+	}
+	RoleReadStub        func(roleID string, q *api.QueryOptions) (*api.ACLRole, *api.QueryMeta, error)
+	roleReadMutex       sync.RWMutex
+	roleReadArgsForCall []struct {
+		roleID string
+		q      *api.QueryOptions
+	}
+	roleReadReturns struct {
+		result1 *api.ACLRole
+		result2 *api.QueryMeta
+		result3 error
+	}
+	RoleReadByNameStub        func(roleName string, q *api.QueryOptions) (*api.ACLRole, *api.QueryMeta, error)
+	roleReadByNameMutex       sync.RWMutex
+	roleReadByNameArgsForCall []struct {
+		roleName string
+		q        *api.QueryOptions
+	}
+	roleReadByNameReturns struct {
+		result1 *api.ACLRole
+		result2 *api.QueryMeta
+		result3 error
+	}
+	RoleUpdateStub        func(role *api.ACLRole, q *api.WriteOptions) (*api.ACLRole, *api.WriteMeta, error)
+	roleUpdateMutex       sync.RWMutex
+	roleUpdateArgsForCall []struct {
+		role *api.ACLRole
+		q    *api.WriteOptions
+	}
+	roleUpdateReturns struct {
+		result1 *api.ACLRole
+		result2 *api.WriteMeta
+		result3 error
+	}
+	RoleDeleteStub        func(roleID string, q *api.WriteOptions) (*api.WriteMeta, error)
+	roleDeleteMutex       sync.RWMutex
+	roleDeleteArgsForCall []struct {
+		roleID string
+		q      *api.WriteOptions
+	}
+	roleDeleteReturns struct {
+		result1 *api.WriteMeta
+		result2 error
+	}
+	RoleListStub        func(q *api.QueryOptions) ([]*api.ACLRole, *api.QueryMeta, error)
+	roleListMutex       sync.RWMutex
+	roleListArgsForCall []struct {
+		q *api.QueryOptions
+	}
+	roleListReturns struct {
+		result1 []*api.ACLRole
+		result2 *api.QueryMeta
+		result3 error
+	}
+	TokenCreateStub        func(token *api.ACLToken, q *api.WriteOptions) (*api.ACLToken, *api.WriteMeta, error)
+	tokenCreateMutex       sync.RWMutex
+	tokenCreateArgsForCall []struct {
+		token *api.ACLToken
+		q     *api.WriteOptions
+	}
+	tokenCreateReturns struct {
+		result1 *api.ACLToken
+		result2 *api.WriteMeta
+		result3 error
+	}
+	TokenReadStub        func(accessorID string, q *api.QueryOptions) (*api.ACLToken, *api.QueryMeta, error)
+	tokenReadMutex       sync.RWMutex
+	tokenReadArgsForCall []struct {
+		accessorID string
+		q          *api.QueryOptions
+	}
+	tokenReadReturns struct {
+		result1 *api.ACLToken
+		result2 *api.QueryMeta
+		result3 error
+	}
+	TokenReadSelfStub        func(q *api.QueryOptions) (*api.ACLToken, *api.QueryMeta, error)
+	tokenReadSelfMutex       sync.RWMutex
+	tokenReadSelfArgsForCall []struct {
+		q *api.QueryOptions
+	}
+	tokenReadSelfReturns struct {
+		result1 *api.ACLToken
+		result2 *api.QueryMeta
+		result3 error
+	}
+	TokenUpdateStub        func(token *api.ACLToken, q *api.WriteOptions) (*api.ACLToken, *api.WriteMeta, error)
+	tokenUpdateMutex       sync.RWMutex
+	tokenUpdateArgsForCall []struct {
+		token *api.ACLToken
+		q     *api.WriteOptions
+	}
+	tokenUpdateReturns struct {
+		result1 *api.ACLToken
+		result2 *api.WriteMeta
+		result3 error
+	}
+	TokenDeleteStub        func(accessorID string, q *api.WriteOptions) (*api.WriteMeta, error)
+	tokenDeleteMutex       sync.RWMutex
+	tokenDeleteArgsForCall []struct {
+		accessorID string
+		q          *api.WriteOptions
+	}
+	tokenDeleteReturns struct {
+		result1 *api.WriteMeta
+		result2 error
+	}
+	TokenListStub        func(q *api.QueryOptions) ([]*api.ACLTokenListEntry, *api.QueryMeta, error)
+	tokenListMutex       sync.RWMutex
+	tokenListArgsForCall []struct {
+		q *api.QueryOptions
+	}
+	tokenListReturns struct {
+		result1 []*api.ACLTokenListEntry
+		result2 *api.QueryMeta
+		result3 error
+	}
+}
+
+func (fake *FakeACL) PolicyCreate(policy *api.ACLPolicy, q *api.WriteOptions) (*api.ACLPolicy, *api.WriteMeta, error) {
+	fake.policyCreateMutex.Lock()
+	fake.policyCreateArgsForCall = append(fake.policyCreateArgsForCall, struct {
+		policy *api.ACLPolicy
+		q      *api.WriteOptions
+	}{policy, q})
+	fake.policyCreateMutex.Unlock()
+	if fake.PolicyCreateStub != nil {
+		return fake.PolicyCreateStub(policy, q)
+	} else {
+		return fake.policyCreateReturns.result1, fake.policyCreateReturns.result2, fake.policyCreateReturns.result3
+	}
+}
+
+func (fake *FakeACL) PolicyCreateCallCount() int {
+	fake.policyCreateMutex.RLock()
+	defer fake.policyCreateMutex.RUnlock()
+	return len(fake.policyCreateArgsForCall)
+}
+
+func (fake *FakeACL) PolicyCreateArgsForCall(i int) (*api.ACLPolicy, *api.WriteOptions) {
+	fake.policyCreateMutex.RLock()
+	defer fake.policyCreateMutex.RUnlock()
+	return fake.policyCreateArgsForCall[i].policy, fake.policyCreateArgsForCall[i].q
+}
+
+func (fake *FakeACL) PolicyCreateReturns(result1 *api.ACLPolicy, result2 *api.WriteMeta, result3 error) {
+	fake.PolicyCreateStub = nil
+	fake.policyCreateReturns = struct {
+		result1 *api.ACLPolicy
+		result2 *api.WriteMeta
+		result3 error
+	}{result1, result2, result3}
+}
+
+func (fake *FakeACL) PolicyRead(policyID string, q *api.QueryOptions) (*api.ACLPolicy, *api.QueryMeta, error) {
+	fake.policyReadMutex.Lock()
+	fake.policyReadArgsForCall = append(fake.policyReadArgsForCall, struct {
+		policyID string
+		q        *api.QueryOptions
+	}{policyID, q})
+	fake.policyReadMutex.Unlock()
+	if fake.PolicyReadStub != nil {
+		return fake.PolicyReadStub(policyID, q)
+	} else {
+		return fake.policyReadReturns.result1, fake.policyReadReturns.result2, fake.policyReadReturns.result3
+	}
+}
+
+func (fake *FakeACL) PolicyReadCallCount() int {
+	fake.policyReadMutex.RLock()
+	defer fake.policyReadMutex.RUnlock()
+	return len(fake.policyReadArgsForCall)
+}
+
+func (fake *FakeACL) PolicyReadArgsForCall(i int) (string, *api.QueryOptions) {
+	fake.policyReadMutex.RLock()
+	defer fake.policyReadMutex.RUnlock()
+	return fake.policyReadArgsForCall[i].policyID, fake.policyReadArgsForCall[i].q
+}
+
+func (fake *FakeACL) PolicyReadReturns(result1 *api.ACLPolicy, result2 *api.QueryMeta, result3 error) {
+	fake.PolicyReadStub = nil
+	fake.policyReadReturns = struct {
+		result1 *api.ACLPolicy
+		result2 *api.QueryMeta
+		result3 error
+	}{result1, result2, result3}
+}
+
+func (fake *FakeACL) PolicyReadByName(policyName string, q *api.QueryOptions) (*api.ACLPolicy, *api.QueryMeta, error) {
+	fake.policyReadByNameMutex.Lock()
+	fake.policyReadByNameArgsForCall = append(fake.policyReadByNameArgsForCall, struct {
+		policyName string
+		q          *api.QueryOptions
+	}{policyName, q})
+	fake.policyReadByNameMutex.Unlock()
+	if fake.PolicyReadByNameStub != nil {
+		return fake.PolicyReadByNameStub(policyName, q)
+	} else {
+		return fake.policyReadByNameReturns.result1, fake.policyReadByNameReturns.result2, fake.policyReadByNameReturns.result3
+	}
+}
+
+func (fake *FakeACL) PolicyReadByNameCallCount() int {
+	fake.policyReadByNameMutex.RLock()
+	defer fake.policyReadByNameMutex.RUnlock()
+	return len(fake.policyReadByNameArgsForCall)
+}
+
+func (fake *FakeACL) PolicyReadByNameArgsForCall(i int) (string, *api.QueryOptions) {
+	fake.policyReadByNameMutex.RLock()
+	defer fake.policyReadByNameMutex.RUnlock()
+	return fake.policyReadByNameArgsForCall[i].policyName, fake.policyReadByNameArgsForCall[i].q
+}
+
+func (fake *FakeACL) PolicyReadByNameReturns(result1 *api.ACLPolicy, result2 *api.QueryMeta, result3 error) {
+	fake.PolicyReadByNameStub = nil
+	fake.policyReadByNameReturns = struct {
+		result1 *api.ACLPolicy
+		result2 *api.QueryMeta
+		result3 error
+	}{result1, result2, result3}
+}
+
+func (fake *FakeACL) PolicyUpdate(policy *api.ACLPolicy, q *api.WriteOptions) (*api.ACLPolicy, *api.WriteMeta, error) {
+	fake.policyUpdateMutex.Lock()
+	fake.policyUpdateArgsForCall = append(fake.policyUpdateArgsForCall, struct {
+		policy *api.ACLPolicy
+		q      *api.WriteOptions
+	}{policy, q})
+	fake.policyUpdateMutex.Unlock()
+	if fake.PolicyUpdateStub != nil {
+		return fake.PolicyUpdateStub(policy, q)
+	} else {
+		return fake.policyUpdateReturns.result1, fake.policyUpdateReturns.result2, fake.policyUpdateReturns.result3
+	}
+}
+
+func (fake *FakeACL) PolicyUpdateCallCount() int {
+	fake.policyUpdateMutex.RLock()
+	defer fake.policyUpdateMutex.RUnlock()
+	return len(fake.policyUpdateArgsForCall)
+}
+
+func (fake *FakeACL) PolicyUpdateArgsForCall(i int) (*api.ACLPolicy, *api.WriteOptions) {
+	fake.policyUpdateMutex.RLock()
+	defer fake.policyUpdateMutex.RUnlock()
+	return fake.policyUpdateArgsForCall[i].policy, fake.policyUpdateArgsForCall[i].q
+}
+
+func (fake *FakeACL) PolicyUpdateReturns(result1 *api.ACLPolicy, result2 *api.WriteMeta, result3 error) {
+	fake.PolicyUpdateStub = nil
+	fake.policyUpdateReturns = struct {
+		result1 *api.ACLPolicy
+		result2 *api.WriteMeta
+		result3 error
+	}{result1, result2, result3}
+}
+
+func (fake *FakeACL) PolicyDelete(policyID string, q *api.WriteOptions) (*api.WriteMeta, error) {
+	fake.policyDeleteMutex.Lock()
+	fake.policyDeleteArgsForCall = append(fake.policyDeleteArgsForCall, struct {
+		policyID string
+		q        *api.WriteOptions
+	}{policyID, q})
+	fake.policyDeleteMutex.Unlock()
+	if fake.PolicyDeleteStub != nil {
+		return fake.PolicyDeleteStub(policyID, q)
+	} else {
+		return fake.policyDeleteReturns.result1, fake.policyDeleteReturns.result2
+	}
+}
+
+func (fake *FakeACL) PolicyDeleteCallCount() int {
+	fake.policyDeleteMutex.RLock()
+	defer fake.policyDeleteMutex.RUnlock()
+	return len(fake.policyDeleteArgsForCall)
+}
+
+func (fake *FakeACL) PolicyDeleteArgsForCall(i int) (string, *api.WriteOptions) {
+	fake.policyDeleteMutex.RLock()
+	defer fake.policyDeleteMutex.RUnlock()
+	return fake.policyDeleteArgsForCall[i].policyID, fake.policyDeleteArgsForCall[i].q
+}
+
+func (fake *FakeACL) PolicyDeleteReturns(result1 *api.WriteMeta, result2 error) {
+	fake.PolicyDeleteStub = nil
+	fake.policyDeleteReturns = struct {
+		result1 *api.WriteMeta
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeACL) PolicyList(q *api.QueryOptions) ([]*api.ACLPolicyListEntry, *api.QueryMeta, error) {
+	fake.policyListMutex.Lock()
+	fake.policyListArgsForCall = append(fake.policyListArgsForCall, struct {
+		q *api.QueryOptions
+	}{q})
+	fake.policyListMutex.Unlock()
+	if fake.PolicyListStub != nil {
+		return fake.PolicyListStub(q)
+	} else {
+		return fake.policyListReturns.result1, fake.policyListReturns.result2, fake.policyListReturns.result3
+	}
+}
+
+func (fake *FakeACL) PolicyListCallCount() int {
+	fake.policyListMutex.RLock()
+	defer fake.policyListMutex.RUnlock()
+	return len(fake.policyListArgsForCall)
+}
+
+func (fake *FakeACL) PolicyListArgsForCall(i int) *api.QueryOptions {
+	fake.policyListMutex.RLock()
+	defer fake.policyListMutex.RUnlock()
+	return fake.policyListArgsForCall[i].q
+}
+
+func (fake *FakeACL) PolicyListReturns(result1 []*api.ACLPolicyListEntry, result2 *api.QueryMeta, result3 error) {
+	fake.PolicyListStub = nil
+	fake.policyListReturns = struct {
+		result1 []*api.ACLPolicyListEntry
+		result2 *api.QueryMeta
+		result3 error
+	}{result1, result2, result3}
+}
+
+func (fake *FakeACL) RoleCreate(role *api.ACLRole, q *api.WriteOptions) (*api.ACLRole, *api.WriteMeta, error) {
+	fake.roleCreateMutex.Lock()
+	fake.roleCreateArgsForCall = append(fake.roleCreateArgsForCall, struct {
+		role *api.ACLRole
+		q    *api.WriteOptions
+	}{role, q})
+	fake.roleCreateMutex.Unlock()
+	if fake.RoleCreateStub != nil {
+		return fake.RoleCreateStub(role, q)
+	} else {
+		return fake.roleCreateReturns.result1, fake.roleCreateReturns.result2, fake.roleCreateReturns.result3
+	}
+}
+
+func (fake *FakeACL) RoleCreateCallCount() int {
+	fake.roleCreateMutex.RLock()
+	defer fake.roleCreateMutex.RUnlock()
+	return len(fake.roleCreateArgsForCall)
+}
+
+func (fake *FakeACL) RoleCreateArgsForCall(i int) (*api.ACLRole, *api.WriteOptions) {
+	fake.roleCreateMutex.RLock()
+	defer fake.roleCreateMutex.RUnlock()
+	return fake.roleCreateArgsForCall[i].role, fake.roleCreateArgsForCall[i].q
+}
+
+func (fake *FakeACL) RoleCreateReturns(result1 *api.ACLRole, result2 *api.WriteMeta, result3 error) {
+	fake.RoleCreateStub = nil
+	fake.roleCreateReturns = struct {
+		result1 *api.ACLRole
+		result2 *api.WriteMeta
+		result3 error
+	}{result1, result2, result3}
+}
+
+func (fake *FakeACL) RoleRead(roleID string, q *api.QueryOptions) (*api.ACLRole, *api.QueryMeta, error) {
+	fake.roleReadMutex.Lock()
+	fake.roleReadArgsForCall = append(fake.roleReadArgsForCall, struct {
+		roleID string
+		q      *api.QueryOptions
+	}{roleID, q})
+	fake.roleReadMutex.Unlock()
+	if fake.RoleReadStub != nil {
+		return fake.RoleReadStub(roleID, q)
+	} else {
+		return fake.roleReadReturns.result1, fake.roleReadReturns.result2, fake.roleReadReturns.result3
+	}
+}
+
+func (fake *FakeACL) RoleReadCallCount() int {
+	fake.roleReadMutex.RLock()
+	defer fake.roleReadMutex.RUnlock()
+	return len(fake.roleReadArgsForCall)
+}
+
+func (fake *FakeACL) RoleReadArgsForCall(i int) (string, *api.QueryOptions) {
+	fake.roleReadMutex.RLock()
+	defer fake.roleReadMutex.RUnlock()
+	return fake.roleReadArgsForCall[i].roleID, fake.roleReadArgsForCall[i].q
+}
+
+func (fake *FakeACL) RoleReadReturns(result1 *api.ACLRole, result2 *api.QueryMeta, result3 error) {
+	fake.RoleReadStub = nil
+	fake.roleReadReturns = struct {
+		result1 *api.ACLRole
+		result2 *api.QueryMeta
+		result3 error
+	}{result1, result2, result3}
+}
+
+func (fake *FakeACL) RoleReadByName(roleName string, q *api.QueryOptions) (*api.ACLRole, *api.QueryMeta, error) {
+	fake.roleReadByNameMutex.Lock()
+	fake.roleReadByNameArgsForCall = append(fake.roleReadByNameArgsForCall, struct {
+		roleName string
+		q        *api.QueryOptions
+	}{roleName, q})
+	fake.roleReadByNameMutex.Unlock()
+	if fake.RoleReadByNameStub != nil {
+		return fake.RoleReadByNameStub(roleName, q)
+	} else {
+		return fake.roleReadByNameReturns.result1, fake.roleReadByNameReturns.result2, fake.roleReadByNameReturns.result3
+	}
+}
+
+func (fake *FakeACL) RoleReadByNameCallCount() int {
+	fake.roleReadByNameMutex.RLock()
+	defer fake.roleReadByNameMutex.RUnlock()
+	return len(fake.roleReadByNameArgsForCall)
+}
+
+func (fake *FakeACL) RoleReadByNameArgsForCall(i int) (string, *api.QueryOptions) {
+	fake.roleReadByNameMutex.RLock()
+	defer fake.roleReadByNameMutex.RUnlock()
+	return fake.roleReadByNameArgsForCall[i].roleName, fake.roleReadByNameArgsForCall[i].q
+}
+
+func (fake *FakeACL) RoleReadByNameReturns(result1 *api.ACLRole, result2 *api.QueryMeta, result3 error) {
+	fake.RoleReadByNameStub = nil
+	fake.roleReadByNameReturns = struct {
+		result1 *api.ACLRole
+		result2 *api.QueryMeta
+		result3 error
+	}{result1, result2, result3}
+}
+
+func (fake *FakeACL) RoleUpdate(role *api.ACLRole, q *api.WriteOptions) (*api.ACLRole, *api.WriteMeta, error) {
+	fake.roleUpdateMutex.Lock()
+	fake.roleUpdateArgsForCall = append(fake.roleUpdateArgsForCall, struct {
+		role *api.ACLRole
+		q    *api.WriteOptions
+	}{role, q})
+	fake.roleUpdateMutex.Unlock()
+	if fake.RoleUpdateStub != nil {
+		return fake.RoleUpdateStub(role, q)
+	} else {
+		return fake.roleUpdateReturns.result1, fake.roleUpdateReturns.result2, fake.roleUpdateReturns.result3
+	}
+}
+
+func (fake *FakeACL) RoleUpdateCallCount() int {
+	fake.roleUpdateMutex.RLock()
+	defer fake.roleUpdateMutex.RUnlock()
+	return len(fake.roleUpdateArgsForCall)
+}
+
+func (fake *FakeACL) RoleUpdateArgsForCall(i int) (*api.ACLRole, *api.WriteOptions) {
+	fake.roleUpdateMutex.RLock()
+	defer fake.roleUpdateMutex.RUnlock()
+	return fake.roleUpdateArgsForCall[i].role, fake.roleUpdateArgsForCall[i].q
+}
+
+func (fake *FakeACL) RoleUpdateReturns(result1 *api.ACLRole, result2 *api.WriteMeta, result3 error) {
+	fake.RoleUpdateStub = nil
+	fake.roleUpdateReturns = struct {
+		result1 *api.ACLRole
+		result2 *api.WriteMeta
+		result3 error
+	}{result1, result2, result3}
+}
+
+func (fake *FakeACL) RoleDelete(roleID string, q *api.WriteOptions) (*api.WriteMeta, error) {
+	fake.roleDeleteMutex.Lock()
+	fake.roleDeleteArgsForCall = append(fake.roleDeleteArgsForCall, struct {
+		roleID string
+		q      *api.WriteOptions
+	}{roleID, q})
+	fake.roleDeleteMutex.Unlock()
+	if fake.RoleDeleteStub != nil {
+		return fake.RoleDeleteStub(roleID, q)
+	} else {
+		return fake.roleDeleteReturns.result1, fake.roleDeleteReturns.result2
+	}
+}
+
+func (fake *FakeACL) RoleDeleteCallCount() int {
+	fake.roleDeleteMutex.RLock()
+	defer fake.roleDeleteMutex.RUnlock()
+	return len(fake.roleDeleteArgsForCall)
+}
+
+func (fake *FakeACL) RoleDeleteArgsForCall(i int) (string, *api.WriteOptions) {
+	fake.roleDeleteMutex.RLock()
+	defer fake.roleDeleteMutex.RUnlock()
+	return fake.roleDeleteArgsForCall[i].roleID, fake.roleDeleteArgsForCall[i].q
+}
+
+func (fake *FakeACL) RoleDeleteReturns(result1 *api.WriteMeta, result2 error) {
+	fake.RoleDeleteStub = nil
+	fake.roleDeleteReturns = struct {
+		result1 *api.WriteMeta
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeACL) RoleList(q *api.QueryOptions) ([]*api.ACLRole, *api.QueryMeta, error) {
+	fake.roleListMutex.Lock()
+	fake.roleListArgsForCall = append(fake.roleListArgsForCall, struct {
+		q *api.QueryOptions
+	}{q})
+	fake.roleListMutex.Unlock()
+	if fake.RoleListStub != nil {
+		return fake.RoleListStub(q)
+	} else {
+		return fake.roleListReturns.result1, fake.roleListReturns.result2, fake.roleListReturns.result3
+	}
+}
+
+func (fake *FakeACL) RoleListCallCount() int {
+	fake.roleListMutex.RLock()
+	defer fake.roleListMutex.RUnlock()
+	return len(fake.roleListArgsForCall)
+}
+
+func (fake *FakeACL) RoleListArgsForCall(i int) *api.QueryOptions {
+	fake.roleListMutex.RLock()
+	defer fake.roleListMutex.RUnlock()
+	return fake.roleListArgsForCall[i].q
+}
+
+func (fake *FakeACL) RoleListReturns(result1 []*api.ACLRole, result2 *api.QueryMeta, result3 error) {
+	fake.RoleListStub = nil
+	fake.roleListReturns = struct {
+		result1 []*api.ACLRole
+		result2 *api.QueryMeta
+		result3 error
+	}{result1, result2, result3}
+}
+
+func (fake *FakeACL) TokenCreate(token *api.ACLToken, q *api.WriteOptions) (*api.ACLToken, *api.WriteMeta, error) {
+	fake.tokenCreateMutex.Lock()
+	fake.tokenCreateArgsForCall = append(fake.tokenCreateArgsForCall, struct {
+		token *api.ACLToken
+		q     *api.WriteOptions
+	}{token, q})
+	fake.tokenCreateMutex.Unlock()
+	if fake.TokenCreateStub != nil {
+		return fake.TokenCreateStub(token, q)
+	} else {
+		return fake.tokenCreateReturns.result1, fake.tokenCreateReturns.result2, fake.tokenCreateReturns.result3
+	}
+}
+
+func (fake *FakeACL) TokenCreateCallCount() int {
+	fake.tokenCreateMutex.RLock()
+	defer fake.tokenCreateMutex.RUnlock()
+	return len(fake.tokenCreateArgsForCall)
+}
+
+func (fake *FakeACL) TokenCreateArgsForCall(i int) (*api.ACLToken, *api.WriteOptions) {
+	fake.tokenCreateMutex.RLock()
+	defer fake.tokenCreateMutex.RUnlock()
+	return fake.tokenCreateArgsForCall[i].token, fake.tokenCreateArgsForCall[i].q
+}
+
+func (fake *FakeACL) TokenCreateReturns(result1 *api.ACLToken, result2 *api.WriteMeta, result3 error) {
+	fake.TokenCreateStub = nil
+	fake.tokenCreateReturns = struct {
+		result1 *api.ACLToken
+		result2 *api.WriteMeta
+		result3 error
+	}{result1, result2, result3}
+}
+
+func (fake *FakeACL) TokenRead(accessorID string, q *api.QueryOptions) (*api.ACLToken, *api.QueryMeta, error) {
+	fake.tokenReadMutex.Lock()
+	fake.tokenReadArgsForCall = append(fake.tokenReadArgsForCall, struct {
+		accessorID string
+		q          *api.QueryOptions
+	}{accessorID, q})
+	fake.tokenReadMutex.Unlock()
+	if fake.TokenReadStub != nil {
+		return fake.TokenReadStub(accessorID, q)
+	} else {
+		return fake.tokenReadReturns.result1, fake.tokenReadReturns.result2, fake.tokenReadReturns.result3
+	}
+}
+
+func (fake *FakeACL) TokenReadCallCount() int {
+	fake.tokenReadMutex.RLock()
+	defer fake.tokenReadMutex.RUnlock()
+	return len(fake.tokenReadArgsForCall)
+}
+
+func (fake *FakeACL) TokenReadArgsForCall(i int) (string, *api.QueryOptions) {
+	fake.tokenReadMutex.RLock()
+	defer fake.tokenReadMutex.RUnlock()
+	return fake.tokenReadArgsForCall[i].accessorID, fake.tokenReadArgsForCall[i].q
+}
+
+func (fake *FakeACL) TokenReadReturns(result1 *api.ACLToken, result2 *api.QueryMeta, result3 error) {
+	fake.TokenReadStub = nil
+	fake.tokenReadReturns = struct {
+		result1 *api.ACLToken
+		result2 *api.QueryMeta
+		result3 error
+	}{result1, result2, result3}
+}
+
+func (fake *FakeACL) TokenReadSelf(q *api.QueryOptions) (*api.ACLToken, *api.QueryMeta, error) {
+	fake.tokenReadSelfMutex.Lock()
+	fake.tokenReadSelfArgsForCall = append(fake.tokenReadSelfArgsForCall, struct {
+		q *api.QueryOptions
+	}{q})
+	fake.tokenReadSelfMutex.Unlock()
+	if fake.TokenReadSelfStub != nil {
+		return fake.TokenReadSelfStub(q)
+	} else {
+		return fake.tokenReadSelfReturns.result1, fake.tokenReadSelfReturns.result2, fake.tokenReadSelfReturns.result3
+	}
+}
+
+func (fake *FakeACL) TokenReadSelfCallCount() int {
+	fake.tokenReadSelfMutex.RLock()
+	defer fake.tokenReadSelfMutex.RUnlock()
+	return len(fake.tokenReadSelfArgsForCall)
+}
+
+func (fake *FakeACL) TokenReadSelfArgsForCall(i int) *api.QueryOptions {
+	fake.tokenReadSelfMutex.RLock()
+	defer fake.tokenReadSelfMutex.RUnlock()
+	return fake.tokenReadSelfArgsForCall[i].q
+}
+
+func (fake *FakeACL) TokenReadSelfReturns(result1 *api.ACLToken, result2 *api.QueryMeta, result3 error) {
+	fake.TokenReadSelfStub = nil
+	fake.tokenReadSelfReturns = struct {
+		result1 *api.ACLToken
+		result2 *api.QueryMeta
+		result3 error
+	}{result1, result2, result3}
+}
+
+func (fake *FakeACL) TokenUpdate(token *api.ACLToken, q *api.WriteOptions) (*api.ACLToken, *api.WriteMeta, error) {
+	fake.tokenUpdateMutex.Lock()
+	fake.tokenUpdateArgsForCall = append(fake.tokenUpdateArgsForCall, struct {
+		token *api.ACLToken
+		q     *api.WriteOptions
+	}{token, q})
+	fake.tokenUpdateMutex.Unlock()
+	if fake.TokenUpdateStub != nil {
+		return fake.TokenUpdateStub(token, q)
+	} else {
+		return fake.tokenUpdateReturns.result1, fake.tokenUpdateReturns.result2, fake.tokenUpdateReturns.result3
+	}
+}
+
+func (fake *FakeACL) TokenUpdateCallCount() int {
+	fake.tokenUpdateMutex.RLock()
+	defer fake.tokenUpdateMutex.RUnlock()
+	return len(fake.tokenUpdateArgsForCall)
+}
+
+func (fake *FakeACL) TokenUpdateArgsForCall(i int) (*api.ACLToken, *api.WriteOptions) {
+	fake.tokenUpdateMutex.RLock()
+	defer fake.tokenUpdateMutex.RUnlock()
+	return fake.tokenUpdateArgsForCall[i].token, fake.tokenUpdateArgsForCall[i].q
+}
+
+func (fake *FakeACL) TokenUpdateReturns(result1 *api.ACLToken, result2 *api.WriteMeta, result3 error) {
+	fake.TokenUpdateStub = nil
+	fake.tokenUpdateReturns = struct {
+		result1 *api.ACLToken
+		result2 *api.WriteMeta
+		result3 error
+	}{result1, result2, result3}
+}
+
+func (fake *FakeACL) TokenDelete(accessorID string, q *api.WriteOptions) (*api.WriteMeta, error) {
+	fake.tokenDeleteMutex.Lock()
+	fake.tokenDeleteArgsForCall = append(fake.tokenDeleteArgsForCall, struct {
+		accessorID string
+		q          *api.WriteOptions
+	}{accessorID, q})
+	fake.tokenDeleteMutex.Unlock()
+	if fake.TokenDeleteStub != nil {
+		return fake.TokenDeleteStub(accessorID, q)
+	} else {
+		return fake.tokenDeleteReturns.result1, fake.tokenDeleteReturns.result2
+	}
+}
+
+func (fake *FakeACL) TokenDeleteCallCount() int {
+	fake.tokenDeleteMutex.RLock()
+	defer fake.tokenDeleteMutex.RUnlock()
+	return len(fake.tokenDeleteArgsForCall)
+}
+
+func (fake *FakeACL) TokenDeleteArgsForCall(i int) (string, *api.WriteOptions) {
+	fake.tokenDeleteMutex.RLock()
+	defer fake.tokenDeleteMutex.RUnlock()
+	return fake.tokenDeleteArgsForCall[i].accessorID, fake.tokenDeleteArgsForCall[i].q
+}
+
+func (fake *FakeACL) TokenDeleteReturns(result1 *api.WriteMeta, result2 error) {
+	fake.TokenDeleteStub = nil
+	fake.tokenDeleteReturns = struct {
+		result1 *api.WriteMeta
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeACL) TokenList(q *api.QueryOptions) ([]*api.ACLTokenListEntry, *api.QueryMeta, error) {
+	fake.tokenListMutex.Lock()
+	fake.tokenListArgsForCall = append(fake.tokenListArgsForCall, struct {
+		q *api.QueryOptions
+	}{q})
+	fake.tokenListMutex.Unlock()
+	if fake.TokenListStub != nil {
+		return fake.TokenListStub(q)
+	} else {
+		return fake.tokenListReturns.result1, fake.tokenListReturns.result2, fake.tokenListReturns.result3
+	}
+}
+
+func (fake *FakeACL) TokenListCallCount() int {
+	fake.tokenListMutex.RLock()
+	defer fake.tokenListMutex.RUnlock()
+	return len(fake.tokenListArgsForCall)
+}
+
+func (fake *FakeACL) TokenListArgsForCall(i int) *api.QueryOptions {
+	fake.tokenListMutex.RLock()
+	defer fake.tokenListMutex.RUnlock()
+	return fake.tokenListArgsForCall[i].q
+}
+
+func (fake *FakeACL) TokenListReturns(result1 []*api.ACLTokenListEntry, result2 *api.QueryMeta, result3 error) {
+	fake.TokenListStub = nil
+	fake.tokenListReturns = struct {
+		result1 []*api.ACLTokenListEntry
+		result2 *api.QueryMeta
+		result3 error
+	}{result1, result2, result3}
+}
+
+var _ consuladapter.ACL = new(FakeACL)
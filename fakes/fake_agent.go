@@ -81,6 +81,61 @@ type FakeAgent struct {
 	checkDeregisterReturns struct {
 		result1 error
 	}
+	MetricsStub        func() (*api.MetricsInfo, error)
+	metricsMutex       sync.RWMutex
+	metricsArgsForCall []struct{}
+	metricsReturns     struct {
+		result1 *api.MetricsInfo
+		result2 error
+	}
+	MembersStub        func(wan bool) ([]*api.AgentMember, error)
+	membersMutex       sync.RWMutex
+	membersArgsForCall []struct {
+		wan bool
+	}
+	membersReturns struct {
+		result1 []*api.AgentMember
+		result2 error
+	}
+	MembersOptsStub        func(opts api.MembersOpts) ([]*api.AgentMember, error)
+	membersOptsMutex       sync.RWMutex
+	membersOptsArgsForCall []struct {
+		opts api.MembersOpts
+	}
+	membersOptsReturns struct {
+		result1 []*api.AgentMember
+		result2 error
+	}
+	EnableServiceMaintenanceStub        func(serviceID, reason string) error
+	enableServiceMaintenanceMutex       sync.RWMutex
+	enableServiceMaintenanceArgsForCall []struct {
+		serviceID string
+		reason    string
+	}
+	enableServiceMaintenanceReturns struct {
+		result1 error
+	}
+	DisableServiceMaintenanceStub        func(serviceID string) error
+	disableServiceMaintenanceMutex       sync.RWMutex
+	disableServiceMaintenanceArgsForCall []struct {
+		serviceID string
+	}
+	disableServiceMaintenanceReturns struct {
+		result1 error
+	}
+	SelfStub        func() (map[string]map[string]interface{}, error)
+	selfMutex       sync.RWMutex
+	selfArgsForCall []struct{}
+	selfReturns     struct {
+		result1 map[string]map[string]interface{}
+		result2 error
+	}
+	ReloadStub        func() error
+	reloadMutex       sync.RWMutex
+	reloadArgsForCall []struct{}
+	reloadReturns     struct {
+		result1 error
+	}
 }
 
 func (fake *FakeAgent) Checks() (map[string]*api.AgentCheck, error) {
@@ -353,4 +408,209 @@ func (fake *FakeAgent) CheckDeregisterReturns(result1 error) {
 	}{result1}
 }
 
+func (fake *FakeAgent) Metrics() (*api.MetricsInfo, error) {
+	fake.metricsMutex.Lock()
+	fake.metricsArgsForCall = append(fake.metricsArgsForCall, struct{}{})
+	fake.metricsMutex.Unlock()
+	if fake.MetricsStub != nil {
+		return fake.MetricsStub()
+	} else {
+		return fake.metricsReturns.result1, fake.metricsReturns.result2
+	}
+}
+
+func (fake *FakeAgent) MetricsCallCount() int {
+	fake.metricsMutex.RLock()
+	defer fake.metricsMutex.RUnlock()
+	return len(fake.metricsArgsForCall)
+}
+
+func (fake *FakeAgent) MetricsReturns(result1 *api.MetricsInfo, result2 error) {
+	fake.MetricsStub = nil
+	fake.metricsReturns = struct {
+		result1 *api.MetricsInfo
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeAgent) Members(wan bool) ([]*api.AgentMember, error) {
+	fake.membersMutex.Lock()
+	fake.membersArgsForCall = append(fake.membersArgsForCall, struct {
+		wan bool
+	}{wan})
+	fake.membersMutex.Unlock()
+	if fake.MembersStub != nil {
+		return fake.MembersStub(wan)
+	} else {
+		return fake.membersReturns.result1, fake.membersReturns.result2
+	}
+}
+
+func (fake *FakeAgent) MembersCallCount() int {
+	fake.membersMutex.RLock()
+	defer fake.membersMutex.RUnlock()
+	return len(fake.membersArgsForCall)
+}
+
+func (fake *FakeAgent) MembersArgsForCall(i int) bool {
+	fake.membersMutex.RLock()
+	defer fake.membersMutex.RUnlock()
+	return fake.membersArgsForCall[i].wan
+}
+
+func (fake *FakeAgent) MembersReturns(result1 []*api.AgentMember, result2 error) {
+	fake.MembersStub = nil
+	fake.membersReturns = struct {
+		result1 []*api.AgentMember
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeAgent) MembersOpts(opts api.MembersOpts) ([]*api.AgentMember, error) {
+	fake.membersOptsMutex.Lock()
+	fake.membersOptsArgsForCall = append(fake.membersOptsArgsForCall, struct {
+		opts api.MembersOpts
+	}{opts})
+	fake.membersOptsMutex.Unlock()
+	if fake.MembersOptsStub != nil {
+		return fake.MembersOptsStub(opts)
+	} else {
+		return fake.membersOptsReturns.result1, fake.membersOptsReturns.result2
+	}
+}
+
+func (fake *FakeAgent) MembersOptsCallCount() int {
+	fake.membersOptsMutex.RLock()
+	defer fake.membersOptsMutex.RUnlock()
+	return len(fake.membersOptsArgsForCall)
+}
+
+func (fake *FakeAgent) MembersOptsArgsForCall(i int) api.MembersOpts {
+	fake.membersOptsMutex.RLock()
+	defer fake.membersOptsMutex.RUnlock()
+	return fake.membersOptsArgsForCall[i].opts
+}
+
+func (fake *FakeAgent) MembersOptsReturns(result1 []*api.AgentMember, result2 error) {
+	fake.MembersOptsStub = nil
+	fake.membersOptsReturns = struct {
+		result1 []*api.AgentMember
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeAgent) EnableServiceMaintenance(serviceID string, reason string) error {
+	fake.enableServiceMaintenanceMutex.Lock()
+	fake.enableServiceMaintenanceArgsForCall = append(fake.enableServiceMaintenanceArgsForCall, struct {
+		serviceID string
+		reason    string
+	}{serviceID, reason})
+	fake.enableServiceMaintenanceMutex.Unlock()
+	if fake.EnableServiceMaintenanceStub != nil {
+		return fake.EnableServiceMaintenanceStub(serviceID, reason)
+	} else {
+		return fake.enableServiceMaintenanceReturns.result1
+	}
+}
+
+func (fake *FakeAgent) EnableServiceMaintenanceCallCount() int {
+	fake.enableServiceMaintenanceMutex.RLock()
+	defer fake.enableServiceMaintenanceMutex.RUnlock()
+	return len(fake.enableServiceMaintenanceArgsForCall)
+}
+
+func (fake *FakeAgent) EnableServiceMaintenanceArgsForCall(i int) (string, string) {
+	fake.enableServiceMaintenanceMutex.RLock()
+	defer fake.enableServiceMaintenanceMutex.RUnlock()
+	return fake.enableServiceMaintenanceArgsForCall[i].serviceID, fake.enableServiceMaintenanceArgsForCall[i].reason
+}
+
+func (fake *FakeAgent) EnableServiceMaintenanceReturns(result1 error) {
+	fake.EnableServiceMaintenanceStub = nil
+	fake.enableServiceMaintenanceReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeAgent) DisableServiceMaintenance(serviceID string) error {
+	fake.disableServiceMaintenanceMutex.Lock()
+	fake.disableServiceMaintenanceArgsForCall = append(fake.disableServiceMaintenanceArgsForCall, struct {
+		serviceID string
+	}{serviceID})
+	fake.disableServiceMaintenanceMutex.Unlock()
+	if fake.DisableServiceMaintenanceStub != nil {
+		return fake.DisableServiceMaintenanceStub(serviceID)
+	} else {
+		return fake.disableServiceMaintenanceReturns.result1
+	}
+}
+
+func (fake *FakeAgent) DisableServiceMaintenanceCallCount() int {
+	fake.disableServiceMaintenanceMutex.RLock()
+	defer fake.disableServiceMaintenanceMutex.RUnlock()
+	return len(fake.disableServiceMaintenanceArgsForCall)
+}
+
+func (fake *FakeAgent) DisableServiceMaintenanceArgsForCall(i int) string {
+	fake.disableServiceMaintenanceMutex.RLock()
+	defer fake.disableServiceMaintenanceMutex.RUnlock()
+	return fake.disableServiceMaintenanceArgsForCall[i].serviceID
+}
+
+func (fake *FakeAgent) DisableServiceMaintenanceReturns(result1 error) {
+	fake.DisableServiceMaintenanceStub = nil
+	fake.disableServiceMaintenanceReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeAgent) Self() (map[string]map[string]interface{}, error) {
+	fake.selfMutex.Lock()
+	fake.selfArgsForCall = append(fake.selfArgsForCall, struct{}{})
+	fake.selfMutex.Unlock()
+	if fake.SelfStub != nil {
+		return fake.SelfStub()
+	} else {
+		return fake.selfReturns.result1, fake.selfReturns.result2
+	}
+}
+
+func (fake *FakeAgent) SelfCallCount() int {
+	fake.selfMutex.RLock()
+	defer fake.selfMutex.RUnlock()
+	return len(fake.selfArgsForCall)
+}
+
+func (fake *FakeAgent) SelfReturns(result1 map[string]map[string]interface{}, result2 error) {
+	fake.SelfStub = nil
+	fake.selfReturns = struct {
+		result1 map[string]map[string]interface{}
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeAgent) Reload() error {
+	fake.reloadMutex.Lock()
+	fake.reloadArgsForCall = append(fake.reloadArgsForCall, struct{}{})
+	fake.reloadMutex.Unlock()
+	if fake.ReloadStub != nil {
+		return fake.ReloadStub()
+	} else {
+		return fake.reloadReturns.result1
+	}
+}
+
+func (fake *FakeAgent) ReloadCallCount() int {
+	fake.reloadMutex.RLock()
+	defer fake.reloadMutex.RUnlock()
+	return len(fake.reloadArgsForCall)
+}
+
+func (fake *FakeAgent) ReloadReturns(result1 error) {
+	fake.ReloadStub = nil
+	fake.reloadReturns = struct {
+		result1 error
+	}{result1}
+}
+
 var _ consuladapter.Agent = new(FakeAgent)
@@ -19,6 +19,28 @@ type FakeCatalog struct {
 		result2 *api.QueryMeta
 		result3 error
 	}
+	ServicesStub        func(q *api.QueryOptions) (map[string][]string, *api.QueryMeta, error)
+	servicesMutex       sync.RWMutex
+	servicesArgsForCall []struct {
+		q *api.QueryOptions
+	}
+	servicesReturns struct {
+		result1 map[string][]string
+		result2 *api.QueryMeta
+		result3 error
+	}
+	ServiceStub        func(service, tag string, q *api.QueryOptions) ([]*api.CatalogService, *api.QueryMeta, error)
+	serviceMutex       sync.RWMutex
+	serviceArgsForCall []struct {
+		service string
+		tag     string
+		q       *api.QueryOptions
+	}
+	serviceReturns struct {
+		result1 []*api.CatalogService
+		result2 *api.QueryMeta
+		result3 error
+	}
 }
 
 func (fake *FakeCatalog) Nodes(q *api.QueryOptions) ([]*api.Node, *api.QueryMeta, error) {
@@ -55,4 +77,74 @@ func (fake *FakeCatalog) NodesReturns(result1 []*api.Node, result2 *api.QueryMet
 	}{result1, result2, result3}
 }
 
+func (fake *FakeCatalog) Services(q *api.QueryOptions) (map[string][]string, *api.QueryMeta, error) {
+	fake.servicesMutex.Lock()
+	fake.servicesArgsForCall = append(fake.servicesArgsForCall, struct {
+		q *api.QueryOptions
+	}{q})
+	fake.servicesMutex.Unlock()
+	if fake.ServicesStub != nil {
+		return fake.ServicesStub(q)
+	} else {
+		return fake.servicesReturns.result1, fake.servicesReturns.result2, fake.servicesReturns.result3
+	}
+}
+
+func (fake *FakeCatalog) ServicesCallCount() int {
+	fake.servicesMutex.RLock()
+	defer fake.servicesMutex.RUnlock()
+	return len(fake.servicesArgsForCall)
+}
+
+func (fake *FakeCatalog) ServicesArgsForCall(i int) *api.QueryOptions {
+	fake.servicesMutex.RLock()
+	defer fake.servicesMutex.RUnlock()
+	return fake.servicesArgsForCall[i].q
+}
+
+func (fake *FakeCatalog) ServicesReturns(result1 map[string][]string, result2 *api.QueryMeta, result3 error) {
+	fake.ServicesStub = nil
+	fake.servicesReturns = struct {
+		result1 map[string][]string
+		result2 *api.QueryMeta
+		result3 error
+	}{result1, result2, result3}
+}
+
+func (fake *FakeCatalog) Service(service string, tag string, q *api.QueryOptions) ([]*api.CatalogService, *api.QueryMeta, error) {
+	fake.serviceMutex.Lock()
+	fake.serviceArgsForCall = append(fake.serviceArgsForCall, struct {
+		service string
+		tag     string
+		q       *api.QueryOptions
+	}{service, tag, q})
+	fake.serviceMutex.Unlock()
+	if fake.ServiceStub != nil {
+		return fake.ServiceStub(service, tag, q)
+	} else {
+		return fake.serviceReturns.result1, fake.serviceReturns.result2, fake.serviceReturns.result3
+	}
+}
+
+func (fake *FakeCatalog) ServiceCallCount() int {
+	fake.serviceMutex.RLock()
+	defer fake.serviceMutex.RUnlock()
+	return len(fake.serviceArgsForCall)
+}
+
+func (fake *FakeCatalog) ServiceArgsForCall(i int) (string, string, *api.QueryOptions) {
+	fake.serviceMutex.RLock()
+	defer fake.serviceMutex.RUnlock()
+	return fake.serviceArgsForCall[i].service, fake.serviceArgsForCall[i].tag, fake.serviceArgsForCall[i].q
+}
+
+func (fake *FakeCatalog) ServiceReturns(result1 []*api.CatalogService, result2 *api.QueryMeta, result3 error) {
+	fake.ServiceStub = nil
+	fake.serviceReturns = struct {
+		result1 []*api.CatalogService
+		result2 *api.QueryMeta
+		result3 error
+	}{result1, result2, result3}
+}
+
 var _ consuladapter.Catalog = new(FakeCatalog)
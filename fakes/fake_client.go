@@ -2,6 +2,7 @@
 package fakes
 
 import (
+	"context"
 	"sync"
 
 	"code.cloudfoundry.org/consuladapter"
@@ -27,6 +28,12 @@ type FakeClient struct {
 	catalogReturns     struct {
 		result1 consuladapter.Catalog
 	}
+	HealthStub        func() consuladapter.Health
+	healthMutex       sync.RWMutex
+	healthArgsForCall []struct{}
+	healthReturns     struct {
+		result1 consuladapter.Health
+	}
 	KVStub        func() consuladapter.KV
 	kVMutex       sync.RWMutex
 	kVArgsForCall []struct{}
@@ -39,6 +46,24 @@ type FakeClient struct {
 	statusReturns     struct {
 		result1 consuladapter.Status
 	}
+	OperatorStub        func() consuladapter.Operator
+	operatorMutex       sync.RWMutex
+	operatorArgsForCall []struct{}
+	operatorReturns     struct {
+		result1 consuladapter.Operator
+	}
+	ConfigEntriesStub        func() consuladapter.ConfigEntries
+	configEntriesMutex       sync.RWMutex
+	configEntriesArgsForCall []struct{}
+	configEntriesReturns     struct {
+		result1 consuladapter.ConfigEntries
+	}
+	ACLStub        func() consuladapter.ACL
+	aCLMutex       sync.RWMutex
+	aCLArgsForCall []struct{}
+	aCLReturns     struct {
+		result1 consuladapter.ACL
+	}
 	LockOptsStub        func(opts *api.LockOptions) (consuladapter.Lock, error)
 	lockOptsMutex       sync.RWMutex
 	lockOptsArgsForCall []struct {
@@ -48,6 +73,42 @@ type FakeClient struct {
 		result1 consuladapter.Lock
 		result2 error
 	}
+	SemaphoreOptsStub        func(opts *api.SemaphoreOptions) (consuladapter.Semaphore, error)
+	semaphoreOptsMutex       sync.RWMutex
+	semaphoreOptsArgsForCall []struct {
+		opts *api.SemaphoreOptions
+	}
+	semaphoreOptsReturns struct {
+		result1 consuladapter.Semaphore
+		result2 error
+	}
+	CapabilitiesStub func() (consuladapter.Capabilities,
+
+		error)
+	capabilitiesMutex       sync.RWMutex
+	capabilitiesArgsForCall []struct{}
+	capabilitiesReturns     struct {
+		result1 consuladapter.Capabilities
+		result2 error
+	}
+	SelfTestStub        func(ctx context.Context) consuladapter.SelfTestReport
+	selfTestMutex       sync.RWMutex
+	selfTestArgsForCall []struct {
+		ctx context.Context
+	}
+	selfTestReturns struct {
+		result1 consuladapter.SelfTestReport
+	}
+	WaitForServiceStub        func(ctx context.Context, name string, minHealthyInstances int) error
+	waitForServiceMutex       sync.RWMutex
+	waitForServiceArgsForCall []struct {
+		ctx                 context.Context
+		name                string
+		minHealthyInstances int
+	}
+	waitForServiceReturns struct {
+		result1 error
+	}
 }
 
 func (fake *FakeClient) Agent() consuladapter.Agent {
@@ -122,6 +183,30 @@ func (fake *FakeClient) CatalogReturns(result1 consuladapter.Catalog) {
 	}{result1}
 }
 
+func (fake *FakeClient) Health() consuladapter.Health {
+	fake.healthMutex.Lock()
+	fake.healthArgsForCall = append(fake.healthArgsForCall, struct{}{})
+	fake.healthMutex.Unlock()
+	if fake.HealthStub != nil {
+		return fake.HealthStub()
+	} else {
+		return fake.healthReturns.result1
+	}
+}
+
+func (fake *FakeClient) HealthCallCount() int {
+	fake.healthMutex.RLock()
+	defer fake.healthMutex.RUnlock()
+	return len(fake.healthArgsForCall)
+}
+
+func (fake *FakeClient) HealthReturns(result1 consuladapter.Health) {
+	fake.HealthStub = nil
+	fake.healthReturns = struct {
+		result1 consuladapter.Health
+	}{result1}
+}
+
 func (fake *FakeClient) KV() consuladapter.KV {
 	fake.kVMutex.Lock()
 	fake.kVArgsForCall = append(fake.kVArgsForCall, struct{}{})
@@ -170,6 +255,78 @@ func (fake *FakeClient) StatusReturns(result1 consuladapter.Status) {
 	}{result1}
 }
 
+func (fake *FakeClient) Operator() consuladapter.Operator {
+	fake.operatorMutex.Lock()
+	fake.operatorArgsForCall = append(fake.operatorArgsForCall, struct{}{})
+	fake.operatorMutex.Unlock()
+	if fake.OperatorStub != nil {
+		return fake.OperatorStub()
+	} else {
+		return fake.operatorReturns.result1
+	}
+}
+
+func (fake *FakeClient) OperatorCallCount() int {
+	fake.operatorMutex.RLock()
+	defer fake.operatorMutex.RUnlock()
+	return len(fake.operatorArgsForCall)
+}
+
+func (fake *FakeClient) OperatorReturns(result1 consuladapter.Operator) {
+	fake.OperatorStub = nil
+	fake.operatorReturns = struct {
+		result1 consuladapter.Operator
+	}{result1}
+}
+
+func (fake *FakeClient) ConfigEntries() consuladapter.ConfigEntries {
+	fake.configEntriesMutex.Lock()
+	fake.configEntriesArgsForCall = append(fake.configEntriesArgsForCall, struct{}{})
+	fake.configEntriesMutex.Unlock()
+	if fake.ConfigEntriesStub != nil {
+		return fake.ConfigEntriesStub()
+	} else {
+		return fake.configEntriesReturns.result1
+	}
+}
+
+func (fake *FakeClient) ConfigEntriesCallCount() int {
+	fake.configEntriesMutex.RLock()
+	defer fake.configEntriesMutex.RUnlock()
+	return len(fake.configEntriesArgsForCall)
+}
+
+func (fake *FakeClient) ConfigEntriesReturns(result1 consuladapter.ConfigEntries) {
+	fake.ConfigEntriesStub = nil
+	fake.configEntriesReturns = struct {
+		result1 consuladapter.ConfigEntries
+	}{result1}
+}
+
+func (fake *FakeClient) ACL() consuladapter.ACL {
+	fake.aCLMutex.Lock()
+	fake.aCLArgsForCall = append(fake.aCLArgsForCall, struct{}{})
+	fake.aCLMutex.Unlock()
+	if fake.ACLStub != nil {
+		return fake.ACLStub()
+	} else {
+		return fake.aCLReturns.result1
+	}
+}
+
+func (fake *FakeClient) ACLCallCount() int {
+	fake.aCLMutex.RLock()
+	defer fake.aCLMutex.RUnlock()
+	return len(fake.aCLArgsForCall)
+}
+
+func (fake *FakeClient) ACLReturns(result1 consuladapter.ACL) {
+	fake.ACLStub = nil
+	fake.aCLReturns = struct {
+		result1 consuladapter.ACL
+	}{result1}
+}
+
 func (fake *FakeClient) LockOpts(opts *api.LockOptions) (consuladapter.Lock, error) {
 	fake.lockOptsMutex.Lock()
 	fake.lockOptsArgsForCall = append(fake.lockOptsArgsForCall, struct {
@@ -203,4 +360,128 @@ func (fake *FakeClient) LockOptsReturns(result1 consuladapter.Lock, result2 erro
 	}{result1, result2}
 }
 
+func (fake *FakeClient) SemaphoreOpts(opts *api.SemaphoreOptions) (consuladapter.Semaphore, error) {
+	fake.semaphoreOptsMutex.Lock()
+	fake.semaphoreOptsArgsForCall = append(fake.semaphoreOptsArgsForCall, struct {
+		opts *api.SemaphoreOptions
+	}{opts})
+	fake.semaphoreOptsMutex.Unlock()
+	if fake.SemaphoreOptsStub != nil {
+		return fake.SemaphoreOptsStub(opts)
+	} else {
+		return fake.semaphoreOptsReturns.result1, fake.semaphoreOptsReturns.result2
+	}
+}
+
+func (fake *FakeClient) SemaphoreOptsCallCount() int {
+	fake.semaphoreOptsMutex.RLock()
+	defer fake.semaphoreOptsMutex.RUnlock()
+	return len(fake.semaphoreOptsArgsForCall)
+}
+
+func (fake *FakeClient) SemaphoreOptsArgsForCall(i int) *api.SemaphoreOptions {
+	fake.semaphoreOptsMutex.RLock()
+	defer fake.semaphoreOptsMutex.RUnlock()
+	return fake.semaphoreOptsArgsForCall[i].opts
+}
+
+func (fake *FakeClient) SemaphoreOptsReturns(result1 consuladapter.Semaphore, result2 error) {
+	fake.SemaphoreOptsStub = nil
+	fake.semaphoreOptsReturns = struct {
+		result1 consuladapter.Semaphore
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeClient) Capabilities() (consuladapter.Capabilities, error) {
+	fake.capabilitiesMutex.Lock()
+	fake.capabilitiesArgsForCall = append(fake.capabilitiesArgsForCall, struct{}{})
+	fake.capabilitiesMutex.Unlock()
+	if fake.CapabilitiesStub != nil {
+		return fake.CapabilitiesStub()
+	} else {
+		return fake.capabilitiesReturns.result1, fake.capabilitiesReturns.result2
+	}
+}
+
+func (fake *FakeClient) CapabilitiesCallCount() int {
+	fake.capabilitiesMutex.RLock()
+	defer fake.capabilitiesMutex.RUnlock()
+	return len(fake.capabilitiesArgsForCall)
+}
+
+func (fake *FakeClient) CapabilitiesReturns(result1 consuladapter.Capabilities, result2 error) {
+	fake.CapabilitiesStub = nil
+	fake.capabilitiesReturns = struct {
+		result1 consuladapter.Capabilities
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeClient) SelfTest(ctx context.Context) consuladapter.SelfTestReport {
+	fake.selfTestMutex.Lock()
+	fake.selfTestArgsForCall = append(fake.selfTestArgsForCall, struct {
+		ctx context.Context
+	}{ctx})
+	fake.selfTestMutex.Unlock()
+	if fake.SelfTestStub != nil {
+		return fake.SelfTestStub(ctx)
+	} else {
+		return fake.selfTestReturns.result1
+	}
+}
+
+func (fake *FakeClient) SelfTestCallCount() int {
+	fake.selfTestMutex.RLock()
+	defer fake.selfTestMutex.RUnlock()
+	return len(fake.selfTestArgsForCall)
+}
+
+func (fake *FakeClient) SelfTestArgsForCall(i int) context.Context {
+	fake.selfTestMutex.RLock()
+	defer fake.selfTestMutex.RUnlock()
+	return fake.selfTestArgsForCall[i].ctx
+}
+
+func (fake *FakeClient) SelfTestReturns(result1 consuladapter.SelfTestReport) {
+	fake.SelfTestStub = nil
+	fake.selfTestReturns = struct {
+		result1 consuladapter.SelfTestReport
+	}{result1}
+}
+
+func (fake *FakeClient) WaitForService(ctx context.Context, name string, minHealthyInstances int) error {
+	fake.waitForServiceMutex.Lock()
+	fake.waitForServiceArgsForCall = append(fake.waitForServiceArgsForCall, struct {
+		ctx                 context.Context
+		name                string
+		minHealthyInstances int
+	}{ctx, name, minHealthyInstances})
+	fake.waitForServiceMutex.Unlock()
+	if fake.WaitForServiceStub != nil {
+		return fake.WaitForServiceStub(ctx, name, minHealthyInstances)
+	} else {
+		return fake.waitForServiceReturns.result1
+	}
+}
+
+func (fake *FakeClient) WaitForServiceCallCount() int {
+	fake.waitForServiceMutex.RLock()
+	defer fake.waitForServiceMutex.RUnlock()
+	return len(fake.waitForServiceArgsForCall)
+}
+
+func (fake *FakeClient) WaitForServiceArgsForCall(i int) (context.Context, string, int) {
+	fake.waitForServiceMutex.RLock()
+	defer fake.waitForServiceMutex.RUnlock()
+	return fake.waitForServiceArgsForCall[i].ctx, fake.waitForServiceArgsForCall[i].name, fake.waitForServiceArgsForCall[i].minHealthyInstances
+}
+
+func (fake *FakeClient) WaitForServiceReturns(result1 error) {
+	fake.WaitForServiceStub = nil
+	fake.waitForServiceReturns = struct {
+		result1 error
+	}{result1}
+}
+
 var _ consuladapter.Client = new(FakeClient)
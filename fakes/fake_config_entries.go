@@ -0,0 +1,248 @@
+// This file was generated by counterfeiter
+package fakes
+
+import (
+	"sync"
+
+	"code.cloudfoundry.org/consuladapter"
+	"github.com/hashicorp/consul/api"
+)
+
+type FakeConfigEntries struct {
+	GetStub        func(kind string, name string, q *api.QueryOptions) (api.ConfigEntry, *api.QueryMeta, error)
+	getMutex       sync.RWMutex
+	getArgsForCall []struct {
+		kind string
+		name string
+		q    *api.QueryOptions
+	}
+	getReturns struct {
+		result1 api.ConfigEntry
+		result2 *api.QueryMeta
+		result3 error
+	}
+	ListStub        func(kind string, q *api.QueryOptions) ([]api.ConfigEntry, *api.QueryMeta, error)
+	listMutex       sync.RWMutex
+	listArgsForCall []struct {
+		kind string
+		q    *api.QueryOptions
+	}
+	listReturns struct {
+		result1 []api.ConfigEntry
+		result2 *api.QueryMeta
+		result3 error
+	}
+	SetStub        func(entry api.ConfigEntry, w *api.WriteOptions) (bool, *api.WriteMeta, error)
+	setMutex       sync.RWMutex
+	setArgsForCall []struct {
+		entry api.ConfigEntry
+		w     *api.WriteOptions
+	}
+	setReturns struct {
+		result1 bool
+		result2 *api.WriteMeta
+		result3 error
+	}
+	CASStub        func(entry api.ConfigEntry, index uint64, w *api.WriteOptions) (bool, *api.WriteMeta, error)
+	cASMutex       sync.RWMutex
+	cASArgsForCall []struct {
+		entry api.ConfigEntry
+		index uint64
+		w     *api.WriteOptions
+	}
+	cASReturns struct {
+		result1 bool
+		result2 *api.WriteMeta
+		result3 error
+	}
+	DeleteStub        func(kind string, name string, w *api.WriteOptions) (*api.WriteMeta, error)
+	deleteMutex       sync.RWMutex
+	deleteArgsForCall []struct {
+		kind string
+		name string
+		w    *api.WriteOptions
+	}
+	deleteReturns struct {
+		result1 *api.WriteMeta
+		result2 error
+	}
+}
+
+func (fake *FakeConfigEntries) Get(kind string, name string, q *api.QueryOptions) (api.ConfigEntry, *api.QueryMeta, error) {
+	fake.getMutex.Lock()
+	fake.getArgsForCall = append(fake.getArgsForCall, struct {
+		kind string
+		name string
+		q    *api.QueryOptions
+	}{kind, name, q})
+	fake.getMutex.Unlock()
+	if fake.GetStub != nil {
+		return fake.GetStub(kind, name, q)
+	} else {
+		return fake.getReturns.result1, fake.getReturns.result2, fake.getReturns.result3
+	}
+}
+
+func (fake *FakeConfigEntries) GetCallCount() int {
+	fake.getMutex.RLock()
+	defer fake.getMutex.RUnlock()
+	return len(fake.getArgsForCall)
+}
+
+func (fake *FakeConfigEntries) GetArgsForCall(i int) (string, string, *api.QueryOptions) {
+	fake.getMutex.RLock()
+	defer fake.getMutex.RUnlock()
+	return fake.getArgsForCall[i].kind, fake.getArgsForCall[i].name, fake.getArgsForCall[i].q
+}
+
+func (fake *FakeConfigEntries) GetReturns(result1 api.ConfigEntry, result2 *api.QueryMeta, result3 error) {
+	fake.GetStub = nil
+	fake.getReturns = struct {
+		result1 api.ConfigEntry
+		result2 *api.QueryMeta
+		result3 error
+	}{result1, result2, result3}
+}
+
+func (fake *FakeConfigEntries) List(kind string, q *api.QueryOptions) ([]api.ConfigEntry, *api.QueryMeta, error) {
+	fake.listMutex.Lock()
+	fake.listArgsForCall = append(fake.listArgsForCall, struct {
+		kind string
+		q    *api.QueryOptions
+	}{kind, q})
+	fake.listMutex.Unlock()
+	if fake.ListStub != nil {
+		return fake.ListStub(kind, q)
+	} else {
+		return fake.listReturns.result1, fake.listReturns.result2, fake.listReturns.result3
+	}
+}
+
+func (fake *FakeConfigEntries) ListCallCount() int {
+	fake.listMutex.RLock()
+	defer fake.listMutex.RUnlock()
+	return len(fake.listArgsForCall)
+}
+
+func (fake *FakeConfigEntries) ListArgsForCall(i int) (string, *api.QueryOptions) {
+	fake.listMutex.RLock()
+	defer fake.listMutex.RUnlock()
+	return fake.listArgsForCall[i].kind, fake.listArgsForCall[i].q
+}
+
+func (fake *FakeConfigEntries) ListReturns(result1 []api.ConfigEntry, result2 *api.QueryMeta, result3 error) {
+	fake.ListStub = nil
+	fake.listReturns = struct {
+		result1 []api.ConfigEntry
+		result2 *api.QueryMeta
+		result3 error
+	}{result1, result2, result3}
+}
+
+func (fake *FakeConfigEntries) Set(entry api.ConfigEntry, w *api.WriteOptions) (bool, *api.WriteMeta, error) {
+	fake.setMutex.Lock()
+	fake.setArgsForCall = append(fake.setArgsForCall, struct {
+		entry api.ConfigEntry
+		w     *api.WriteOptions
+	}{entry, w})
+	fake.setMutex.Unlock()
+	if fake.SetStub != nil {
+		return fake.SetStub(entry, w)
+	} else {
+		return fake.setReturns.result1, fake.setReturns.result2, fake.setReturns.result3
+	}
+}
+
+func (fake *FakeConfigEntries) SetCallCount() int {
+	fake.setMutex.RLock()
+	defer fake.setMutex.RUnlock()
+	return len(fake.setArgsForCall)
+}
+
+func (fake *FakeConfigEntries) SetArgsForCall(i int) (api.ConfigEntry, *api.WriteOptions) {
+	fake.setMutex.RLock()
+	defer fake.setMutex.RUnlock()
+	return fake.setArgsForCall[i].entry, fake.setArgsForCall[i].w
+}
+
+func (fake *FakeConfigEntries) SetReturns(result1 bool, result2 *api.WriteMeta, result3 error) {
+	fake.SetStub = nil
+	fake.setReturns = struct {
+		result1 bool
+		result2 *api.WriteMeta
+		result3 error
+	}{result1, result2, result3}
+}
+
+func (fake *FakeConfigEntries) CAS(entry api.ConfigEntry, index uint64, w *api.WriteOptions) (bool, *api.WriteMeta, error) {
+	fake.cASMutex.Lock()
+	fake.cASArgsForCall = append(fake.cASArgsForCall, struct {
+		entry api.ConfigEntry
+		index uint64
+		w     *api.WriteOptions
+	}{entry, index, w})
+	fake.cASMutex.Unlock()
+	if fake.CASStub != nil {
+		return fake.CASStub(entry, index, w)
+	} else {
+		return fake.cASReturns.result1, fake.cASReturns.result2, fake.cASReturns.result3
+	}
+}
+
+func (fake *FakeConfigEntries) CASCallCount() int {
+	fake.cASMutex.RLock()
+	defer fake.cASMutex.RUnlock()
+	return len(fake.cASArgsForCall)
+}
+
+func (fake *FakeConfigEntries) CASArgsForCall(i int) (api.ConfigEntry, uint64, *api.WriteOptions) {
+	fake.cASMutex.RLock()
+	defer fake.cASMutex.RUnlock()
+	return fake.cASArgsForCall[i].entry, fake.cASArgsForCall[i].index, fake.cASArgsForCall[i].w
+}
+
+func (fake *FakeConfigEntries) CASReturns(result1 bool, result2 *api.WriteMeta, result3 error) {
+	fake.CASStub = nil
+	fake.cASReturns = struct {
+		result1 bool
+		result2 *api.WriteMeta
+		result3 error
+	}{result1, result2, result3}
+}
+
+func (fake *FakeConfigEntries) Delete(kind string, name string, w *api.WriteOptions) (*api.WriteMeta, error) {
+	fake.deleteMutex.Lock()
+	fake.deleteArgsForCall = append(fake.deleteArgsForCall, struct {
+		kind string
+		name string
+		w    *api.WriteOptions
+	}{kind, name, w})
+	fake.deleteMutex.Unlock()
+	if fake.DeleteStub != nil {
+		return fake.DeleteStub(kind, name, w)
+	} else {
+		return fake.deleteReturns.result1, fake.deleteReturns.result2
+	}
+}
+
+func (fake *FakeConfigEntries) DeleteCallCount() int {
+	fake.deleteMutex.RLock()
+	defer fake.deleteMutex.RUnlock()
+	return len(fake.deleteArgsForCall)
+}
+
+func (fake *FakeConfigEntries) DeleteArgsForCall(i int) (string, string, *api.WriteOptions) {
+	fake.deleteMutex.RLock()
+	defer fake.deleteMutex.RUnlock()
+	return fake.deleteArgsForCall[i].kind, fake.deleteArgsForCall[i].name, fake.deleteArgsForCall[i].w
+}
+
+func (fake *FakeConfigEntries) DeleteReturns(result1 *api.WriteMeta, result2 error) {
+	fake.DeleteStub = nil
+	fake.deleteReturns = struct {
+		result1 *api.WriteMeta
+		result2 error
+	}{result1, result2}
+}
+
+var _ consuladapter.ConfigEntries = new(FakeConfigEntries)
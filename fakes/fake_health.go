@@ -0,0 +1,110 @@
+// This file was generated by counterfeiter
+package fakes
+
+import (
+	"sync"
+
+	"code.cloudfoundry.org/consuladapter"
+	"github.com/hashicorp/consul/api"
+)
+
+type FakeHealth struct {
+	ServiceStub        func(service, tag string, passingOnly bool, q *api.QueryOptions) ([]*api.ServiceEntry, *api.QueryMeta, error)
+	serviceMutex       sync.RWMutex
+	serviceArgsForCall []struct {
+		service     string
+		tag         string
+		passingOnly bool
+		q           *api.QueryOptions
+	}
+	serviceReturns struct {
+		result1 []*api.ServiceEntry
+		result2 *api.QueryMeta
+		result3 error
+	}
+	ChecksStub        func(service string, q *api.QueryOptions) (api.HealthChecks, *api.QueryMeta, error)
+	checksMutex       sync.RWMutex
+	checksArgsForCall []struct {
+		service string
+		q       *api.QueryOptions
+	}
+	checksReturns struct {
+		result1 api.HealthChecks
+		result2 *api.QueryMeta
+		result3 error
+	}
+}
+
+func (fake *FakeHealth) Service(service string, tag string, passingOnly bool, q *api.QueryOptions) ([]*api.ServiceEntry, *api.QueryMeta, error) {
+	fake.serviceMutex.Lock()
+	fake.serviceArgsForCall = append(fake.serviceArgsForCall, struct {
+		service     string
+		tag         string
+		passingOnly bool
+		q           *api.QueryOptions
+	}{service, tag, passingOnly, q})
+	fake.serviceMutex.Unlock()
+	if fake.ServiceStub != nil {
+		return fake.ServiceStub(service, tag, passingOnly, q)
+	} else {
+		return fake.serviceReturns.result1, fake.serviceReturns.result2, fake.serviceReturns.result3
+	}
+}
+
+func (fake *FakeHealth) ServiceCallCount() int {
+	fake.serviceMutex.RLock()
+	defer fake.serviceMutex.RUnlock()
+	return len(fake.serviceArgsForCall)
+}
+
+func (fake *FakeHealth) ServiceArgsForCall(i int) (string, string, bool, *api.QueryOptions) {
+	fake.serviceMutex.RLock()
+	defer fake.serviceMutex.RUnlock()
+	return fake.serviceArgsForCall[i].service, fake.serviceArgsForCall[i].tag, fake.serviceArgsForCall[i].passingOnly, fake.serviceArgsForCall[i].q
+}
+
+func (fake *FakeHealth) ServiceReturns(result1 []*api.ServiceEntry, result2 *api.QueryMeta, result3 error) {
+	fake.ServiceStub = nil
+	fake.serviceReturns = struct {
+		result1 []*api.ServiceEntry
+		result2 *api.QueryMeta
+		result3 error
+	}{result1, result2, result3}
+}
+
+func (fake *FakeHealth) Checks(service string, q *api.QueryOptions) (api.HealthChecks, *api.QueryMeta, error) {
+	fake.checksMutex.Lock()
+	fake.checksArgsForCall = append(fake.checksArgsForCall, struct {
+		service string
+		q       *api.QueryOptions
+	}{service, q})
+	fake.checksMutex.Unlock()
+	if fake.ChecksStub != nil {
+		return fake.ChecksStub(service, q)
+	} else {
+		return fake.checksReturns.result1, fake.checksReturns.result2, fake.checksReturns.result3
+	}
+}
+
+func (fake *FakeHealth) ChecksCallCount() int {
+	fake.checksMutex.RLock()
+	defer fake.checksMutex.RUnlock()
+	return len(fake.checksArgsForCall)
+}
+
+func (fake *FakeHealth) ChecksArgsForCall(i int) (string, *api.QueryOptions) {
+	fake.checksMutex.RLock()
+	defer fake.checksMutex.RUnlock()
+	return fake.checksArgsForCall[i].service, fake.checksArgsForCall[i].q
+}
+
+func (fake *FakeHealth) ChecksReturns(result1 api.HealthChecks, result2 *api.QueryMeta, result3 error) {
+	fake.ChecksStub = nil
+	fake.checksReturns = struct {
+		result1 api.HealthChecks
+		result2 *api.QueryMeta
+		result3 error
+	}{result1, result2, result3}
+}
+
+var _ consuladapter.Health = new(FakeHealth)
@@ -41,6 +41,28 @@ type FakeKV struct {
 		result1 *api.WriteMeta
 		result2 error
 	}
+	CASStub        func(p *api.KVPair, q *api.WriteOptions) (bool, *api.WriteMeta, error)
+	cASMutex       sync.RWMutex
+	cASArgsForCall []struct {
+		p *api.KVPair
+		q *api.WriteOptions
+	}
+	cASReturns struct {
+		result1 bool
+		result2 *api.WriteMeta
+		result3 error
+	}
+	AcquireStub        func(p *api.KVPair, q *api.WriteOptions) (bool, *api.WriteMeta, error)
+	acquireMutex       sync.RWMutex
+	acquireArgsForCall []struct {
+		p *api.KVPair
+		q *api.WriteOptions
+	}
+	acquireReturns struct {
+		result1 bool
+		result2 *api.WriteMeta
+		result3 error
+	}
 	ReleaseStub        func(p *api.KVPair, q *api.WriteOptions) (bool, *api.WriteMeta, error)
 	releaseMutex       sync.RWMutex
 	releaseArgsForCall []struct {
@@ -52,6 +74,17 @@ type FakeKV struct {
 		result2 *api.WriteMeta
 		result3 error
 	}
+	DeleteCASStub        func(p *api.KVPair, w *api.WriteOptions) (bool, *api.WriteMeta, error)
+	deleteCASMutex       sync.RWMutex
+	deleteCASArgsForCall []struct {
+		p *api.KVPair
+		w *api.WriteOptions
+	}
+	deleteCASReturns struct {
+		result1 bool
+		result2 *api.WriteMeta
+		result3 error
+	}
 	DeleteTreeStub        func(prefix string, w *api.WriteOptions) (*api.WriteMeta, error)
 	deleteTreeMutex       sync.RWMutex
 	deleteTreeArgsForCall []struct {
@@ -168,6 +201,76 @@ func (fake *FakeKV) PutReturns(result1 *api.WriteMeta, result2 error) {
 	}{result1, result2}
 }
 
+func (fake *FakeKV) CAS(p *api.KVPair, q *api.WriteOptions) (bool, *api.WriteMeta, error) {
+	fake.cASMutex.Lock()
+	fake.cASArgsForCall = append(fake.cASArgsForCall, struct {
+		p *api.KVPair
+		q *api.WriteOptions
+	}{p, q})
+	fake.cASMutex.Unlock()
+	if fake.CASStub != nil {
+		return fake.CASStub(p, q)
+	} else {
+		return fake.cASReturns.result1, fake.cASReturns.result2, fake.cASReturns.result3
+	}
+}
+
+func (fake *FakeKV) CASCallCount() int {
+	fake.cASMutex.RLock()
+	defer fake.cASMutex.RUnlock()
+	return len(fake.cASArgsForCall)
+}
+
+func (fake *FakeKV) CASArgsForCall(i int) (*api.KVPair, *api.WriteOptions) {
+	fake.cASMutex.RLock()
+	defer fake.cASMutex.RUnlock()
+	return fake.cASArgsForCall[i].p, fake.cASArgsForCall[i].q
+}
+
+func (fake *FakeKV) CASReturns(result1 bool, result2 *api.WriteMeta, result3 error) {
+	fake.CASStub = nil
+	fake.cASReturns = struct {
+		result1 bool
+		result2 *api.WriteMeta
+		result3 error
+	}{result1, result2, result3}
+}
+
+func (fake *FakeKV) Acquire(p *api.KVPair, q *api.WriteOptions) (bool, *api.WriteMeta, error) {
+	fake.acquireMutex.Lock()
+	fake.acquireArgsForCall = append(fake.acquireArgsForCall, struct {
+		p *api.KVPair
+		q *api.WriteOptions
+	}{p, q})
+	fake.acquireMutex.Unlock()
+	if fake.AcquireStub != nil {
+		return fake.AcquireStub(p, q)
+	} else {
+		return fake.acquireReturns.result1, fake.acquireReturns.result2, fake.acquireReturns.result3
+	}
+}
+
+func (fake *FakeKV) AcquireCallCount() int {
+	fake.acquireMutex.RLock()
+	defer fake.acquireMutex.RUnlock()
+	return len(fake.acquireArgsForCall)
+}
+
+func (fake *FakeKV) AcquireArgsForCall(i int) (*api.KVPair, *api.WriteOptions) {
+	fake.acquireMutex.RLock()
+	defer fake.acquireMutex.RUnlock()
+	return fake.acquireArgsForCall[i].p, fake.acquireArgsForCall[i].q
+}
+
+func (fake *FakeKV) AcquireReturns(result1 bool, result2 *api.WriteMeta, result3 error) {
+	fake.AcquireStub = nil
+	fake.acquireReturns = struct {
+		result1 bool
+		result2 *api.WriteMeta
+		result3 error
+	}{result1, result2, result3}
+}
+
 func (fake *FakeKV) Release(p *api.KVPair, q *api.WriteOptions) (bool, *api.WriteMeta, error) {
 	fake.releaseMutex.Lock()
 	fake.releaseArgsForCall = append(fake.releaseArgsForCall, struct {
@@ -203,6 +306,41 @@ func (fake *FakeKV) ReleaseReturns(result1 bool, result2 *api.WriteMeta, result3
 	}{result1, result2, result3}
 }
 
+func (fake *FakeKV) DeleteCAS(p *api.KVPair, w *api.WriteOptions) (bool, *api.WriteMeta, error) {
+	fake.deleteCASMutex.Lock()
+	fake.deleteCASArgsForCall = append(fake.deleteCASArgsForCall, struct {
+		p *api.KVPair
+		w *api.WriteOptions
+	}{p, w})
+	fake.deleteCASMutex.Unlock()
+	if fake.DeleteCASStub != nil {
+		return fake.DeleteCASStub(p, w)
+	} else {
+		return fake.deleteCASReturns.result1, fake.deleteCASReturns.result2, fake.deleteCASReturns.result3
+	}
+}
+
+func (fake *FakeKV) DeleteCASCallCount() int {
+	fake.deleteCASMutex.RLock()
+	defer fake.deleteCASMutex.RUnlock()
+	return len(fake.deleteCASArgsForCall)
+}
+
+func (fake *FakeKV) DeleteCASArgsForCall(i int) (*api.KVPair, *api.WriteOptions) {
+	fake.deleteCASMutex.RLock()
+	defer fake.deleteCASMutex.RUnlock()
+	return fake.deleteCASArgsForCall[i].p, fake.deleteCASArgsForCall[i].w
+}
+
+func (fake *FakeKV) DeleteCASReturns(result1 bool, result2 *api.WriteMeta, result3 error) {
+	fake.DeleteCASStub = nil
+	fake.deleteCASReturns = struct {
+		result1 bool
+		result2 *api.WriteMeta
+		result3 error
+	}{result1, result2, result3}
+}
+
 func (fake *FakeKV) DeleteTree(prefix string, w *api.WriteOptions) (*api.WriteMeta, error) {
 	fake.deleteTreeMutex.Lock()
 	fake.deleteTreeArgsForCall = append(fake.deleteTreeArgsForCall, struct {
@@ -17,6 +17,12 @@ type FakeLock struct {
 		result1 <-chan struct{}
 		result2 error
 	}
+	UnlockStub        func() error
+	unlockMutex       sync.RWMutex
+	unlockArgsForCall []struct{}
+	unlockReturns     struct {
+		result1 error
+	}
 }
 
 func (fake *FakeLock) Lock(stopCh <-chan struct{}) (lostLock <-chan struct{}, err error) {
@@ -52,4 +58,28 @@ func (fake *FakeLock) LockReturns(result1 <-chan struct{}, result2 error) {
 	}{result1, result2}
 }
 
+func (fake *FakeLock) Unlock() error {
+	fake.unlockMutex.Lock()
+	fake.unlockArgsForCall = append(fake.unlockArgsForCall, struct{}{})
+	fake.unlockMutex.Unlock()
+	if fake.UnlockStub != nil {
+		return fake.UnlockStub()
+	} else {
+		return fake.unlockReturns.result1
+	}
+}
+
+func (fake *FakeLock) UnlockCallCount() int {
+	fake.unlockMutex.RLock()
+	defer fake.unlockMutex.RUnlock()
+	return len(fake.unlockArgsForCall)
+}
+
+func (fake *FakeLock) UnlockReturns(result1 error) {
+	fake.UnlockStub = nil
+	fake.unlockReturns = struct {
+		result1 error
+	}{result1}
+}
+
 var _ consuladapter.Lock = new(FakeLock)
@@ -0,0 +1,56 @@
+// This file was generated by counterfeiter
+package fakes
+
+import (
+	"sync"
+
+	"code.cloudfoundry.org/consuladapter"
+	"github.com/hashicorp/consul/api"
+)
+
+type FakeOperator struct {
+	RaftGetConfigurationStub        func(q *api.QueryOptions) (*api.RaftConfiguration, error)
+	raftGetConfigurationMutex       sync.RWMutex
+	raftGetConfigurationArgsForCall []struct {
+		q *api.QueryOptions
+	}
+	raftGetConfigurationReturns struct {
+		result1 *api.RaftConfiguration
+		result2 error
+	}
+}
+
+func (fake *FakeOperator) RaftGetConfiguration(q *api.QueryOptions) (*api.RaftConfiguration, error) {
+	fake.raftGetConfigurationMutex.Lock()
+	fake.raftGetConfigurationArgsForCall = append(fake.raftGetConfigurationArgsForCall, struct {
+		q *api.QueryOptions
+	}{q})
+	fake.raftGetConfigurationMutex.Unlock()
+	if fake.RaftGetConfigurationStub != nil {
+		return fake.RaftGetConfigurationStub(q)
+	} else {
+		return fake.raftGetConfigurationReturns.result1, fake.raftGetConfigurationReturns.result2
+	}
+}
+
+func (fake *FakeOperator) RaftGetConfigurationCallCount() int {
+	fake.raftGetConfigurationMutex.RLock()
+	defer fake.raftGetConfigurationMutex.RUnlock()
+	return len(fake.raftGetConfigurationArgsForCall)
+}
+
+func (fake *FakeOperator) RaftGetConfigurationArgsForCall(i int) *api.QueryOptions {
+	fake.raftGetConfigurationMutex.RLock()
+	defer fake.raftGetConfigurationMutex.RUnlock()
+	return fake.raftGetConfigurationArgsForCall[i].q
+}
+
+func (fake *FakeOperator) RaftGetConfigurationReturns(result1 *api.RaftConfiguration, result2 error) {
+	fake.RaftGetConfigurationStub = nil
+	fake.raftGetConfigurationReturns = struct {
+		result1 *api.RaftConfiguration
+		result2 error
+	}{result1, result2}
+}
+
+var _ consuladapter.Operator = new(FakeOperator)
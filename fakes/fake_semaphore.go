@@ -0,0 +1,85 @@
+// This file was generated by counterfeiter
+package fakes
+
+import (
+	"sync"
+
+	"code.cloudfoundry.org/consuladapter"
+)
+
+type FakeSemaphore struct {
+	AcquireStub        func(stopCh <-chan struct{}) (lostSlot <-chan struct{}, err error)
+	acquireMutex       sync.RWMutex
+	acquireArgsForCall []struct {
+		stopCh <-chan struct{}
+	}
+	acquireReturns struct {
+		result1 <-chan struct{}
+		result2 error
+	}
+	ReleaseStub        func() error
+	releaseMutex       sync.RWMutex
+	releaseArgsForCall []struct{}
+	releaseReturns     struct {
+		result1 error
+	}
+}
+
+func (fake *FakeSemaphore) Acquire(stopCh <-chan struct{}) (lostSlot <-chan struct{}, err error) {
+	fake.acquireMutex.Lock()
+	fake.acquireArgsForCall = append(fake.acquireArgsForCall, struct {
+		stopCh <-chan struct{}
+	}{stopCh})
+	fake.acquireMutex.Unlock()
+	if fake.AcquireStub != nil {
+		return fake.AcquireStub(stopCh)
+	} else {
+		return fake.acquireReturns.result1, fake.acquireReturns.result2
+	}
+}
+
+func (fake *FakeSemaphore) AcquireCallCount() int {
+	fake.acquireMutex.RLock()
+	defer fake.acquireMutex.RUnlock()
+	return len(fake.acquireArgsForCall)
+}
+
+func (fake *FakeSemaphore) AcquireArgsForCall(i int) <-chan struct{} {
+	fake.acquireMutex.RLock()
+	defer fake.acquireMutex.RUnlock()
+	return fake.acquireArgsForCall[i].stopCh
+}
+
+func (fake *FakeSemaphore) AcquireReturns(result1 <-chan struct{}, result2 error) {
+	fake.AcquireStub = nil
+	fake.acquireReturns = struct {
+		result1 <-chan struct{}
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeSemaphore) Release() error {
+	fake.releaseMutex.Lock()
+	fake.releaseArgsForCall = append(fake.releaseArgsForCall, struct{}{})
+	fake.releaseMutex.Unlock()
+	if fake.ReleaseStub != nil {
+		return fake.ReleaseStub()
+	} else {
+		return fake.releaseReturns.result1
+	}
+}
+
+func (fake *FakeSemaphore) ReleaseCallCount() int {
+	fake.releaseMutex.RLock()
+	defer fake.releaseMutex.RUnlock()
+	return len(fake.releaseArgsForCall)
+}
+
+func (fake *FakeSemaphore) ReleaseReturns(result1 error) {
+	fake.ReleaseStub = nil
+	fake.releaseReturns = struct {
+		result1 error
+	}{result1}
+}
+
+var _ consuladapter.Semaphore = new(FakeSemaphore)
@@ -0,0 +1,56 @@
+package consuladapter
+
+import "sync"
+
+// ErrorFanOut delivers the one error sent on an error channel, such as the
+// renewal error from CreateTTLSession, to any number of subscribers, each
+// with its own buffer so none can miss it or hold up the others.
+type ErrorFanOut struct {
+	mutex       sync.Mutex
+	done        bool
+	err         error
+	subscribers []chan error
+}
+
+// NewErrorFanOut receives from errCh on a background goroutine, which exits
+// once errCh delivers or is closed.
+func NewErrorFanOut(errCh <-chan error) *ErrorFanOut {
+	f := &ErrorFanOut{}
+	goBackground(nil, func() {
+		f.publish(<-errCh)
+	}, func(err *PanicError) {
+		f.publish(err)
+	})
+	return f
+}
+
+// Subscribe returns a channel that receives the error once it arrives, or
+// straight away if it already has.
+func (f *ErrorFanOut) Subscribe() <-chan error {
+	subscriber := make(chan error, 1)
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if f.done {
+		subscriber <- f.err
+	} else {
+		f.subscribers = append(f.subscribers, subscriber)
+	}
+	return subscriber
+}
+
+func (f *ErrorFanOut) publish(err error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if f.done {
+		return
+	}
+	f.done = true
+	f.err = err
+	for _, subscriber := range f.subscribers {
+		sendError(subscriber, err, ErrorFanOutChannel)
+	}
+	f.subscribers = nil
+}
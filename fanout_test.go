@@ -0,0 +1,62 @@
+package consuladapter_test
+
+import (
+	"errors"
+
+	"code.cloudfoundry.org/consuladapter"
+	"code.cloudfoundry.org/consuladapter/fakes"
+	"github.com/hashicorp/consul/api"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ErrorFanOut", func() {
+	It("delivers the error to every subscriber", func() {
+		errCh := make(chan error, 1)
+		fanOut := consuladapter.NewErrorFanOut(errCh)
+
+		first := fanOut.Subscribe()
+		second := fanOut.Subscribe()
+
+		sessionErr := errors.New("session lost")
+		errCh <- sessionErr
+
+		Eventually(first).Should(Receive(Equal(sessionErr)))
+		Eventually(second).Should(Receive(Equal(sessionErr)))
+		Eventually(fanOut.Subscribe()).Should(Receive(Equal(sessionErr)))
+	})
+
+	It("does not wait for subscribers that never receive", func() {
+		errCh := make(chan error)
+		fanOut := consuladapter.NewErrorFanOut(errCh)
+		fanOut.Subscribe()
+
+		errCh <- errors.New("session lost")
+		Eventually(fanOut.Subscribe()).Should(Receive(HaveOccurred()))
+	})
+
+	Describe("TTLSession", func() {
+		It("lets several consumers observe session loss", func() {
+			renewErr := errors.New("session expired")
+			renew := make(chan struct{})
+			session := &fakes.FakeSession{}
+			session.CreateNoChecksReturns("session-id", nil, nil)
+			session.RenewStub = func(id string, q *api.WriteOptions) (*api.SessionEntry, *api.WriteMeta, error) {
+				<-renew
+				return nil, nil, renewErr
+			}
+
+			ttlSession, err := consuladapter.NewTTLSession(session, &api.SessionEntry{TTL: "20ms"})
+			Expect(err).NotTo(HaveOccurred())
+
+			first := ttlSession.Subscribe()
+			second := ttlSession.Subscribe()
+			close(renew)
+
+			Eventually(first).Should(Receive(Equal(renewErr)))
+			Eventually(second).Should(Receive(Equal(renewErr)))
+			Expect(ttlSession.Destroy()).To(Equal(renewErr))
+		})
+	})
+})
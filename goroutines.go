@@ -0,0 +1,114 @@
+package consuladapter
+
+import (
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+)
+
+var backgroundGoroutines int64
+
+var panicLogger struct {
+	mutex  sync.RWMutex
+	logger lager.Logger
+}
+
+// PanicError is reported in place of a panic in a background goroutine,
+// e.g. from a callback, so that it does not take down the process.
+type PanicError struct {
+	Value interface{}
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic in background goroutine: %v", e.Value)
+}
+
+// SetPanicLogger logs panics recovered in background goroutines, with their
+// stack traces, to logger, along with calls left waiting on blocked
+// callbacks when callback checks are enabled. Nothing is logged by default.
+func SetPanicLogger(logger lager.Logger) {
+	panicLogger.mutex.Lock()
+	defer panicLogger.mutex.Unlock()
+	panicLogger.logger = logger
+}
+
+// goBackground runs f on a goroutine that is counted by
+// BackgroundGoroutines and, if wg is not nil, joined by wg. A panic in f is
+// recovered, logged, and passed to onPanic if it is not nil.
+func goBackground(wg *sync.WaitGroup, f func(), onPanic func(*PanicError)) {
+	atomic.AddInt64(&backgroundGoroutines, 1)
+	if wg != nil {
+		wg.Add(1)
+	}
+
+	go func() {
+		defer atomic.AddInt64(&backgroundGoroutines, -1)
+		if wg != nil {
+			defer wg.Done()
+		}
+		defer recoverPanic(onPanic)
+		f()
+	}()
+}
+
+// recoverPanic recovers a panic, logs it, and passes it to onPanic if it is
+// not nil. It only works when deferred directly.
+func recoverPanic(onPanic func(*PanicError)) {
+	if value := recover(); value != nil {
+		err := &PanicError{Value: value, Stack: debug.Stack()}
+		logPanic(err)
+		if onPanic != nil {
+			onPanic(err)
+		}
+	}
+}
+
+func currentLogger() lager.Logger {
+	panicLogger.mutex.RLock()
+	defer panicLogger.mutex.RUnlock()
+	return panicLogger.logger
+}
+
+func logPanic(err *PanicError) {
+	if logger := currentLogger(); logger != nil {
+		logger.Error("background-goroutine-panicked", err, lager.Data{"stack": string(err.Stack)})
+	}
+}
+
+// BackgroundGoroutines returns the number of goroutines the package is
+// running on behalf of its callers, such as session renewals and lock
+// monitors.
+func BackgroundGoroutines() int {
+	return int(atomic.LoadInt64(&backgroundGoroutines))
+}
+
+type LeakedGoroutinesError struct {
+	Count int
+}
+
+func (e LeakedGoroutinesError) Error() string {
+	return fmt.Sprintf("%d background goroutines still running", e.Count)
+}
+
+// WaitForBackgroundGoroutines waits up to timeout for every background
+// goroutine to exit, returning a LeakedGoroutinesError otherwise. It is a
+// hook for test suites to check, after destroying sessions and closing the
+// package's types, that nothing was left running.
+func WaitForBackgroundGoroutines(timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		count := BackgroundGoroutines()
+		if count == 0 {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return LeakedGoroutinesError{Count: count}
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,127 @@
+package consuladapter_test
+
+import (
+	"errors"
+	"time"
+
+	"code.cloudfoundry.org/consuladapter"
+	"code.cloudfoundry.org/consuladapter/fakes"
+	"code.cloudfoundry.org/lager/lagertest"
+	"github.com/hashicorp/consul/api"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Background goroutines", func() {
+	var session *fakes.FakeSession
+
+	BeforeEach(func() {
+		// other specs' goroutines may still be exiting
+		Expect(consuladapter.WaitForBackgroundGoroutines(time.Second)).To(Succeed())
+
+		session = &fakes.FakeSession{}
+		session.CreateNoChecksReturns("session-id", nil, nil)
+		session.RenewPeriodicStub = func(initialTTL string, id string, q *api.WriteOptions, doneCh chan struct{}) error {
+			<-doneCh
+			return nil
+		}
+	})
+
+	AfterEach(func() {
+		Expect(consuladapter.WaitForBackgroundGoroutines(time.Second)).To(Succeed())
+	})
+
+	Describe("TTLSession", func() {
+		It("joins the renewal goroutine on Destroy", func() {
+			ttlSession, err := consuladapter.NewTTLSession(session, &api.SessionEntry{TTL: "10s"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ttlSession.ID()).To(Equal("session-id"))
+			Expect(consuladapter.BackgroundGoroutines()).To(Equal(1))
+
+			Expect(ttlSession.Destroy()).To(Succeed())
+			Expect(consuladapter.BackgroundGoroutines()).To(Equal(0))
+			Expect(ttlSession.Lost()).To(BeClosed())
+		})
+
+		It("reports why renewal stopped", func() {
+			renewErr := errors.New("connection refused")
+			session.RenewReturns(nil, nil, renewErr)
+
+			ttlSession, err := consuladapter.NewTTLSession(session, &api.SessionEntry{TTL: "20ms"})
+			Expect(err).NotTo(HaveOccurred())
+
+			Eventually(ttlSession.Lost()).Should(BeClosed())
+			Expect(ttlSession.Err()).To(Equal(renewErr))
+			Expect(ttlSession.Destroy()).To(Equal(renewErr))
+		})
+	})
+
+	Describe("panics", func() {
+		var logger *lagertest.TestLogger
+
+		BeforeEach(func() {
+			logger = lagertest.NewTestLogger("test")
+			consuladapter.SetPanicLogger(logger)
+
+			session.RenewPeriodicStub = func(initialTTL string, id string, q *api.WriteOptions, doneCh chan struct{}) error {
+				panic("renewal exploded")
+			}
+			session.RenewStub = func(id string, q *api.WriteOptions) (*api.SessionEntry, *api.WriteMeta, error) {
+				panic("renewal exploded")
+			}
+		})
+
+		AfterEach(func() {
+			consuladapter.SetPanicLogger(nil)
+		})
+
+		It("reports them as the session's error", func() {
+			ttlSession, err := consuladapter.NewTTLSession(session, &api.SessionEntry{TTL: "20ms"})
+			Expect(err).NotTo(HaveOccurred())
+
+			Eventually(ttlSession.Lost()).Should(BeClosed())
+			Expect(ttlSession.Err()).To(BeAssignableToTypeOf(&consuladapter.PanicError{}))
+			Expect(ttlSession.Err().(*consuladapter.PanicError).Value).To(Equal("renewal exploded"))
+		})
+
+		It("delivers them on the renewal channel", func() {
+			_, renewErr, err := consuladapter.CreateTTLSession(session, &api.SessionEntry{TTL: "10s"}, make(chan struct{}))
+			Expect(err).NotTo(HaveOccurred())
+
+			var panicErr error
+			Eventually(renewErr).Should(Receive(&panicErr))
+			Expect(panicErr).To(BeAssignableToTypeOf(&consuladapter.PanicError{}))
+		})
+
+		It("logs them with their stack traces", func() {
+			_, _, err := consuladapter.CreateTTLSession(session, &api.SessionEntry{TTL: "10s"}, make(chan struct{}))
+			Expect(err).NotTo(HaveOccurred())
+
+			Eventually(logger.LogMessages).Should(ContainElement("test.background-goroutine-panicked"))
+			Expect(logger.Logs()[0].Data["stack"]).To(ContainSubstring("goroutines_test.go"))
+		})
+	})
+
+	Describe("LifecycleRecorder", func() {
+		It("joins lock monitors on Close", func() {
+			recorder := consuladapter.NewLifecycleRecorder(0)
+			recorder.TrackLock("the-lock", make(chan struct{}))
+			Expect(consuladapter.BackgroundGoroutines()).To(Equal(1))
+
+			recorder.Close()
+			Expect(consuladapter.BackgroundGoroutines()).To(Equal(0))
+		})
+	})
+
+	Describe("WaitForBackgroundGoroutines", func() {
+		It("reports goroutines that are still running", func() {
+			doneCh := make(chan struct{})
+			_, _, err := consuladapter.CreateTTLSession(session, &api.SessionEntry{TTL: "10s"}, doneCh)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(consuladapter.WaitForBackgroundGoroutines(10 * time.Millisecond)).To(Equal(consuladapter.LeakedGoroutinesError{Count: 1}))
+			close(doneCh)
+		})
+	})
+})
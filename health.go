@@ -0,0 +1,26 @@
+package consuladapter
+
+import "github.com/hashicorp/consul/api"
+
+//go:generate counterfeiter -o fakes/fake_health.go . Health
+
+type Health interface {
+	Service(service, tag string, passingOnly bool, q *api.QueryOptions) ([]*api.ServiceEntry, *api.QueryMeta, error)
+	Checks(service string, q *api.QueryOptions) (api.HealthChecks, *api.QueryMeta, error)
+}
+
+type health struct {
+	health *api.Health
+}
+
+func NewConsulHealth(h *api.Health) Health {
+	return &health{health: h}
+}
+
+func (h *health) Service(service, tag string, passingOnly bool, q *api.QueryOptions) ([]*api.ServiceEntry, *api.QueryMeta, error) {
+	return h.health.Service(service, tag, passingOnly, q)
+}
+
+func (h *health) Checks(service string, q *api.QueryOptions) (api.HealthChecks, *api.QueryMeta, error) {
+	return h.health.Checks(service, q)
+}
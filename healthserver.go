@@ -0,0 +1,60 @@
+package consuladapter
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+)
+
+const healthPath = "/health"
+
+// HealthServer serves a local HTTP health endpoint reporting the result of a
+// health function, for use as the target of an HTTPCheck.
+type HealthServer struct {
+	listener net.Listener
+	server   *http.Server
+	wg       sync.WaitGroup
+}
+
+// NewHealthServer listens on address, e.g. "127.0.0.1:0", and responds 200
+// while health returns nil and 503 with the error otherwise.
+func NewHealthServer(address string, health func() error) (*HealthServer, error) {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, err
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(healthPath, func(w http.ResponseWriter, r *http.Request) {
+		err := health()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+
+	s := &HealthServer{
+		listener: listener,
+		server:   &http.Server{Handler: mux},
+	}
+	goBackground(&s.wg, func() { s.server.Serve(listener) }, nil)
+
+	return s, nil
+}
+
+func (s *HealthServer) URL() string {
+	return fmt.Sprintf("http://%s%s", s.listener.Addr().String(), healthPath)
+}
+
+// Check returns an HTTPCheck that polls this server.
+func (s *HealthServer) Check(opts CheckOptions) HTTPCheck {
+	return HTTPCheck{CheckOptions: opts, URL: s.URL()}
+}
+
+func (s *HealthServer) Close() error {
+	err := s.server.Close()
+	s.wg.Wait()
+	return err
+}
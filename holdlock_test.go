@@ -0,0 +1,37 @@
+package consuladapter_test
+
+import (
+	"code.cloudfoundry.org/consuladapter"
+	"code.cloudfoundry.org/consuladapter/matchers"
+	"github.com/hashicorp/consul/api"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("matchers.HoldLock", func() {
+	var client consuladapter.Client
+
+	BeforeEach(func() {
+		clusterRunner.Start()
+		clusterRunner.WaitUntilReady()
+		client = clusterRunner.NewClient()
+	})
+
+	AfterEach(func() {
+		clusterRunner.Stop()
+	})
+
+	It("matches a lock taken through the adapter's LockOpts", func() {
+		lock, err := client.LockOpts(&api.LockOptions{Key: "v1/locks/bbs", SessionName: "bbs"})
+		Expect(err).NotTo(HaveOccurred())
+
+		lostLock, err := lock.Lock(nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lostLock).NotTo(BeNil())
+		defer lock.Unlock()
+
+		Expect(client).To(matchers.HoldLock("bbs", "v1/locks/bbs"))
+		Expect(client).NotTo(matchers.HoldLock("auctioneer", "v1/locks/bbs"))
+	})
+})
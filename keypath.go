@@ -0,0 +1,75 @@
+package consuladapter
+
+import "strings"
+
+// KeyPath builds keys under a prefix, so components share one definition of
+// where their keys live instead of concatenating strings.
+type KeyPath struct {
+	prefix string
+}
+
+func NewKeyPath(segments ...string) KeyPath {
+	return KeyPath{prefix: joinKey(segments)}
+}
+
+// Key returns the key for name under the path, e.g.
+// LockKeys.Key("bbs") is "v1/locks/bbs".
+func (p KeyPath) Key(name ...string) string {
+	return joinKey(append([]string{p.prefix}, name...))
+}
+
+// Prefix returns the path with a trailing slash, for listing its keys.
+func (p KeyPath) Prefix() string {
+	if p.prefix == "" {
+		return ""
+	}
+	return p.prefix + "/"
+}
+
+// Sub returns the path of segments under this one.
+func (p KeyPath) Sub(segments ...string) KeyPath {
+	return KeyPath{prefix: p.Key(segments...)}
+}
+
+// Name returns key relative to the path, and false if key is not under it.
+func (p KeyPath) Name(key string) (string, bool) {
+	prefix := p.Prefix()
+	if !strings.HasPrefix(key, prefix) || len(key) == len(prefix) {
+		return "", false
+	}
+	return key[len(prefix):], true
+}
+
+func (p KeyPath) String() string {
+	return p.prefix
+}
+
+func joinKey(segments []string) string {
+	parts := make([]string, 0, len(segments))
+	for _, segment := range segments {
+		if segment = strings.Trim(segment, "/"); segment != "" {
+			parts = append(parts, segment)
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+// KeyConventions names the key paths shared by Cloud Foundry components.
+// Root is the version prefix all of them live under.
+type KeyConventions struct {
+	Root string
+}
+
+var DefaultKeyConventions = KeyConventions{Root: "v1"}
+
+func (c KeyConventions) Locks() KeyPath    { return NewKeyPath(c.Root, "locks") }
+func (c KeyConventions) Presence() KeyPath { return NewKeyPath(c.Root, "presence") }
+func (c KeyConventions) Draining() KeyPath { return NewKeyPath(c.Root, "draining") }
+
+// Key paths under DefaultKeyConventions, e.g. LockKeys.Key("bbs") for the
+// BBS lock and PresenceKeys.Key(cellID) for a cell's presence.
+var (
+	LockKeys     = DefaultKeyConventions.Locks()
+	PresenceKeys = DefaultKeyConventions.Presence()
+	DrainingKeys = DefaultKeyConventions.Draining()
+)
@@ -0,0 +1,39 @@
+package consuladapter_test
+
+import (
+	"code.cloudfoundry.org/consuladapter"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("KeyPath", func() {
+	It("builds keys following the shared conventions", func() {
+		Expect(consuladapter.LockKeys.Key("bbs")).To(Equal("v1/locks/bbs"))
+		Expect(consuladapter.PresenceKeys.Key("cell-1")).To(Equal("v1/presence/cell-1"))
+		Expect(consuladapter.LockKeys.Prefix()).To(Equal("v1/locks/"))
+	})
+
+	It("joins segments without doubling slashes", func() {
+		path := consuladapter.NewKeyPath("/v1/", "routes/")
+		Expect(path.String()).To(Equal("v1/routes"))
+		Expect(path.Key("tcp", "/a")).To(Equal("v1/routes/tcp/a"))
+		Expect(path.Sub("tcp").Prefix()).To(Equal("v1/routes/tcp/"))
+	})
+
+	It("extracts names of keys under the path", func() {
+		name, ok := consuladapter.LockKeys.Name("v1/locks/bbs")
+		Expect(ok).To(BeTrue())
+		Expect(name).To(Equal("bbs"))
+
+		_, ok = consuladapter.LockKeys.Name("v1/presence/cell-1")
+		Expect(ok).To(BeFalse())
+		_, ok = consuladapter.LockKeys.Name("v1/locks/")
+		Expect(ok).To(BeFalse())
+	})
+
+	It("supports other roots", func() {
+		conventions := consuladapter.KeyConventions{Root: "v2"}
+		Expect(conventions.Locks().Key("bbs")).To(Equal("v2/locks/bbs"))
+	})
+})
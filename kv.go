@@ -8,12 +8,17 @@ type KV interface {
 	Get(key string, q *api.QueryOptions) (*api.KVPair, *api.QueryMeta, error)
 	List(prefix string, q *api.QueryOptions) (api.KVPairs, *api.QueryMeta, error)
 	Put(p *api.KVPair, q *api.WriteOptions) (*api.WriteMeta, error)
+	CAS(p *api.KVPair, q *api.WriteOptions) (bool, *api.WriteMeta, error)
+	Acquire(p *api.KVPair, q *api.WriteOptions) (bool, *api.WriteMeta, error)
 	Release(p *api.KVPair, q *api.WriteOptions) (bool, *api.WriteMeta, error)
+	DeleteCAS(p *api.KVPair, w *api.WriteOptions) (bool, *api.WriteMeta, error)
 	DeleteTree(prefix string, w *api.WriteOptions) (*api.WriteMeta, error)
 }
 
 type keyValue struct {
-	keyValue *api.KV
+	keyValue     *api.KV
+	maxValueSize int
+	readOnly     bool
 }
 
 func NewConsulKV(kv *api.KV) KV {
@@ -21,21 +26,111 @@ func NewConsulKV(kv *api.KV) KV {
 }
 
 func (kv *keyValue) Get(key string, q *api.QueryOptions) (*api.KVPair, *api.QueryMeta, error) {
-	return kv.keyValue.Get(key, q)
+	pair, qm, err := kv.keyValue.Get(key, q)
+	return pair, qm, AsPermissionDeniedError(err)
 }
 
 func (kv *keyValue) List(prefix string, q *api.QueryOptions) (api.KVPairs, *api.QueryMeta, error) {
-	return kv.keyValue.List(prefix, q)
+	pairs, qm, err := kv.keyValue.List(prefix, q)
+	return pairs, qm, AsPermissionDeniedError(err)
 }
 
 func (kv *keyValue) Put(p *api.KVPair, q *api.WriteOptions) (*api.WriteMeta, error) {
-	return kv.keyValue.Put(p, q)
+	if err := kv.checkWritable(p.Key); err != nil {
+		return nil, err
+	}
+	if err := checkValueSize(p, kv.maxValueSize); err != nil {
+		return nil, err
+	}
+	wm, err := kv.keyValue.Put(p, q)
+	return wm, AsPermissionDeniedError(err)
+}
+
+func (kv *keyValue) CAS(p *api.KVPair, q *api.WriteOptions) (bool, *api.WriteMeta, error) {
+	if err := kv.checkWritable(p.Key); err != nil {
+		return false, nil, err
+	}
+	if err := checkValueSize(p, kv.maxValueSize); err != nil {
+		return false, nil, err
+	}
+	ok, wm, err := kv.keyValue.CAS(p, q)
+	return ok, wm, AsPermissionDeniedError(err)
+}
+
+func (kv *keyValue) Acquire(p *api.KVPair, q *api.WriteOptions) (bool, *api.WriteMeta, error) {
+	if err := kv.checkWritable(p.Key); err != nil {
+		return false, nil, err
+	}
+	if err := checkValueSize(p, kv.maxValueSize); err != nil {
+		return false, nil, err
+	}
+	ok, wm, err := kv.keyValue.Acquire(p, q)
+	return ok, wm, AsPermissionDeniedError(err)
 }
 
 func (kv *keyValue) Release(p *api.KVPair, q *api.WriteOptions) (bool, *api.WriteMeta, error) {
-	return kv.keyValue.Release(p, q)
+	if err := kv.checkWritable(p.Key); err != nil {
+		return false, nil, err
+	}
+	ok, wm, err := kv.keyValue.Release(p, q)
+	return ok, wm, AsPermissionDeniedError(err)
+}
+
+func (kv *keyValue) DeleteCAS(p *api.KVPair, w *api.WriteOptions) (bool, *api.WriteMeta, error) {
+	if err := kv.checkWritable(p.Key); err != nil {
+		return false, nil, err
+	}
+	ok, wm, err := kv.keyValue.DeleteCAS(p, w)
+	return ok, wm, AsPermissionDeniedError(err)
 }
 
 func (kv *keyValue) DeleteTree(prefix string, w *api.WriteOptions) (*api.WriteMeta, error) {
-	return kv.keyValue.DeleteTree(prefix, w)
+	if err := kv.checkWritable(prefix); err != nil {
+		return nil, err
+	}
+	wm, err := kv.keyValue.DeleteTree(prefix, w)
+	return wm, AsPermissionDeniedError(err)
+}
+
+func (kv *keyValue) checkWritable(key string) error {
+	if kv.readOnly {
+		return ReadOnlyError{Path: key}
+	}
+	return nil
+}
+
+// DeleteIfIndex deletes key only if its ModifyIndex is still index, and
+// reports whether it was deleted.
+func DeleteIfIndex(kv KV, key string, index uint64) (bool, error) {
+	ok, _, err := kv.DeleteCAS(&api.KVPair{Key: key, ModifyIndex: index}, nil)
+	return ok, err
+}
+
+// DeleteTreeIfUnchanged deletes the keys under prefix that have not been
+// modified since snapshotIndex, e.g. the LastIndex of the read that decided
+// they should be cleaned up. Keys written later are left in place and
+// returned.
+func DeleteTreeIfUnchanged(kv KV, prefix string, snapshotIndex uint64) ([]string, error) {
+	pairs, _, err := kv.List(prefix, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var skipped []string
+	for _, pair := range pairs {
+		if pair.ModifyIndex > snapshotIndex {
+			skipped = append(skipped, pair.Key)
+			continue
+		}
+
+		deleted, err := DeleteIfIndex(kv, pair.Key, pair.ModifyIndex)
+		if err != nil {
+			return skipped, err
+		}
+		if !deleted {
+			skipped = append(skipped, pair.Key)
+		}
+	}
+
+	return skipped, nil
 }
@@ -0,0 +1,78 @@
+package consuladapter_test
+
+import (
+	"time"
+
+	"code.cloudfoundry.org/consuladapter"
+	"code.cloudfoundry.org/consuladapter/fakes"
+	"github.com/hashicorp/consul/api"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("DeleteTreeIfUnchanged", func() {
+	var kv *fakes.FakeKV
+
+	BeforeEach(func() {
+		kv = &fakes.FakeKV{}
+		kv.ListReturns(api.KVPairs{
+			{Key: "jobs/a", ModifyIndex: 5},
+			{Key: "jobs/b", ModifyIndex: 12},
+			{Key: "jobs/c", ModifyIndex: 8},
+		}, nil, nil)
+		kv.DeleteCASStub = func(p *api.KVPair, w *api.WriteOptions) (bool, *api.WriteMeta, error) {
+			return p.Key != "jobs/c", nil, nil
+		}
+	})
+
+	It("deletes only keys unchanged since the snapshot", func() {
+		skipped, err := consuladapter.DeleteTreeIfUnchanged(kv, "jobs/", 10)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(skipped).To(Equal([]string{"jobs/b", "jobs/c"}))
+
+		Expect(kv.DeleteCASCallCount()).To(Equal(2))
+		pair, _ := kv.DeleteCASArgsForCall(0)
+		Expect(pair).To(Equal(&api.KVPair{Key: "jobs/a", ModifyIndex: 5}))
+	})
+})
+
+var _ = Describe("Tombstones", func() {
+	var (
+		kv         *fakes.FakeKV
+		tombstones *consuladapter.Tombstones
+	)
+
+	BeforeEach(func() {
+		kv = &fakes.FakeKV{}
+		tombstones = consuladapter.NewTombstones(kv, time.Minute)
+	})
+
+	It("replaces deleted keys with a tombstone that readers report as deleted", func() {
+		Expect(tombstones.Delete("jobs/a")).To(Succeed())
+		tombstone, _ := kv.PutArgsForCall(0)
+		Expect(consuladapter.IsTombstone(tombstone)).To(BeTrue())
+
+		kv.GetReturns(tombstone, nil, nil)
+		pair, deleted, err := tombstones.Get("jobs/a", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pair).To(BeNil())
+		Expect(deleted).To(BeTrue())
+	})
+
+	It("filters tombstones from lists and purges expired ones", func() {
+		expired := []byte(time.Now().Add(-time.Second).UTC().Format(time.RFC3339))
+		kv.ListReturns(api.KVPairs{
+			{Key: "jobs/a", Value: []byte("live")},
+			{Key: "jobs/b", Flags: consuladapter.TombstoneFlag, Value: expired, ModifyIndex: 4},
+		}, nil, nil)
+		kv.DeleteCASReturns(true, nil, nil)
+
+		live, err := tombstones.List("jobs/", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(live).To(HaveLen(1))
+		Expect(live[0].Key).To(Equal("jobs/a"))
+
+		Expect(tombstones.Purge("jobs/")).To(Equal([]string{"jobs/b"}))
+	})
+})
@@ -0,0 +1,254 @@
+package consuladapter
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+type LifecycleEventKind string
+
+const (
+	SessionCreated   LifecycleEventKind = "session_created"
+	SessionDestroyed LifecycleEventKind = "session_destroyed"
+	LockAcquired     LifecycleEventKind = "lock_acquired"
+	LockReleased     LifecycleEventKind = "lock_released"
+	LockLost         LifecycleEventKind = "lock_lost"
+)
+
+type LifecycleEvent struct {
+	Time    time.Time          `json:"time"`
+	Kind    LifecycleEventKind `json:"kind"`
+	Session string             `json:"session,omitempty"`
+	Lock    string             `json:"lock,omitempty"`
+	Error   string             `json:"error,omitempty"`
+}
+
+type HeldLock struct {
+	Key        string    `json:"key"`
+	AcquiredAt time.Time `json:"acquired_at"`
+}
+
+type LifecycleSnapshot struct {
+	Sessions      []string          `json:"sessions"`
+	Locks         []HeldLock        `json:"locks"`
+	Events        []LifecycleEvent  `json:"events"`
+	DroppedEvents map[string]uint64 `json:"dropped_events"`
+}
+
+const defaultLifecycleHistory = 100
+
+// LifecycleRecorder keeps the sessions and locks a process currently holds,
+// and its most recent session and lock events, for operator tooling. It is
+// an http.Handler serving the snapshot as JSON; with a "lock" query
+// parameter it instead reports whether that lock is held, responding 404
+// if it is not.
+type LifecycleRecorder struct {
+	history int
+
+	mu       sync.RWMutex
+	sessions map[string]struct{}
+	locks    map[string]time.Time
+	events   []LifecycleEvent
+
+	closed    chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+}
+
+// NewLifecycleRecorder keeps up to history events; zero means 100.
+func NewLifecycleRecorder(history int) *LifecycleRecorder {
+	if history <= 0 {
+		history = defaultLifecycleHistory
+	}
+	return &LifecycleRecorder{
+		history:  history,
+		sessions: map[string]struct{}{},
+		locks:    map[string]time.Time{},
+		closed:   make(chan struct{}),
+	}
+}
+
+func (r *LifecycleRecorder) Record(event LifecycleEvent) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	switch event.Kind {
+	case SessionCreated:
+		r.sessions[event.Session] = struct{}{}
+	case SessionDestroyed:
+		delete(r.sessions, event.Session)
+	case LockAcquired:
+		r.locks[event.Lock] = event.Time
+	case LockReleased, LockLost:
+		delete(r.locks, event.Lock)
+	}
+
+	r.events = append(r.events, event)
+	if len(r.events) > r.history {
+		r.events = r.events[len(r.events)-r.history:]
+	}
+}
+
+// TrackLock records key as acquired, and as lost once lostLock is closed
+// unless it has been released first.
+func (r *LifecycleRecorder) TrackLock(key string, lostLock <-chan struct{}) {
+	r.Record(LifecycleEvent{Kind: LockAcquired, Lock: key})
+
+	goBackground(&r.wg, func() {
+		select {
+		case <-lostLock:
+		case <-r.closed:
+			return
+		}
+		if r.HoldsLock(key) {
+			r.Record(LifecycleEvent{Kind: LockLost, Lock: key})
+		}
+	}, nil)
+}
+
+// Close stops tracking locks and waits for the goroutines watching them to
+// exit.
+func (r *LifecycleRecorder) Close() {
+	r.closeOnce.Do(func() { close(r.closed) })
+	r.wg.Wait()
+}
+
+func (r *LifecycleRecorder) HoldsLock(key string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	_, ok := r.locks[key]
+	return ok
+}
+
+func (r *LifecycleRecorder) Snapshot() LifecycleSnapshot {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	snapshot := LifecycleSnapshot{
+		Sessions: []string{},
+		Locks:    []HeldLock{},
+		Events:   append([]LifecycleEvent{}, r.events...),
+
+		DroppedEvents: DroppedEvents(),
+	}
+	for id := range r.sessions {
+		snapshot.Sessions = append(snapshot.Sessions, id)
+	}
+	for key, acquiredAt := range r.locks {
+		snapshot.Locks = append(snapshot.Locks, HeldLock{Key: key, AcquiredAt: acquiredAt})
+	}
+	sort.Strings(snapshot.Sessions)
+	sort.Slice(snapshot.Locks, func(i, j int) bool { return snapshot.Locks[i].Key < snapshot.Locks[j].Key })
+
+	return snapshot
+}
+
+func (r *LifecycleRecorder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	key := req.URL.Query().Get("lock")
+	if key == "" {
+		json.NewEncoder(w).Encode(r.Snapshot())
+		return
+	}
+
+	held := r.HoldsLock(key)
+	if !held {
+		w.WriteHeader(http.StatusNotFound)
+	}
+	json.NewEncoder(w).Encode(struct {
+		Key  string `json:"key"`
+		Held bool   `json:"held"`
+	}{key, held})
+}
+
+var lifecycleRecorder struct {
+	mutex    sync.RWMutex
+	recorder *LifecycleRecorder
+}
+
+// SetLifecycleRecorder records the adapter's TTL sessions, from
+// CreateTTLSession and NewTTLSession, and the locks held by LockRunners to
+// recorder. Locks taken with AcquireLock or the Client directly are not
+// recorded, as the adapter cannot tell when they are released; use
+// TrackLock for those. Nothing is recorded by default.
+func SetLifecycleRecorder(recorder *LifecycleRecorder) {
+	lifecycleRecorder.mutex.Lock()
+	defer lifecycleRecorder.mutex.Unlock()
+	lifecycleRecorder.recorder = recorder
+}
+
+func currentLifecycleRecorder() *LifecycleRecorder {
+	lifecycleRecorder.mutex.RLock()
+	defer lifecycleRecorder.mutex.RUnlock()
+	return lifecycleRecorder.recorder
+}
+
+func recordLifecycle(event LifecycleEvent) {
+	if recorder := currentLifecycleRecorder(); recorder != nil {
+		recorder.Record(event)
+	}
+}
+
+// recordSessionEnd records the session as destroyed once its renewal
+// stops, with the renewal error if it was lost.
+func recordSessionEnd(id string, err error) {
+	event := LifecycleEvent{Kind: SessionDestroyed, Session: id}
+	if err != nil {
+		event.Error = err.Error()
+	}
+	recordLifecycle(event)
+}
+
+// DebugServer serves a LifecycleRecorder on a local address or unix socket.
+type DebugServer struct {
+	listener net.Listener
+	server   *http.Server
+	wg       sync.WaitGroup
+	serveErr error
+}
+
+// NewDebugServer listens on network and address, e.g. "unix" and
+// "/var/vcap/sys/run/locket/consul.sock", or "tcp" and "127.0.0.1:0".
+func NewDebugServer(network, address string, recorder *LifecycleRecorder) (*DebugServer, error) {
+	listener, err := net.Listen(network, address)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &DebugServer{
+		listener: listener,
+		server:   &http.Server{Handler: recorder},
+	}
+	goBackground(&s.wg, func() {
+		err := s.server.Serve(listener)
+		if err != http.ErrServerClosed {
+			s.serveErr = err
+		}
+	}, nil)
+
+	return s, nil
+}
+
+func (s *DebugServer) Addr() net.Addr {
+	return s.listener.Addr()
+}
+
+// Close stops the server and waits for it to exit. It returns the error
+// that stopped it serving early, if any, e.g. from accepting connections.
+func (s *DebugServer) Close() error {
+	err := s.server.Close()
+	s.wg.Wait()
+	if s.serveErr != nil {
+		return s.serveErr
+	}
+	return err
+}
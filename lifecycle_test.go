@@ -0,0 +1,161 @@
+package consuladapter_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"time"
+
+	"code.cloudfoundry.org/consuladapter"
+	"code.cloudfoundry.org/consuladapter/fakes"
+	"github.com/hashicorp/consul/api"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("LifecycleRecorder", func() {
+	var recorder *consuladapter.LifecycleRecorder
+
+	BeforeEach(func() {
+		recorder = consuladapter.NewLifecycleRecorder(2)
+	})
+
+	It("tracks held locks until they are lost", func() {
+		lostLock := make(chan struct{})
+		recorder.TrackLock("locks/a", lostLock)
+		Expect(recorder.HoldsLock("locks/a")).To(BeTrue())
+
+		close(lostLock)
+		Eventually(func() bool { return recorder.HoldsLock("locks/a") }).Should(BeFalse())
+		Expect(recorder.Snapshot().Events[1].Kind).To(Equal(consuladapter.LockLost))
+	})
+
+	It("keeps only the most recent events", func() {
+		recorder.Record(consuladapter.LifecycleEvent{Kind: consuladapter.SessionCreated, Session: "s1"})
+		recorder.Record(consuladapter.LifecycleEvent{Kind: consuladapter.SessionCreated, Session: "s2"})
+		recorder.Record(consuladapter.LifecycleEvent{Kind: consuladapter.SessionDestroyed, Session: "s1"})
+
+		snapshot := recorder.Snapshot()
+		Expect(snapshot.Sessions).To(Equal([]string{"s2"}))
+		Expect(snapshot.Events).To(HaveLen(2))
+		Expect(snapshot.Events[0].Session).To(Equal("s2"))
+	})
+
+	Describe("ServeHTTP", func() {
+		BeforeEach(func() {
+			recorder.Record(consuladapter.LifecycleEvent{Kind: consuladapter.LockAcquired, Lock: "locks/a"})
+		})
+
+		It("reports whether a lock is held", func() {
+			response := httptest.NewRecorder()
+			recorder.ServeHTTP(response, httptest.NewRequest("GET", "/?lock=locks/a", nil))
+			Expect(response.Code).To(Equal(http.StatusOK))
+			Expect(response.Body.String()).To(MatchJSON(`{"key":"locks/a","held":true}`))
+
+			response = httptest.NewRecorder()
+			recorder.ServeHTTP(response, httptest.NewRequest("GET", "/?lock=locks/b", nil))
+			Expect(response.Code).To(Equal(http.StatusNotFound))
+		})
+
+		It("serves the snapshot as JSON", func() {
+			response := httptest.NewRecorder()
+			recorder.ServeHTTP(response, httptest.NewRequest("GET", "/", nil))
+
+			var snapshot consuladapter.LifecycleSnapshot
+			Expect(json.Unmarshal(response.Body.Bytes(), &snapshot)).To(Succeed())
+			Expect(snapshot.Locks).To(HaveLen(1))
+			Expect(snapshot.Locks[0].Key).To(Equal("locks/a"))
+		})
+	})
+
+	Describe("SetLifecycleRecorder", func() {
+		var backend *fakes.FakeBackend
+
+		BeforeEach(func() {
+			backend = fakes.NewFakeBackend()
+			consuladapter.SetLifecycleRecorder(recorder)
+		})
+
+		AfterEach(func() {
+			consuladapter.SetLifecycleRecorder(nil)
+			recorder.Close()
+		})
+
+		It("records TTL sessions until they are destroyed", func() {
+			session, err := consuladapter.NewTTLSession(backend.Session(), &api.SessionEntry{TTL: "10s"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(recorder.Snapshot().Sessions).To(Equal([]string{session.ID()}))
+
+			Expect(session.Destroy()).To(Succeed())
+			Expect(recorder.Snapshot().Sessions).To(BeEmpty())
+		})
+
+		It("records the errors of lost TTL sessions", func() {
+			session, err := consuladapter.NewTTLSession(backend.Session(), &api.SessionEntry{TTL: "20ms"})
+			Expect(err).NotTo(HaveOccurred())
+
+			backend.Expire(session.ID())
+			Eventually(session.Lost()).Should(BeClosed())
+
+			events := recorder.Snapshot().Events
+			Expect(events[len(events)-1].Kind).To(Equal(consuladapter.SessionDestroyed))
+			Expect(events[len(events)-1].Error).NotTo(BeEmpty())
+		})
+
+		It("records the locks held by LockRunners until they are released", func() {
+			client, _ := backend.Client()
+			runner := consuladapter.NewLockRunner(client, api.LockOptions{Key: "locks/a"}, consuladapter.FixedRetry{Interval: 10 * time.Millisecond})
+			signals := make(chan os.Signal, 1)
+			ready := make(chan struct{})
+			done := make(chan error, 1)
+			go func() {
+				done <- runner.Run(signals, ready)
+			}()
+
+			Eventually(ready).Should(BeClosed())
+			Expect(recorder.HoldsLock("locks/a")).To(BeTrue())
+
+			signals <- os.Interrupt
+			Eventually(done).Should(Receive())
+			Expect(recorder.HoldsLock("locks/a")).To(BeFalse())
+			Consistently(func() consuladapter.LifecycleEventKind {
+				events := recorder.Snapshot().Events
+				return events[len(events)-1].Kind
+			}).Should(Equal(consuladapter.LockReleased))
+		})
+	})
+})
+
+var _ = Describe("DebugServer", func() {
+	It("serves the recorder until it is closed", func() {
+		recorder := consuladapter.NewLifecycleRecorder(0)
+		recorder.Record(consuladapter.LifecycleEvent{Kind: consuladapter.LockAcquired, Lock: "locks/a"})
+
+		server, err := consuladapter.NewDebugServer("tcp", "127.0.0.1:0", recorder)
+		Expect(err).NotTo(HaveOccurred())
+
+		response, err := http.Get("http://" + server.Addr().String() + "/?lock=locks/a")
+		Expect(err).NotTo(HaveOccurred())
+		body, err := ioutil.ReadAll(response.Body)
+		response.Body.Close()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(response.StatusCode).To(Equal(http.StatusOK))
+		Expect(body).To(MatchJSON(`{"key":"locks/a","held":true}`))
+
+		Expect(server.Close()).To(Succeed())
+		_, err = http.Get("http://" + server.Addr().String() + "/")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("fails to listen on an address in use", func() {
+		server, err := consuladapter.NewDebugServer("tcp", "127.0.0.1:0", consuladapter.NewLifecycleRecorder(0))
+		Expect(err).NotTo(HaveOccurred())
+		defer server.Close()
+
+		_, err = consuladapter.NewDebugServer("tcp", server.Addr().String(), consuladapter.NewLifecycleRecorder(0))
+		Expect(err).To(HaveOccurred())
+	})
+})
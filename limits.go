@@ -0,0 +1,72 @@
+package consuladapter
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/hashicorp/consul/api"
+)
+
+type responseLimitTransport struct {
+	transport http.RoundTripper
+	limit     int64
+}
+
+func newResponseLimitTransport(transport http.RoundTripper, limit int64) http.RoundTripper {
+	return &responseLimitTransport{transport: transport, limit: limit}
+}
+
+func (t *responseLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	body := &limitedBody{
+		body:      resp.Body,
+		remaining: t.limit,
+		err:       ResponseTooLargeError{Path: req.URL.Path, Limit: t.limit},
+	}
+	if resp.ContentLength > t.limit {
+		// fail on the first read, so the error reaches the caller unwrapped
+		body.remaining = -1
+	}
+
+	resp.Body = body
+	return resp, nil
+}
+
+// limitedBody fails reads with err once more than the limit has been read,
+// rather than silently truncating like io.LimitReader.
+type limitedBody struct {
+	body      io.ReadCloser
+	remaining int64
+	err       error
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.remaining < 0 {
+		return 0, b.err
+	}
+	if int64(len(p)) > b.remaining+1 {
+		p = p[:b.remaining+1]
+	}
+
+	n, err := b.body.Read(p)
+	b.remaining -= int64(n)
+	if b.remaining < 0 {
+		return 0, b.err
+	}
+	return n, err
+}
+
+func (b *limitedBody) Close() error {
+	return b.body.Close()
+}
+
+func checkValueSize(p *api.KVPair, limit int) error {
+	if limit > 0 && p != nil && len(p.Value) > limit {
+		return ValueTooLargeError{Key: p.Key, Size: len(p.Value), Limit: limit}
+	}
+	return nil
+}
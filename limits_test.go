@@ -0,0 +1,47 @@
+package consuladapter_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"code.cloudfoundry.org/consuladapter"
+	"github.com/hashicorp/consul/api"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("size limits", func() {
+	var (
+		server *httptest.Server
+		client consuladapter.Client
+	)
+
+	BeforeEach(func() {
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`[{"Key":"key","Value":"` + strings.Repeat("a", 1024) + `"}]`))
+		}))
+
+		var err error
+		client, err = consuladapter.NewClientFromUrlWithOptions(server.URL, consuladapter.ClientOptions{
+			MaxValueSize:    8,
+			MaxResponseSize: 512,
+		})
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("rejects oversized values before writing them", func() {
+		_, err := client.KV().Put(&api.KVPair{Key: "key", Value: []byte("123456789")}, nil)
+		Expect(err).To(Equal(consuladapter.ValueTooLargeError{Key: "key", Size: 9, Limit: 8}))
+	})
+
+	It("fails reads of oversized responses", func() {
+		_, _, err := client.KV().Get("key", nil)
+		Expect(err).To(Equal(consuladapter.ResponseTooLargeError{Path: "/v1/kv/key", Limit: 512}))
+	})
+})
@@ -0,0 +1,228 @@
+package lockhistory
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+const (
+	PropertyMutualExclusion = "mutual-exclusion"
+	PropertyLiveness        = "liveness"
+	PropertyWellFormed      = "well-formed"
+)
+
+type Violation struct {
+	Property string
+	Client   string
+	At       time.Duration
+	Detail   string
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("%s violated by %s at %s: %s", v.Property, v.Client, v.At, v.Detail)
+}
+
+// Checker verifies a lock history.
+//
+// Mutual exclusion: a client certainly holds the lock from when its acquire
+// returns until it invokes release or observes the loss, and no two such
+// intervals may overlap.
+//
+// Liveness: while a client waits to acquire, the lock must not stay free for
+// longer than MaxIdleWait in total. Releases are only known to have taken
+// effect once they return, and losses once observed, so the lock counts as
+// free from then. Zero disables the check.
+type Checker struct {
+	MaxIdleWait time.Duration
+}
+
+type interval struct {
+	client     string
+	start, end time.Duration
+}
+
+func (c Checker) Check(events []Event) []Violation {
+	events = append([]Event(nil), events...)
+	sort.SliceStable(events, func(i, j int) bool { return events[i].At < events[j].At })
+
+	var end time.Duration
+	if len(events) > 0 {
+		end = events[len(events)-1].At
+	}
+
+	holds, free, violations := c.intervals(events, end)
+	violations = append(violations, c.checkExclusion(holds)...)
+	if c.MaxIdleWait > 0 {
+		violations = append(violations, c.checkLiveness(events, free, end)...)
+	}
+	return violations
+}
+
+// intervals returns, per client, when it certainly held the lock and when
+// it certainly did not. The latter runs from the start of the history, a
+// completed release or an observed loss to the next acquire.
+func (c Checker) intervals(events []Event, end time.Duration) ([]interval, map[string][]interval, []Violation) {
+	var (
+		holds      []interval
+		violations []Violation
+		free       = map[string][]interval{}
+		holding    = map[string]time.Duration{}
+		releasing  = map[string]bool{}
+		freeSince  = map[string]time.Duration{}
+		seen       = map[string]bool{}
+	)
+
+	for _, event := range events {
+		if !seen[event.Client] {
+			seen[event.Client] = true
+			freeSince[event.Client] = 0
+		}
+		start, held := holding[event.Client]
+
+		switch event.Kind {
+		case Acquired:
+			if held {
+				violations = append(violations, Violation{PropertyWellFormed, event.Client, event.At, "acquired a lock it already holds"})
+				continue
+			}
+			holding[event.Client] = event.At
+			if since, ok := freeSince[event.Client]; ok {
+				free[event.Client] = append(free[event.Client], interval{event.Client, since, event.At})
+				delete(freeSince, event.Client)
+			}
+
+		case ReleaseInvoked, Lost:
+			if !held {
+				if event.Kind == ReleaseInvoked && !releasing[event.Client] {
+					violations = append(violations, Violation{PropertyWellFormed, event.Client, event.At, "released a lock it does not hold"})
+				}
+				continue
+			}
+			holds = append(holds, interval{event.Client, start, event.At})
+			delete(holding, event.Client)
+			if event.Kind == ReleaseInvoked {
+				releasing[event.Client] = true
+			} else {
+				freeSince[event.Client] = event.At
+			}
+
+		case Released:
+			if releasing[event.Client] {
+				delete(releasing, event.Client)
+				freeSince[event.Client] = event.At
+			}
+		}
+	}
+
+	for client, start := range holding {
+		holds = append(holds, interval{client, start, end})
+	}
+	for client, since := range freeSince {
+		free[client] = append(free[client], interval{client, since, end})
+	}
+
+	return holds, free, violations
+}
+
+func (c Checker) checkExclusion(holds []interval) []Violation {
+	sort.Slice(holds, func(i, j int) bool { return holds[i].start < holds[j].start })
+
+	var violations []Violation
+	for i := range holds {
+		for j := i + 1; j < len(holds) && holds[j].start < holds[i].end; j++ {
+			if holds[j].client == holds[i].client {
+				continue
+			}
+			violations = append(violations, Violation{
+				Property: PropertyMutualExclusion,
+				Client:   holds[j].client,
+				At:       holds[j].start,
+				Detail:   fmt.Sprintf("acquired while %s held the lock (since %s)", holds[i].client, holds[i].start),
+			})
+		}
+	}
+	return violations
+}
+
+// checkLiveness measures, for each wait, how long the lock was certainly
+// free: times when every client that ever acquired it had certainly let it
+// go.
+func (c Checker) checkLiveness(events []Event, free map[string][]interval, end time.Duration) []Violation {
+	acquirers := map[string]bool{}
+	for _, event := range events {
+		if event.Kind == Acquired {
+			acquirers[event.Client] = true
+		}
+	}
+
+	freeAt := func(t time.Duration) bool {
+		for client := range acquirers {
+			if !covered(free[client], t) {
+				return false
+			}
+		}
+		return true
+	}
+
+	var violations []Violation
+	waiting := map[string]time.Duration{}
+	check := func(client string, from, to time.Duration) {
+		idle := idleTime(events, from, to, freeAt)
+		if idle > c.MaxIdleWait {
+			violations = append(violations, Violation{
+				Property: PropertyLiveness,
+				Client:   client,
+				At:       from,
+				Detail:   fmt.Sprintf("waited while the lock was free for %s", idle),
+			})
+		}
+	}
+
+	for _, event := range events {
+		switch event.Kind {
+		case AcquireInvoked:
+			waiting[event.Client] = event.At
+		case Acquired, AcquireFailed:
+			if from, ok := waiting[event.Client]; ok {
+				check(event.Client, from, event.At)
+				delete(waiting, event.Client)
+			}
+		}
+	}
+	for client, from := range waiting {
+		check(client, from, end)
+	}
+
+	return violations
+}
+
+func covered(intervals []interval, t time.Duration) bool {
+	for _, i := range intervals {
+		if i.start <= t && t < i.end {
+			return true
+		}
+	}
+	return false
+}
+
+// idleTime sums the segments between event times in [from, to) during which
+// freeAt holds. Lock state only changes at events, so sampling the start of
+// each segment is exact.
+func idleTime(events []Event, from, to time.Duration, freeAt func(time.Duration) bool) time.Duration {
+	points := []time.Duration{from}
+	for _, event := range events {
+		if event.At > from && event.At < to {
+			points = append(points, event.At)
+		}
+	}
+	points = append(points, to)
+
+	var idle time.Duration
+	for i := 0; i+1 < len(points); i++ {
+		if freeAt(points[i]) {
+			idle += points[i+1] - points[i]
+		}
+	}
+	return idle
+}
@@ -0,0 +1,95 @@
+package lockhistory_test
+
+import (
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/consuladapter/fakes"
+	"code.cloudfoundry.org/consuladapter/lockhistory"
+	"github.com/hashicorp/consul/api"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Checker", func() {
+	event := func(client string, kind lockhistory.EventKind, at int) lockhistory.Event {
+		return lockhistory.Event{Client: client, Kind: kind, At: time.Duration(at) * time.Second}
+	}
+
+	checker := lockhistory.Checker{MaxIdleWait: 2 * time.Second}
+
+	It("accepts a history of handovers", func() {
+		Expect(checker.Check([]lockhistory.Event{
+			event("a", lockhistory.AcquireInvoked, 0),
+			event("a", lockhistory.Acquired, 1),
+			event("b", lockhistory.AcquireInvoked, 2),
+			event("a", lockhistory.ReleaseInvoked, 5),
+			event("a", lockhistory.Released, 6),
+			event("b", lockhistory.Acquired, 6),
+			event("b", lockhistory.Lost, 9),
+		})).To(BeEmpty())
+	})
+
+	It("reports overlapping holders", func() {
+		violations := checker.Check([]lockhistory.Event{
+			event("a", lockhistory.Acquired, 1),
+			event("b", lockhistory.Acquired, 3),
+			event("a", lockhistory.Lost, 4),
+		})
+		Expect(violations).To(HaveLen(1))
+		Expect(violations[0].Property).To(Equal(lockhistory.PropertyMutualExclusion))
+		Expect(violations[0].Client).To(Equal("b"))
+		Expect(violations[0].At).To(Equal(3 * time.Second))
+	})
+
+	It("reports waiters left behind by a free lock", func() {
+		violations := checker.Check([]lockhistory.Event{
+			event("a", lockhistory.Acquired, 0),
+			event("b", lockhistory.AcquireInvoked, 1),
+			event("a", lockhistory.ReleaseInvoked, 2),
+			event("a", lockhistory.Released, 3),
+			event("b", lockhistory.Acquired, 10),
+		})
+		Expect(violations).To(HaveLen(1))
+		Expect(violations[0].Property).To(Equal(lockhistory.PropertyLiveness))
+		Expect(violations[0].Detail).To(ContainSubstring("7s"))
+	})
+
+	It("reports releases of locks that are not held", func() {
+		violations := checker.Check([]lockhistory.Event{
+			event("a", lockhistory.ReleaseInvoked, 0),
+		})
+		Expect(violations).To(HaveLen(1))
+		Expect(violations[0].Property).To(Equal(lockhistory.PropertyWellFormed))
+	})
+
+	It("checks histories recorded from contending clients", func() {
+		backend := fakes.NewFakeBackend()
+		history := lockhistory.NewHistory()
+
+		var wg sync.WaitGroup
+		for _, client := range []string{"a", "b", "c"} {
+			wg.Add(1)
+			go func(client string) {
+				defer GinkgoRecover()
+				defer wg.Done()
+
+				for i := 0; i < 5; i++ {
+					lock, err := backend.LockOpts(&api.LockOptions{Key: "lock"})
+					Expect(err).NotTo(HaveOccurred())
+					lock = history.Lock(client, lock)
+
+					_, err = lock.Lock(nil)
+					Expect(err).NotTo(HaveOccurred())
+					time.Sleep(time.Millisecond)
+					Expect(lock.Unlock()).To(Succeed())
+				}
+			}(client)
+		}
+		wg.Wait()
+
+		Expect(history.Events()).To(HaveLen(3 * 5 * 4))
+		Expect(lockhistory.Checker{MaxIdleWait: time.Second}.Check(history.Events())).To(BeEmpty())
+	})
+})
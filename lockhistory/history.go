@@ -0,0 +1,119 @@
+package lockhistory
+
+import (
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/consuladapter"
+)
+
+type EventKind string
+
+const (
+	AcquireInvoked EventKind = "acquire-invoked"
+	Acquired       EventKind = "acquired"
+	AcquireFailed  EventKind = "acquire-failed"
+	ReleaseInvoked EventKind = "release-invoked"
+	Released       EventKind = "released"
+	Lost           EventKind = "lost"
+)
+
+// Event is one step of a client's interaction with a lock. At is measured
+// from the start of the history on the recording process's monotonic clock,
+// so histories from concurrent clients in one process are comparable.
+type Event struct {
+	Client string
+	Kind   EventKind
+	At     time.Duration
+	Err    string
+}
+
+// History records the lock events of concurrent clients.
+type History struct {
+	mutex  sync.Mutex
+	start  time.Time
+	events []Event
+}
+
+func NewHistory() *History {
+	return &History{start: time.Now()}
+}
+
+func (h *History) Record(client string, kind EventKind, err error) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	event := Event{Client: client, Kind: kind, At: time.Since(h.start)}
+	if err != nil {
+		event.Err = err.Error()
+	}
+	h.events = append(h.events, event)
+}
+
+// Events returns the recorded events in the order they were recorded.
+func (h *History) Events() []Event {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return append([]Event(nil), h.events...)
+}
+
+// Lock wraps lock so that its acquisitions, releases and losses are recorded
+// for client. Wrap a separate lock for each concurrent client.
+func (h *History) Lock(client string, lock consuladapter.Lock) consuladapter.Lock {
+	return &recordingLock{history: h, client: client, lock: lock}
+}
+
+type recordingLock struct {
+	history *History
+	client  string
+	lock    consuladapter.Lock
+
+	mutex     sync.Mutex
+	releasing chan struct{}
+}
+
+func (l *recordingLock) Lock(stopCh <-chan struct{}) (<-chan struct{}, error) {
+	l.history.Record(l.client, AcquireInvoked, nil)
+
+	lost, err := l.lock.Lock(stopCh)
+	if err != nil || lost == nil {
+		l.history.Record(l.client, AcquireFailed, err)
+		return lost, err
+	}
+	l.history.Record(l.client, Acquired, nil)
+
+	releasing := make(chan struct{})
+	l.mutex.Lock()
+	l.releasing = releasing
+	l.mutex.Unlock()
+
+	recorded := make(chan struct{})
+	go func() {
+		defer close(recorded)
+		<-lost
+		// the lost channel is also closed by Unlock, which is not a loss
+		select {
+		case <-releasing:
+		default:
+			l.history.Record(l.client, Lost, nil)
+		}
+	}()
+
+	return recorded, nil
+}
+
+func (l *recordingLock) Unlock() error {
+	l.mutex.Lock()
+	if l.releasing != nil {
+		close(l.releasing)
+		l.releasing = nil
+	}
+	l.mutex.Unlock()
+
+	l.history.Record(l.client, ReleaseInvoked, nil)
+	err := l.lock.Unlock()
+	if err == nil {
+		l.history.Record(l.client, Released, nil)
+	}
+	return err
+}
@@ -0,0 +1,13 @@
+package lockhistory_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestLockHistory(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Lock History Suite")
+}
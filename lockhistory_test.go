@@ -0,0 +1,110 @@
+package consuladapter_test
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/consuladapter/consulrunner"
+	"code.cloudfoundry.org/consuladapter/lockhistory"
+	"github.com/hashicorp/consul/api"
+	"github.com/tedsuo/ifrit"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Lock history against a cluster", func() {
+	BeforeEach(func() {
+		clusterRunner.Start()
+		clusterRunner.WaitUntilReady()
+	})
+
+	AfterEach(func() {
+		clusterRunner.Stop()
+	})
+
+	It("keeps contending clients mutually exclusive", func() {
+		history := lockhistory.NewHistory()
+
+		var wg sync.WaitGroup
+		for i := 0; i < 3; i++ {
+			wg.Add(1)
+			go func(name string) {
+				defer GinkgoRecover()
+				defer wg.Done()
+
+				client := clusterRunner.NewClient()
+				for j := 0; j < 3; j++ {
+					lock, err := client.LockOpts(&api.LockOptions{Key: "lock-history", SessionTTL: clusterRunner.SessionTTL().String()})
+					Expect(err).NotTo(HaveOccurred())
+					lock = history.Lock(name, lock)
+
+					_, err = lock.Lock(nil)
+					Expect(err).NotTo(HaveOccurred())
+					time.Sleep(50 * time.Millisecond)
+					Expect(lock.Unlock()).To(Succeed())
+				}
+			}(fmt.Sprintf("client-%d", i))
+		}
+		wg.Wait()
+
+		checker := lockhistory.Checker{MaxIdleWait: 5 * time.Second}
+		Expect(checker.Check(history.Events())).To(BeEmpty())
+	})
+
+	It("keeps contending clients mutually exclusive while agents restart", func() {
+		history := lockhistory.NewHistory()
+		chaos := ifrit.Invoke(clusterRunner.ChaosRunner(consulrunner.ChaosConfig{
+			Interval: time.Second,
+			Downtime: 500 * time.Millisecond,
+			Seed:     GinkgoRandomSeed(),
+		}))
+
+		stop := make(chan struct{})
+		var wg sync.WaitGroup
+		for i := 0; i < 3; i++ {
+			wg.Add(1)
+			go func(name string) {
+				defer GinkgoRecover()
+				defer wg.Done()
+
+				client := clusterRunner.NewClient()
+				for {
+					select {
+					case <-stop:
+						return
+					default:
+					}
+
+					lock, err := client.LockOpts(&api.LockOptions{Key: "lock-history-chaos", SessionTTL: clusterRunner.SessionTTL().String()})
+					Expect(err).NotTo(HaveOccurred())
+					lock = history.Lock(name, lock)
+
+					lost, err := lock.Lock(stop)
+					if err != nil || lost == nil {
+						// the agent is down; try again once it may be back
+						time.Sleep(100 * time.Millisecond)
+						continue
+					}
+					select {
+					case <-lost:
+					case <-time.After(50 * time.Millisecond):
+					}
+					lock.Unlock()
+				}
+			}(fmt.Sprintf("client-%d", i))
+		}
+
+		time.Sleep(5 * time.Second)
+		chaos.Signal(os.Interrupt)
+		Eventually(chaos.Wait(), 5*time.Second).Should(Receive(BeNil()))
+		close(stop)
+		wg.Wait()
+
+		// liveness is not expected to hold while agents are down
+		checker := lockhistory.Checker{}
+		Expect(checker.Check(history.Events())).To(BeEmpty())
+	})
+})
@@ -0,0 +1,89 @@
+package consuladapter
+
+import (
+	"time"
+
+	"github.com/hashicorp/consul/api"
+)
+
+type LockProgress struct {
+	Key     string
+	Reports int
+	Waited  time.Duration
+
+	// Holder is the session currently holding the key and HolderValue the
+	// value it wrote, when they can be read.
+	Holder      *api.SessionEntry
+	HolderValue []byte
+	Err         error
+}
+
+// LockWithProgress acquires the lock described by opts like Lock.Lock, calling
+// progress every interval while it waits. progress is never called after
+// LockWithProgress returns.
+func LockWithProgress(
+	client Client,
+	opts *api.LockOptions,
+	stopCh <-chan struct{},
+	interval time.Duration,
+	progress func(LockProgress),
+) (<-chan struct{}, error) {
+	if err := checkReentrant("LockWithProgress", callbackLockProgress); err != nil {
+		return nil, err
+	}
+
+	lock, err := client.LockOpts(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	done := make(chan struct{})
+	exited := make(chan struct{})
+	goBackground(nil, func() {
+		defer close(exited)
+		reportLockProgress(client, opts.Key, interval, progress, done)
+	}, nil)
+
+	lostLock, err := lock.Lock(stopCh)
+	close(done)
+	waitForCallback("LockWithProgress", callbackLockProgress, exited)
+
+	return lostLock, err
+}
+
+func reportLockProgress(client Client, key string, interval time.Duration, progress func(LockProgress), done <-chan struct{}) {
+	start := time.Now()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	reports := 0
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+
+		reports++
+		report := LockProgress{
+			Key:     key,
+			Reports: reports,
+			Waited:  time.Since(start),
+		}
+
+		pair, _, err := client.KV().Get(key, nil)
+		if err != nil {
+			report.Err = err
+		} else if pair != nil && pair.Session != "" {
+			report.HolderValue = pair.Value
+			report.Holder, _, report.Err = client.Session().Info(pair.Session, nil)
+		}
+
+		select {
+		case <-done:
+			return
+		default:
+			runCallback(callbackLockProgress, func() { progress(report) })
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package consuladapter_test
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/consuladapter"
+	"code.cloudfoundry.org/consuladapter/fakes"
+	"github.com/hashicorp/consul/api"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("LockWithProgress", func() {
+	var (
+		client     *fakes.FakeClient
+		components *fakes.FakeClientComponents
+		lock       *fakes.FakeLock
+		release    chan struct{}
+
+		mutex   sync.Mutex
+		reports []consuladapter.LockProgress
+	)
+
+	BeforeEach(func() {
+		client, components = fakes.NewFakeClient()
+		lock = &fakes.FakeLock{}
+		client.LockOptsReturns(lock, nil)
+
+		release = make(chan struct{})
+		lock.LockStub = func(stopCh <-chan struct{}) (<-chan struct{}, error) {
+			<-release
+			return make(chan struct{}), nil
+		}
+
+		components.KV.GetReturns(&api.KVPair{Key: "the-key", Value: []byte("holder"), Session: "session-id"}, nil, nil)
+		components.Session.InfoReturns(&api.SessionEntry{ID: "session-id", Name: "other-component"}, nil, nil)
+
+		reports = nil
+	})
+
+	progress := func(report consuladapter.LockProgress) {
+		mutex.Lock()
+		reports = append(reports, report)
+		mutex.Unlock()
+	}
+
+	currentReports := func() []consuladapter.LockProgress {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return append([]consuladapter.LockProgress{}, reports...)
+	}
+
+	It("reports the current holder while waiting", func() {
+		errCh := make(chan error)
+		go func() {
+			_, err := consuladapter.LockWithProgress(client, &api.LockOptions{Key: "the-key"}, nil, 10*time.Millisecond, progress)
+			errCh <- err
+		}()
+
+		Eventually(func() int { return len(currentReports()) }).Should(BeNumerically(">=", 2))
+		close(release)
+		Eventually(errCh).Should(Receive(BeNil()))
+
+		report := currentReports()[1]
+		Expect(report.Key).To(Equal("the-key"))
+		Expect(report.Reports).To(Equal(2))
+		Expect(report.Waited).To(BeNumerically(">", 0))
+		Expect(report.Holder.Name).To(Equal("other-component"))
+		Expect(report.HolderValue).To(Equal([]byte("holder")))
+
+		Expect(components.Session.InfoArgsForCall(0)).To(Equal("session-id"))
+	})
+
+	It("stops reporting once the lock is acquired", func() {
+		close(release)
+		_, err := consuladapter.LockWithProgress(client, &api.LockOptions{Key: "the-key"}, nil, 10*time.Millisecond, progress)
+		Expect(err).NotTo(HaveOccurred())
+
+		Consistently(func() int { return len(currentReports()) }, 50*time.Millisecond).Should(Equal(0))
+	})
+
+	It("returns errors creating the lock", func() {
+		client.LockOptsReturns(nil, errors.New("boom"))
+		_, err := consuladapter.LockWithProgress(client, &api.LockOptions{Key: "the-key"}, nil, 10*time.Millisecond, progress)
+		Expect(err).To(MatchError("boom"))
+	})
+})
@@ -0,0 +1,125 @@
+package consuladapter
+
+import (
+	"time"
+
+	"github.com/hashicorp/consul/api"
+)
+
+const minLockWaitTime = time.Millisecond
+
+// DefaultLockRetryStrategy is used by AcquireLock when no strategy is given.
+// It wakes waiters as soon as the lock is released, falling back to polling
+// every second if blocking queries on the lock key fail.
+var DefaultLockRetryStrategy LockRetryStrategy = WatchRetry{
+	Fallback: FixedRetry{Interval: time.Second},
+}
+
+// LockRetryStrategy controls how AcquireLock paces acquire attempts while
+// the lock is held elsewhere.
+type LockRetryStrategy interface {
+	// WaitTime is how long attempt may block waiting for the holder to
+	// release the lock.
+	WaitTime(attempt int) time.Duration
+
+	// Delay is how long to pause after a failed attempt before the next.
+	Delay(attempt int) time.Duration
+}
+
+// FixedRetry checks the lock and, if it is held, retries after Interval.
+type FixedRetry struct {
+	Interval time.Duration
+}
+
+func (r FixedRetry) WaitTime(attempt int) time.Duration { return minLockWaitTime }
+func (r FixedRetry) Delay(attempt int) time.Duration    { return r.Interval }
+
+// BackoffRetry checks the lock and, if it is held, retries after a delay that
+// doubles from Initial up to Max.
+type BackoffRetry struct {
+	Initial time.Duration
+	Max     time.Duration
+}
+
+func (r BackoffRetry) WaitTime(attempt int) time.Duration { return minLockWaitTime }
+
+func (r BackoffRetry) Delay(attempt int) time.Duration {
+	delay := r.Initial
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+		if r.Max > 0 && delay >= r.Max {
+			return r.Max
+		}
+	}
+	return delay
+}
+
+// WatchRetry blocks on the lock key for up to MaxWait per attempt, so it
+// wakes up as soon as the holder releases the lock. If an attempt fails and
+// Fallback is set, AcquireLock continues with Fallback instead of returning
+// the error.
+type WatchRetry struct {
+	MaxWait  time.Duration
+	Fallback LockRetryStrategy
+}
+
+func (r WatchRetry) WaitTime(attempt int) time.Duration {
+	if r.MaxWait <= 0 {
+		return api.DefaultLockWaitTime
+	}
+	return r.MaxWait
+}
+
+func (r WatchRetry) Delay(attempt int) time.Duration { return 0 }
+
+// AcquireLock repeatedly attempts to acquire the lock described by opts,
+// pacing attempts according to strategy, until it is acquired, an attempt
+// fails with an error, or stopCh is closed. It returns a nil lock when
+// stopped. A nil strategy means DefaultLockRetryStrategy.
+func AcquireLock(client Client, opts api.LockOptions, stopCh <-chan struct{}, strategy LockRetryStrategy) (Lock, <-chan struct{}, error) {
+	if strategy == nil {
+		strategy = DefaultLockRetryStrategy
+	}
+	opts.LockTryOnce = true
+
+	for attempt := 1; ; attempt++ {
+		opts.LockWaitTime = strategy.WaitTime(attempt)
+
+		lock, err := client.LockOpts(&opts)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		lostLock, err := lock.Lock(stopCh)
+		if err != nil {
+			fallback := watchFallback(strategy)
+			if fallback == nil {
+				return nil, nil, err
+			}
+			strategy = fallback
+		}
+		if lostLock != nil {
+			return lock, lostLock, nil
+		}
+
+		select {
+		case <-stopCh:
+			return nil, nil, nil
+		case <-time.After(strategy.Delay(attempt)):
+		}
+	}
+}
+
+// watchFallback returns the fallback of a WatchRetry, given by value or by
+// pointer, and nil for any other strategy.
+func watchFallback(strategy LockRetryStrategy) LockRetryStrategy {
+	switch watch := strategy.(type) {
+	case WatchRetry:
+		return watch.Fallback
+	case *WatchRetry:
+		if watch != nil {
+			return watch.Fallback
+		}
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package consuladapter_test
+
+import (
+	"errors"
+	"time"
+
+	"code.cloudfoundry.org/consuladapter"
+	"code.cloudfoundry.org/consuladapter/fakes"
+	"github.com/hashicorp/consul/api"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("AcquireLock", func() {
+	var (
+		client   *fakes.FakeClient
+		lock     *fakes.FakeLock
+		lostLock chan struct{}
+		firstErr error
+	)
+
+	BeforeEach(func() {
+		client, _ = fakes.NewFakeClient()
+		lock = &fakes.FakeLock{}
+		client.LockOptsReturns(lock, nil)
+
+		lostLock = make(chan struct{})
+		firstErr = nil
+		attempts := 0
+		lock.LockStub = func(stopCh <-chan struct{}) (<-chan struct{}, error) {
+			attempts++
+			switch {
+			case attempts == 1 && firstErr != nil:
+				return nil, firstErr
+			case attempts < 3:
+				return nil, nil
+			default:
+				return lostLock, nil
+			}
+		}
+	})
+
+	It("retries single attempts until the lock is acquired", func() {
+		acquired, lost, err := consuladapter.AcquireLock(client, api.LockOptions{Key: "the-key"}, nil, consuladapter.FixedRetry{Interval: time.Millisecond})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(acquired).To(Equal(lock))
+		Expect(lost).To(Equal((<-chan struct{})(lostLock)))
+
+		Expect(lock.LockCallCount()).To(Equal(3))
+		opts := client.LockOptsArgsForCall(2)
+		Expect(opts.Key).To(Equal("the-key"))
+		Expect(opts.LockTryOnce).To(BeTrue())
+		Expect(opts.LockWaitTime).To(Equal(time.Millisecond))
+	})
+
+	It("blocks on the lock key with the default strategy", func() {
+		_, _, err := consuladapter.AcquireLock(client, api.LockOptions{Key: "the-key"}, nil, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(client.LockOptsArgsForCall(0).LockWaitTime).To(Equal(api.DefaultLockWaitTime))
+	})
+
+	It("falls back to polling when a watch attempt fails", func() {
+		firstErr = errors.New("boom")
+		strategy := consuladapter.WatchRetry{Fallback: consuladapter.FixedRetry{Interval: time.Millisecond}}
+
+		_, _, err := consuladapter.AcquireLock(client, api.LockOptions{Key: "the-key"}, nil, strategy)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(client.LockOptsArgsForCall(0).LockWaitTime).To(Equal(api.DefaultLockWaitTime))
+		Expect(client.LockOptsArgsForCall(1).LockWaitTime).To(Equal(time.Millisecond))
+	})
+
+	It("falls back to polling when the watch strategy is given by pointer", func() {
+		firstErr = errors.New("boom")
+		strategy := &consuladapter.WatchRetry{Fallback: consuladapter.FixedRetry{Interval: time.Millisecond}}
+
+		_, _, err := consuladapter.AcquireLock(client, api.LockOptions{Key: "the-key"}, nil, strategy)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(client.LockOptsArgsForCall(1).LockWaitTime).To(Equal(time.Millisecond))
+	})
+
+	It("returns attempt errors without a fallback", func() {
+		firstErr = errors.New("boom")
+		_, _, err := consuladapter.AcquireLock(client, api.LockOptions{Key: "the-key"}, nil, consuladapter.WatchRetry{})
+		Expect(err).To(MatchError("boom"))
+	})
+
+	It("stops retrying when stopCh is closed", func() {
+		stopCh := make(chan struct{})
+		close(stopCh)
+		acquired, lost, err := consuladapter.AcquireLock(client, api.LockOptions{Key: "the-key"}, stopCh, consuladapter.FixedRetry{Interval: time.Hour})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(acquired).To(BeNil())
+		Expect(lost).To(BeNil())
+	})
+
+	It("doubles the backoff delay up to the maximum", func() {
+		backoff := consuladapter.BackoffRetry{Initial: time.Second, Max: 5 * time.Second}
+		Expect(backoff.Delay(1)).To(Equal(time.Second))
+		Expect(backoff.Delay(3)).To(Equal(4 * time.Second))
+		Expect(backoff.Delay(4)).To(Equal(5 * time.Second))
+	})
+
+	It("doubles the backoff delay without limit when there is no maximum", func() {
+		backoff := consuladapter.BackoffRetry{Initial: time.Second}
+		Expect(backoff.Delay(6)).To(Equal(32 * time.Second))
+	})
+
+	It("only checks the lock with polling strategies, without blocking on it", func() {
+		Expect(consuladapter.FixedRetry{Interval: time.Second}.WaitTime(5)).To(Equal(time.Millisecond))
+		Expect(consuladapter.BackoffRetry{Initial: time.Second}.WaitTime(5)).To(Equal(time.Millisecond))
+	})
+
+	It("blocks on the lock key for up to MaxWait, with no delay between watch attempts", func() {
+		watch := consuladapter.WatchRetry{MaxWait: time.Minute}
+		Expect(watch.WaitTime(1)).To(Equal(time.Minute))
+		Expect(watch.Delay(1)).To(BeZero())
+	})
+
+	It("returns errors building the lock", func() {
+		client.LockOptsReturns(nil, errors.New("bad options"))
+		_, _, err := consuladapter.AcquireLock(client, api.LockOptions{Key: "the-key"}, nil, consuladapter.FixedRetry{Interval: time.Millisecond})
+		Expect(err).To(MatchError("bad options"))
+		Expect(lock.LockCallCount()).To(BeZero())
+	})
+
+	It("paces attempts by the strategy's delay", func() {
+		start := time.Now()
+		_, _, err := consuladapter.AcquireLock(client, api.LockOptions{Key: "the-key"}, nil, consuladapter.BackoffRetry{Initial: 20 * time.Millisecond})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(time.Since(start)).To(BeNumerically(">=", 60*time.Millisecond))
+	})
+})
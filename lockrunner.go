@@ -0,0 +1,100 @@
+package consuladapter
+
+import (
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/hashicorp/consul/api"
+)
+
+// LockLostError is returned by LockRunner when it loses the lock, e.g.
+// because its session was invalidated.
+type LockLostError struct {
+	Key string
+}
+
+func (e LockLostError) Error() string {
+	return fmt.Sprintf("lost lock '%s'", e.Key)
+}
+
+// LockSignaledError is returned by LockRunner when it is signalled, after
+// releasing the lock if it held it.
+type LockSignaledError struct {
+	Key    string
+	Signal os.Signal
+}
+
+func (e LockSignaledError) Error() string {
+	return fmt.Sprintf("released lock '%s' on signal %s", e.Key, e.Signal)
+}
+
+// LockRunner is an ifrit.Runner that acquires the lock described by its
+// options, becoming ready once it holds it, and holds it, renewing its
+// session, until the lock is lost or it is signalled. It always exits with
+// an error so that a grouper can tell the two apart: a LockLostError or a
+// LockSignaledError, or the error that stopped it acquiring the lock.
+type LockRunner struct {
+	client Client
+	opts   api.LockOptions
+	retry  LockRetryStrategy
+}
+
+// NewLockRunner returns a LockRunner pacing acquisition attempts with retry.
+// A nil retry means DefaultLockRetryStrategy.
+func NewLockRunner(client Client, opts api.LockOptions, retry LockRetryStrategy) *LockRunner {
+	return &LockRunner{client: client, opts: opts, retry: retry}
+}
+
+type lockResult struct {
+	lock     Lock
+	lostLock <-chan struct{}
+	err      error
+}
+
+func (r *LockRunner) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	results := make(chan lockResult, 1)
+	goBackground(&wg, func() {
+		lock, lostLock, err := AcquireLock(r.client, r.opts, stop, r.retry)
+		results <- lockResult{lock: lock, lostLock: lostLock, err: err}
+	}, func(err *PanicError) {
+		results <- lockResult{err: err}
+	})
+
+	var result lockResult
+	select {
+	case signal := <-signals:
+		close(stop)
+		wg.Wait()
+		result = <-results
+		if result.lock != nil {
+			result.lock.Unlock()
+		}
+		return LockSignaledError{Key: r.opts.Key, Signal: signal}
+	case result = <-results:
+	}
+	if result.err != nil {
+		return result.err
+	}
+	if recorder := currentLifecycleRecorder(); recorder != nil {
+		recorder.TrackLock(r.opts.Key, result.lostLock)
+	}
+	close(ready)
+
+	select {
+	case <-result.lostLock:
+		result.lock.Unlock()
+		return LockLostError{Key: r.opts.Key}
+	case signal := <-signals:
+		// recorded first, so that the lock closing its lost channel as it is
+		// released does not record it as lost
+		recordLifecycle(LifecycleEvent{Kind: LockReleased, Lock: r.opts.Key})
+		err := result.lock.Unlock()
+		if err != nil {
+			return fmt.Errorf("releasing lock '%s': %w", r.opts.Key, err)
+		}
+		return LockSignaledError{Key: r.opts.Key, Signal: signal}
+	}
+}
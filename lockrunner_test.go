@@ -0,0 +1,83 @@
+package consuladapter_test
+
+import (
+	"os"
+	"time"
+
+	"code.cloudfoundry.org/consuladapter"
+	"code.cloudfoundry.org/consuladapter/fakes"
+	"github.com/hashicorp/consul/api"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("LockRunner", func() {
+	const key = "v1/locks/bbs"
+
+	var (
+		backend *fakes.FakeBackend
+		client  *fakes.FakeClient
+		signals chan os.Signal
+		ready   chan struct{}
+		done    chan error
+	)
+
+	BeforeEach(func() {
+		backend = fakes.NewFakeBackend()
+		client, _ = backend.Client()
+		signals = make(chan os.Signal, 1)
+		ready = make(chan struct{})
+		done = make(chan error, 1)
+	})
+
+	run := func(value string) {
+		runner := consuladapter.NewLockRunner(client, api.LockOptions{Key: key, Value: []byte(value)}, consuladapter.FixedRetry{Interval: 10 * time.Millisecond})
+		go func() {
+			done <- runner.Run(signals, ready)
+		}()
+	}
+
+	It("becomes ready holding the lock, and releases it when signalled", func() {
+		run("bbs-1")
+		Eventually(ready).Should(BeClosed())
+		Expect(backend.Holder(key)).NotTo(BeEmpty())
+
+		signals <- os.Interrupt
+		Eventually(done).Should(Receive(Equal(consuladapter.LockSignaledError{Key: key, Signal: os.Interrupt})))
+		Expect(backend.Holder(key)).To(BeEmpty())
+	})
+
+	It("exits with a LockLostError when the lock is lost", func() {
+		run("bbs-1")
+		Eventually(ready).Should(BeClosed())
+
+		backend.Expire(backend.Holder(key))
+		Eventually(done).Should(Receive(Equal(consuladapter.LockLostError{Key: key})))
+	})
+
+	It("does not become ready while the lock is held elsewhere, and exits when signalled", func() {
+		holder, _, err := backend.Session().Create(&api.SessionEntry{Name: "holder"}, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(consuladapter.TryAcquireLock(backend.KV(), holder, key, nil)).To(Succeed())
+
+		run("bbs-2")
+		Consistently(ready).ShouldNot(BeClosed())
+
+		signals <- os.Interrupt
+		Eventually(done).Should(Receive(Equal(consuladapter.LockSignaledError{Key: key, Signal: os.Interrupt})))
+		Expect(backend.Holder(key)).To(Equal(holder))
+	})
+
+	It("exits with the panic if acquiring the lock panics", func() {
+		client.LockOptsStub = func(*api.LockOptions) (consuladapter.Lock, error) {
+			panic("boom")
+		}
+		run("bbs-1")
+
+		var err error
+		Eventually(done).Should(Receive(&err))
+		Expect(err).To(BeAssignableToTypeOf(&consuladapter.PanicError{}))
+		Expect(ready).NotTo(BeClosed())
+	})
+})
@@ -0,0 +1,235 @@
+// Package matchers provides Gomega matchers that check consul's state
+// through a consuladapter.Client. Each match reads the current state, so
+// they poll when used with Eventually or Consistently:
+//
+//	Eventually(client).Should(matchers.HoldLock("bbs", "v1/locks/bbs"))
+package matchers
+
+import (
+	"bytes"
+	"fmt"
+
+	"code.cloudfoundry.org/consuladapter"
+	"github.com/hashicorp/consul/api"
+	"github.com/onsi/gomega/types"
+)
+
+func asClient(actual interface{}) (consuladapter.Client, error) {
+	client, ok := actual.(consuladapter.Client)
+	if !ok {
+		return nil, fmt.Errorf("expected a consuladapter.Client, got %T", actual)
+	}
+	return client, nil
+}
+
+func describePair(pair *api.KVPair) string {
+	if pair == nil {
+		return "does not exist"
+	}
+	description := fmt.Sprintf("has value %q (modify index %d)", pair.Value, pair.ModifyIndex)
+	if pair.Session != "" {
+		description += fmt.Sprintf(", held by session %s", pair.Session)
+	}
+	return description
+}
+
+// HaveKeyWithValue succeeds if key exists and its value equals value, given
+// as a string or []byte, or satisfies value, given as a matcher of the
+// value as a string.
+func HaveKeyWithValue(key string, value interface{}) types.GomegaMatcher {
+	return &keyWithValueMatcher{key: key, value: value}
+}
+
+type keyWithValueMatcher struct {
+	key   string
+	value interface{}
+
+	pair *api.KVPair
+}
+
+func (m *keyWithValueMatcher) Match(actual interface{}) (bool, error) {
+	client, err := asClient(actual)
+	if err != nil {
+		return false, err
+	}
+
+	m.pair, _, err = client.KV().Get(m.key, nil)
+	if err != nil || m.pair == nil {
+		return false, err
+	}
+
+	switch expected := m.value.(type) {
+	case string:
+		return string(m.pair.Value) == expected, nil
+	case []byte:
+		return bytes.Equal(m.pair.Value, expected), nil
+	case types.GomegaMatcher:
+		return expected.Match(string(m.pair.Value))
+	default:
+		return false, fmt.Errorf("HaveKeyWithValue expects a string, []byte or matcher, got %T", m.value)
+	}
+}
+
+func (m *keyWithValueMatcher) expected() string {
+	if matcher, ok := m.value.(types.GomegaMatcher); ok && m.pair != nil {
+		return matcher.FailureMessage(string(m.pair.Value))
+	}
+	return fmt.Sprintf("%q", m.value)
+}
+
+func (m *keyWithValueMatcher) FailureMessage(actual interface{}) string {
+	return fmt.Sprintf("Expected key '%s' to have value %s, but it %s", m.key, m.expected(), describePair(m.pair))
+}
+
+func (m *keyWithValueMatcher) NegatedFailureMessage(actual interface{}) string {
+	return fmt.Sprintf("Expected key '%s' not to have value %s, but it %s", m.key, m.expected(), describePair(m.pair))
+}
+
+// HoldLock succeeds if key is held by a session named sessionName, either
+// as given or tagged with consuladapter.SessionNamePrefix as the adapter's
+// LockOpts names it.
+func HoldLock(sessionName, key string) types.GomegaMatcher {
+	return &holdLockMatcher{sessionName: sessionName, key: key}
+}
+
+type holdLockMatcher struct {
+	sessionName string
+	key         string
+
+	pair    *api.KVPair
+	session *api.SessionEntry
+}
+
+func (m *holdLockMatcher) Match(actual interface{}) (bool, error) {
+	client, err := asClient(actual)
+	if err != nil {
+		return false, err
+	}
+
+	m.session = nil
+	m.pair, _, err = client.KV().Get(m.key, nil)
+	if err != nil || m.pair == nil || m.pair.Session == "" {
+		return false, err
+	}
+
+	m.session, _, err = client.Session().Info(m.pair.Session, nil)
+	if err != nil || m.session == nil {
+		return false, err
+	}
+	return m.session.Name == m.sessionName || m.session.Name == consuladapter.TagSessionName(m.sessionName), nil
+}
+
+func (m *holdLockMatcher) holder() string {
+	switch {
+	case m.pair == nil:
+		return "the key does not exist"
+	case m.pair.Session == "":
+		return "it is not held"
+	case m.session == nil:
+		return fmt.Sprintf("it is held by session %s, which no longer exists", m.pair.Session)
+	default:
+		return fmt.Sprintf("it is held by session %s named '%s' on node '%s'", m.session.ID, m.session.Name, m.session.Node)
+	}
+}
+
+func (m *holdLockMatcher) FailureMessage(actual interface{}) string {
+	return fmt.Sprintf("Expected a session named '%s' to hold lock '%s', but %s", m.sessionName, m.key, m.holder())
+}
+
+func (m *holdLockMatcher) NegatedFailureMessage(actual interface{}) string {
+	return fmt.Sprintf("Expected no session named '%s' to hold lock '%s', but %s", m.sessionName, m.key, m.holder())
+}
+
+// BePresentUnder succeeds if the presence key for name under prefix, e.g.
+// a cell's key under v1/presence/, is held by a live session.
+func BePresentUnder(prefix, name string) types.GomegaMatcher {
+	return &presenceMatcher{path: consuladapter.NewKeyPath(prefix), name: name}
+}
+
+type presenceMatcher struct {
+	path consuladapter.KeyPath
+	name string
+
+	present []string
+	pair    *api.KVPair
+}
+
+func (m *presenceMatcher) Match(actual interface{}) (bool, error) {
+	client, err := asClient(actual)
+	if err != nil {
+		return false, err
+	}
+
+	pairs, _, err := client.KV().List(m.path.Prefix(), nil)
+	if err != nil {
+		return false, err
+	}
+
+	key := m.path.Key(m.name)
+	m.pair = nil
+	m.present = nil
+	for _, pair := range pairs {
+		if pair.Session != "" {
+			name, _ := m.path.Name(pair.Key)
+			m.present = append(m.present, name)
+		}
+		if pair.Key == key {
+			m.pair = pair
+		}
+	}
+	return m.pair != nil && m.pair.Session != "", nil
+}
+
+func (m *presenceMatcher) FailureMessage(actual interface{}) string {
+	return fmt.Sprintf("Expected '%s' to be present under '%s', but it %s\nPresent: %v", m.name, m.path.Prefix(), describePair(m.pair), m.present)
+}
+
+func (m *presenceMatcher) NegatedFailureMessage(actual interface{}) string {
+	return fmt.Sprintf("Expected '%s' not to be present under '%s', but it %s", m.name, m.path.Prefix(), describePair(m.pair))
+}
+
+// HaveHealthyInstances succeeds if exactly count instances of service are
+// passing all of their checks.
+func HaveHealthyInstances(service string, count int) types.GomegaMatcher {
+	return &healthyInstancesMatcher{service: service, count: count}
+}
+
+type healthyInstancesMatcher struct {
+	service string
+	count   int
+
+	healthy []string
+	failing []string
+}
+
+func (m *healthyInstancesMatcher) Match(actual interface{}) (bool, error) {
+	client, err := asClient(actual)
+	if err != nil {
+		return false, err
+	}
+
+	entries, _, err := client.Health().Service(m.service, "", false, nil)
+	if err != nil {
+		return false, err
+	}
+
+	m.healthy = nil
+	m.failing = nil
+	for _, entry := range entries {
+		id := entry.Service.ID
+		if status := entry.Checks.AggregatedStatus(); status == api.HealthPassing {
+			m.healthy = append(m.healthy, id)
+		} else {
+			m.failing = append(m.failing, fmt.Sprintf("%s (%s)", id, status))
+		}
+	}
+	return len(m.healthy) == m.count, nil
+}
+
+func (m *healthyInstancesMatcher) FailureMessage(actual interface{}) string {
+	return fmt.Sprintf("Expected %d healthy instances of '%s', but found %d\nHealthy: %v\nNot healthy: %v", m.count, m.service, len(m.healthy), m.healthy, m.failing)
+}
+
+func (m *healthyInstancesMatcher) NegatedFailureMessage(actual interface{}) string {
+	return fmt.Sprintf("Expected other than %d healthy instances of '%s'\nHealthy: %v", m.count, m.service, m.healthy)
+}
@@ -0,0 +1,112 @@
+package matchers_test
+
+import (
+	"code.cloudfoundry.org/consuladapter"
+	"code.cloudfoundry.org/consuladapter/fakes"
+	"code.cloudfoundry.org/consuladapter/matchers"
+	"github.com/hashicorp/consul/api"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Matchers", func() {
+	var (
+		backend    *fakes.FakeBackend
+		client     *fakes.FakeClient
+		components *fakes.FakeClientComponents
+	)
+
+	BeforeEach(func() {
+		backend = fakes.NewFakeBackend()
+		client, components = backend.Client()
+	})
+
+	acquire := func(name, key string) string {
+		id, _, err := backend.Session().Create(&api.SessionEntry{Name: name}, nil)
+		Expect(err).NotTo(HaveOccurred())
+		acquired, _, err := backend.KV().Acquire(&api.KVPair{Key: key, Session: id}, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(acquired).To(BeTrue())
+		return id
+	}
+
+	Describe("HaveKeyWithValue", func() {
+		BeforeEach(func() {
+			_, err := backend.KV().Put(&api.KVPair{Key: "v1/config", Value: []byte("enabled")}, nil)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("matches values and value matchers", func() {
+			Expect(client).To(matchers.HaveKeyWithValue("v1/config", "enabled"))
+			Expect(client).To(matchers.HaveKeyWithValue("v1/config", []byte("enabled")))
+			Expect(client).To(matchers.HaveKeyWithValue("v1/config", HavePrefix("en")))
+			Expect(client).NotTo(matchers.HaveKeyWithValue("v1/config", "disabled"))
+			Expect(client).NotTo(matchers.HaveKeyWithValue("v1/missing", "enabled"))
+		})
+
+		It("describes what it found", func() {
+			matcher := matchers.HaveKeyWithValue("v1/config", "disabled")
+			Expect(matcher.Match(client)).To(BeFalse())
+			Expect(matcher.FailureMessage(client)).To(ContainSubstring(`but it has value "enabled"`))
+		})
+
+		It("rejects anything but a client", func() {
+			_, err := matchers.HaveKeyWithValue("v1/config", "enabled").Match("client")
+			Expect(err).To(MatchError(ContainSubstring("expected a consuladapter.Client")))
+		})
+	})
+
+	Describe("HoldLock", func() {
+		It("matches the name of the holding session", func() {
+			Expect(client).NotTo(matchers.HoldLock("bbs", "v1/locks/bbs"))
+
+			acquire("bbs", "v1/locks/bbs")
+			Expect(client).To(matchers.HoldLock("bbs", "v1/locks/bbs"))
+
+			matcher := matchers.HoldLock("auctioneer", "v1/locks/bbs")
+			Expect(matcher.Match(client)).To(BeFalse())
+			Expect(matcher.FailureMessage(client)).To(ContainSubstring("named 'bbs'"))
+		})
+
+		It("matches sessions named with the adapter's tag", func() {
+			acquire(consuladapter.TagSessionName("bbs"), "v1/locks/bbs")
+			Expect(client).To(matchers.HoldLock("bbs", "v1/locks/bbs"))
+			Expect(client).NotTo(matchers.HoldLock("auctioneer", "v1/locks/bbs"))
+		})
+	})
+
+	Describe("BePresentUnder", func() {
+		It("matches keys held by live sessions", func() {
+			id := acquire("cell-1", "v1/presence/cell-1")
+			Expect(client).To(matchers.BePresentUnder("v1/presence", "cell-1"))
+			Expect(client).NotTo(matchers.BePresentUnder("v1/presence", "cell-2"))
+
+			backend.Expire(id)
+			matcher := matchers.BePresentUnder("v1/presence", "cell-1")
+			Expect(matcher.Match(client)).To(BeFalse())
+		})
+	})
+
+	Describe("HaveHealthyInstances", func() {
+		entry := func(id, status string) *api.ServiceEntry {
+			return &api.ServiceEntry{
+				Service: &api.AgentService{ID: id, Service: "worker"},
+				Checks:  api.HealthChecks{{Status: status}},
+			}
+		}
+
+		It("counts instances passing all of their checks", func() {
+			components.Health.ServiceReturns([]*api.ServiceEntry{
+				entry("worker-1", api.HealthPassing),
+				entry("worker-2", api.HealthCritical),
+			}, nil, nil)
+
+			Expect(client).To(matchers.HaveHealthyInstances("worker", 1))
+
+			matcher := matchers.HaveHealthyInstances("worker", 2)
+			Expect(matcher.Match(client)).To(BeFalse())
+			Expect(matcher.FailureMessage(client)).To(ContainSubstring("worker-2 (critical)"))
+		})
+	})
+})
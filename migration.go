@@ -0,0 +1,297 @@
+package consuladapter
+
+import (
+	"sort"
+	"strings"
+	"sync/atomic"
+
+	"github.com/hashicorp/consul/api"
+)
+
+// KeyMigration moves the keys under Legacy to Current, e.g. from v1/locks
+// to a namespaced path.
+type KeyMigration struct {
+	Legacy  KeyPath
+	Current KeyPath
+}
+
+// MigratingKV lets components of mixed versions share keys while they move
+// between paths. Callers use the current paths; MigratingKV maps them to
+// the legacy paths while older components may still be running.
+//
+// Before cutover the legacy key is authoritative, and after it the current
+// key is. Before cutover the legacy keys are complete, since every
+// component writes them, so reads only go to the legacy key: a key a
+// legacy component deleted must not come back from the current path.
+// After cutover reads fall back to the legacy key, which only older
+// components may still have written. Puts and deletes go to both. Locks
+// and CAS writes only go to the authoritative key, since two keys cannot be
+// updated atomically; CAS writes that succeed are then mirrored.
+type MigratingKV struct {
+	kv         KV
+	migrations []KeyMigration
+	cutOver    int32
+}
+
+func NewMigratingKV(kv KV, cutOver bool, migrations ...KeyMigration) *MigratingKV {
+	m := &MigratingKV{kv: kv, migrations: migrations}
+	m.SetCutOver(cutOver)
+	return m
+}
+
+// SetCutOver switches the authoritative keys to the current paths once
+// every component writes them, or back to the legacy paths.
+func (m *MigratingKV) SetCutOver(cutOver bool) {
+	var value int32
+	if cutOver {
+		value = 1
+	}
+	atomic.StoreInt32(&m.cutOver, value)
+}
+
+func (m *MigratingKV) CutOver() bool {
+	return atomic.LoadInt32(&m.cutOver) == 1
+}
+
+// legacy returns the legacy key for a current one, and false for keys
+// outside every migration.
+func (m *MigratingKV) legacy(key string) (string, bool) {
+	for _, migration := range m.migrations {
+		if key == migration.Current.Prefix() {
+			return migration.Legacy.Prefix(), true
+		}
+		if name, ok := migration.Current.Name(key); ok {
+			legacy := migration.Legacy.Key(name)
+			if strings.HasSuffix(key, "/") {
+				legacy += "/"
+			}
+			return legacy, true
+		}
+	}
+	return "", false
+}
+
+func (m *MigratingKV) current(legacyKey string) string {
+	for _, migration := range m.migrations {
+		if name, ok := migration.Legacy.Name(legacyKey); ok {
+			return migration.Current.Key(name)
+		}
+	}
+	return legacyKey
+}
+
+// keys returns the authoritative key for key, and the other one if key is
+// migrating.
+func (m *MigratingKV) keys(key string) (string, string, bool) {
+	legacy, ok := m.legacy(key)
+	if !ok {
+		return key, "", false
+	}
+	if m.CutOver() {
+		return key, legacy, true
+	}
+	return legacy, key, true
+}
+
+// LockKey returns the key to lock for key, for use in api.LockOptions.
+func (m *MigratingKV) LockKey(key string) string {
+	authoritative, _, _ := m.keys(key)
+	return authoritative
+}
+
+// fallback returns the key reads fall back to for key, which is only the
+// legacy key after cutover.
+func (m *MigratingKV) fallback(key string) (string, string, bool) {
+	authoritative, other, migrating := m.keys(key)
+	return authoritative, other, migrating && m.CutOver()
+}
+
+func (m *MigratingKV) Get(key string, q *api.QueryOptions) (*api.KVPair, *api.QueryMeta, error) {
+	authoritative, other, fallback := m.fallback(key)
+
+	pair, meta, err := m.kv.Get(authoritative, q)
+	if err != nil || pair != nil || !fallback {
+		return m.asCurrent(pair), meta, err
+	}
+
+	fallbackQuery := q
+	if q != nil {
+		// the authoritative read already blocked
+		copied := *q
+		copied.WaitIndex = 0
+		fallbackQuery = &copied
+	}
+	pair, _, err = m.kv.Get(other, fallbackQuery)
+	if err != nil {
+		return nil, nil, err
+	}
+	return m.asCurrent(pair), meta, nil
+}
+
+func (m *MigratingKV) List(prefix string, q *api.QueryOptions) (api.KVPairs, *api.QueryMeta, error) {
+	authoritative, other, fallback := m.fallback(prefix)
+
+	pairs, meta, err := m.kv.List(authoritative, q)
+	if err != nil || !fallback {
+		return m.allAsCurrent(pairs), meta, err
+	}
+
+	fallbackQuery := q
+	if q != nil {
+		copied := *q
+		copied.WaitIndex = 0
+		fallbackQuery = &copied
+	}
+	others, _, err := m.kv.List(other, fallbackQuery)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	merged := m.allAsCurrent(pairs)
+	seen := map[string]bool{}
+	for _, pair := range merged {
+		seen[pair.Key] = true
+	}
+	for _, pair := range m.allAsCurrent(others) {
+		if !seen[pair.Key] {
+			merged = append(merged, pair)
+		}
+	}
+	sort.Slice(merged, func(i, j int) bool { return merged[i].Key < merged[j].Key })
+	return merged, meta, nil
+}
+
+func (m *MigratingKV) asCurrent(pair *api.KVPair) *api.KVPair {
+	if pair == nil {
+		return nil
+	}
+	copied := *pair
+	copied.Key = m.current(pair.Key)
+	return &copied
+}
+
+func (m *MigratingKV) allAsCurrent(pairs api.KVPairs) api.KVPairs {
+	if pairs == nil {
+		return nil
+	}
+	converted := make(api.KVPairs, 0, len(pairs))
+	for _, pair := range pairs {
+		converted = append(converted, m.asCurrent(pair))
+	}
+	return converted
+}
+
+func withKey(p *api.KVPair, key string) *api.KVPair {
+	copied := *p
+	copied.Key = key
+	return &copied
+}
+
+func (m *MigratingKV) Put(p *api.KVPair, q *api.WriteOptions) (*api.WriteMeta, error) {
+	authoritative, other, migrating := m.keys(p.Key)
+
+	wm, err := m.kv.Put(withKey(p, authoritative), q)
+	if err != nil || !migrating {
+		return wm, err
+	}
+	return m.mirror(p, other, q, wm)
+}
+
+func (m *MigratingKV) mirror(p *api.KVPair, other string, q *api.WriteOptions, wm *api.WriteMeta) (*api.WriteMeta, error) {
+	mirrored := withKey(p, other)
+	mirrored.ModifyIndex = 0
+	mirrored.Session = ""
+	if _, err := m.kv.Put(mirrored, q); err != nil {
+		return nil, err
+	}
+	return wm, nil
+}
+
+// CAS checks p.ModifyIndex against the authoritative key. If only the other
+// key exists, as Get then returns, it checks it against the other key and
+// copies the write to the authoritative key, which must still not exist.
+func (m *MigratingKV) CAS(p *api.KVPair, q *api.WriteOptions) (bool, *api.WriteMeta, error) {
+	authoritative, other, migrating := m.keys(p.Key)
+
+	ok, wm, err := m.kv.CAS(withKey(p, authoritative), q)
+	if err == nil && !ok && migrating && p.ModifyIndex != 0 {
+		ok, wm, err = m.casFromOther(p, authoritative, other, q)
+	}
+	if err != nil || !ok || !migrating {
+		return ok, wm, err
+	}
+	wm, err = m.mirror(p, other, q, wm)
+	return err == nil, wm, err
+}
+
+func (m *MigratingKV) casFromOther(p *api.KVPair, authoritative, other string, q *api.WriteOptions) (bool, *api.WriteMeta, error) {
+	pair, _, err := m.kv.Get(authoritative, nil)
+	if err != nil || pair != nil {
+		return false, nil, err
+	}
+
+	pair, _, err = m.kv.Get(other, nil)
+	if err != nil || pair == nil || pair.ModifyIndex != p.ModifyIndex {
+		return false, nil, err
+	}
+
+	created := withKey(p, authoritative)
+	created.ModifyIndex = 0
+	return m.kv.CAS(created, q)
+}
+
+func (m *MigratingKV) Acquire(p *api.KVPair, q *api.WriteOptions) (bool, *api.WriteMeta, error) {
+	authoritative, _, _ := m.keys(p.Key)
+	return m.kv.Acquire(withKey(p, authoritative), q)
+}
+
+func (m *MigratingKV) Release(p *api.KVPair, q *api.WriteOptions) (bool, *api.WriteMeta, error) {
+	authoritative, _, _ := m.keys(p.Key)
+	return m.kv.Release(withKey(p, authoritative), q)
+}
+
+// DeleteCAS checks p.ModifyIndex against the authoritative key, or, as CAS
+// does, against the other key if only that one exists.
+func (m *MigratingKV) DeleteCAS(p *api.KVPair, w *api.WriteOptions) (bool, *api.WriteMeta, error) {
+	authoritative, other, migrating := m.keys(p.Key)
+
+	ok, wm, err := m.kv.DeleteCAS(withKey(p, authoritative), w)
+	if err == nil && !ok && migrating && p.ModifyIndex != 0 {
+		return m.deleteFromOther(p, authoritative, other, w)
+	}
+	if err != nil || !ok || !migrating {
+		return ok, wm, err
+	}
+
+	pair, _, err := m.kv.Get(other, nil)
+	if err != nil || pair == nil {
+		return err == nil, wm, err
+	}
+	// a concurrent write to the other key wins
+	if _, err := DeleteIfIndex(m.kv, other, pair.ModifyIndex); err != nil {
+		return false, nil, err
+	}
+	return true, wm, nil
+}
+
+func (m *MigratingKV) deleteFromOther(p *api.KVPair, authoritative, other string, w *api.WriteOptions) (bool, *api.WriteMeta, error) {
+	pair, _, err := m.kv.Get(authoritative, nil)
+	if err != nil || pair != nil {
+		return false, nil, err
+	}
+
+	return m.kv.DeleteCAS(withKey(p, other), w)
+}
+
+func (m *MigratingKV) DeleteTree(prefix string, w *api.WriteOptions) (*api.WriteMeta, error) {
+	authoritative, other, migrating := m.keys(prefix)
+
+	wm, err := m.kv.DeleteTree(authoritative, w)
+	if err != nil || !migrating {
+		return wm, err
+	}
+	if _, err := m.kv.DeleteTree(other, w); err != nil {
+		return nil, err
+	}
+	return wm, nil
+}
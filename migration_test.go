@@ -0,0 +1,167 @@
+package consuladapter_test
+
+import (
+	"code.cloudfoundry.org/consuladapter"
+	"code.cloudfoundry.org/consuladapter/fakes"
+	"github.com/hashicorp/consul/api"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("MigratingKV", func() {
+	var (
+		backend   *fakes.FakeBackend
+		kv        consuladapter.KV
+		migrating *consuladapter.MigratingKV
+	)
+
+	value := func(key string) string {
+		pair, _, err := kv.Get(key, nil)
+		Expect(err).NotTo(HaveOccurred())
+		if pair == nil {
+			return ""
+		}
+		return string(pair.Value)
+	}
+
+	BeforeEach(func() {
+		backend = fakes.NewFakeBackend()
+		kv = backend.KV()
+		migrating = consuladapter.NewMigratingKV(kv, false, consuladapter.KeyMigration{
+			Legacy:  consuladapter.LockKeys,
+			Current: consuladapter.NewKeyPath("v2", "diego", "locks"),
+		})
+	})
+
+	It("writes both paths", func() {
+		_, err := migrating.Put(&api.KVPair{Key: "v2/diego/locks/bbs", Value: []byte("bbs-1")}, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(value("v1/locks/bbs")).To(Equal("bbs-1"))
+		Expect(value("v2/diego/locks/bbs")).To(Equal("bbs-1"))
+	})
+
+	It("reads keys written only by legacy components under their current path", func() {
+		_, err := kv.Put(&api.KVPair{Key: "v1/locks/auctioneer", Value: []byte("auctioneer-1")}, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		pair, _, err := migrating.Get("v2/diego/locks/auctioneer", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pair.Key).To(Equal("v2/diego/locks/auctioneer"))
+		Expect(string(pair.Value)).To(Equal("auctioneer-1"))
+
+		migrating.SetCutOver(true)
+		pair, _, err = migrating.Get("v2/diego/locks/auctioneer", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(pair.Value)).To(Equal("auctioneer-1"))
+	})
+
+	It("prefers the authoritative path", func() {
+		_, err := kv.Put(&api.KVPair{Key: "v1/locks/bbs", Value: []byte("legacy")}, nil)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = kv.Put(&api.KVPair{Key: "v2/diego/locks/bbs", Value: []byte("current")}, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		pair, _, err := migrating.Get("v2/diego/locks/bbs", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(pair.Value)).To(Equal("legacy"))
+
+		migrating.SetCutOver(true)
+		pairs, _, err := migrating.List("v2/diego/locks/", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pairs).To(HaveLen(1))
+		Expect(string(pairs[0].Value)).To(Equal("current"))
+	})
+
+	It("locks the authoritative key only", func() {
+		id, _, err := backend.Session().Create(&api.SessionEntry{}, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(migrating.LockKey("v2/diego/locks/bbs")).To(Equal("v1/locks/bbs"))
+		acquired, _, err := migrating.Acquire(&api.KVPair{Key: "v2/diego/locks/bbs", Session: id}, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(acquired).To(BeTrue())
+		Expect(backend.Holder("v1/locks/bbs")).To(Equal(id))
+		Expect(backend.Holder("v2/diego/locks/bbs")).To(BeEmpty())
+
+		migrating.SetCutOver(true)
+		Expect(migrating.LockKey("v2/diego/locks/bbs")).To(Equal("v2/diego/locks/bbs"))
+	})
+
+	It("does not bring back keys a legacy component deleted before cutover", func() {
+		_, err := migrating.Put(&api.KVPair{Key: "v2/diego/locks/bbs", Value: []byte("bbs-1")}, nil)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = kv.DeleteTree("v1/locks/bbs", nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		pair, _, err := migrating.Get("v2/diego/locks/bbs", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pair).To(BeNil())
+
+		pairs, _, err := migrating.List("v2/diego/locks/", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pairs).To(BeEmpty())
+	})
+
+	It("compare-and-swaps keys read through the other path", func() {
+		migrating.SetCutOver(true)
+		_, err := kv.Put(&api.KVPair{Key: "v1/locks/bbs", Value: []byte("legacy")}, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		pair, _, err := migrating.Get("v2/diego/locks/bbs", nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		stale := *pair
+		pair.Value = []byte("updated")
+		ok, _, err := migrating.CAS(pair, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeTrue())
+		Expect(value("v1/locks/bbs")).To(Equal("updated"))
+		Expect(value("v2/diego/locks/bbs")).To(Equal("updated"))
+
+		stale.Value = []byte("stale")
+		ok, _, err = migrating.CAS(&stale, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeFalse())
+		Expect(value("v2/diego/locks/bbs")).To(Equal("updated"))
+	})
+
+	It("deletes keys read through the other path, unless they have changed", func() {
+		migrating.SetCutOver(true)
+		_, err := kv.Put(&api.KVPair{Key: "v1/locks/bbs", Value: []byte("legacy")}, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		pair, _, err := migrating.Get("v2/diego/locks/bbs", nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		stale := *pair
+		stale.ModifyIndex--
+		ok, _, err := migrating.DeleteCAS(&stale, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeFalse())
+		Expect(value("v1/locks/bbs")).To(Equal("legacy"))
+
+		ok, _, err = migrating.DeleteCAS(pair, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeTrue())
+		Expect(value("v1/locks/bbs")).To(BeEmpty())
+		Expect(value("v2/diego/locks/bbs")).To(BeEmpty())
+	})
+
+	It("deletes both paths", func() {
+		_, err := migrating.Put(&api.KVPair{Key: "v2/diego/locks/bbs", Value: []byte("bbs-1")}, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = migrating.DeleteTree("v2/diego/locks/", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(value("v1/locks/bbs")).To(BeEmpty())
+		Expect(value("v2/diego/locks/bbs")).To(BeEmpty())
+	})
+
+	It("passes other keys through", func() {
+		_, err := migrating.Put(&api.KVPair{Key: "v1/presence/cell-1", Value: []byte("cell")}, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(value("v1/presence/cell-1")).To(Equal("cell"))
+	})
+})
@@ -0,0 +1,73 @@
+package consuladapter_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+
+	"code.cloudfoundry.org/consuladapter"
+	"github.com/hashicorp/consul/api"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("scoped clients", func() {
+	var (
+		server   *httptest.Server
+		received chan url.Values
+		client   consuladapter.Client
+	)
+
+	BeforeEach(func() {
+		received = make(chan url.Values, 1)
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			received <- r.URL.Query()
+			w.WriteHeader(http.StatusNotFound)
+		}))
+
+		var err error
+		client, err = consuladapter.NewClientFromUrlWithOptions(server.URL, consuladapter.ClientOptions{
+			Namespace: "team-a",
+			Partition: "west",
+		})
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("scopes requests to the client's namespace and partition", func() {
+		_, _, err := client.KV().Get("key", nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		var query url.Values
+		Eventually(received).Should(Receive(&query))
+		Expect(query.Get("ns")).To(Equal("team-a"))
+		Expect(query.Get("partition")).To(Equal("west"))
+	})
+
+	It("lets individual requests override them", func() {
+		_, _, err := client.KV().Get("key", &api.QueryOptions{Namespace: "team-b", Partition: "east"})
+		Expect(err).NotTo(HaveOccurred())
+
+		var query url.Values
+		Eventually(received).Should(Receive(&query))
+		Expect(query.Get("ns")).To(Equal("team-b"))
+		Expect(query.Get("partition")).To(Equal("east"))
+	})
+
+	It("leaves requests unscoped by default", func() {
+		client, err := consuladapter.NewClientFromUrl(server.URL)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, _, err = client.KV().Get("key", nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		var query url.Values
+		Eventually(received).Should(Receive(&query))
+		Expect(query).NotTo(HaveKey("ns"))
+		Expect(query).NotTo(HaveKey("partition"))
+	})
+})
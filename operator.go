@@ -0,0 +1,21 @@
+package consuladapter
+
+import "github.com/hashicorp/consul/api"
+
+//go:generate counterfeiter -o fakes/fake_operator.go . Operator
+
+type Operator interface {
+	RaftGetConfiguration(q *api.QueryOptions) (*api.RaftConfiguration, error)
+}
+
+type operator struct {
+	operator *api.Operator
+}
+
+func NewConsulOperator(o *api.Operator) Operator {
+	return &operator{operator: o}
+}
+
+func (o *operator) RaftGetConfiguration(q *api.QueryOptions) (*api.RaftConfiguration, error) {
+	return o.operator.RaftGetConfiguration(q)
+}
@@ -0,0 +1,48 @@
+package consuladapter
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// ReadOnlyError is returned by clients built with ClientOptions.ReadOnly
+// for any operation that would change consul's state. KV writes and
+// LockOpts return it directly; other operations are rejected before they
+// are sent and return it wrapped, so check for it with IsReadOnlyError.
+type ReadOnlyError struct {
+	Method string
+	Path   string
+}
+
+func (e ReadOnlyError) Error() string {
+	if e.Method == "" {
+		return fmt.Sprintf("read-only client: refusing to write '%s'", e.Path)
+	}
+	return fmt.Sprintf("read-only client: refusing %s %s", e.Method, e.Path)
+}
+
+func IsReadOnlyError(err error) bool {
+	var readOnlyErr ReadOnlyError
+	return errors.As(err, &readOnlyErr)
+}
+
+// readOnlyTransport rejects every request that is not a read. Consul's HTTP
+// API only reads with GET.
+type readOnlyTransport struct {
+	transport http.RoundTripper
+}
+
+func newReadOnlyTransport(transport http.RoundTripper) http.RoundTripper {
+	return &readOnlyTransport{transport: transport}
+}
+
+func (t *readOnlyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, ReadOnlyError{Method: req.Method, Path: req.URL.Path}
+	}
+	return t.transport.RoundTrip(req)
+}
@@ -0,0 +1,64 @@
+package consuladapter_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+
+	"code.cloudfoundry.org/consuladapter"
+	"github.com/hashicorp/consul/api"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("read-only clients", func() {
+	var (
+		server *httptest.Server
+		client consuladapter.Client
+		writes int32
+	)
+
+	BeforeEach(func() {
+		atomic.StoreInt32(&writes, 0)
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet {
+				atomic.AddInt32(&writes, 1)
+			}
+			w.Write([]byte(`"127.0.0.1:8300"`))
+		}))
+
+		var err error
+		client, err = consuladapter.NewClientFromUrlWithOptions(server.URL, consuladapter.ClientOptions{ReadOnly: true})
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("allows reads", func() {
+		Expect(client.Status().Leader()).To(Equal("127.0.0.1:8300"))
+	})
+
+	It("rejects KV writes and locks", func() {
+		_, err := client.KV().Put(&api.KVPair{Key: "key", Value: []byte("value")}, nil)
+		Expect(err).To(Equal(consuladapter.ReadOnlyError{Path: "key"}))
+
+		_, err = client.KV().DeleteTree("prefix/", nil)
+		Expect(err).To(Equal(consuladapter.ReadOnlyError{Path: "prefix/"}))
+
+		_, err = client.LockOpts(&api.LockOptions{Key: "v1/locks/bbs"})
+		Expect(err).To(Equal(consuladapter.ReadOnlyError{Path: "v1/locks/bbs"}))
+	})
+
+	It("rejects every other write before it is sent", func() {
+		err := client.Agent().ServiceRegister(&api.AgentServiceRegistration{Name: "service"})
+		Expect(consuladapter.IsReadOnlyError(err)).To(BeTrue())
+
+		_, _, err = client.Session().Create(&api.SessionEntry{}, nil)
+		Expect(consuladapter.IsReadOnlyError(err)).To(BeTrue())
+
+		Expect(atomic.LoadInt32(&writes)).To(BeZero())
+	})
+})
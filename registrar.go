@@ -0,0 +1,289 @@
+package consuladapter
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+)
+
+type RegistrarConfig struct {
+	// DeregisterCriticalServiceAfter is applied to every check that does not
+	// set its own, so that crashed instances are eventually removed from the
+	// catalog. Consul reaps critical services at most every 30 seconds, and
+	// never sooner than one minute. Zero leaves checks unchanged.
+	DeregisterCriticalServiceAfter time.Duration
+}
+
+// Registrar registers services on the local agent with a common policy and
+// remembers what it registered.
+type Registrar struct {
+	agent  Agent
+	config RegistrarConfig
+
+	mutex    sync.Mutex
+	services map[string]*api.AgentServiceRegistration
+}
+
+func NewRegistrar(agent Agent, config RegistrarConfig) *Registrar {
+	return &Registrar{
+		agent:    agent,
+		config:   config,
+		services: map[string]*api.AgentServiceRegistration{},
+	}
+}
+
+func (r *Registrar) Register(service *api.AgentServiceRegistration, definitions ...CheckDefinition) error {
+	registration, err := serviceRegistration(service, definitions)
+	if err != nil {
+		return err
+	}
+
+	registration.Check = r.applyPolicy(registration.Check)
+	for i, check := range registration.Checks {
+		registration.Checks[i] = r.applyPolicy(check)
+	}
+
+	err = r.agent.ServiceRegister(registration)
+	if err != nil {
+		return err
+	}
+
+	r.mutex.Lock()
+	r.services[serviceID(registration)] = registration
+	r.mutex.Unlock()
+
+	return nil
+}
+
+func (r *Registrar) Deregister(serviceID string) error {
+	err := r.agent.ServiceDeregister(serviceID)
+	if err != nil {
+		return err
+	}
+
+	r.mutex.Lock()
+	delete(r.services, serviceID)
+	r.mutex.Unlock()
+
+	return nil
+}
+
+type DrainOptions struct {
+	// Reason is recorded on the maintenance check while draining.
+	Reason string
+
+	// GracePeriod bounds how long to wait before deregistering.
+	GracePeriod time.Duration
+
+	// InFlight, if set, is polled every PollInterval, and the service is
+	// deregistered as soon as it returns zero rather than after the full
+	// GracePeriod.
+	InFlight     func() int
+	PollInterval time.Duration
+}
+
+const defaultDrainPollInterval = 100 * time.Millisecond
+
+// DeregisterWithDrain puts the service into maintenance mode, so discovery
+// stops returning it, waits for in-flight work to drain, and then
+// deregisters it. If deregistering fails, it takes the service back out of
+// maintenance mode rather than leave it registered but undiscoverable.
+func (r *Registrar) DeregisterWithDrain(serviceID string, opts DrainOptions) error {
+	reason := opts.Reason
+	if reason == "" {
+		reason = "draining before deregistration"
+	}
+
+	err := r.agent.EnableServiceMaintenance(serviceID, reason)
+	if err != nil {
+		return err
+	}
+
+	pollInterval := opts.PollInterval
+	if pollInterval <= 0 {
+		pollInterval = defaultDrainPollInterval
+	}
+
+	deadline := time.After(opts.GracePeriod)
+	if opts.InFlight != nil {
+		ticker := time.NewTicker(pollInterval)
+		defer ticker.Stop()
+
+	drain:
+		for opts.InFlight() > 0 {
+			select {
+			case <-deadline:
+				break drain
+			case <-ticker.C:
+			}
+		}
+	} else {
+		<-deadline
+	}
+
+	err = r.Deregister(serviceID)
+	if err != nil {
+		if disableErr := r.agent.DisableServiceMaintenance(serviceID); disableErr != nil {
+			return fmt.Errorf("%w; disabling maintenance mode also failed: %s", err, disableErr)
+		}
+		return err
+	}
+	return nil
+}
+
+type DriftKind string
+
+const (
+	DriftMissingService DriftKind = "missing-service"
+	DriftChangedService DriftKind = "changed-service"
+	DriftMissingCheck   DriftKind = "missing-check"
+	DriftExtraCheck     DriftKind = "extra-check"
+)
+
+type Drift struct {
+	Kind      DriftKind
+	ServiceID string
+	CheckID   string
+	Detail    string
+}
+
+func (d Drift) String() string {
+	if d.CheckID != "" {
+		return fmt.Sprintf("%s: service %s check %s", d.Kind, d.ServiceID, d.CheckID)
+	}
+	if d.Detail != "" {
+		return fmt.Sprintf("%s: service %s %s", d.Kind, d.ServiceID, d.Detail)
+	}
+	return fmt.Sprintf("%s: service %s", d.Kind, d.ServiceID)
+}
+
+// Verify compares the services registered through r with the agent's current
+// state and reports any drift, ordered by service. An empty result means the
+// agent matches.
+func (r *Registrar) Verify() ([]Drift, error) {
+	services, err := r.agent.Services()
+	if err != nil {
+		return nil, err
+	}
+
+	checks, err := r.agent.Checks()
+	if err != nil {
+		return nil, err
+	}
+
+	r.mutex.Lock()
+	registered := make(map[string]*api.AgentServiceRegistration, len(r.services))
+	ids := make([]string, 0, len(r.services))
+	for id, service := range r.services {
+		registered[id] = service
+		ids = append(ids, id)
+	}
+	r.mutex.Unlock()
+	sort.Strings(ids)
+
+	drift := []Drift{}
+	for _, id := range ids {
+		intended := registered[id]
+		actual, ok := services[id]
+		if !ok {
+			drift = append(drift, Drift{Kind: DriftMissingService, ServiceID: id})
+			continue
+		}
+
+		if actual.Port != intended.Port {
+			drift = append(drift, Drift{Kind: DriftChangedService, ServiceID: id, Detail: fmt.Sprintf("port %d, expected %d", actual.Port, intended.Port)})
+		}
+		if actual.Address != intended.Address {
+			drift = append(drift, Drift{Kind: DriftChangedService, ServiceID: id, Detail: fmt.Sprintf("address '%s', expected '%s'", actual.Address, intended.Address)})
+		}
+		if !sameTags(actual.Tags, intended.Tags) {
+			drift = append(drift, Drift{Kind: DriftChangedService, ServiceID: id, Detail: fmt.Sprintf("tags %v, expected %v", actual.Tags, intended.Tags)})
+		}
+
+		drift = append(drift, checkDrift(id, intended, checks)...)
+	}
+
+	return drift, nil
+}
+
+func checkDrift(id string, intended *api.AgentServiceRegistration, checks map[string]*api.AgentCheck) []Drift {
+	drift := []Drift{}
+
+	expected := map[string]bool{}
+	for _, checkID := range serviceCheckIDs(id, intended) {
+		expected[checkID] = true
+		if _, ok := checks[checkID]; !ok {
+			drift = append(drift, Drift{Kind: DriftMissingCheck, ServiceID: id, CheckID: checkID})
+		}
+	}
+
+	extra := []string{}
+	for checkID, check := range checks {
+		if check.ServiceID == id && !expected[checkID] {
+			extra = append(extra, checkID)
+		}
+	}
+	sort.Strings(extra)
+	for _, checkID := range extra {
+		drift = append(drift, Drift{Kind: DriftExtraCheck, ServiceID: id, CheckID: checkID})
+	}
+
+	return drift
+}
+
+// serviceCheckIDs returns the IDs the agent gives a registration's checks,
+// following consul's naming for checks registered without a CheckID.
+func serviceCheckIDs(id string, service *api.AgentServiceRegistration) []string {
+	checks := api.AgentServiceChecks{}
+	if service.Check != nil {
+		checks = append(checks, service.Check)
+	}
+	checks = append(checks, service.Checks...)
+
+	ids := make([]string, len(checks))
+	for i, check := range checks {
+		switch {
+		case check.CheckID != "":
+			ids[i] = check.CheckID
+		case len(checks) > 1:
+			ids[i] = fmt.Sprintf("service:%s:%d", id, i+1)
+		default:
+			ids[i] = "service:" + id
+		}
+	}
+
+	return ids
+}
+
+func sameTags(a, b []string) bool {
+	if len(a) == 0 && len(b) == 0 {
+		return true
+	}
+
+	sortedA := append([]string{}, a...)
+	sortedB := append([]string{}, b...)
+	sort.Strings(sortedA)
+	sort.Strings(sortedB)
+	return reflect.DeepEqual(sortedA, sortedB)
+}
+
+func (r *Registrar) applyPolicy(check *api.AgentServiceCheck) *api.AgentServiceCheck {
+	if check == nil || check.DeregisterCriticalServiceAfter != "" || r.config.DeregisterCriticalServiceAfter <= 0 {
+		return check
+	}
+
+	withPolicy := *check
+	withPolicy.DeregisterCriticalServiceAfter = r.config.DeregisterCriticalServiceAfter.String()
+	return &withPolicy
+}
+
+func serviceID(service *api.AgentServiceRegistration) string {
+	if service.ID != "" {
+		return service.ID
+	}
+	return service.Name
+}
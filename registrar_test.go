@@ -0,0 +1,161 @@
+package consuladapter_test
+
+import (
+	"errors"
+	"time"
+
+	"code.cloudfoundry.org/consuladapter"
+	"code.cloudfoundry.org/consuladapter/fakes"
+	"github.com/hashicorp/consul/api"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Registrar", func() {
+	var (
+		agent     *fakes.FakeAgent
+		registrar *consuladapter.Registrar
+	)
+
+	BeforeEach(func() {
+		agent = &fakes.FakeAgent{}
+		registrar = consuladapter.NewRegistrar(agent, consuladapter.RegistrarConfig{
+			DeregisterCriticalServiceAfter: time.Minute,
+		})
+	})
+
+	Describe("Register", func() {
+		It("applies the deregister policy to checks that do not set their own", func() {
+			err := registrar.Register(
+				&api.AgentServiceRegistration{
+					Name:  "service",
+					Check: &api.AgentServiceCheck{TTL: "10s", DeregisterCriticalServiceAfter: "5m"},
+				},
+				consuladapter.TTLCheck{CheckOptions: consuladapter.CheckOptions{Name: "ttl"}, TTL: 10 * time.Second},
+			)
+			Expect(err).NotTo(HaveOccurred())
+
+			registration := agent.ServiceRegisterArgsForCall(0)
+			Expect(registration.Check.DeregisterCriticalServiceAfter).To(Equal("5m"))
+			Expect(registration.Checks).To(HaveLen(1))
+			Expect(registration.Checks[0].DeregisterCriticalServiceAfter).To(Equal("1m0s"))
+		})
+
+		It("does not modify the caller's checks", func() {
+			check := &api.AgentServiceCheck{TTL: "10s"}
+			err := registrar.Register(&api.AgentServiceRegistration{Name: "service", Checks: api.AgentServiceChecks{check}})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(check.DeregisterCriticalServiceAfter).To(BeEmpty())
+		})
+	})
+
+	Describe("Verify", func() {
+		BeforeEach(func() {
+			err := registrar.Register(
+				&api.AgentServiceRegistration{ID: "service-1", Name: "service", Port: 8080, Tags: []string{"a", "b"}},
+				consuladapter.TTLCheck{CheckOptions: consuladapter.CheckOptions{ID: "ttl", Name: "ttl"}, TTL: 10 * time.Second},
+				consuladapter.TCPCheck{CheckOptions: consuladapter.CheckOptions{Name: "tcp", Interval: time.Second}, Address: "127.0.0.1:8080"},
+			)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("reports no drift when the agent matches", func() {
+			agent.ServicesReturns(map[string]*api.AgentService{
+				"service-1": {ID: "service-1", Service: "service", Port: 8080, Tags: []string{"b", "a"}},
+			}, nil)
+			agent.ChecksReturns(map[string]*api.AgentCheck{
+				"ttl":                 {CheckID: "ttl", ServiceID: "service-1"},
+				"service:service-1:2": {CheckID: "service:service-1:2", ServiceID: "service-1"},
+			}, nil)
+
+			Expect(registrar.Verify()).To(BeEmpty())
+		})
+
+		It("reports missing services", func() {
+			agent.ServicesReturns(map[string]*api.AgentService{}, nil)
+			agent.ChecksReturns(map[string]*api.AgentCheck{}, nil)
+
+			Expect(registrar.Verify()).To(Equal([]consuladapter.Drift{
+				{Kind: consuladapter.DriftMissingService, ServiceID: "service-1"},
+			}))
+		})
+
+		It("reports changed ports and missing or extra checks", func() {
+			agent.ServicesReturns(map[string]*api.AgentService{
+				"service-1": {ID: "service-1", Service: "service", Port: 9090, Tags: []string{"a", "b"}},
+			}, nil)
+			agent.ChecksReturns(map[string]*api.AgentCheck{
+				"ttl":   {CheckID: "ttl", ServiceID: "service-1"},
+				"other": {CheckID: "other", ServiceID: "service-1"},
+			}, nil)
+
+			Expect(registrar.Verify()).To(Equal([]consuladapter.Drift{
+				{Kind: consuladapter.DriftChangedService, ServiceID: "service-1", Detail: "port 9090, expected 8080"},
+				{Kind: consuladapter.DriftMissingCheck, ServiceID: "service-1", CheckID: "service:service-1:2"},
+				{Kind: consuladapter.DriftExtraCheck, ServiceID: "service-1", CheckID: "other"},
+			}))
+		})
+
+		It("stops verifying deregistered services", func() {
+			Expect(registrar.Deregister("service-1")).To(Succeed())
+			Expect(registrar.Verify()).To(BeEmpty())
+		})
+	})
+
+	Describe("DeregisterWithDrain", func() {
+		It("enters maintenance, waits for in-flight work, then deregisters", func() {
+			inFlight := 3
+			err := registrar.DeregisterWithDrain("service-1", consuladapter.DrainOptions{
+				GracePeriod:  time.Minute,
+				PollInterval: time.Millisecond,
+				InFlight: func() int {
+					Expect(agent.EnableServiceMaintenanceCallCount()).To(Equal(1))
+					Expect(agent.ServiceDeregisterCallCount()).To(Equal(0))
+					inFlight--
+					return inFlight
+				},
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(inFlight).To(Equal(0))
+			serviceID, reason := agent.EnableServiceMaintenanceArgsForCall(0)
+			Expect(serviceID).To(Equal("service-1"))
+			Expect(reason).NotTo(BeEmpty())
+			Expect(agent.ServiceDeregisterArgsForCall(0)).To(Equal("service-1"))
+		})
+
+		It("deregisters after the grace period even if work is still in flight", func() {
+			err := registrar.DeregisterWithDrain("service-1", consuladapter.DrainOptions{
+				GracePeriod:  10 * time.Millisecond,
+				PollInterval: time.Millisecond,
+				InFlight:     func() int { return 1 },
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(agent.ServiceDeregisterCallCount()).To(Equal(1))
+		})
+
+		It("takes the service out of maintenance mode when deregistering fails", func() {
+			deregisterErr := errors.New("agent unavailable")
+			agent.ServiceDeregisterReturns(deregisterErr)
+
+			err := registrar.DeregisterWithDrain("service-1", consuladapter.DrainOptions{})
+			Expect(err).To(Equal(deregisterErr))
+			Expect(agent.DisableServiceMaintenanceCallCount()).To(Equal(1))
+			Expect(agent.DisableServiceMaintenanceArgsForCall(0)).To(Equal("service-1"))
+		})
+
+		It("reports both errors when maintenance mode cannot be disabled either", func() {
+			agent.ServiceDeregisterReturns(errors.New("agent unavailable"))
+			agent.DisableServiceMaintenanceReturns(errors.New("still unavailable"))
+
+			err := registrar.DeregisterWithDrain("service-1", consuladapter.DrainOptions{})
+			Expect(err).To(MatchError("agent unavailable; disabling maintenance mode also failed: still unavailable"))
+		})
+
+		It("leaves maintenance mode alone when deregistering succeeds", func() {
+			Expect(registrar.DeregisterWithDrain("service-1", consuladapter.DrainOptions{})).To(Succeed())
+			Expect(agent.DisableServiceMaintenanceCallCount()).To(BeZero())
+		})
+	})
+})
@@ -0,0 +1,132 @@
+package consuladapter
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// HostResolver looks up the addresses of a host name. *net.Resolver
+// implements it.
+type HostResolver interface {
+	LookupHost(ctx context.Context, host string) ([]string, error)
+}
+
+// resolveTimeout bounds each background lookup of the agent's host name.
+const resolveTimeout = 10 * time.Second
+
+// reresolvingTransport re-resolves the agent's host name at most once per
+// interval and, when its addresses change, replaces its transport with a
+// fresh one so that subsequent requests connect to the agent's new location
+// rather than reusing connections, idle or not, to the old one. Requests
+// already in flight, such as blocking queries, finish on the old transport.
+//
+// Lookups run in the background, one at a time, so requests never wait for
+// DNS; they use the current transport until a lookup finds new addresses.
+type reresolvingTransport struct {
+	newTransport func() http.RoundTripper
+	host         string
+	interval     time.Duration
+	resolver     HostResolver
+
+	mu           sync.Mutex
+	transport    http.RoundTripper
+	addrs        []string
+	lastResolved time.Time
+	resolving    bool
+}
+
+// NewReresolvingTransport returns a transport sending requests through a
+// transport built by newTransport, re-resolving address's host name at most
+// once per interval and building a new transport whenever its addresses
+// change. If address is an IP address there is nothing to re-resolve, and it
+// returns newTransport() as is. A nil resolver means net.DefaultResolver.
+func NewReresolvingTransport(newTransport func() http.RoundTripper, address string, interval time.Duration, resolver HostResolver) http.RoundTripper {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		host = address
+	}
+	if net.ParseIP(host) != nil {
+		return newTransport()
+	}
+
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	return &reresolvingTransport{
+		newTransport: newTransport,
+		host:         host,
+		interval:     interval,
+		resolver:     resolver,
+		transport:    newTransport(),
+	}
+}
+
+func (t *reresolvingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.mu.Lock()
+	transport := t.transport
+	if !t.resolving && time.Since(t.lastResolved) >= t.interval {
+		t.resolving = true
+		t.lastResolved = time.Now()
+		goBackground(nil, t.reresolve, nil)
+	}
+	t.mu.Unlock()
+
+	return transport.RoundTrip(req)
+}
+
+func (t *reresolvingTransport) reresolve() {
+	defer func() {
+		t.mu.Lock()
+		t.resolving = false
+		t.mu.Unlock()
+	}()
+
+	// not the request's context: the lookup outlives the request that
+	// started it, and a cancelled request must not fail it
+	ctx, cancel := context.WithTimeout(context.Background(), resolveTimeout)
+	defer cancel()
+	addrs, err := t.resolver.LookupHost(ctx, t.host)
+	if err != nil {
+		// keep using the existing transport until resolution recovers
+		return
+	}
+
+	t.mu.Lock()
+	changed := t.addrs != nil && !sameAddrs(t.addrs, addrs)
+	t.addrs = addrs
+	t.mu.Unlock()
+	if !changed {
+		return
+	}
+
+	transport := t.newTransport()
+	t.mu.Lock()
+	old := t.transport
+	t.transport = transport
+	t.mu.Unlock()
+
+	if closer, ok := old.(interface{ CloseIdleConnections() }); ok {
+		closer.CloseIdleConnections()
+	}
+}
+
+func sameAddrs(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	sortedA := append([]string{}, a...)
+	sortedB := append([]string{}, b...)
+	sort.Strings(sortedA)
+	sort.Strings(sortedB)
+	for i := range sortedA {
+		if sortedA[i] != sortedB[i] {
+			return false
+		}
+	}
+	return true
+}
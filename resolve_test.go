@@ -0,0 +1,168 @@
+package consuladapter_test
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/consuladapter"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type fakeResolver struct {
+	mutex   sync.Mutex
+	addrs   []string
+	lookups int
+	block   chan struct{}
+}
+
+func (r *fakeResolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	r.mutex.Lock()
+	r.lookups++
+	block := r.block
+	r.mutex.Unlock()
+
+	if block != nil {
+		<-block
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.addrs, nil
+}
+
+func (r *fakeResolver) setAddrs(addrs ...string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.addrs = addrs
+}
+
+func (r *fakeResolver) lookupCount() int {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.lookups
+}
+
+type generationTransport struct {
+	generation int
+
+	mutex      sync.Mutex
+	closedIdle bool
+}
+
+func (t *generationTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return &http.Response{StatusCode: http.StatusOK, Header: http.Header{"Generation": {strconv.Itoa(t.generation)}}}, nil
+}
+
+func (t *generationTransport) CloseIdleConnections() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.closedIdle = true
+}
+
+func (t *generationTransport) idleClosed() bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.closedIdle
+}
+
+var _ = Describe("NewReresolvingTransport", func() {
+	const interval = 20 * time.Millisecond
+
+	var (
+		resolver   *fakeResolver
+		mutex      sync.Mutex
+		transports []*generationTransport
+		transport  http.RoundTripper
+	)
+
+	newTransport := func() http.RoundTripper {
+		mutex.Lock()
+		defer mutex.Unlock()
+		t := &generationTransport{generation: len(transports) + 1}
+		transports = append(transports, t)
+		return t
+	}
+
+	transportAt := func(i int) *generationTransport {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return transports[i]
+	}
+
+	built := func() int {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return len(transports)
+	}
+
+	generation := func() string {
+		req, err := http.NewRequest("GET", "http://consul.service:8500/v1/kv/key", nil)
+		Expect(err).NotTo(HaveOccurred())
+		resp, err := transport.RoundTrip(req)
+		Expect(err).NotTo(HaveOccurred())
+		return resp.Header.Get("Generation")
+	}
+
+	BeforeEach(func() {
+		resolver = &fakeResolver{addrs: []string{"10.0.0.1", "10.0.0.2"}}
+		mutex.Lock()
+		transports = nil
+		mutex.Unlock()
+		transport = consuladapter.NewReresolvingTransport(newTransport, "consul.service:8500", interval, resolver)
+	})
+
+	It("does not re-resolve IP addresses", func() {
+		transport = consuladapter.NewReresolvingTransport(newTransport, "10.0.0.1:8500", interval, resolver)
+		Expect(generation()).To(Equal("2"))
+		time.Sleep(2 * interval)
+		Expect(generation()).To(Equal("2"))
+		Expect(resolver.lookupCount()).To(BeZero())
+	})
+
+	It("keeps its transport while the addresses stay the same, in any order", func() {
+		Expect(generation()).To(Equal("1"))
+		Eventually(resolver.lookupCount).Should(Equal(1))
+
+		resolver.setAddrs("10.0.0.2", "10.0.0.1")
+		time.Sleep(2 * interval)
+		Expect(generation()).To(Equal("1"))
+		Eventually(resolver.lookupCount).Should(Equal(2))
+
+		time.Sleep(2 * interval)
+		Expect(generation()).To(Equal("1"))
+		Expect(built()).To(Equal(1))
+	})
+
+	It("builds a new transport when the addresses change, closing the old one's idle connections", func() {
+		Expect(generation()).To(Equal("1"))
+		Eventually(resolver.lookupCount).Should(Equal(1))
+
+		resolver.setAddrs("10.0.0.3")
+		Eventually(generation).Should(Equal("2"))
+		Expect(transportAt(0).idleClosed()).To(BeTrue())
+	})
+
+	It("does not hold up requests while looking up, and looks up one at a time", func() {
+		block := make(chan struct{})
+		resolver.block = block
+		defer close(block)
+
+		done := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			for i := 0; i < 5; i++ {
+				generation()
+				time.Sleep(interval)
+			}
+			close(done)
+		}()
+
+		Eventually(done).Should(BeClosed())
+		Expect(resolver.lookupCount()).To(Equal(1))
+	})
+})
@@ -0,0 +1,192 @@
+package consuladapter
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RequestPriority orders outbound requests when ClientOptions.RequestRateLimit
+// is exceeded; queued requests of higher priority are sent first.
+type RequestPriority int
+
+const (
+	// RequestPriorityWatch is used for blocking queries.
+	RequestPriorityWatch RequestPriority = iota
+	// RequestPriorityDefault is used for KV writes and any request not
+	// otherwise classified.
+	RequestPriorityDefault
+	// RequestPriorityLock is used for session creation and destruction and
+	// KV acquire and release.
+	RequestPriorityLock
+	// RequestPrioritySessionRenewal is used for session renewals, so that
+	// bulk traffic cannot cause sessions, and the locks they hold, to expire.
+	RequestPrioritySessionRenewal
+
+	numRequestPriorities = int(RequestPrioritySessionRenewal) + 1
+)
+
+// ClassifyRequest returns the priority the scheduler gives req.
+func ClassifyRequest(req *http.Request) RequestPriority {
+	path := req.URL.Path
+	query := req.URL.Query()
+
+	switch {
+	case strings.HasPrefix(path, "/v1/session/renew/"):
+		return RequestPrioritySessionRenewal
+	case strings.HasPrefix(path, "/v1/session/create"), strings.HasPrefix(path, "/v1/session/destroy/"):
+		return RequestPriorityLock
+	case strings.HasPrefix(path, "/v1/kv/") && (query.Get("acquire") != "" || query.Get("release") != ""):
+		return RequestPriorityLock
+	case req.Method == http.MethodGet && query.Get("index") != "":
+		return RequestPriorityWatch
+	default:
+		return RequestPriorityDefault
+	}
+}
+
+type schedulingTransport struct {
+	transport http.RoundTripper
+	scheduler *requestScheduler
+}
+
+// NewSchedulingTransport limits requests through transport to rate per
+// second, with bursts of up to burst, queueing requests over the limit by
+// ClassifyRequest priority. ClientOptions.RequestRateLimit installs it.
+func NewSchedulingTransport(transport http.RoundTripper, rate float64, burst int) http.RoundTripper {
+	return &schedulingTransport{
+		transport: transport,
+		scheduler: newRequestScheduler(rate, burst),
+	}
+}
+
+func (t *schedulingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	err := t.scheduler.wait(req.Context(), ClassifyRequest(req))
+	if err != nil {
+		return nil, err
+	}
+	return t.transport.RoundTrip(req)
+}
+
+type schedulerWaiter struct {
+	ready   chan struct{}
+	granted bool
+}
+
+// requestScheduler is a token bucket that hands out tokens to queued
+// waiters in priority order, and in arrival order within a priority.
+type requestScheduler struct {
+	rate  float64
+	burst float64
+
+	mu         sync.Mutex
+	tokens     float64
+	lastRefill time.Time
+	queues     [numRequestPriorities][]*schedulerWaiter
+	timer      *time.Timer
+}
+
+func newRequestScheduler(rate float64, burst int) *requestScheduler {
+	if burst < 1 {
+		burst = 1
+	}
+	return &requestScheduler{
+		rate:       rate,
+		burst:      float64(burst),
+		tokens:     float64(burst),
+		lastRefill: time.Now(),
+	}
+}
+
+func (s *requestScheduler) wait(ctx context.Context, priority RequestPriority) error {
+	s.mu.Lock()
+	s.refill()
+	if s.tokens >= 1 && !s.hasWaitersAtOrAbove(priority) {
+		s.tokens--
+		s.mu.Unlock()
+		return nil
+	}
+
+	w := &schedulerWaiter{ready: make(chan struct{})}
+	s.queues[priority] = append(s.queues[priority], w)
+	s.scheduleDispatch()
+	s.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return nil
+	case <-ctx.Done():
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if w.granted {
+			// the token was handed over as ctx finished; give it back
+			s.tokens++
+			s.scheduleDispatch()
+			return ctx.Err()
+		}
+		s.remove(priority, w)
+		return ctx.Err()
+	}
+}
+
+func (s *requestScheduler) dispatch() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.timer = nil
+	s.refill()
+	for priority := numRequestPriorities - 1; priority >= 0; priority-- {
+		for len(s.queues[priority]) > 0 && s.tokens >= 1 {
+			w := s.queues[priority][0]
+			s.queues[priority] = s.queues[priority][1:]
+			w.granted = true
+			close(w.ready)
+			s.tokens--
+		}
+	}
+	s.scheduleDispatch()
+}
+
+// scheduleDispatch must be called with mu held.
+func (s *requestScheduler) scheduleDispatch() {
+	if s.timer != nil || !s.hasWaitersAtOrAbove(RequestPriorityWatch) {
+		return
+	}
+
+	var delay time.Duration
+	if s.tokens < 1 {
+		delay = time.Duration((1 - s.tokens) / s.rate * float64(time.Second))
+	}
+	s.timer = time.AfterFunc(delay, s.dispatch)
+}
+
+// refill must be called with mu held.
+func (s *requestScheduler) refill() {
+	now := time.Now()
+	s.tokens += now.Sub(s.lastRefill).Seconds() * s.rate
+	if s.tokens > s.burst {
+		s.tokens = s.burst
+	}
+	s.lastRefill = now
+}
+
+func (s *requestScheduler) hasWaitersAtOrAbove(priority RequestPriority) bool {
+	for p := int(priority); p < numRequestPriorities; p++ {
+		if len(s.queues[p]) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *requestScheduler) remove(priority RequestPriority, w *schedulerWaiter) {
+	queue := s.queues[priority]
+	for i := range queue {
+		if queue[i] == w {
+			s.queues[priority] = append(queue[:i], queue[i+1:]...)
+			return
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package consuladapter_test
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/consuladapter"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type recordingTransport struct {
+	mu   sync.Mutex
+	sent []string
+}
+
+func (t *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.sent = append(t.sent, req.URL.Path)
+	return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+}
+
+func (t *recordingTransport) Sent() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]string{}, t.sent...)
+}
+
+var _ = Describe("ClassifyRequest", func() {
+	classify := func(method, url string) consuladapter.RequestPriority {
+		req, err := http.NewRequest(method, url, nil)
+		Expect(err).NotTo(HaveOccurred())
+		return consuladapter.ClassifyRequest(req)
+	}
+
+	It("prioritises session renewals, then lock traffic, then writes, then watches", func() {
+		Expect(classify("PUT", "http://consul/v1/session/renew/abc")).To(Equal(consuladapter.RequestPrioritySessionRenewal))
+		Expect(classify("PUT", "http://consul/v1/session/create")).To(Equal(consuladapter.RequestPriorityLock))
+		Expect(classify("PUT", "http://consul/v1/session/destroy/abc")).To(Equal(consuladapter.RequestPriorityLock))
+		Expect(classify("PUT", "http://consul/v1/kv/v1/locks/bbs?acquire=abc")).To(Equal(consuladapter.RequestPriorityLock))
+		Expect(classify("PUT", "http://consul/v1/kv/v1/locks/bbs?release=abc")).To(Equal(consuladapter.RequestPriorityLock))
+		Expect(classify("PUT", "http://consul/v1/kv/v1/presence/cell-1")).To(Equal(consuladapter.RequestPriorityDefault))
+		Expect(classify("GET", "http://consul/v1/kv/v1/presence/?recurse&index=42")).To(Equal(consuladapter.RequestPriorityWatch))
+		Expect(classify("GET", "http://consul/v1/kv/v1/presence/?recurse")).To(Equal(consuladapter.RequestPriorityDefault))
+	})
+})
+
+var _ = Describe("NewSchedulingTransport", func() {
+	var (
+		recorder  *recordingTransport
+		transport http.RoundTripper
+	)
+
+	BeforeEach(func() {
+		recorder = &recordingTransport{}
+		transport = consuladapter.NewSchedulingTransport(recorder, 10, 1)
+	})
+
+	send := func(ctx context.Context, method, url string) <-chan error {
+		errCh := make(chan error, 1)
+		go func() {
+			req, err := http.NewRequest(method, url, nil)
+			if err == nil {
+				_, err = transport.RoundTrip(req.WithContext(ctx))
+			}
+			errCh <- err
+		}()
+		return errCh
+	}
+
+	It("sends queued requests in priority order", func() {
+		Eventually(send(context.Background(), "PUT", "http://consul/v1/kv/first")).Should(Receive(BeNil()))
+
+		watch := send(context.Background(), "GET", "http://consul/v1/kv/watched?index=42")
+		time.Sleep(20 * time.Millisecond)
+		write := send(context.Background(), "PUT", "http://consul/v1/kv/written")
+		time.Sleep(20 * time.Millisecond)
+		renewal := send(context.Background(), "PUT", "http://consul/v1/session/renew/abc")
+
+		Eventually(watch).Should(Receive(BeNil()))
+		Eventually(write).Should(Receive(BeNil()))
+		Eventually(renewal).Should(Receive(BeNil()))
+		Expect(recorder.Sent()).To(Equal([]string{"/v1/kv/first", "/v1/session/renew/abc", "/v1/kv/written", "/v1/kv/watched"}))
+	})
+
+	It("gives up on queued requests whose context is done without using a token", func() {
+		Eventually(send(context.Background(), "PUT", "http://consul/v1/kv/first")).Should(Receive(BeNil()))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancelled := send(ctx, "PUT", "http://consul/v1/kv/cancelled")
+		next := send(context.Background(), "PUT", "http://consul/v1/kv/next")
+		cancel()
+
+		Eventually(cancelled).Should(Receive(Equal(context.Canceled)))
+		Eventually(next).Should(Receive(BeNil()))
+		Expect(recorder.Sent()).To(Equal([]string{"/v1/kv/first", "/v1/kv/next"}))
+	})
+
+	It("does not lose tokens to requests cancelled as they are granted", func() {
+		transport = consuladapter.NewSchedulingTransport(recorder, 200, 1)
+
+		var results []<-chan error
+		for i := 0; i < 20; i++ {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Duration(i)*5*time.Millisecond)
+			defer cancel()
+			results = append(results, send(ctx, "PUT", "http://consul/v1/kv/cancellable"))
+		}
+		for _, result := range results {
+			Eventually(result).Should(Receive())
+		}
+
+		started := time.Now()
+		for i := 0; i < 5; i++ {
+			Eventually(send(context.Background(), "PUT", "http://consul/v1/kv/after")).Should(Receive(BeNil()))
+		}
+		Expect(time.Since(started)).To(BeNumerically("<", 500*time.Millisecond))
+	})
+})
@@ -0,0 +1,21 @@
+package consuladapter
+
+import "github.com/hashicorp/consul/api"
+
+// NetworkSegmentNodeMetaKey is the node metadata key Consul Enterprise sets
+// to the name of the network segment a node belongs to. Nodes in the default
+// segment have an empty value.
+const NetworkSegmentNodeMetaKey = "consul-network-segment"
+
+// SegmentMembers returns the LAN members of the given network segment.
+func SegmentMembers(agent Agent, segment string) ([]*api.AgentMember, error) {
+	return agent.MembersOpts(api.MembersOpts{Segment: segment})
+}
+
+// SegmentQueryOptions restricts catalog and health queries to nodes in the
+// given network segment.
+func SegmentQueryOptions(segment string) *api.QueryOptions {
+	return &api.QueryOptions{
+		NodeMeta: map[string]string{NetworkSegmentNodeMetaKey: segment},
+	}
+}
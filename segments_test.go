@@ -0,0 +1,27 @@
+package consuladapter_test
+
+import (
+	"code.cloudfoundry.org/consuladapter"
+	"code.cloudfoundry.org/consuladapter/fakes"
+	"github.com/hashicorp/consul/api"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Network segments", func() {
+	It("lists the members of a segment", func() {
+		agent := &fakes.FakeAgent{}
+		agent.MembersOptsReturns([]*api.AgentMember{{Name: "cell-1"}}, nil)
+
+		members, err := consuladapter.SegmentMembers(agent, "cells")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(members).To(HaveLen(1))
+		Expect(agent.MembersOptsArgsForCall(0)).To(Equal(api.MembersOpts{Segment: "cells"}))
+	})
+
+	It("scopes queries to a segment by node metadata", func() {
+		q := consuladapter.SegmentQueryOptions("cells")
+		Expect(q.NodeMeta).To(Equal(map[string]string{consuladapter.NetworkSegmentNodeMetaKey: "cells"}))
+	})
+})
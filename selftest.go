@@ -0,0 +1,168 @@
+package consuladapter
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+)
+
+// SelfTestKeyPrefix is where SelfTest writes its scratch keys; the token
+// needs key_prefix write on it.
+const SelfTestKeyPrefix = "consuladapter-self-test/"
+
+const (
+	SelfTestLeader  = "leader"
+	SelfTestKV      = "kv"
+	SelfTestSession = "session"
+)
+
+type SelfTestStep struct {
+	Name     string        `json:"name"`
+	OK       bool          `json:"ok"`
+	Skipped  bool          `json:"skipped,omitempty"`
+	Duration time.Duration `json:"duration_ns"`
+	Error    string        `json:"error,omitempty"`
+
+	// Hint suggests what to check when the step failed.
+	Hint string `json:"hint,omitempty"`
+}
+
+type SelfTestReport struct {
+	OK    bool           `json:"ok"`
+	Steps []SelfTestStep `json:"steps"`
+}
+
+// Err returns nil if every step passed, and otherwise an error describing
+// the first failure.
+func (r SelfTestReport) Err() error {
+	for _, step := range r.Steps {
+		if step.OK || step.Skipped {
+			continue
+		}
+		if step.Hint != "" {
+			return fmt.Errorf("consul self-test step %s failed: %s (%s)", step.Name, step.Error, step.Hint)
+		}
+		return fmt.Errorf("consul self-test step %s failed: %s", step.Name, step.Error)
+	}
+	return nil
+}
+
+// RunSelfTest checks that client can do what components need from consul:
+// that the cluster has a leader, that a scratch key can be written, read and
+// deleted, and that a session can be created and destroyed. The remaining
+// steps are skipped if there is no leader, or once ctx is done.
+func RunSelfTest(ctx context.Context, client Client) SelfTestReport {
+	steps := []struct {
+		name string
+		fn   func(context.Context, Client) error
+	}{
+		{SelfTestLeader, selfTestLeader},
+		{SelfTestKV, selfTestKV},
+		{SelfTestSession, selfTestSession},
+	}
+
+	report := SelfTestReport{OK: true}
+	skip := false
+	for _, step := range steps {
+		if skip || ctx.Err() != nil {
+			report.Steps = append(report.Steps, SelfTestStep{Name: step.name, Skipped: true})
+			report.OK = false
+			continue
+		}
+
+		start := time.Now()
+		err := step.fn(ctx, client)
+		result := SelfTestStep{Name: step.name, OK: err == nil, Duration: time.Since(start)}
+		if err != nil {
+			result.Error = err.Error()
+			result.Hint = selfTestHint(step.name, err)
+			report.OK = false
+			skip = step.name == SelfTestLeader
+		}
+		report.Steps = append(report.Steps, result)
+	}
+
+	return report
+}
+
+func selfTestLeader(ctx context.Context, client Client) error {
+	leader, err := client.Status().Leader()
+	if err != nil {
+		return err
+	}
+	if leader == "" {
+		return errors.New("cluster has no leader")
+	}
+	return nil
+}
+
+func selfTestKV(ctx context.Context, client Client) error {
+	key := fmt.Sprintf("%s%d", SelfTestKeyPrefix, time.Now().UnixNano())
+	value := []byte("self-test")
+
+	_, err := client.KV().Put(&api.KVPair{Key: key, Value: value}, (&api.WriteOptions{}).WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("writing %s: %s", key, err)
+	}
+
+	pair, _, err := client.KV().Get(key, (&api.QueryOptions{RequireConsistent: true}).WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("reading %s: %s", key, err)
+	}
+	if pair == nil || !bytes.Equal(pair.Value, value) {
+		return fmt.Errorf("reading %s: did not return the value written", key)
+	}
+
+	deleted, _, err := client.KV().DeleteCAS(pair, (&api.WriteOptions{}).WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("deleting %s: %s", key, err)
+	}
+	if !deleted {
+		return fmt.Errorf("deleting %s: it was modified concurrently", key)
+	}
+	return nil
+}
+
+func selfTestSession(ctx context.Context, client Client) error {
+	id, _, err := client.Session().Create(&api.SessionEntry{
+		Name:     "consuladapter-self-test",
+		TTL:      "10s",
+		Behavior: api.SessionBehaviorDelete,
+	}, (&api.WriteOptions{}).WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("creating session: %s", err)
+	}
+
+	err = DestroySessionWithContext(ctx, client.Session(), id)
+	if err != nil {
+		return fmt.Errorf("destroying session %s: %s", id, err)
+	}
+	return nil
+}
+
+func selfTestHint(step string, err error) string {
+	var opErr *net.OpError
+	switch {
+	case errors.As(err, &opErr):
+		return "check that the consul agent is running and listening on the configured address"
+	case strings.Contains(err.Error(), "permission denied") || strings.Contains(err.Error(), "Permission denied"):
+		switch step {
+		case SelfTestKV:
+			return fmt.Sprintf("the ACL token needs key_prefix \"%s\" write", SelfTestKeyPrefix)
+		case SelfTestSession:
+			return "the ACL token needs session write on this agent's node"
+		}
+		return "check the ACL token"
+	case step == SelfTestLeader:
+		return "the servers have not elected a leader; check that enough of them are running to form a quorum"
+	case strings.Contains(err.Error(), "No cluster leader"):
+		return "the servers have lost their leader; check that enough of them are running to form a quorum"
+	}
+	return ""
+}
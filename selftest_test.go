@@ -0,0 +1,79 @@
+package consuladapter_test
+
+import (
+	"context"
+	"errors"
+
+	"code.cloudfoundry.org/consuladapter"
+	"code.cloudfoundry.org/consuladapter/fakes"
+	"github.com/hashicorp/consul/api"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RunSelfTest", func() {
+	var (
+		client     *fakes.FakeClient
+		components *fakes.FakeClientComponents
+		status     *fakes.FakeStatus
+	)
+
+	BeforeEach(func() {
+		client, components = fakes.NewFakeClient()
+		status = &fakes.FakeStatus{}
+		status.LeaderReturns("10.0.0.1:8300", nil)
+		client.StatusReturns(status)
+
+		components.KV.GetStub = func(key string, q *api.QueryOptions) (*api.KVPair, *api.QueryMeta, error) {
+			return &api.KVPair{Key: key, Value: []byte("self-test"), ModifyIndex: 7}, nil, nil
+		}
+		components.KV.DeleteCASReturns(true, nil, nil)
+		components.Session.CreateReturns("session-id", nil, nil)
+	})
+
+	It("writes, reads and deletes a scratch key and creates a session", func() {
+		report := consuladapter.RunSelfTest(context.Background(), client)
+		Expect(report.OK).To(BeTrue())
+		Expect(report.Err()).NotTo(HaveOccurred())
+		Expect(report.Steps).To(HaveLen(3))
+
+		pair, _ := components.KV.PutArgsForCall(0)
+		Expect(pair.Key).To(HavePrefix(consuladapter.SelfTestKeyPrefix))
+		deleted, _ := components.KV.DeleteCASArgsForCall(0)
+		Expect(deleted.Key).To(Equal(pair.Key))
+		Expect(deleted.ModifyIndex).To(BeEquivalentTo(7))
+
+		id, _ := components.Session.DestroyArgsForCall(0)
+		Expect(id).To(Equal("session-id"))
+	})
+
+	It("skips the remaining steps when there is no leader", func() {
+		status.LeaderReturns("", nil)
+
+		report := consuladapter.RunSelfTest(context.Background(), client)
+		Expect(report.OK).To(BeFalse())
+		Expect(report.Steps[0].Error).To(Equal("cluster has no leader"))
+		Expect(report.Steps[0].Hint).To(ContainSubstring("quorum"))
+		Expect(report.Steps[1].Skipped).To(BeTrue())
+		Expect(report.Steps[2].Skipped).To(BeTrue())
+		Expect(components.KV.PutCallCount()).To(Equal(0))
+	})
+
+	It("explains permission failures", func() {
+		components.KV.PutReturns(nil, consuladapter.PermissionDeniedError{Resource: "key", Verb: "write"})
+
+		report := consuladapter.RunSelfTest(context.Background(), client)
+		Expect(report.Steps[1].OK).To(BeFalse())
+		Expect(report.Steps[1].Hint).To(ContainSubstring(`key_prefix "consuladapter-self-test/" write`))
+		Expect(report.Steps[2].OK).To(BeTrue())
+		Expect(report.Err()).To(MatchError(ContainSubstring("consul self-test step kv failed")))
+	})
+
+	It("reports sessions that cannot be created", func() {
+		components.Session.CreateReturns("", nil, errors.New("boom"))
+
+		report := consuladapter.RunSelfTest(context.Background(), client)
+		Expect(report.Steps[2].Error).To(Equal("creating session: boom"))
+	})
+})
@@ -0,0 +1,95 @@
+package consuladapter
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/hashicorp/consul/api"
+)
+
+// Limits consul applies to service metadata.
+const (
+	MaxServiceMetaPairs       = 64
+	MaxServiceMetaKeyLength   = 128
+	MaxServiceMetaValueLength = 512
+)
+
+var serviceMetaKeyRegexp = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+type InvalidServiceError struct {
+	Service string
+	Reason  string
+}
+
+func (e InvalidServiceError) Error() string {
+	return fmt.Sprintf("invalid service '%s': %s", e.Service, e.Reason)
+}
+
+// ServiceWeights returns weights for a service registration. Consul uses
+// them to shape DNS SRV responses between passing and warning instances.
+func ServiceWeights(passing, warning int) *api.AgentWeights {
+	return &api.AgentWeights{Passing: passing, Warning: warning}
+}
+
+// ValidateService checks a registration's Weights and Meta against the limits
+// consul enforces, so mistakes surface before the agent rejects them.
+func ValidateService(service *api.AgentServiceRegistration) error {
+	invalid := func(reason string) error {
+		return InvalidServiceError{Service: service.Name, Reason: reason}
+	}
+
+	if service.Weights != nil {
+		if service.Weights.Passing < 1 {
+			return invalid("passing weight must be at least 1")
+		}
+		if service.Weights.Warning < 0 {
+			return invalid("warning weight must not be negative")
+		}
+	}
+
+	if len(service.Meta) > MaxServiceMetaPairs {
+		return invalid(fmt.Sprintf("meta has more than %d pairs", MaxServiceMetaPairs))
+	}
+	for key, value := range service.Meta {
+		switch {
+		case len(key) > MaxServiceMetaKeyLength:
+			return invalid(fmt.Sprintf("meta key '%s' is longer than %d characters", key, MaxServiceMetaKeyLength))
+		case !serviceMetaKeyRegexp.MatchString(key):
+			return invalid(fmt.Sprintf("meta key '%s' may only contain letters, numbers, '_' and '-'", key))
+		case strings.HasPrefix(key, "consul-"):
+			return invalid(fmt.Sprintf("meta key '%s' uses the reserved 'consul-' prefix", key))
+		case len(value) > MaxServiceMetaValueLength:
+			return invalid(fmt.Sprintf("meta value for '%s' is longer than %d characters", key, MaxServiceMetaValueLength))
+		}
+	}
+
+	return nil
+}
+
+// RegisterService registers service with the given checks added to any it
+// already has. Nothing is registered if the service or a check is invalid.
+func RegisterService(agent Agent, service *api.AgentServiceRegistration, definitions ...CheckDefinition) error {
+	registration, err := serviceRegistration(service, definitions)
+	if err != nil {
+		return err
+	}
+
+	return agent.ServiceRegister(registration)
+}
+
+func serviceRegistration(service *api.AgentServiceRegistration, definitions []CheckDefinition) (*api.AgentServiceRegistration, error) {
+	err := ValidateService(service)
+	if err != nil {
+		return nil, err
+	}
+
+	checks, err := ServiceChecks(definitions...)
+	if err != nil {
+		return nil, err
+	}
+
+	registration := *service
+	registration.Checks = append(append(api.AgentServiceChecks{}, service.Checks...), checks...)
+	return &registration, nil
+}
@@ -1,6 +1,17 @@
 package consuladapter
 
-import "github.com/hashicorp/consul/api"
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+)
 
 //go:generate counterfeiter -o fakes/fake_session.go . Session
 
@@ -24,33 +35,345 @@ func NewConsulSession(s *api.Session) Session {
 }
 
 func (s *session) Create(se *api.SessionEntry, q *api.WriteOptions) (string, *api.WriteMeta, error) {
-	return s.session.Create(se, q)
+	id, wm, err := s.session.Create(se, q)
+	return id, wm, AsPermissionDeniedError(asTTLTooShortError(se, err))
 }
 
 func (s *session) CreateNoChecks(se *api.SessionEntry, q *api.WriteOptions) (string, *api.WriteMeta, error) {
-	return s.session.CreateNoChecks(se, q)
+	if se == nil || se.TTL == "" {
+		return "", nil, ErrNoChecksSessionWithoutTTL
+	}
+
+	id, wm, err := s.session.CreateNoChecks(se, q)
+	return id, wm, AsPermissionDeniedError(asTTLTooShortError(se, err))
 }
 
 func (s *session) Destroy(id string, q *api.WriteOptions) (*api.WriteMeta, error) {
-	return s.session.Destroy(id, q)
+	wm, err := s.session.Destroy(id, q)
+	return wm, AsPermissionDeniedError(err)
 }
 
 func (s *session) Info(id string, q *api.QueryOptions) (*api.SessionEntry, *api.QueryMeta, error) {
-	return s.session.Info(id, q)
+	entry, qm, err := s.session.Info(id, q)
+	return entry, qm, AsPermissionDeniedError(err)
 }
 
 func (s *session) List(q *api.QueryOptions) ([]*api.SessionEntry, *api.QueryMeta, error) {
-	return s.session.List(q)
+	entries, qm, err := s.session.List(q)
+	return entries, qm, AsPermissionDeniedError(err)
 }
 
 func (s *session) Node(node string, q *api.QueryOptions) ([]*api.SessionEntry, *api.QueryMeta, error) {
-	return s.session.Node(node, q)
+	entries, qm, err := s.session.Node(node, q)
+	return entries, qm, AsPermissionDeniedError(err)
 }
 
 func (s *session) Renew(id string, q *api.WriteOptions) (*api.SessionEntry, *api.WriteMeta, error) {
-	return s.session.Renew(id, q)
+	entry, wm, err := s.session.Renew(id, q)
+	return entry, wm, AsPermissionDeniedError(err)
 }
 
 func (s *session) RenewPeriodic(initialTTL string, id string, q *api.WriteOptions, doneCh chan struct{}) error {
-	return s.session.RenewPeriodic(initialTTL, id, q, doneCh)
+	return AsPermissionDeniedError(s.session.RenewPeriodic(initialTTL, id, q, doneCh))
+}
+
+// SessionNamePrefix starts the name of every session the adapter creates:
+// TTL sessions and the sessions behind its locks and semaphores. Resetting
+// a test cluster destroys only sessions named with it unless forced, so
+// other users' sessions on a shared cluster survive.
+const SessionNamePrefix = "consuladapter: "
+
+// TagSessionName returns name starting with SessionNamePrefix.
+func TagSessionName(name string) string {
+	if strings.HasPrefix(name, SessionNamePrefix) {
+		return name
+	}
+	return SessionNamePrefix + name
+}
+
+// tagSessionEntry returns a copy of se with its name tagged.
+func tagSessionEntry(se *api.SessionEntry) *api.SessionEntry {
+	if se == nil {
+		return nil
+	}
+	tagged := *se
+	tagged.Name = TagSessionName(se.Name)
+	return &tagged
+}
+
+// ErrNoChecksSessionWithoutTTL is returned by CreateNoChecks and NewTTLSession
+// for sessions without a TTL, which nothing would ever invalidate.
+var ErrNoChecksSessionWithoutTTL = errors.New("sessions without health checks must have a TTL")
+
+// CreateTTLSession creates a session with no health checks, not even the
+// default serfHealth check, so that gossip flapping cannot invalidate it.
+// It stays valid only while it is renewed: CreateTTLSession renews it until
+// doneCh is closed, then destroys it. The returned channel receives the
+// renewal error if the session is lost, or nil after doneCh is closed.
+func CreateTTLSession(session Session, se *api.SessionEntry, doneCh chan struct{}) (string, <-chan error, error) {
+	id, _, err := session.CreateNoChecks(tagSessionEntry(se), nil)
+	if err != nil {
+		return "", nil, err
+	}
+	recordLifecycle(LifecycleEvent{Kind: SessionCreated, Session: id})
+
+	renewErr := make(chan error, 1)
+	goBackground(nil, func() {
+		err := session.RenewPeriodic(se.TTL, id, nil, doneCh)
+		recordSessionEnd(id, err)
+		sendError(renewErr, err, SessionRenewalErrorsChannel)
+	}, func(err *PanicError) {
+		recordSessionEnd(id, err)
+		sendError(renewErr, err, SessionRenewalErrorsChannel)
+	})
+
+	return id, renewErr, nil
+}
+
+// ErrSessionExpired is the error of a TTLSession whose session no longer
+// exists when it is renewed, e.g. because it expired while renewal was
+// paused.
+var ErrSessionExpired = errors.New("session expired")
+
+// ttlSessionRetryInterval is how often a TTLSession retries a failed
+// renewal, as api.Session.RenewPeriodic does, until its TTL runs out.
+const ttlSessionRetryInterval = time.Second
+
+// TTLSession is a session created like CreateTTLSession whose renewal
+// goroutine is joined by Destroy, so nothing is left running afterwards.
+// It renews the session every half TTL, retrying failed renewals until the
+// TTL runs out, and its renewal can be paused, e.g. to let the session
+// expire in a test.
+type TTLSession struct {
+	id       string
+	ttl      time.Duration
+	doneCh   chan struct{}
+	renewed  chan struct{}
+	renewErr error
+	events   *ErrorFanOut
+
+	mutex         sync.Mutex
+	paused        bool
+	pauseChanged  chan struct{}
+	lastRenewed   time.Time
+	renewalErrors uint64
+	heldKeys      map[string]struct{}
+
+	destroyOnce sync.Once
+	wg          sync.WaitGroup
+}
+
+func NewTTLSession(session Session, se *api.SessionEntry) (*TTLSession, error) {
+	if se == nil || se.TTL == "" {
+		return nil, ErrNoChecksSessionWithoutTTL
+	}
+
+	ttl, err := time.ParseDuration(se.TTL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid session TTL '%s': %s", se.TTL, err)
+	}
+
+	id, _, err := session.CreateNoChecks(tagSessionEntry(se), nil)
+	if err != nil {
+		return nil, err
+	}
+	recordLifecycle(LifecycleEvent{Kind: SessionCreated, Session: id})
+
+	s := &TTLSession{
+		id:           id,
+		ttl:          ttl,
+		doneCh:       make(chan struct{}),
+		renewed:      make(chan struct{}),
+		events:       &ErrorFanOut{},
+		pauseChanged: make(chan struct{}, 1),
+		lastRenewed:  time.Now(),
+		heldKeys:     map[string]struct{}{},
+	}
+	goBackground(&s.wg, func() {
+		runCallback(callbackSessionRenewal+" "+id, func() {
+			s.renewErr = s.renew(session)
+		})
+		recordSessionEnd(id, s.renewErr)
+		close(s.renewed)
+		s.events.publish(s.renewErr)
+	}, func(err *PanicError) {
+		s.renewErr = err
+		recordSessionEnd(id, err)
+		close(s.renewed)
+		s.events.publish(err)
+	})
+
+	return s, nil
+}
+
+// renew renews the session until doneCh is closed, then destroys it, or
+// until renewal fails for longer than the TTL.
+func (s *TTLSession) renew(session Session) error {
+	interval := s.ttl / 2
+	retryInterval := ttlSessionRetryInterval
+	if retryInterval > interval {
+		retryInterval = interval
+	}
+
+	wait := interval
+	for {
+		var due <-chan time.Time
+		if !s.RenewalPaused() {
+			due = time.After(wait)
+		}
+
+		select {
+		case <-s.doneCh:
+			_, err := session.Destroy(s.id, nil)
+			return err
+		case <-s.pauseChanged:
+			// renew straight away on resuming, as the session may be close
+			// to expiring
+			wait = 0
+			continue
+		case <-due:
+		}
+
+		entry, _, err := session.Renew(s.id, nil)
+		if err != nil {
+			s.mutex.Lock()
+			s.renewalErrors++
+			expired := time.Since(s.lastRenewed) > s.ttl
+			s.mutex.Unlock()
+			if expired {
+				return err
+			}
+			wait = retryInterval
+			continue
+		}
+		if entry == nil {
+			return ErrSessionExpired
+		}
+
+		s.mutex.Lock()
+		s.lastRenewed = time.Now()
+		s.mutex.Unlock()
+		wait = interval
+	}
+}
+
+func (s *TTLSession) ID() string {
+	return s.id
+}
+
+// PauseRenewal stops renewing the session, which expires once its TTL runs
+// out unless renewal is resumed first.
+func (s *TTLSession) PauseRenewal() {
+	s.setPaused(true)
+}
+
+// ResumeRenewal renews the session straight away, and periodically again
+// from then on. If the session expired while paused, it is lost.
+func (s *TTLSession) ResumeRenewal() {
+	s.setPaused(false)
+}
+
+func (s *TTLSession) RenewalPaused() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.paused
+}
+
+func (s *TTLSession) setPaused(paused bool) {
+	s.mutex.Lock()
+	changed := s.paused != paused
+	s.paused = paused
+	s.mutex.Unlock()
+
+	if changed {
+		select {
+		case s.pauseChanged <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Lost is closed if renewal stops, after which Err returns why.
+func (s *TTLSession) Lost() <-chan struct{} {
+	return s.renewed
+}
+
+// Subscribe returns a channel of its own that receives the renewal error
+// once renewal stops, or nil if it stopped because of Destroy.
+func (s *TTLSession) Subscribe() <-chan error {
+	return s.events.Subscribe()
+}
+
+func (s *TTLSession) Err() error {
+	select {
+	case <-s.renewed:
+		return s.renewErr
+	default:
+		return nil
+	}
+}
+
+// TTLSessionState is a snapshot of a TTLSession for debugging, e.g. why
+// it lost a lock.
+type TTLSessionState struct {
+	ID            string    `json:"id"`
+	TTL           string    `json:"ttl"`
+	LastRenewed   time.Time `json:"last_renewed"`
+	RenewalPaused bool      `json:"renewal_paused"`
+	RenewalErrors uint64    `json:"renewal_errors"`
+	HeldKeys      []string  `json:"held_keys"`
+	Lost          bool      `json:"lost"`
+	Error         string    `json:"error,omitempty"`
+}
+
+// State returns a snapshot of the session. LastRenewed is when it was
+// created until its first renewal, RenewalErrors counts every failed
+// renewal, even those retried successfully, and HeldKeys are the locks
+// acquired with its TryAcquireLock and not yet released with its
+// ReleaseLock, while the session lasts.
+func (s *TTLSession) State() TTLSessionState {
+	s.mutex.Lock()
+	state := TTLSessionState{
+		ID:            s.id,
+		TTL:           s.ttl.String(),
+		LastRenewed:   s.lastRenewed,
+		RenewalPaused: s.paused,
+		RenewalErrors: s.renewalErrors,
+		HeldKeys:      []string{},
+	}
+	for key := range s.heldKeys {
+		state.HeldKeys = append(state.HeldKeys, key)
+	}
+	s.mutex.Unlock()
+	sort.Strings(state.HeldKeys)
+
+	select {
+	case <-s.renewed:
+		// the session no longer exists, so neither do its locks
+		state.Lost = true
+		state.HeldKeys = []string{}
+		if s.renewErr != nil {
+			state.Error = s.renewErr.Error()
+		}
+	default:
+	}
+	return state
+}
+
+// ServeHTTP serves the session's State as JSON.
+func (s *TTLSession) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.State())
+}
+
+// Destroy stops renewing the session, which destroys it, and waits for the
+// renewal goroutine to exit.
+func (s *TTLSession) Destroy() error {
+	if err := checkReentrant("TTLSession.Destroy", callbackSessionRenewal+" "+s.id); err != nil {
+		return err
+	}
+
+	s.destroyOnce.Do(func() { close(s.doneCh) })
+	s.wg.Wait()
+	return s.renewErr
 }
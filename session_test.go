@@ -0,0 +1,152 @@
+package consuladapter_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"sync/atomic"
+	"time"
+
+	"code.cloudfoundry.org/consuladapter"
+	"code.cloudfoundry.org/consuladapter/fakes"
+	"github.com/hashicorp/consul/api"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("TTLSession", func() {
+	var (
+		session    *fakes.FakeSession
+		ttlSession *consuladapter.TTLSession
+	)
+
+	BeforeEach(func() {
+		session = &fakes.FakeSession{}
+		session.CreateNoChecksReturns("session-id", nil, nil)
+		session.RenewReturns(&api.SessionEntry{ID: "session-id"}, nil, nil)
+
+		var err error
+		ttlSession, err = consuladapter.NewTTLSession(session, &api.SessionEntry{TTL: "40ms"})
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		ttlSession.Destroy()
+	})
+
+	It("renews the session every half TTL until it is destroyed", func() {
+		Eventually(session.RenewCallCount).Should(BeNumerically(">=", 2))
+		id, _ := session.RenewArgsForCall(0)
+		Expect(id).To(Equal("session-id"))
+
+		Expect(ttlSession.Destroy()).To(Succeed())
+		Expect(session.DestroyCallCount()).To(Equal(1))
+		renewals := session.RenewCallCount()
+		Consistently(session.RenewCallCount).Should(Equal(renewals))
+	})
+
+	It("rejects invalid TTLs", func() {
+		_, err := consuladapter.NewTTLSession(session, &api.SessionEntry{TTL: "forever"})
+		Expect(err).To(HaveOccurred())
+		Expect(session.CreateNoChecksCallCount()).To(Equal(1))
+	})
+
+	It("rejects sessions without a TTL", func() {
+		_, err := consuladapter.NewTTLSession(session, nil)
+		Expect(err).To(Equal(consuladapter.ErrNoChecksSessionWithoutTTL))
+		_, err = consuladapter.NewTTLSession(session, &api.SessionEntry{})
+		Expect(err).To(Equal(consuladapter.ErrNoChecksSessionWithoutTTL))
+		Expect(session.CreateNoChecksCallCount()).To(Equal(1))
+	})
+
+	Describe("PauseRenewal", func() {
+		BeforeEach(func() {
+			Expect(ttlSession.Destroy()).To(Succeed())
+
+			var err error
+			ttlSession, err = consuladapter.NewTTLSession(session, &api.SessionEntry{TTL: "10s"})
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("stops renewing, and renews straight away when resumed", func() {
+			ttlSession.PauseRenewal()
+			Expect(ttlSession.RenewalPaused()).To(BeTrue())
+			Consistently(session.RenewCallCount).Should(BeZero())
+
+			ttlSession.ResumeRenewal()
+			Expect(ttlSession.RenewalPaused()).To(BeFalse())
+			Eventually(session.RenewCallCount, 200*time.Millisecond).Should(Equal(1))
+			Expect(ttlSession.Lost()).NotTo(BeClosed())
+		})
+
+		It("loses the session if it expired while paused", func() {
+			ttlSession.PauseRenewal()
+			session.RenewReturns(nil, nil, nil)
+
+			ttlSession.ResumeRenewal()
+			Eventually(ttlSession.Lost()).Should(BeClosed())
+			Expect(ttlSession.Err()).To(Equal(consuladapter.ErrSessionExpired))
+		})
+	})
+
+	It("retries failed renewals until the TTL runs out", func() {
+		renewErr := errors.New("connection refused")
+		session.RenewReturns(nil, nil, renewErr)
+		Eventually(ttlSession.Lost()).Should(BeClosed())
+		Expect(ttlSession.Err()).To(Equal(renewErr))
+		Expect(session.RenewCallCount()).To(BeNumerically(">=", 2))
+	})
+
+	Describe("State", func() {
+		It("reports renewals, pauses and errors", func() {
+			var failed int32
+			session.RenewStub = func(id string, q *api.WriteOptions) (*api.SessionEntry, *api.WriteMeta, error) {
+				if atomic.CompareAndSwapInt32(&failed, 0, 1) {
+					return nil, nil, errors.New("connection refused")
+				}
+				return &api.SessionEntry{ID: id}, nil, nil
+			}
+			Eventually(session.RenewCallCount).Should(BeNumerically(">=", 2))
+			ttlSession.PauseRenewal()
+
+			state := ttlSession.State()
+			Expect(state.ID).To(Equal("session-id"))
+			Expect(state.TTL).To(Equal("40ms"))
+			Expect(state.RenewalPaused).To(BeTrue())
+			Expect(state.RenewalErrors).To(BeEquivalentTo(1))
+			Expect(state.LastRenewed).To(BeTemporally("~", time.Now(), time.Second))
+			Expect(state.Lost).To(BeFalse())
+
+			ttlSession.Destroy()
+			Expect(ttlSession.State().Lost).To(BeTrue())
+		})
+
+		It("lists the locks acquired through the session until they are released", func() {
+			backend := fakes.NewFakeBackend()
+			held, err := consuladapter.NewTTLSession(backend.Session(), &api.SessionEntry{TTL: "10s"})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(held.TryAcquireLock(backend.KV(), "locks/b", nil)).To(Succeed())
+			Expect(held.TryAcquireLock(backend.KV(), "locks/a", nil)).To(Succeed())
+			Expect(held.State().HeldKeys).To(Equal([]string{"locks/a", "locks/b"}))
+
+			Expect(held.ReleaseLock(backend.KV(), "locks/a")).To(Succeed())
+			Expect(backend.Holder("locks/a")).To(BeEmpty())
+			Expect(held.State().HeldKeys).To(Equal([]string{"locks/b"}))
+
+			Expect(held.Destroy()).To(Succeed())
+			Expect(held.State().HeldKeys).To(BeEmpty())
+		})
+
+		It("is served as JSON", func() {
+			response := httptest.NewRecorder()
+			ttlSession.ServeHTTP(response, httptest.NewRequest("GET", "/", nil))
+
+			var state consuladapter.TTLSessionState
+			Expect(json.Unmarshal(response.Body.Bytes(), &state)).To(Succeed())
+			Expect(state.ID).To(Equal("session-id"))
+			Expect(state.HeldKeys).To(BeEmpty())
+		})
+	})
+})
@@ -0,0 +1,113 @@
+package consuladapter
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/consul/api"
+)
+
+// SessionFilter selects sessions for DestroySessions. Empty fields match
+// every session.
+type SessionFilter struct {
+	NamePrefix string
+	Node       string
+
+	// CreatedBefore matches sessions whose CreateIndex, the raft index of
+	// the write that created them, is below it. Consul does not record
+	// session creation times, and raft indexes advance with every write to
+	// the cluster rather than with time, so to select sessions older than
+	// some moment, record the LastIndex of a query made at that moment and
+	// pass it here later. Indexes are only comparable within one cluster.
+	CreatedBefore uint64
+}
+
+func (f SessionFilter) Matches(s *api.SessionEntry) bool {
+	if !strings.HasPrefix(s.Name, f.NamePrefix) {
+		return false
+	}
+	if f.Node != "" && s.Node != f.Node {
+		return false
+	}
+	if f.CreatedBefore > 0 && s.CreateIndex >= f.CreatedBefore {
+		return false
+	}
+	return true
+}
+
+type SessionDestroyError struct {
+	ID  string
+	Err error
+}
+
+type DestroySessionsError []SessionDestroyError
+
+func (e DestroySessionsError) Error() string {
+	messages := make([]string, len(e))
+	for i, failure := range e {
+		messages[i] = fmt.Sprintf("%s: %s", failure.ID, failure.Err)
+	}
+	return fmt.Sprintf("failed to destroy %d session(s): %s", len(e), strings.Join(messages, "; "))
+}
+
+// MaxConcurrentSessionDestroys bounds how many sessions DestroySessions
+// destroys at once, so that cleaning up many sessions does not flood the
+// agent with requests.
+const MaxConcurrentSessionDestroys = 8
+
+// DestroySessions destroys all sessions matching filter, up to
+// MaxConcurrentSessionDestroys at a time, and returns the IDs of those
+// destroyed. Failures do not stop the remaining destroys and are returned
+// together as a DestroySessionsError.
+func DestroySessions(session Session, filter SessionFilter) ([]string, error) {
+	var sessions []*api.SessionEntry
+	var err error
+	if filter.Node != "" {
+		sessions, _, err = session.Node(filter.Node, nil)
+	} else {
+		sessions, _, err = session.List(nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		wg        sync.WaitGroup
+		mutex     sync.Mutex
+		destroyed []string
+		failures  DestroySessionsError
+		slots     = make(chan struct{}, MaxConcurrentSessionDestroys)
+	)
+
+	for _, entry := range sessions {
+		if !filter.Matches(entry) {
+			continue
+		}
+
+		id := entry.ID
+		slots <- struct{}{}
+		goBackground(&wg, func() {
+			defer func() { <-slots }()
+			_, err := session.Destroy(id, nil)
+
+			mutex.Lock()
+			defer mutex.Unlock()
+			if err != nil {
+				failures = append(failures, SessionDestroyError{ID: id, Err: err})
+			} else {
+				destroyed = append(destroyed, id)
+			}
+		}, func(err *PanicError) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			failures = append(failures, SessionDestroyError{ID: id, Err: err})
+		})
+	}
+	wg.Wait()
+
+	if len(failures) > 0 {
+		return destroyed, failures
+	}
+	return destroyed, nil
+}
@@ -0,0 +1,119 @@
+package consuladapter_test
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/consuladapter"
+	"code.cloudfoundry.org/consuladapter/fakes"
+	"github.com/hashicorp/consul/api"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("DestroySessions", func() {
+	var (
+		session *fakes.FakeSession
+		entries []*api.SessionEntry
+	)
+
+	BeforeEach(func() {
+		entries = []*api.SessionEntry{
+			{ID: "a", Name: "bbs-lock", Node: "node-1", CreateIndex: 10},
+			{ID: "b", Name: "bbs-presence", Node: "node-2", CreateIndex: 20},
+			{ID: "c", Name: "auctioneer-lock", Node: "node-1", CreateIndex: 30},
+		}
+		session = &fakes.FakeSession{}
+		session.ListReturns(entries, nil, nil)
+	})
+
+	It("destroys the sessions matching the filter", func() {
+		destroyed, err := consuladapter.DestroySessions(session, consuladapter.SessionFilter{NamePrefix: "bbs-"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(destroyed).To(ConsistOf("a", "b"))
+		Expect(session.DestroyCallCount()).To(Equal(2))
+	})
+
+	It("lists the node's sessions when filtering by node", func() {
+		session.NodeReturns(entries[:1], nil, nil)
+
+		destroyed, err := consuladapter.DestroySessions(session, consuladapter.SessionFilter{Node: "node-1"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(destroyed).To(Equal([]string{"a"}))
+		node, _ := session.NodeArgsForCall(0)
+		Expect(node).To(Equal("node-1"))
+		Expect(session.ListCallCount()).To(BeZero())
+	})
+
+	It("returns listing errors", func() {
+		session.ListReturns(nil, nil, errors.New("connection refused"))
+		_, err := consuladapter.DestroySessions(session, consuladapter.SessionFilter{})
+		Expect(err).To(MatchError("connection refused"))
+	})
+
+	It("keeps destroying after failures, and reports them together", func() {
+		session.DestroyStub = func(id string, q *api.WriteOptions) (*api.WriteMeta, error) {
+			if id == "b" {
+				return nil, errors.New("permission denied")
+			}
+			return nil, nil
+		}
+
+		destroyed, err := consuladapter.DestroySessions(session, consuladapter.SessionFilter{})
+		Expect(destroyed).To(ConsistOf("a", "c"))
+		Expect(err).To(Equal(consuladapter.DestroySessionsError{
+			{ID: "b", Err: errors.New("permission denied")},
+		}))
+	})
+
+	It("destroys a bounded number of sessions at once", func() {
+		entries = nil
+		for i := 0; i < 5*consuladapter.MaxConcurrentSessionDestroys; i++ {
+			entries = append(entries, &api.SessionEntry{ID: fmt.Sprintf("session-%d", i)})
+		}
+		session.ListReturns(entries, nil, nil)
+
+		var (
+			mutex            sync.Mutex
+			running, maxSeen int
+		)
+		session.DestroyStub = func(id string, q *api.WriteOptions) (*api.WriteMeta, error) {
+			mutex.Lock()
+			running++
+			if running > maxSeen {
+				maxSeen = running
+			}
+			mutex.Unlock()
+
+			time.Sleep(time.Millisecond)
+
+			mutex.Lock()
+			running--
+			mutex.Unlock()
+			return nil, nil
+		}
+
+		destroyed, err := consuladapter.DestroySessions(session, consuladapter.SessionFilter{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(destroyed).To(HaveLen(len(entries)))
+		Expect(maxSeen).To(BeNumerically("<=", consuladapter.MaxConcurrentSessionDestroys))
+	})
+
+	Describe("SessionFilter", func() {
+		It("matches sessions created before the given index", func() {
+			filter := consuladapter.SessionFilter{CreatedBefore: 20}
+			Expect(filter.Matches(entries[0])).To(BeTrue())
+			Expect(filter.Matches(entries[1])).To(BeFalse())
+			Expect(filter.Matches(entries[2])).To(BeFalse())
+		})
+
+		It("matches every session when empty", func() {
+			for _, entry := range entries {
+				Expect(consuladapter.SessionFilter{}.Matches(entry)).To(BeTrue())
+			}
+		})
+	})
+})
@@ -0,0 +1,138 @@
+package consuladapter
+
+import (
+	"context"
+	"hash/fnv"
+	"sort"
+	"sync"
+
+	"github.com/hashicorp/consul/api"
+)
+
+// ShardRing assigns keys to members using rendezvous (highest random weight)
+// hashing: each key belongs to the member scoring highest for it. When a
+// member joins or leaves, only the keys it gains or loses move; every other
+// assignment stays put.
+type ShardRing struct {
+	mutex   sync.RWMutex
+	members []string
+}
+
+func NewShardRing(members ...string) *ShardRing {
+	r := &ShardRing{}
+	r.SetMembers(members)
+	return r
+}
+
+// SetMembers replaces the ring's members. Duplicates are ignored.
+func (r *ShardRing) SetMembers(members []string) {
+	unique := map[string]bool{}
+	sorted := make([]string, 0, len(members))
+	for _, member := range members {
+		if !unique[member] {
+			unique[member] = true
+			sorted = append(sorted, member)
+		}
+	}
+	sort.Strings(sorted)
+
+	r.mutex.Lock()
+	r.members = sorted
+	r.mutex.Unlock()
+}
+
+// Members returns the ring's members in sorted order.
+func (r *ShardRing) Members() []string {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return append([]string(nil), r.members...)
+}
+
+// Owner returns the member that key is assigned to, or false if the ring is
+// empty.
+func (r *ShardRing) Owner(key string) (string, bool) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	var (
+		owner string
+		best  uint64
+	)
+	for i, member := range r.members {
+		// members are sorted, so ties go to the lowest consistently
+		if score := shardScore(member, key); i == 0 || score > best {
+			owner, best = member, score
+		}
+	}
+	return owner, len(r.members) > 0
+}
+
+// Owns reports whether key is assigned to member.
+func (r *ShardRing) Owns(member, key string) bool {
+	owner, ok := r.Owner(key)
+	return ok && owner == member
+}
+
+func shardScore(member, key string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(member))
+	h.Write([]byte{0})
+	h.Write([]byte(key))
+
+	// fnv alone scores similar names too alike; finish with murmur3's mixer
+	x := h.Sum64()
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+	return x
+}
+
+// HealthyInstances returns the IDs of the passing instances of service, for
+// use as ShardRing members. Service IDs must be unique across the cluster.
+func HealthyInstances(health Health, service, tag string, q *api.QueryOptions) ([]string, *api.QueryMeta, error) {
+	entries, meta, err := health.Service(service, tag, true, q)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ids := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if entry.Service != nil {
+			ids = append(ids, entry.Service.ID)
+		}
+	}
+	return ids, meta, nil
+}
+
+// WatchShardRing keeps ring's members in step with the healthy instances of
+// service until ctx is done, calling onChange, if set, after each change.
+// While consul is unreachable the ring keeps its last known members.
+func WatchShardRing(ctx context.Context, health Health, service, tag string, ring *ShardRing, onChange func(members []string)) error {
+	return BlockingQuery(ctx, DefaultWatchBackoff, nil, func(q *api.QueryOptions) (*api.QueryMeta, error) {
+		ids, meta, err := HealthyInstances(health, service, tag, q)
+		if err != nil {
+			return nil, err
+		}
+
+		previous := ring.Members()
+		ring.SetMembers(ids)
+		if members := ring.Members(); onChange != nil && !equalStrings(previous, members) {
+			onChange(members)
+		}
+		return meta, nil
+	})
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
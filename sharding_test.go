@@ -0,0 +1,148 @@
+package consuladapter_test
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"code.cloudfoundry.org/consuladapter"
+	"code.cloudfoundry.org/consuladapter/fakes"
+	"github.com/hashicorp/consul/api"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ShardRing", func() {
+	keys := func() []string {
+		var keys []string
+		for i := 0; i < 1000; i++ {
+			keys = append(keys, fmt.Sprintf("key-%d", i))
+		}
+		return keys
+	}()
+
+	assignments := func(ring *consuladapter.ShardRing) map[string]string {
+		owners := map[string]string{}
+		for _, key := range keys {
+			owner, ok := ring.Owner(key)
+			Expect(ok).To(BeTrue())
+			owners[key] = owner
+		}
+		return owners
+	}
+
+	It("has no owner when empty", func() {
+		_, ok := consuladapter.NewShardRing().Owner("key")
+		Expect(ok).To(BeFalse())
+	})
+
+	It("spreads keys across members", func() {
+		counts := map[string]int{}
+		for _, owner := range assignments(consuladapter.NewShardRing("a", "b", "c", "d")) {
+			counts[owner]++
+		}
+
+		Expect(counts).To(HaveLen(4))
+		for _, count := range counts {
+			Expect(count).To(BeNumerically("~", 250, 60))
+		}
+	})
+
+	It("does not depend on member order", func() {
+		Expect(assignments(consuladapter.NewShardRing("a", "b", "c"))).To(Equal(assignments(consuladapter.NewShardRing("c", "a", "b", "a"))))
+	})
+
+	It("only moves the keys of a member that leaves", func() {
+		ring := consuladapter.NewShardRing("a", "b", "c", "d")
+		before := assignments(ring)
+
+		ring.SetMembers([]string{"a", "b", "d"})
+		after := assignments(ring)
+
+		for _, key := range keys {
+			if before[key] != "c" {
+				Expect(after[key]).To(Equal(before[key]))
+			}
+		}
+	})
+
+	It("only moves keys to a member that joins", func() {
+		ring := consuladapter.NewShardRing("a", "b", "c")
+		before := assignments(ring)
+
+		ring.SetMembers([]string{"a", "b", "c", "d"})
+		after := assignments(ring)
+
+		for _, key := range keys {
+			if after[key] != "d" {
+				Expect(after[key]).To(Equal(before[key]))
+			}
+		}
+		Expect(ring.Owns("d", keys[0])).To(Equal(after[keys[0]] == "d"))
+	})
+
+	Describe("WatchShardRing", func() {
+		var (
+			health *fakes.FakeHealth
+			ring   *consuladapter.ShardRing
+		)
+
+		entries := func(ids ...string) []*api.ServiceEntry {
+			var entries []*api.ServiceEntry
+			for _, id := range ids {
+				entries = append(entries, &api.ServiceEntry{Service: &api.AgentService{ID: id, Service: "worker"}})
+			}
+			return entries
+		}
+
+		BeforeEach(func() {
+			health = &fakes.FakeHealth{}
+			ring = consuladapter.NewShardRing()
+		})
+
+		It("follows the passing instances of the service", func() {
+			health.ServiceStub = func(service, tag string, passingOnly bool, q *api.QueryOptions) ([]*api.ServiceEntry, *api.QueryMeta, error) {
+				Expect(service).To(Equal("worker"))
+				Expect(passingOnly).To(BeTrue())
+				switch q.WaitIndex {
+				case 0:
+					return entries("worker-1", "worker-2"), &api.QueryMeta{LastIndex: 5}, nil
+				case 5:
+					return entries("worker-2", "worker-3"), &api.QueryMeta{LastIndex: 6}, nil
+				default:
+					<-q.Context().Done()
+					return nil, nil, q.Context().Err()
+				}
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			changes := make(chan []string, 2)
+			errCh := make(chan error, 1)
+			go func() {
+				errCh <- consuladapter.WatchShardRing(ctx, health, "worker", "", ring, func(members []string) {
+					changes <- members
+				})
+			}()
+
+			Eventually(changes).Should(Receive(Equal([]string{"worker-1", "worker-2"})))
+			Eventually(changes).Should(Receive(Equal([]string{"worker-2", "worker-3"})))
+			Expect(ring.Members()).To(Equal([]string{"worker-2", "worker-3"}))
+
+			cancel()
+			Eventually(errCh).Should(Receive(Equal(context.Canceled)))
+		})
+
+		It("keeps the last known members while consul is unavailable", func() {
+			ring.SetMembers([]string{"worker-1"})
+			health.ServiceReturns(nil, nil, fmt.Errorf("connection refused"))
+
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+
+			err := consuladapter.WatchShardRing(ctx, health, "worker", "", ring, nil)
+			Expect(err).To(Equal(context.DeadlineExceeded))
+			Expect(ring.Members()).To(Equal([]string{"worker-1"}))
+		})
+	})
+})
@@ -0,0 +1,93 @@
+package consuladapter
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+type ShutdownConfig struct {
+	ServiceIDs []string
+	Locks      []Lock
+	SessionIDs []string
+
+	// Timeout bounds the whole teardown so an unreachable consul does not
+	// hold up shutdown. Zero means no bound.
+	Timeout time.Duration
+}
+
+type ShutdownError []error
+
+func (e ShutdownError) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return fmt.Sprintf("shutdown failed: %s", strings.Join(messages, "; "))
+}
+
+// ShutdownRunner is an ifrit.Runner that, when signalled, tears down a
+// component's consul state in dependency order: it deregisters services so
+// no new traffic arrives, releases locks, and finally destroys sessions.
+// Compose it in a grouper after the runners that use that state so it is
+// signalled before them.
+type ShutdownRunner struct {
+	client Client
+	config ShutdownConfig
+}
+
+func NewShutdownRunner(client Client, config ShutdownConfig) *ShutdownRunner {
+	return &ShutdownRunner{client: client, config: config}
+}
+
+func (r *ShutdownRunner) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
+	close(ready)
+	<-signals
+	return r.Shutdown()
+}
+
+// Shutdown performs the teardown immediately, continuing past failures and
+// returning them together as a ShutdownError.
+func (r *ShutdownRunner) Shutdown() error {
+	ctx := context.Background()
+	if r.config.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.config.Timeout)
+		defer cancel()
+	}
+	return r.ShutdownWithContext(ctx)
+}
+
+// ShutdownWithContext is like Shutdown, but gives up on each remaining step
+// once ctx is done.
+func (r *ShutdownRunner) ShutdownWithContext(ctx context.Context) error {
+	var errs ShutdownError
+
+	for _, serviceID := range r.config.ServiceIDs {
+		err := DeregisterServiceWithContext(ctx, r.client.Agent(), serviceID)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("deregister service %s: %s", serviceID, err))
+		}
+	}
+
+	for _, lock := range r.config.Locks {
+		err := ReleaseLockWithContext(ctx, lock)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("release lock: %s", err))
+		}
+	}
+
+	for _, sessionID := range r.config.SessionIDs {
+		err := DestroySessionWithContext(ctx, r.client.Session(), sessionID)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("destroy session %s: %s", sessionID, err))
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package consuladapter_test
+
+import (
+	"errors"
+	"os"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/consuladapter"
+	"code.cloudfoundry.org/consuladapter/fakes"
+	"github.com/hashicorp/consul/api"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ShutdownRunner", func() {
+	var (
+		client     *fakes.FakeClient
+		components *fakes.FakeClientComponents
+	)
+
+	BeforeEach(func() {
+		client, components = fakes.NewFakeClient()
+	})
+
+	It("deregisters services, then releases locks, then destroys sessions when signalled", func() {
+		var (
+			mu    sync.Mutex
+			steps []string
+		)
+		record := func(step string) {
+			mu.Lock()
+			defer mu.Unlock()
+			steps = append(steps, step)
+		}
+
+		components.Agent.ServiceDeregisterStub = func(id string) error {
+			record("deregister " + id)
+			return nil
+		}
+		lock := &fakes.FakeLock{}
+		lock.UnlockStub = func() error {
+			record("unlock")
+			return nil
+		}
+		components.Session.DestroyStub = func(id string, _ *api.WriteOptions) (*api.WriteMeta, error) {
+			record("destroy " + id)
+			return nil, nil
+		}
+
+		runner := consuladapter.NewShutdownRunner(client, consuladapter.ShutdownConfig{
+			ServiceIDs: []string{"cell-1", "cell-2"},
+			Locks:      []consuladapter.Lock{lock},
+			SessionIDs: []string{"session-1"},
+		})
+
+		signals := make(chan os.Signal, 1)
+		ready := make(chan struct{})
+		errCh := make(chan error, 1)
+		go func() { errCh <- runner.Run(signals, ready) }()
+		Eventually(ready).Should(BeClosed())
+		Consistently(func() []string {
+			mu.Lock()
+			defer mu.Unlock()
+			return steps
+		}).Should(BeEmpty())
+
+		signals <- os.Interrupt
+		Eventually(errCh).Should(Receive(BeNil()))
+		Expect(steps).To(Equal([]string{"deregister cell-1", "deregister cell-2", "unlock", "destroy session-1"}))
+	})
+
+	It("continues past failures and returns them together", func() {
+		components.Agent.ServiceDeregisterReturns(errors.New("agent unreachable"))
+		lock := &fakes.FakeLock{}
+		lock.UnlockReturns(errors.New("lock not held"))
+		components.Session.DestroyReturns(nil, errors.New("session not found"))
+
+		runner := consuladapter.NewShutdownRunner(client, consuladapter.ShutdownConfig{
+			ServiceIDs: []string{"cell-1"},
+			Locks:      []consuladapter.Lock{lock},
+			SessionIDs: []string{"session-1"},
+		})
+
+		err := runner.Shutdown()
+		Expect(err).To(Equal(consuladapter.ShutdownError{
+			errors.New("deregister service cell-1: agent unreachable"),
+			errors.New("release lock: lock not held"),
+			errors.New("destroy session session-1: session not found"),
+		}))
+		Expect(err).To(MatchError("shutdown failed: deregister service cell-1: agent unreachable; release lock: lock not held; destroy session session-1: session not found"))
+	})
+
+	It("bounds deregistering services by the timeout", func() {
+		blockCh := make(chan struct{})
+		defer close(blockCh)
+		components.Agent.ServiceDeregisterStub = func(string) error {
+			<-blockCh
+			return nil
+		}
+
+		runner := consuladapter.NewShutdownRunner(client, consuladapter.ShutdownConfig{
+			ServiceIDs: []string{"cell-1"},
+			SessionIDs: []string{"session-1"},
+			Timeout:    50 * time.Millisecond,
+		})
+
+		errCh := make(chan error, 1)
+		go func() { errCh <- runner.Shutdown() }()
+
+		var err error
+		Eventually(errCh).Should(Receive(&err))
+		Expect(err).To(MatchError(ContainSubstring("deregister service cell-1: context deadline exceeded")))
+		Expect(components.Session.DestroyCallCount()).To(Equal(1))
+	})
+})
@@ -0,0 +1,21 @@
+package simulation
+
+import "time"
+
+// Clock is a virtual clock that only moves when advanced, so a simulation
+// runs in no real time and replays identically.
+type Clock struct {
+	now time.Time
+}
+
+func NewClock() *Clock {
+	return &Clock{now: time.Unix(0, 0).UTC()}
+}
+
+func (c *Clock) Now() time.Time {
+	return c.now
+}
+
+func (c *Clock) Advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
@@ -0,0 +1,204 @@
+package simulation
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"time"
+
+	"code.cloudfoundry.org/consuladapter"
+	"code.cloudfoundry.org/consuladapter/fakes"
+	"github.com/hashicorp/consul/api"
+)
+
+type RunnerConfig struct {
+	Runners  int
+	Key      string
+	Handoffs int
+
+	// Timeout bounds each wait for a runner to become ready or to exit.
+	// Defaults to DefaultRunnerTimeout.
+	Timeout time.Duration
+}
+
+const DefaultRunnerTimeout = 5 * time.Second
+
+// runnerRetryInterval paces the runners' acquisition attempts. CheckLockRunners
+// waits for twice as long before checking that no second runner is ready.
+const runnerRetryInterval = 10 * time.Millisecond
+
+type lockRunner struct {
+	name    string
+	ready   chan struct{}
+	signals chan os.Signal
+	exited  chan error
+}
+
+// CheckLockRunners runs config.Runners consuladapter.LockRunners contending
+// for one lock on a fakes.FakeBackend, and hands the lock over
+// config.Handoffs times. At each handoff the holder either loses its
+// session, as when its TTL runs out, or is signalled to release the lock,
+// chosen from seed, and is replaced by a new runner once it exits.
+//
+// Unlike Run, this exercises the adapter's own acquisition code, AcquireLock
+// and LockRunner, on their own goroutines in real time. It checks that
+// exactly one runner is ready at a time, that the lock's value names it and
+// its session is valid, and that the holder exits with a LockLostError or a
+// LockSignaledError as appropriate.
+func CheckLockRunners(config RunnerConfig, seed int64) error {
+	if config.Runners <= 0 {
+		return fmt.Errorf("invalid number of runners %d", config.Runners)
+	}
+	if config.Key == "" {
+		config.Key = DefaultKey
+	}
+	if config.Timeout == 0 {
+		config.Timeout = DefaultRunnerTimeout
+	}
+
+	backend := fakes.NewFakeBackend()
+	client, _ := backend.Client()
+	random := rand.New(rand.NewSource(seed))
+	var trace []string
+
+	started := 0
+	start := func() *lockRunner {
+		r := &lockRunner{
+			name:    fmt.Sprintf("runner-%d", started),
+			ready:   make(chan struct{}),
+			signals: make(chan os.Signal, 1),
+			exited:  make(chan error, 1),
+		}
+		started++
+
+		runner := consuladapter.NewLockRunner(client, api.LockOptions{Key: config.Key, Value: []byte(r.name)}, consuladapter.FixedRetry{Interval: runnerRetryInterval})
+		go func() {
+			r.exited <- runner.Run(r.signals, r.ready)
+		}()
+		return r
+	}
+
+	runners := make([]*lockRunner, config.Runners)
+	for i := range runners {
+		runners[i] = start()
+	}
+	defer stopRunners(runners, config.Timeout)
+
+	for handoff := 0; ; handoff++ {
+		violation := func(invariant string) error {
+			return &InvariantViolation{Seed: seed, Step: handoff, Invariant: invariant, Trace: trace}
+		}
+
+		holder, err := waitForReady(runners, config.Timeout)
+		if err != nil {
+			return violation(err.Error())
+		}
+		h := runners[holder]
+		trace = append(trace, fmt.Sprintf("%s ready", h.name))
+
+		time.Sleep(2 * runnerRetryInterval)
+		if invariant := checkHolder(backend, config.Key, runners, holder); invariant != "" {
+			return violation(invariant)
+		}
+
+		if handoff == config.Handoffs {
+			break
+		}
+
+		var expected error
+		if random.Intn(2) == 0 {
+			pair, _, _ := backend.KV().Get(config.Key, nil)
+			backend.Expire(pair.Session)
+			expected = consuladapter.LockLostError{Key: config.Key}
+			trace = append(trace, fmt.Sprintf("%s session expired", h.name))
+		} else {
+			h.signals <- os.Interrupt
+			expected = consuladapter.LockSignaledError{Key: config.Key, Signal: os.Interrupt}
+			trace = append(trace, fmt.Sprintf("%s signalled", h.name))
+		}
+
+		select {
+		case err := <-h.exited:
+			trace = append(trace, fmt.Sprintf("%s exited: %v", h.name, err))
+			if err != expected {
+				return violation(fmt.Sprintf("%s exited with %v rather than %v", h.name, err, expected))
+			}
+		case <-time.After(config.Timeout):
+			return violation(fmt.Sprintf("%s did not exit", h.name))
+		}
+
+		runners[holder] = start()
+	}
+
+	return nil
+}
+
+// waitForReady returns the index of the first runner found ready, failing
+// if any runner exits first.
+func waitForReady(runners []*lockRunner, timeout time.Duration) (int, error) {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		for i, r := range runners {
+			select {
+			case err := <-r.exited:
+				return -1, fmt.Errorf("%s exited unexpectedly: %v", r.name, err)
+			default:
+			}
+
+			if isClosed(r.ready) {
+				return i, nil
+			}
+		}
+		time.Sleep(time.Millisecond)
+	}
+	return -1, fmt.Errorf("no runner became ready within %s", timeout)
+}
+
+// checkHolder returns a description of how the lock's state disagrees with
+// holder being its only ready runner, or "" if it does not.
+func checkHolder(backend *fakes.FakeBackend, key string, runners []*lockRunner, holder int) string {
+	for i, r := range runners {
+		if i != holder && isClosed(r.ready) {
+			return fmt.Sprintf("%s and %s are both ready", runners[holder].name, r.name)
+		}
+	}
+
+	pair, _, err := backend.KV().Get(key, nil)
+	if err != nil {
+		return err.Error()
+	}
+	if pair == nil || pair.Session == "" {
+		return fmt.Sprintf("%s is ready, but the lock is free", runners[holder].name)
+	}
+	if string(pair.Value) != runners[holder].name {
+		return fmt.Sprintf("%s is ready, but the lock's value is %q", runners[holder].name, pair.Value)
+	}
+	if entry, _, _ := backend.Session().Info(pair.Session, nil); entry == nil {
+		return fmt.Sprintf("%s is ready, but its session %q is invalid", runners[holder].name, pair.Session)
+	}
+	return ""
+}
+
+func stopRunners(runners []*lockRunner, timeout time.Duration) {
+	for _, r := range runners {
+		select {
+		case r.signals <- os.Interrupt:
+		default:
+		}
+	}
+	for _, r := range runners {
+		select {
+		case <-r.exited:
+		case <-time.After(timeout):
+		}
+	}
+}
+
+func isClosed(ch <-chan struct{}) bool {
+	select {
+	case <-ch:
+		return true
+	default:
+		return false
+	}
+}
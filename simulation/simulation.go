@@ -0,0 +1,359 @@
+package simulation
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/consuladapter"
+	"code.cloudfoundry.org/consuladapter/fakes"
+	"github.com/hashicorp/consul/api"
+)
+
+type ActionKind string
+
+const (
+	ActionAcquire   ActionKind = "acquire"
+	ActionRelease   ActionKind = "release"
+	ActionRenew     ActionKind = "renew"
+	ActionPartition ActionKind = "partition"
+	ActionHeal      ActionKind = "heal"
+	ActionAdvance   ActionKind = "advance"
+)
+
+// Action is one step of a schedule. Process is ignored for ActionAdvance,
+// and Duration is only used by it.
+type Action struct {
+	Kind     ActionKind
+	Process  int
+	Duration time.Duration
+}
+
+func (a Action) String() string {
+	if a.Kind == ActionAdvance {
+		return fmt.Sprintf("advance %s", a.Duration)
+	}
+	return fmt.Sprintf("process %d %s", a.Process, a.Kind)
+}
+
+type Config struct {
+	Processes  int
+	Key        string
+	SessionTTL time.Duration
+}
+
+const (
+	DefaultKey        = "v1/locks/simulation"
+	DefaultSessionTTL = 15 * time.Second
+)
+
+// InvariantViolation describes a schedule that broke the lock's guarantees.
+// Seed is set for schedules from RandomSchedule so the run can be replayed.
+type InvariantViolation struct {
+	Seed      int64
+	Step      int
+	Invariant string
+	Trace     []string
+}
+
+func (v *InvariantViolation) Error() string {
+	return fmt.Sprintf("invariant violated at step %d (seed %d): %s\n%s", v.Step, v.Seed, v.Invariant, strings.Join(v.Trace, "\n"))
+}
+
+type process struct {
+	session     string
+	partitioned bool
+
+	// holding is what the process believes: it holds the lock until it
+	// releases it, learns its session is gone, or its lease runs out.
+	holding    bool
+	leaseUntil time.Time
+}
+
+// Simulation drives simulated processes contending for one lock on a shared
+// fakes.FakeBackend, acquiring it with consuladapter.TryAcquireLock. It runs on a single goroutine under a virtual clock, so
+// a schedule always produces the same trace.
+type Simulation struct {
+	config  Config
+	clock   *Clock
+	backend *fakes.FakeBackend
+	session consuladapter.Session
+	kv      consuladapter.KV
+
+	processes []*process
+	// deadlines is when the backend expires each session that has not been
+	// renewed, standing in for consul's TTL timers.
+	deadlines map[string]time.Time
+
+	trace []string
+}
+
+func New(config Config) *Simulation {
+	if config.Key == "" {
+		config.Key = DefaultKey
+	}
+	if config.SessionTTL == 0 {
+		config.SessionTTL = DefaultSessionTTL
+	}
+
+	backend := fakes.NewFakeBackend()
+	sim := &Simulation{
+		config:    config,
+		clock:     NewClock(),
+		backend:   backend,
+		session:   backend.Session(),
+		kv:        backend.KV(),
+		deadlines: map[string]time.Time{},
+	}
+	for i := 0; i < config.Processes; i++ {
+		sim.processes = append(sim.processes, &process{})
+	}
+	return sim
+}
+
+// Run applies each action in turn, checking the invariants after every
+// step, and returns the first violation.
+func (s *Simulation) Run(schedule []Action) error {
+	for step, action := range schedule {
+		err := s.apply(action)
+		if err != nil {
+			return fmt.Errorf("step %d (%s): %s", step, action, err)
+		}
+
+		if violation := s.check(); violation != "" {
+			return &InvariantViolation{Step: step, Invariant: violation, Trace: s.Trace()}
+		}
+	}
+	return nil
+}
+
+// Trace returns a line per applied action and its outcome.
+func (s *Simulation) Trace() []string {
+	return append([]string(nil), s.trace...)
+}
+
+func (s *Simulation) apply(action Action) error {
+	if action.Kind == ActionAdvance {
+		s.clock.Advance(action.Duration)
+		s.expire()
+		s.record(action, "")
+		return nil
+	}
+
+	if action.Process < 0 || action.Process >= len(s.processes) {
+		return fmt.Errorf("no process %d", action.Process)
+	}
+	p := s.processes[action.Process]
+
+	switch action.Kind {
+	case ActionPartition:
+		p.partitioned = true
+		s.record(action, "")
+		return nil
+	case ActionHeal:
+		p.partitioned = false
+		s.record(action, "")
+		return nil
+	}
+
+	if p.partitioned {
+		s.record(action, "unreachable")
+		return nil
+	}
+
+	switch action.Kind {
+	case ActionAcquire:
+		return s.acquire(action, p)
+	case ActionRelease:
+		return s.release(action, p)
+	case ActionRenew:
+		return s.renew(action, p)
+	default:
+		return fmt.Errorf("unknown action %q", action.Kind)
+	}
+}
+
+func (s *Simulation) acquire(action Action, p *process) error {
+	if p.holding {
+		s.record(action, "already holding")
+		return nil
+	}
+
+	if p.session == "" {
+		id, _, err := s.session.Create(&api.SessionEntry{TTL: s.config.SessionTTL.String()}, nil)
+		if err != nil {
+			return err
+		}
+		p.session = id
+		s.deadlines[id] = s.clock.Now().Add(s.config.SessionTTL)
+	}
+
+	err := consuladapter.TryAcquireLock(s.kv, p.session, s.config.Key, nil)
+	switch {
+	case err == fakes.ErrInvalidSession:
+		p.session = ""
+		s.record(action, "session invalid")
+		return nil
+	case consuladapter.IsLockHeldError(err):
+		s.record(action, "held elsewhere")
+		return nil
+	case err != nil:
+		return err
+	}
+
+	p.holding = true
+	p.leaseUntil = s.deadlines[p.session]
+	s.record(action, "acquired with "+p.session)
+	return nil
+}
+
+func (s *Simulation) release(action Action, p *process) error {
+	if !p.holding {
+		s.record(action, "not holding")
+		return nil
+	}
+
+	_, _, err := s.kv.Release(&api.KVPair{Key: s.config.Key, Session: p.session}, nil)
+	if err != nil {
+		return err
+	}
+	p.holding = false
+	s.record(action, "released")
+	return nil
+}
+
+func (s *Simulation) renew(action Action, p *process) error {
+	if p.session == "" {
+		s.record(action, "no session")
+		return nil
+	}
+
+	entry, _, err := s.session.Renew(p.session, nil)
+	if err != nil {
+		return err
+	}
+	if entry == nil {
+		p.session = ""
+		p.holding = false
+		s.record(action, "session gone")
+		return nil
+	}
+
+	deadline := s.clock.Now().Add(s.config.SessionTTL)
+	s.deadlines[p.session] = deadline
+	if p.holding {
+		p.leaseUntil = deadline
+	}
+	s.record(action, "renewed")
+	return nil
+}
+
+// expire drops leases that have run out, then invalidates sessions whose
+// TTL has elapsed, in process order so that runs are deterministic.
+func (s *Simulation) expire() {
+	now := s.clock.Now()
+	for _, p := range s.processes {
+		if p.holding && !now.Before(p.leaseUntil) {
+			p.holding = false
+		}
+
+		deadline, ok := s.deadlines[p.session]
+		if p.session != "" && ok && !now.Before(deadline) {
+			s.backend.Expire(p.session)
+			delete(s.deadlines, p.session)
+		}
+	}
+}
+
+func (s *Simulation) check() string {
+	var holders []int
+	for i, p := range s.processes {
+		if p.holding {
+			holders = append(holders, i)
+		}
+	}
+	if len(holders) > 1 {
+		return fmt.Sprintf("processes %v all believe they hold the lock", holders)
+	}
+
+	holder := s.backend.Holder(s.config.Key)
+	if len(holders) == 1 {
+		p := s.processes[holders[0]]
+		if !p.partitioned && holder != p.session {
+			return fmt.Sprintf("process %d believes it holds the lock, but %q does", holders[0], holder)
+		}
+	}
+
+	if holder != "" {
+		if entry, _, _ := s.session.Info(holder, nil); entry == nil {
+			return fmt.Sprintf("lock is held by invalidated session %q", holder)
+		}
+	}
+
+	return ""
+}
+
+func (s *Simulation) record(action Action, outcome string) {
+	line := fmt.Sprintf("%8s %s", s.clock.Now().Sub(time.Unix(0, 0)), action)
+	if outcome != "" {
+		line += ": " + outcome
+	}
+	s.trace = append(s.trace, line)
+}
+
+// RandomSchedule generates a schedule of steps actions from seed. Time
+// advances by up to maxAdvance between operations, so sessions expire when
+// renewals are too sparse or processes are partitioned.
+func RandomSchedule(seed int64, processes, steps int, maxAdvance time.Duration) ([]Action, error) {
+	if processes <= 0 {
+		return nil, fmt.Errorf("invalid number of processes %d", processes)
+	}
+	if maxAdvance <= 0 {
+		return nil, fmt.Errorf("invalid maximum advance %s", maxAdvance)
+	}
+
+	random := rand.New(rand.NewSource(seed))
+	kinds := []ActionKind{
+		ActionAcquire, ActionAcquire, ActionRelease,
+		ActionRenew, ActionRenew, ActionRenew,
+		ActionPartition, ActionHeal, ActionHeal,
+		ActionAdvance, ActionAdvance,
+	}
+
+	schedule := make([]Action, 0, steps)
+	for i := 0; i < steps; i++ {
+		action := Action{Kind: kinds[random.Intn(len(kinds))], Process: random.Intn(processes)}
+		if action.Kind == ActionAdvance {
+			action.Duration = time.Duration(random.Int63n(int64(maxAdvance))) + 1
+		}
+		schedule = append(schedule, action)
+	}
+	return schedule, nil
+}
+
+// Check runs runs random schedules, with seeds counting up from seed, and
+// returns the first violation found.
+func Check(config Config, seed int64, runs, steps int) error {
+	ttl := config.SessionTTL
+	if ttl == 0 {
+		ttl = DefaultSessionTTL
+	}
+
+	for i := int64(0); i < int64(runs); i++ {
+		schedule, err := RandomSchedule(seed+i, config.Processes, steps, ttl)
+		if err != nil {
+			return err
+		}
+
+		err = New(config).Run(schedule)
+		if violation, ok := err.(*InvariantViolation); ok {
+			violation.Seed = seed + i
+			return violation
+		}
+		if err != nil {
+			return fmt.Errorf("seed %d: %s", seed+i, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package simulation_test
+
+import (
+	"time"
+
+	"code.cloudfoundry.org/consuladapter/simulation"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Simulation", func() {
+	config := simulation.Config{Processes: 2, SessionTTL: 10 * time.Second}
+
+	acquire := func(p int) simulation.Action { return simulation.Action{Kind: simulation.ActionAcquire, Process: p} }
+	advance := func(d time.Duration) simulation.Action {
+		return simulation.Action{Kind: simulation.ActionAdvance, Duration: d}
+	}
+
+	It("hands the lock over once a partitioned holder's session expires", func() {
+		sim := simulation.New(config)
+		err := sim.Run([]simulation.Action{
+			acquire(0),
+			{Kind: simulation.ActionPartition, Process: 0},
+			acquire(1),
+			advance(5 * time.Second),
+			{Kind: simulation.ActionRenew, Process: 1},
+			{Kind: simulation.ActionRenew, Process: 0},
+			advance(5 * time.Second),
+			acquire(1),
+			{Kind: simulation.ActionHeal, Process: 0},
+			acquire(0),
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		trace := sim.Trace()
+		Expect(trace[2]).To(ContainSubstring("process 1 acquire: held elsewhere"))
+		Expect(trace[5]).To(ContainSubstring("process 0 renew: unreachable"))
+		Expect(trace[7]).To(ContainSubstring("process 1 acquire: acquired"))
+		Expect(trace[9]).To(ContainSubstring("process 0 acquire: session invalid"))
+	})
+
+	It("keeps the lock with its holder while it renews", func() {
+		sim := simulation.New(config)
+		err := sim.Run([]simulation.Action{
+			acquire(0),
+			advance(8 * time.Second),
+			{Kind: simulation.ActionRenew, Process: 0},
+			advance(8 * time.Second),
+			acquire(1),
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(sim.Trace()[4]).To(ContainSubstring("held elsewhere"))
+	})
+
+	It("produces the same trace for the same schedule", func() {
+		config := simulation.Config{Processes: 3}
+		schedule, err := simulation.RandomSchedule(42, 3, 200, 10*time.Second)
+		Expect(err).NotTo(HaveOccurred())
+		first, second := simulation.New(config), simulation.New(config)
+
+		Expect(first.Run(schedule)).To(Succeed())
+		Expect(second.Run(schedule)).To(Succeed())
+		Expect(first.Trace()).To(Equal(second.Trace()))
+	})
+
+	It("never finds two holders across random schedules", func() {
+		Expect(simulation.Check(simulation.Config{Processes: 4}, 1, 200, 300)).To(Succeed())
+	})
+
+	It("rejects schedules without processes or time to advance", func() {
+		_, err := simulation.RandomSchedule(42, 0, 10, time.Second)
+		Expect(err).To(MatchError("invalid number of processes 0"))
+
+		_, err = simulation.RandomSchedule(42, 2, 10, 0)
+		Expect(err).To(MatchError("invalid maximum advance 0s"))
+
+		Expect(simulation.Check(simulation.Config{Processes: 2, SessionTTL: -time.Second}, 1, 1, 10)).To(MatchError("invalid maximum advance -1s"))
+	})
+})
+
+var _ = Describe("CheckLockRunners", func() {
+	It("hands the lock between contending LockRunners, one holder at a time", func() {
+		config := simulation.RunnerConfig{Runners: 3, Handoffs: 10}
+		Expect(simulation.CheckLockRunners(config, 1)).To(Succeed())
+		Expect(simulation.CheckLockRunners(config, 2)).To(Succeed())
+	})
+
+	It("rejects configurations without runners", func() {
+		Expect(simulation.CheckLockRunners(simulation.RunnerConfig{}, 1)).To(MatchError("invalid number of runners 0"))
+	})
+})
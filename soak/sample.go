@@ -0,0 +1,48 @@
+package soak
+
+import (
+	"os"
+	"runtime"
+	"time"
+)
+
+// Sample is a snapshot of the resources held by the process running the
+// soak. FDs is -1 where open files cannot be counted.
+type Sample struct {
+	At         time.Duration `json:"at_ns"`
+	Goroutines int           `json:"goroutines"`
+	FDs        int           `json:"fds"`
+	HeapInuse  uint64        `json:"heap_inuse"`
+}
+
+// TakeSample collects garbage first, so that HeapInuse reflects live data.
+func TakeSample(at time.Duration) Sample {
+	runtime.GC()
+
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+
+	return Sample{
+		At:         at,
+		Goroutines: runtime.NumGoroutine(),
+		FDs:        countFDs(),
+		HeapInuse:  stats.HeapInuse,
+	}
+}
+
+func countFDs() int {
+	for _, dir := range []string{"/proc/self/fd", "/dev/fd"} {
+		f, err := os.Open(dir)
+		if err != nil {
+			continue
+		}
+		names, err := f.Readdirnames(-1)
+		f.Close()
+		if err != nil {
+			continue
+		}
+		// exclude the descriptor used to read the directory
+		return len(names) - 1
+	}
+	return -1
+}
@@ -0,0 +1,277 @@
+package soak
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/consuladapter"
+	"github.com/hashicorp/consul/api"
+)
+
+const (
+	DefaultSampleInterval     = time.Minute
+	DefaultWorkers            = 2
+	DefaultKeyPrefix          = "soak/"
+	DefaultSessionTTL         = "10s"
+	DefaultMaxGoroutineGrowth = 20
+	DefaultMaxFDGrowth        = 10
+	DefaultMaxHeapGrowth      = 64 << 20
+	DefaultSettleTime         = 30 * time.Second
+)
+
+type Config struct {
+	Duration       time.Duration
+	SampleInterval time.Duration
+
+	// Workers is the number of concurrent workers for each of the session,
+	// lock and watch workloads.
+	Workers    int
+	KeyPrefix  string
+	SessionTTL string
+
+	// The limits on growth from the sample taken before the workloads start
+	// to the one taken after they have stopped. Idle HTTP connections
+	// account for some goroutines and file descriptors.
+	MaxGoroutineGrowth int
+	MaxFDGrowth        int
+	MaxHeapGrowth      uint64
+
+	// SettleTime bounds how long to wait for background goroutines to exit
+	// after the workloads stop, before taking the final sample.
+	SettleTime time.Duration
+
+	// OnSample, if set, is called with each periodic sample.
+	OnSample func(Sample)
+}
+
+type Report struct {
+	Baseline   Sample         `json:"baseline"`
+	Samples    []Sample       `json:"samples"`
+	Final      Sample         `json:"final"`
+	Operations map[string]int `json:"operations"`
+	Errors     map[string]int `json:"errors"`
+}
+
+type LeakError struct {
+	Resource string
+	Baseline uint64
+	Final    uint64
+	Limit    uint64
+}
+
+func (e LeakError) Error() string {
+	return fmt.Sprintf("%s leaked: grew from %d to %d, more than the allowed %d", e.Resource, e.Baseline, e.Final, e.Limit)
+}
+
+// Run exercises sessions, locks and watches through client for
+// config.Duration or until ctx is done, sampling the process's goroutines,
+// file descriptors and heap as it goes. Once the workloads have stopped it
+// returns a LeakError if any resource grew beyond its limit.
+func Run(ctx context.Context, client consuladapter.Client, config Config) (Report, error) {
+	config = withDefaults(config)
+
+	ctx, cancel := context.WithTimeout(ctx, config.Duration)
+	defer cancel()
+
+	start := time.Now()
+	report := Report{
+		Baseline:   TakeSample(0),
+		Operations: map[string]int{},
+		Errors:     map[string]int{},
+	}
+
+	var mutex sync.Mutex
+	count := func(workload string, err error) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		report.Operations[workload]++
+		if err != nil {
+			report.Errors[workload]++
+		}
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < config.Workers; i++ {
+		workers := []func(context.Context, consuladapter.Client, Config, int, func(string, error)){
+			sessionWorkload, lockWorkload, watchWorkload,
+		}
+		for _, worker := range workers {
+			wg.Add(1)
+			go func(worker func(context.Context, consuladapter.Client, Config, int, func(string, error)), i int) {
+				defer wg.Done()
+				worker(ctx, client, config, i, count)
+			}(worker, i)
+		}
+	}
+
+	ticker := time.NewTicker(config.SampleInterval)
+	for done := false; !done; {
+		select {
+		case <-ctx.Done():
+			done = true
+		case <-ticker.C:
+			sample := TakeSample(time.Since(start))
+			report.Samples = append(report.Samples, sample)
+			if config.OnSample != nil {
+				config.OnSample(sample)
+			}
+		}
+	}
+	ticker.Stop()
+	wg.Wait()
+
+	report.Final = settle(report.Baseline, config, start)
+	return report, checkLeaks(report.Baseline, report.Final, config)
+}
+
+func withDefaults(config Config) Config {
+	if config.SampleInterval == 0 {
+		config.SampleInterval = DefaultSampleInterval
+	}
+	if config.Workers == 0 {
+		config.Workers = DefaultWorkers
+	}
+	if config.KeyPrefix == "" {
+		config.KeyPrefix = DefaultKeyPrefix
+	}
+	if config.SessionTTL == "" {
+		config.SessionTTL = DefaultSessionTTL
+	}
+	if config.MaxGoroutineGrowth == 0 {
+		config.MaxGoroutineGrowth = DefaultMaxGoroutineGrowth
+	}
+	if config.MaxFDGrowth == 0 {
+		config.MaxFDGrowth = DefaultMaxFDGrowth
+	}
+	if config.MaxHeapGrowth == 0 {
+		config.MaxHeapGrowth = DefaultMaxHeapGrowth
+	}
+	if config.SettleTime == 0 {
+		config.SettleTime = DefaultSettleTime
+	}
+	return config
+}
+
+// settle samples until goroutines are back within their limit or
+// config.SettleTime passes, so that goroutines that are merely slow to exit
+// are not reported as leaks.
+func settle(baseline Sample, config Config, start time.Time) Sample {
+	deadline := time.Now().Add(config.SettleTime)
+	for {
+		sample := TakeSample(time.Since(start))
+		if sample.Goroutines-baseline.Goroutines <= config.MaxGoroutineGrowth || time.Now().After(deadline) {
+			return sample
+		}
+		time.Sleep(time.Second)
+	}
+}
+
+func checkLeaks(baseline, final Sample, config Config) error {
+	if growth := final.Goroutines - baseline.Goroutines; growth > config.MaxGoroutineGrowth {
+		return LeakError{"goroutines", uint64(baseline.Goroutines), uint64(final.Goroutines), uint64(config.MaxGoroutineGrowth)}
+	}
+	if baseline.FDs >= 0 && final.FDs-baseline.FDs > config.MaxFDGrowth {
+		return LeakError{"file descriptors", uint64(baseline.FDs), uint64(final.FDs), uint64(config.MaxFDGrowth)}
+	}
+	if final.HeapInuse > baseline.HeapInuse && final.HeapInuse-baseline.HeapInuse > config.MaxHeapGrowth {
+		return LeakError{"heap", baseline.HeapInuse, final.HeapInuse, config.MaxHeapGrowth}
+	}
+	return nil
+}
+
+// pause waits between operations, and after errors so that an unavailable
+// agent does not turn a workload into a busy loop.
+func pause(ctx context.Context, d time.Duration) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(d):
+		return true
+	}
+}
+
+// sessionWorkload creates renewed TTL sessions and destroys them again,
+// exercising the renewal goroutines.
+func sessionWorkload(ctx context.Context, client consuladapter.Client, config Config, i int, count func(string, error)) {
+	for ctx.Err() == nil {
+		doneCh := make(chan struct{})
+		_, renewErr, err := consuladapter.CreateTTLSession(client.Session(), &api.SessionEntry{
+			Name: fmt.Sprintf("soak-%d", i),
+			TTL:  config.SessionTTL,
+		}, doneCh)
+		if err == nil {
+			pause(ctx, 100*time.Millisecond)
+			close(doneCh)
+			err = <-renewErr
+		}
+		count("sessions", err)
+
+		if err != nil && !pause(ctx, time.Second) {
+			return
+		}
+	}
+}
+
+// lockWorkload has pairs of workers contend for the same lock.
+func lockWorkload(ctx context.Context, client consuladapter.Client, config Config, i int, count func(string, error)) {
+	key := fmt.Sprintf("%slock-%d", config.KeyPrefix, i/2)
+	for ctx.Err() == nil {
+		lock, err := client.LockOpts(&api.LockOptions{Key: key, SessionTTL: config.SessionTTL})
+		if err == nil {
+			var lost <-chan struct{}
+			lost, err = lock.Lock(ctx.Done())
+			if lost != nil {
+				pause(ctx, 50*time.Millisecond)
+				err = lock.Unlock()
+			}
+		}
+		count("locks", err)
+
+		if err != nil && !pause(ctx, time.Second) {
+			return
+		}
+	}
+}
+
+// watchWorkload writes a key and waits for a blocking query to observe the
+// write.
+func watchWorkload(ctx context.Context, client consuladapter.Client, config Config, i int, count func(string, error)) {
+	key := fmt.Sprintf("%swatch-%d", config.KeyPrefix, i)
+	var index uint64
+
+	for n := 0; ctx.Err() == nil; n++ {
+		_, err := client.KV().Put(&api.KVPair{Key: key, Value: []byte(fmt.Sprint(n))}, nil)
+		if err != nil {
+			count("watches", err)
+			if !pause(ctx, time.Second) {
+				return
+			}
+			continue
+		}
+
+		from := index
+		err = consuladapter.BlockingQuery(ctx, consuladapter.DefaultWatchBackoff, func(err error) {
+			count("watches", err)
+		}, func(q *api.QueryOptions) (*api.QueryMeta, error) {
+			q.WaitTime = time.Second
+			if q.WaitIndex == 0 {
+				q.WaitIndex = from
+			}
+			_, meta, err := client.KV().Get(key, q)
+			if err != nil {
+				return nil, err
+			}
+			if meta.LastIndex > from {
+				index = meta.LastIndex
+				return nil, consuladapter.StopQuerying
+			}
+			return meta, nil
+		})
+		if err != nil {
+			return
+		}
+		count("watches", nil)
+	}
+}
@@ -0,0 +1,87 @@
+package soak_test
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"code.cloudfoundry.org/consuladapter/consulrunner/execrunner"
+	"code.cloudfoundry.org/consuladapter/soak"
+)
+
+var duration = flag.Duration("soak.duration", 0, "how long to soak; the soak test is skipped when unset")
+var startingPort = flag.Int("soak.port", 7001, "starting port of the consul cluster")
+var reportPath = flag.String("soak.report", "", "file to write the JSON report to")
+
+var clusterRunner *execrunner.ClusterRunner
+
+func TestMain(m *testing.M) {
+	flag.Parse()
+	if *duration == 0 {
+		os.Exit(m.Run())
+	}
+
+	var err error
+	clusterRunner, err = execrunner.NewClusterRunner(execrunner.ClusterRunnerConfig{
+		StartingPort: *startingPort,
+		NumNodes:     1,
+		Scheme:       "http",
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	err = clusterRunner.Start()
+	if err == nil {
+		err = clusterRunner.WaitUntilReady(10 * time.Second)
+	}
+	if err != nil {
+		clusterRunner.Stop()
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	code := m.Run()
+
+	clusterRunner.Stop()
+	os.Exit(code)
+}
+
+func TestSoak(t *testing.T) {
+	if *duration == 0 {
+		t.Skip("set -soak.duration to run the soak test")
+	}
+
+	client, err := clusterRunner.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := soak.Run(context.Background(), client, soak.Config{
+		Duration: *duration,
+		OnSample: func(sample soak.Sample) {
+			t.Logf("%s: %d goroutines, %d fds, %d MiB heap", sample.At.Round(time.Second), sample.Goroutines, sample.FDs, sample.HeapInuse>>20)
+		},
+	})
+
+	if *reportPath != "" {
+		contents, marshalErr := json.MarshalIndent(report, "", "  ")
+		if marshalErr == nil {
+			marshalErr = ioutil.WriteFile(*reportPath, contents, 0644)
+		}
+		if marshalErr != nil {
+			t.Error(marshalErr)
+		}
+	}
+
+	t.Logf("operations: %v, errors: %v", report.Operations, report.Errors)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
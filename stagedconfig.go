@@ -0,0 +1,252 @@
+package consuladapter
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/consul/api"
+)
+
+// StagedConfig publishes config trees in two phases so readers never see a
+// half-written tree. PublishStaged writes a tree under a new version, and
+// Commit then flips the prefix's active-version pointer to it with a single
+// write. Readers follow the pointer. Under prefix, the layout is:
+//
+//	active                 the committed version
+//	next-version           counter used to allocate versions
+//	staged/<version>       written once the version's tree is complete
+//	versions/<version>/... the version's tree
+//
+// Versions are kept after a newer one is committed, so that committing an
+// older version rolls back to it, until Prune deletes them.
+type StagedConfig struct {
+	kv KV
+}
+
+func NewStagedConfig(kv KV) *StagedConfig {
+	return &StagedConfig{kv: kv}
+}
+
+type ConfigVersionNotStagedError struct {
+	Prefix  string
+	Version string
+}
+
+func (e ConfigVersionNotStagedError) Error() string {
+	return fmt.Sprintf("config version '%s' is not staged under '%s'", e.Version, e.Prefix)
+}
+
+// InvalidConfigKeyError is returned by PublishStaged for a key that is not
+// a clean relative path, such as "../app" or "/db", which would be written
+// outside the version's tree.
+type InvalidConfigKeyError struct {
+	Key string
+}
+
+func (e InvalidConfigKeyError) Error() string {
+	return fmt.Sprintf("invalid config key '%s': keys must be clean paths within the version", e.Key)
+}
+
+// PublishStaged writes tree, keyed by paths relative to the version, under
+// a newly allocated version and returns it. Nothing reads it until the
+// version is committed. If writing the tree fails, PublishStaged removes
+// what it wrote.
+func (c *StagedConfig) PublishStaged(prefix string, tree map[string][]byte) (string, error) {
+	for key := range tree {
+		if key == "" || path.IsAbs(key) || path.Clean(key) != key || key == ".." || strings.HasPrefix(key, "../") {
+			return "", InvalidConfigKeyError{Key: key}
+		}
+	}
+
+	version, err := c.nextVersion(prefix)
+	if err != nil {
+		return "", err
+	}
+
+	versionPrefix := path.Join(prefix, "versions", version)
+	for key, value := range tree {
+		_, err := c.kv.Put(&api.KVPair{Key: path.Join(versionPrefix, key), Value: value}, nil)
+		if err != nil {
+			c.kv.DeleteTree(versionPrefix+"/", nil)
+			return "", err
+		}
+	}
+
+	_, err = c.kv.Put(&api.KVPair{Key: path.Join(prefix, "staged", version), Value: []byte(strconv.Itoa(len(tree)))}, nil)
+	if err != nil {
+		return "", err
+	}
+	return version, nil
+}
+
+func (c *StagedConfig) nextVersion(prefix string) (string, error) {
+	key := path.Join(prefix, "next-version")
+	for {
+		pair, _, err := c.kv.Get(key, nil)
+		if err != nil {
+			return "", err
+		}
+
+		next := uint64(1)
+		var index uint64
+		if pair != nil {
+			current, err := strconv.ParseUint(string(pair.Value), 10, 64)
+			if err != nil {
+				return "", fmt.Errorf("invalid config version counter '%s': %s", key, err)
+			}
+			next = current + 1
+			index = pair.ModifyIndex
+		}
+
+		version := strconv.FormatUint(next, 10)
+		ok, _, err := c.kv.CAS(&api.KVPair{Key: key, Value: []byte(version), ModifyIndex: index}, nil)
+		if err != nil {
+			return "", err
+		}
+		if ok {
+			return version, nil
+		}
+	}
+}
+
+// Commit makes version the active config under prefix. It returns a
+// ConfigVersionNotStagedError if PublishStaged did not complete for version.
+func (c *StagedConfig) Commit(prefix, version string) error {
+	pair, _, err := c.kv.Get(path.Join(prefix, "staged", version), nil)
+	if err != nil {
+		return err
+	}
+	if pair == nil {
+		return ConfigVersionNotStagedError{Prefix: prefix, Version: version}
+	}
+
+	_, err = c.kv.Put(&api.KVPair{Key: path.Join(prefix, "active"), Value: []byte(version)}, nil)
+	return err
+}
+
+// Prune deletes the versions under prefix older than the active version,
+// except for the keep most recent of them, which remain available to roll
+// back to. Versions newer than the active one, e.g. staged but not yet
+// committed, are left alone, as is everything if nothing is committed.
+// Rolling back to a version while Prune deletes it leaves the active
+// version empty, so do not prune concurrently with rollbacks.
+func (c *StagedConfig) Prune(prefix string, keep int) error {
+	pair, _, err := c.kv.Get(path.Join(prefix, "active"), nil)
+	if err != nil || pair == nil {
+		return err
+	}
+	active, err := strconv.ParseUint(string(pair.Value), 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid active config version '%s': %s", pair.Value, err)
+	}
+
+	stagedPrefix := path.Join(prefix, "staged") + "/"
+	staged, _, err := c.kv.List(stagedPrefix, nil)
+	if err != nil {
+		return err
+	}
+
+	var older []*api.KVPair
+	versions := map[*api.KVPair]uint64{}
+	for _, marker := range staged {
+		version, err := strconv.ParseUint(strings.TrimPrefix(marker.Key, stagedPrefix), 10, 64)
+		if err != nil || version >= active {
+			continue
+		}
+		versions[marker] = version
+		older = append(older, marker)
+	}
+	sort.Slice(older, func(i, j int) bool {
+		return versions[older[i]] > versions[older[j]]
+	})
+	if keep < 0 {
+		keep = 0
+	}
+	if len(older) <= keep {
+		return nil
+	}
+
+	for _, marker := range older[keep:] {
+		// delete the marker first, so the version cannot be committed with
+		// part of its tree gone
+		_, _, err := c.kv.DeleteCAS(marker, nil)
+		if err != nil {
+			return err
+		}
+		version := strconv.FormatUint(versions[marker], 10)
+		_, err = c.kv.DeleteTree(path.Join(prefix, "versions", version)+"/", nil)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Active returns the committed version under prefix and its tree, or an
+// empty version if nothing has been committed. The returned meta is that of
+// the pointer read, for use in blocking queries.
+func (c *StagedConfig) Active(prefix string, q *api.QueryOptions) (string, map[string][]byte, *api.QueryMeta, error) {
+	pair, meta, err := c.kv.Get(path.Join(prefix, "active"), q)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	if pair == nil {
+		return "", nil, meta, nil
+	}
+
+	version := string(pair.Value)
+	tree, err := c.tree(prefix, version, q)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	return version, tree, meta, nil
+}
+
+func (c *StagedConfig) tree(prefix, version string, q *api.QueryOptions) (map[string][]byte, error) {
+	versionPrefix := path.Join(prefix, "versions", version) + "/"
+
+	var opts *api.QueryOptions
+	if q != nil {
+		// the pointer read already blocked; the tree is immutable once staged
+		copied := *q
+		copied.WaitIndex = 0
+		opts = &copied
+	}
+
+	pairs, _, err := c.kv.List(versionPrefix, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	tree := make(map[string][]byte, len(pairs))
+	for _, pair := range pairs {
+		tree[strings.TrimPrefix(pair.Key, versionPrefix)] = pair.Value
+	}
+	return tree, nil
+}
+
+// Watch calls onChange with the active version under prefix and its tree,
+// once at the start and then after every commit, until ctx is done.
+func (c *StagedConfig) Watch(ctx context.Context, prefix string, onChange func(version string, tree map[string][]byte)) error {
+	var (
+		previous string
+		notified bool
+	)
+	return BlockingQuery(ctx, DefaultWatchBackoff, nil, func(q *api.QueryOptions) (*api.QueryMeta, error) {
+		version, tree, meta, err := c.Active(prefix, q)
+		if err != nil {
+			return nil, err
+		}
+
+		if !notified || version != previous {
+			notified = true
+			previous = version
+			onChange(version, tree)
+		}
+		return meta, nil
+	})
+}
@@ -0,0 +1,138 @@
+package consuladapter_test
+
+import (
+	"context"
+
+	"code.cloudfoundry.org/consuladapter"
+	"code.cloudfoundry.org/consuladapter/fakes"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("StagedConfig", func() {
+	var (
+		kv     consuladapter.KV
+		config *consuladapter.StagedConfig
+	)
+
+	BeforeEach(func() {
+		kv = fakes.NewFakeBackend().KV()
+		config = consuladapter.NewStagedConfig(kv)
+	})
+
+	It("does not expose staged trees until they are committed", func() {
+		version, err := config.PublishStaged("v1/config/app", map[string][]byte{"db/url": []byte("postgres://db")})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(version).To(Equal("1"))
+
+		active, tree, _, err := config.Active("v1/config/app", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(active).To(BeEmpty())
+		Expect(tree).To(BeNil())
+
+		Expect(config.Commit("v1/config/app", version)).To(Succeed())
+
+		active, tree, _, err = config.Active("v1/config/app", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(active).To(Equal("1"))
+		Expect(tree).To(Equal(map[string][]byte{"db/url": []byte("postgres://db")}))
+	})
+
+	It("allocates a new version for each publication", func() {
+		first, err := config.PublishStaged("v1/config/app", map[string][]byte{"a": []byte("1")})
+		Expect(err).NotTo(HaveOccurred())
+		second, err := config.PublishStaged("v1/config/app", map[string][]byte{"b": []byte("2")})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(second).NotTo(Equal(first))
+
+		Expect(config.Commit("v1/config/app", second)).To(Succeed())
+		_, tree, _, err := config.Active("v1/config/app", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(tree).To(Equal(map[string][]byte{"b": []byte("2")}))
+	})
+
+	It("refuses to commit a version that was not staged", func() {
+		err := config.Commit("v1/config/app", "7")
+		Expect(err).To(Equal(consuladapter.ConfigVersionNotStagedError{Prefix: "v1/config/app", Version: "7"}))
+	})
+
+	It("rejects keys that would be written outside the version", func() {
+		for _, key := range []string{"", "/db/url", "../active", "db/../../active", "db//url", "db/url/"} {
+			_, err := config.PublishStaged("v1/config/app", map[string][]byte{key: []byte("x")})
+			Expect(err).To(Equal(consuladapter.InvalidConfigKeyError{Key: key}))
+		}
+
+		pairs, _, err := kv.List("v1/config/app", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pairs).To(BeEmpty())
+	})
+
+	Describe("Prune", func() {
+		publish := func(value string) string {
+			version, err := config.PublishStaged("v1/config/app", map[string][]byte{"key": []byte(value)})
+			Expect(err).NotTo(HaveOccurred())
+			return version
+		}
+
+		staged := func(version string) bool {
+			pair, _, err := kv.Get("v1/config/app/staged/"+version, nil)
+			Expect(err).NotTo(HaveOccurred())
+			pairs, _, err := kv.List("v1/config/app/versions/"+version+"/", nil)
+			Expect(err).NotTo(HaveOccurred())
+			return pair != nil && len(pairs) > 0
+		}
+
+		It("does nothing while no version is committed", func() {
+			version := publish("a")
+			Expect(config.Prune("v1/config/app", 0)).To(Succeed())
+			Expect(staged(version)).To(BeTrue())
+		})
+
+		It("deletes versions older than the active one beyond the most recent keep", func() {
+			var versions []string
+			for i := 1; i <= 12; i++ {
+				versions = append(versions, publish(string(rune('a'+i))))
+			}
+			Expect(config.Commit("v1/config/app", versions[10])).To(Succeed())
+
+			Expect(config.Prune("v1/config/app", 2)).To(Succeed())
+
+			for _, version := range versions[:8] {
+				Expect(staged(version)).To(BeFalse(), version)
+			}
+			for _, version := range versions[8:] {
+				Expect(staged(version)).To(BeTrue(), version)
+			}
+
+			Expect(config.Commit("v1/config/app", versions[0])).To(Equal(consuladapter.ConfigVersionNotStagedError{Prefix: "v1/config/app", Version: versions[0]}))
+			Expect(config.Commit("v1/config/app", versions[8])).To(Succeed())
+			_, tree, _, err := config.Active("v1/config/app", nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(tree).To(Equal(map[string][]byte{"key": []byte("j")}))
+		})
+	})
+
+	It("notifies watchers of each commit", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		versions := make(chan string, 10)
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- config.Watch(ctx, "v1/config/app", func(version string, tree map[string][]byte) {
+				versions <- version + ":" + string(tree["key"])
+			})
+		}()
+
+		Eventually(versions).Should(Receive(Equal(":")))
+
+		version, err := config.PublishStaged("v1/config/app", map[string][]byte{"key": []byte("value")})
+		Expect(err).NotTo(HaveOccurred())
+		Consistently(versions).ShouldNot(Receive())
+
+		Expect(config.Commit("v1/config/app", version)).To(Succeed())
+		Eventually(versions).Should(Receive(Equal("1:value")))
+
+		cancel()
+		Eventually(errCh).Should(Receive(Equal(context.Canceled)))
+	})
+})
@@ -1,6 +1,15 @@
 package consuladapter
 
-import "github.com/hashicorp/consul/api"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+)
 
 //go:generate counterfeiter -o fakes/fake_status.go . Status
 
@@ -24,3 +33,89 @@ func (s *status) Leader() (string, error) {
 func (s *status) Peers() ([]string, error) {
 	return s.status.Peers()
 }
+
+type ServerAddress struct {
+	Host string
+	Port int
+}
+
+func (a ServerAddress) String() string {
+	return net.JoinHostPort(a.Host, strconv.Itoa(a.Port))
+}
+
+func ParseServerAddress(address string) (ServerAddress, error) {
+	host, portString, err := net.SplitHostPort(address)
+	if err != nil {
+		return ServerAddress{}, err
+	}
+
+	port, err := strconv.Atoi(portString)
+	if err != nil {
+		return ServerAddress{}, fmt.Errorf("invalid port in server address '%s'", address)
+	}
+
+	return ServerAddress{Host: host, Port: port}, nil
+}
+
+type ClusterStatus struct {
+	// HasLeader is false while the cluster has no elected leader, in which
+	// case Leader is empty.
+	HasLeader bool
+	Leader    ServerAddress
+	Peers     []ServerAddress
+}
+
+func GetClusterStatus(status Status) (ClusterStatus, error) {
+	result := ClusterStatus{}
+
+	leader, err := status.Leader()
+	if err != nil {
+		return ClusterStatus{}, err
+	}
+	if leader != "" {
+		result.HasLeader = true
+		result.Leader, err = ParseServerAddress(leader)
+		if err != nil {
+			return ClusterStatus{}, err
+		}
+	}
+
+	peers, err := status.Peers()
+	if err != nil {
+		return ClusterStatus{}, err
+	}
+	for _, peer := range peers {
+		address, err := ParseServerAddress(peer)
+		if err != nil {
+			return ClusterStatus{}, err
+		}
+		result.Peers = append(result.Peers, address)
+	}
+
+	return result, nil
+}
+
+const waitForLeaderInterval = 100 * time.Millisecond
+
+// WaitForLeader polls until the cluster has an elected leader and returns
+// its address, or returns the last error once ctx is done.
+func WaitForLeader(ctx context.Context, status Status) (ServerAddress, error) {
+	ticker := time.NewTicker(waitForLeaderInterval)
+	defer ticker.Stop()
+
+	for {
+		leader, err := status.Leader()
+		if err == nil && leader != "" {
+			return ParseServerAddress(leader)
+		}
+		if err == nil {
+			err = errors.New("no leader elected")
+		}
+
+		select {
+		case <-ctx.Done():
+			return ServerAddress{}, fmt.Errorf("%v: %v", ctx.Err(), err)
+		case <-ticker.C:
+		}
+	}
+}
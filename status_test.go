@@ -0,0 +1,76 @@
+package consuladapter_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"code.cloudfoundry.org/consuladapter"
+	"code.cloudfoundry.org/consuladapter/fakes"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Status", func() {
+	Describe("ParseServerAddress", func() {
+		It("splits the host and port", func() {
+			address, err := consuladapter.ParseServerAddress("10.0.0.1:8300")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(address).To(Equal(consuladapter.ServerAddress{Host: "10.0.0.1", Port: 8300}))
+			Expect(address.String()).To(Equal("10.0.0.1:8300"))
+		})
+
+		It("handles IPv6 hosts", func() {
+			address, err := consuladapter.ParseServerAddress("[::1]:8300")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(address.Host).To(Equal("::1"))
+			Expect(address.String()).To(Equal("[::1]:8300"))
+		})
+
+		It("rejects addresses without a numeric port", func() {
+			_, err := consuladapter.ParseServerAddress("10.0.0.1")
+			Expect(err).To(HaveOccurred())
+
+			_, err = consuladapter.ParseServerAddress("10.0.0.1:rpc")
+			Expect(err).To(MatchError("invalid port in server address '10.0.0.1:rpc'"))
+		})
+	})
+
+	Describe("WaitForLeader", func() {
+		var status *fakes.FakeStatus
+
+		BeforeEach(func() {
+			status = &fakes.FakeStatus{}
+		})
+
+		It("returns the leader once one is elected", func() {
+			var calls int32
+			status.LeaderStub = func() (string, error) {
+				switch atomic.AddInt32(&calls, 1) {
+				case 1:
+					return "", errors.New("connection refused")
+				case 2:
+					return "", nil
+				default:
+					return "10.0.0.2:8300", nil
+				}
+			}
+
+			leader, err := consuladapter.WaitForLeader(context.Background(), status)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(leader).To(Equal(consuladapter.ServerAddress{Host: "10.0.0.2", Port: 8300}))
+			Expect(status.LeaderCallCount()).To(Equal(3))
+		})
+
+		It("returns the last error once the context is done", func() {
+			status.LeaderReturns("", nil)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			_, err := consuladapter.WaitForLeader(ctx, status)
+			Expect(err).To(MatchError("context deadline exceeded: no leader elected"))
+		})
+	})
+})
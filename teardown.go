@@ -0,0 +1,48 @@
+package consuladapter
+
+import (
+	"context"
+
+	"github.com/hashicorp/consul/api"
+)
+
+// DestroySessionWithContext destroys a session, abandoning the request once
+// ctx is done rather than waiting for the client's HTTP timeout.
+func DestroySessionWithContext(ctx context.Context, session Session, id string) error {
+	_, err := session.Destroy(id, (&api.WriteOptions{}).WithContext(ctx))
+	return err
+}
+
+// ReleaseLockWithContext releases a lock, returning ctx's error once ctx is
+// done. Lock.Unlock cannot be cancelled, so it is left to finish in the
+// background; this is intended for best-effort teardown paths.
+func ReleaseLockWithContext(ctx context.Context, lock Lock) error {
+	return untilDone(ctx, LockReleaseErrorsChannel, lock.Unlock)
+}
+
+// DeregisterServiceWithContext deregisters a service from agent, returning
+// ctx's error once ctx is done. As with ReleaseLockWithContext, the request
+// is left to finish in the background.
+func DeregisterServiceWithContext(ctx context.Context, agent Agent, serviceID string) error {
+	return untilDone(ctx, ServiceDeregisterErrorsChannel, func() error {
+		return agent.ServiceDeregister(serviceID)
+	})
+}
+
+// untilDone runs f in the background and returns its error, or ctx's error
+// if ctx is done first.
+func untilDone(ctx context.Context, channel string, f func() error) error {
+	errCh := make(chan error, 1)
+	goBackground(nil, func() {
+		sendError(errCh, f(), channel)
+	}, func(err *PanicError) {
+		sendError(errCh, err, channel)
+	})
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,69 @@
+package consuladapter_test
+
+import (
+	"context"
+	"time"
+
+	"code.cloudfoundry.org/consuladapter"
+	"code.cloudfoundry.org/consuladapter/fakes"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ReleaseLockWithContext", func() {
+	var lock *fakes.FakeLock
+
+	BeforeEach(func() {
+		lock = &fakes.FakeLock{}
+	})
+
+	It("returns the result of Unlock", func() {
+		Expect(consuladapter.ReleaseLockWithContext(context.Background(), lock)).To(Succeed())
+		Expect(lock.UnlockCallCount()).To(Equal(1))
+	})
+
+	It("gives up once the context is done", func() {
+		blockCh := make(chan struct{})
+		defer close(blockCh)
+		lock.UnlockStub = func() error {
+			<-blockCh
+			return nil
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		err := consuladapter.ReleaseLockWithContext(ctx, lock)
+		Expect(err).To(Equal(context.DeadlineExceeded))
+	})
+})
+
+var _ = Describe("DeregisterServiceWithContext", func() {
+	var agent *fakes.FakeAgent
+
+	BeforeEach(func() {
+		agent = &fakes.FakeAgent{}
+	})
+
+	It("deregisters the service", func() {
+		Expect(consuladapter.DeregisterServiceWithContext(context.Background(), agent, "cell-1")).To(Succeed())
+		Expect(agent.ServiceDeregisterCallCount()).To(Equal(1))
+		Expect(agent.ServiceDeregisterArgsForCall(0)).To(Equal("cell-1"))
+	})
+
+	It("gives up once the context is done", func() {
+		blockCh := make(chan struct{})
+		defer close(blockCh)
+		agent.ServiceDeregisterStub = func(string) error {
+			<-blockCh
+			return nil
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		err := consuladapter.DeregisterServiceWithContext(ctx, agent, "cell-1")
+		Expect(err).To(Equal(context.DeadlineExceeded))
+	})
+})
@@ -0,0 +1,93 @@
+package consuladapter
+
+import (
+	"time"
+
+	"github.com/hashicorp/consul/api"
+)
+
+// TombstoneFlag marks a KV pair as a tombstone. Its value is the time, in
+// RFC 3339 format, after which the key is treated as never having existed.
+const TombstoneFlag uint64 = 0x746f6d62
+
+// Tombstones soft-deletes keys by replacing them with a tombstone marker for
+// TTL, so readers can tell "deleted" from "never existed" while they resync.
+type Tombstones struct {
+	kv  KV
+	ttl time.Duration
+}
+
+func NewTombstones(kv KV, ttl time.Duration) *Tombstones {
+	return &Tombstones{kv: kv, ttl: ttl}
+}
+
+func IsTombstone(pair *api.KVPair) bool {
+	return pair != nil && pair.Flags == TombstoneFlag
+}
+
+// Delete replaces key with a tombstone.
+func (t *Tombstones) Delete(key string) error {
+	expiry := time.Now().Add(t.ttl).UTC().Format(time.RFC3339)
+	_, err := t.kv.Put(&api.KVPair{Key: key, Flags: TombstoneFlag, Value: []byte(expiry)}, nil)
+	return err
+}
+
+// Get returns the pair at key, or reports deleted if key holds an unexpired
+// tombstone. Expired tombstones read as missing keys.
+func (t *Tombstones) Get(key string, q *api.QueryOptions) (pair *api.KVPair, deleted bool, err error) {
+	pair, _, err = t.kv.Get(key, q)
+	if err != nil || !IsTombstone(pair) {
+		return pair, false, err
+	}
+	return nil, !tombstoneExpired(pair), nil
+}
+
+// List returns the pairs under prefix with tombstones filtered out.
+func (t *Tombstones) List(prefix string, q *api.QueryOptions) (api.KVPairs, error) {
+	pairs, _, err := t.kv.List(prefix, q)
+	if err != nil {
+		return nil, err
+	}
+
+	live := make(api.KVPairs, 0, len(pairs))
+	for _, pair := range pairs {
+		if !IsTombstone(pair) {
+			live = append(live, pair)
+		}
+	}
+	return live, nil
+}
+
+// Purge removes expired tombstones under prefix, leaving any key rewritten
+// since it was read, and returns the keys it removed.
+func (t *Tombstones) Purge(prefix string) ([]string, error) {
+	pairs, _, err := t.kv.List(prefix, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var purged []string
+	for _, pair := range pairs {
+		if !IsTombstone(pair) || !tombstoneExpired(pair) {
+			continue
+		}
+
+		deleted, err := DeleteIfIndex(t.kv, pair.Key, pair.ModifyIndex)
+		if err != nil {
+			return purged, err
+		}
+		if deleted {
+			purged = append(purged, pair.Key)
+		}
+	}
+
+	return purged, nil
+}
+
+func tombstoneExpired(pair *api.KVPair) bool {
+	expiry, err := time.Parse(time.RFC3339, string(pair.Value))
+	if err != nil {
+		return true
+	}
+	return time.Now().After(expiry)
+}
@@ -0,0 +1,77 @@
+package consuladapter
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/hashicorp/consul/api"
+)
+
+// LockHeldError is returned by TryAcquireLock when the lock could not be
+// acquired. Session is the session holding it, which is empty if the lock
+// was free but still within its lock delay.
+type LockHeldError struct {
+	Key     string
+	Session string
+}
+
+func (e LockHeldError) Error() string {
+	if e.Session == "" {
+		return fmt.Sprintf("lock '%s' is not held but cannot be acquired yet", e.Key)
+	}
+	return fmt.Sprintf("lock '%s' is held by session %s", e.Key, e.Session)
+}
+
+func IsLockHeldError(err error) bool {
+	var heldErr LockHeldError
+	return errors.As(err, &heldErr)
+}
+
+// TryAcquireLock makes a single attempt to acquire key for sessionID,
+// setting its value, and returns a LockHeldError rather than waiting if it
+// is contended. Acquiring a lock the session already holds updates its
+// value.
+func TryAcquireLock(kv KV, sessionID, key string, value []byte) error {
+	acquired, _, err := kv.Acquire(&api.KVPair{Key: key, Value: value, Session: sessionID}, nil)
+	if err != nil || acquired {
+		return err
+	}
+
+	pair, _, err := kv.Get(key, nil)
+	if err != nil {
+		return err
+	}
+
+	heldErr := LockHeldError{Key: key}
+	if pair != nil {
+		heldErr.Session = pair.Session
+	}
+	return heldErr
+}
+
+// TryAcquireLock is TryAcquireLock for this session. The key is listed in
+// the session's State until it is released with ReleaseLock.
+func (s *TTLSession) TryAcquireLock(kv KV, key string, value []byte) error {
+	err := TryAcquireLock(kv, s.id, key, value)
+	if err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	s.heldKeys[key] = struct{}{}
+	s.mutex.Unlock()
+	return nil
+}
+
+// ReleaseLock releases key if this session holds it.
+func (s *TTLSession) ReleaseLock(kv KV, key string) error {
+	_, _, err := kv.Release(&api.KVPair{Key: key, Session: s.id}, nil)
+	if err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	delete(s.heldKeys, key)
+	s.mutex.Unlock()
+	return nil
+}
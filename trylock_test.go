@@ -0,0 +1,51 @@
+package consuladapter_test
+
+import (
+	"code.cloudfoundry.org/consuladapter"
+	"code.cloudfoundry.org/consuladapter/fakes"
+	"github.com/hashicorp/consul/api"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("TryAcquireLock", func() {
+	var (
+		backend           *fakes.FakeBackend
+		holder, contender string
+	)
+
+	BeforeEach(func() {
+		backend = fakes.NewFakeBackend()
+
+		var err error
+		holder, _, err = backend.Session().Create(&api.SessionEntry{Name: "holder"}, nil)
+		Expect(err).NotTo(HaveOccurred())
+		contender, _, err = backend.Session().Create(&api.SessionEntry{Name: "contender"}, nil)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("acquires a free lock", func() {
+		Expect(consuladapter.TryAcquireLock(backend.KV(), holder, "v1/locks/bbs", []byte("bbs-1"))).To(Succeed())
+
+		pair, _, err := backend.KV().Get("v1/locks/bbs", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pair.Session).To(Equal(holder))
+		Expect(pair.Value).To(Equal([]byte("bbs-1")))
+	})
+
+	It("returns a LockHeldError naming the holder when the lock is held", func() {
+		Expect(consuladapter.TryAcquireLock(backend.KV(), holder, "v1/locks/bbs", nil)).To(Succeed())
+
+		err := consuladapter.TryAcquireLock(backend.KV(), contender, "v1/locks/bbs", []byte("bbs-2"))
+		Expect(err).To(Equal(consuladapter.LockHeldError{Key: "v1/locks/bbs", Session: holder}))
+		Expect(consuladapter.IsLockHeldError(err)).To(BeTrue())
+		Expect(backend.Holder("v1/locks/bbs")).To(Equal(holder))
+	})
+
+	It("returns other errors as they are", func() {
+		err := consuladapter.TryAcquireLock(backend.KV(), "missing-session", "v1/locks/bbs", nil)
+		Expect(err).To(HaveOccurred())
+		Expect(consuladapter.IsLockHeldError(err)).To(BeFalse())
+	})
+})
@@ -0,0 +1,72 @@
+package consuladapter
+
+import (
+	"net/http"
+	"runtime/debug"
+)
+
+const modulePath = "code.cloudfoundry.org/consuladapter"
+
+// UserAgent returns the User-Agent sent by clients built with the given
+// component name, e.g. "locket consuladapter/v0.0.0-20190101000000-abcdef".
+func UserAgent(component string) string {
+	agent := "consuladapter/" + moduleVersion()
+	if component == "" {
+		return agent
+	}
+	return component + " " + agent
+}
+
+func moduleVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+	if info.Main.Path == modulePath && info.Main.Version != "" {
+		return info.Main.Version
+	}
+	for _, dep := range info.Deps {
+		if dep.Path == modulePath {
+			return dep.Version
+		}
+	}
+	return "unknown"
+}
+
+type headerTransport struct {
+	transport http.RoundTripper
+	headers   http.Header
+}
+
+func newHeaderTransport(transport http.RoundTripper, userAgent string, headers http.Header) http.RoundTripper {
+	all := http.Header{}
+	for key, values := range headers {
+		all[http.CanonicalHeaderKey(key)] = append([]string{}, values...)
+	}
+	if all.Get("User-Agent") == "" {
+		all.Set("User-Agent", userAgent)
+	}
+	return &headerTransport{transport: transport, headers: all}
+}
+
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	if req.Header == nil {
+		req.Header = http.Header{}
+	}
+	for key, values := range t.headers {
+		if _, ok := req.Header[key]; !ok {
+			req.Header[key] = values
+		}
+	}
+	return t.transport.RoundTrip(req)
+}
+
+// CloseIdleConnections forwards to the wrapped transport, so that the
+// re-resolving and discovering transports, which build header transports,
+// can drop connections to an agent that has moved.
+func (t *headerTransport) CloseIdleConnections() {
+	if closer, ok := t.transport.(interface{ CloseIdleConnections() }); ok {
+		closer.CloseIdleConnections()
+	}
+}
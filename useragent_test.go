@@ -0,0 +1,59 @@
+package consuladapter_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+
+	"code.cloudfoundry.org/consuladapter"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("request headers", func() {
+	var (
+		server   *httptest.Server
+		received chan http.Header
+	)
+
+	BeforeEach(func() {
+		received = make(chan http.Header, 1)
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			received <- r.Header
+			w.Write([]byte(`"127.0.0.1:8300"`))
+		}))
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("sends the component's User-Agent and custom headers", func() {
+		client, err := consuladapter.NewClientFromUrlWithOptions(server.URL, consuladapter.ClientOptions{
+			Component: "locket",
+			Headers:   http.Header{"X-Request-Source": []string{"tests"}},
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = client.Status().Leader()
+		Expect(err).NotTo(HaveOccurred())
+
+		var headers http.Header
+		Eventually(received).Should(Receive(&headers))
+		Expect(headers.Get("User-Agent")).To(Equal(consuladapter.UserAgent("locket")))
+		Expect(headers.Get("User-Agent")).To(HavePrefix("locket consuladapter/"))
+		Expect(headers.Get("X-Request-Source")).To(Equal("tests"))
+	})
+
+	It("sends the client's ACL token", func() {
+		client, err := consuladapter.NewClientFromUrlWithOptions(server.URL, consuladapter.ClientOptions{Token: "secret"})
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = client.Status().Leader()
+		Expect(err).NotTo(HaveOccurred())
+
+		var headers http.Header
+		Eventually(received).Should(Receive(&headers))
+		Expect(headers.Get("X-Consul-Token")).To(Equal("secret"))
+	})
+})
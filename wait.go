@@ -0,0 +1,109 @@
+package consuladapter
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+)
+
+// WaitTimeoutError is returned by the WaitFor helpers when their condition
+// does not hold within the timeout. Last describes what the final query
+// saw, and Err is its error, if any.
+type WaitTimeoutError struct {
+	Condition string
+	Timeout   time.Duration
+	Last      string
+	Err       error
+}
+
+func (e WaitTimeoutError) Error() string {
+	message := fmt.Sprintf("timed out after %s waiting for %s", e.Timeout, e.Condition)
+	if e.Err != nil {
+		return fmt.Sprintf("%s: last query failed: %v", message, e.Err)
+	}
+	return fmt.Sprintf("%s: %s", message, e.Last)
+}
+
+// waitFor runs query with BlockingQuery until it reports done, returning a
+// WaitTimeoutError for condition once timeout passes.
+func waitFor(timeout time.Duration, condition string, query func(q *api.QueryOptions) (bool, string, *api.QueryMeta, error)) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var (
+		last    = "no query completed"
+		lastErr error
+	)
+	err := BlockingQuery(ctx, DefaultWatchBackoff, func(err error) {
+		lastErr = err
+	}, func(q *api.QueryOptions) (*api.QueryMeta, error) {
+		done, state, meta, err := query(q)
+		if err != nil {
+			return nil, err
+		}
+		if done {
+			return nil, StopQuerying
+		}
+		last = state
+		lastErr = nil
+		return meta, nil
+	})
+	if err != nil {
+		return WaitTimeoutError{Condition: condition, Timeout: timeout, Last: last, Err: lastErr}
+	}
+	return nil
+}
+
+// WaitForKey waits up to timeout for key to exist and returns it.
+func WaitForKey(kv KV, key string, timeout time.Duration) (*api.KVPair, error) {
+	var pair *api.KVPair
+	err := waitFor(timeout, fmt.Sprintf("key '%s' to exist", key), func(q *api.QueryOptions) (bool, string, *api.QueryMeta, error) {
+		var (
+			meta *api.QueryMeta
+			err  error
+		)
+		pair, meta, err = kv.Get(key, q)
+		return pair != nil, "it does not exist", meta, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return pair, nil
+}
+
+// WaitForSessionGone waits up to timeout for the session with id to be
+// destroyed or invalidated, e.g. after its TTL lapses.
+func WaitForSessionGone(session Session, id string, timeout time.Duration) error {
+	return waitFor(timeout, fmt.Sprintf("session %s to be gone", id), func(q *api.QueryOptions) (bool, string, *api.QueryMeta, error) {
+		entry, meta, err := session.Info(id, q)
+		if err != nil || entry == nil {
+			return err == nil, "", meta, err
+		}
+		return false, fmt.Sprintf("it still exists on node '%s' with TTL %s", entry.Node, entry.TTL), meta, nil
+	})
+}
+
+// WaitForIndexAdvance waits up to timeout for a write under prefix to move
+// its index past fromIndex, and returns the new index. Pass the index of an
+// earlier read to wait for the next change.
+func WaitForIndexAdvance(kv KV, prefix string, fromIndex uint64, timeout time.Duration) (uint64, error) {
+	var index uint64
+	condition := fmt.Sprintf("the index of '%s' to advance past %d", prefix, fromIndex)
+	err := waitFor(timeout, condition, func(q *api.QueryOptions) (bool, string, *api.QueryMeta, error) {
+		if q.WaitIndex == 0 {
+			q.WaitIndex = fromIndex
+		}
+		_, meta, err := kv.List(prefix, q)
+		if err != nil {
+			return false, "", nil, err
+		}
+		index = meta.LastIndex
+		return index > fromIndex, fmt.Sprintf("it is still at %d", index), meta, nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return index, nil
+}
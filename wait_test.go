@@ -0,0 +1,92 @@
+package consuladapter_test
+
+import (
+	"time"
+
+	"code.cloudfoundry.org/consuladapter"
+	"code.cloudfoundry.org/consuladapter/fakes"
+	"github.com/hashicorp/consul/api"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("WaitFor helpers", func() {
+	var backend *fakes.FakeBackend
+
+	BeforeEach(func() {
+		backend = fakes.NewFakeBackend()
+	})
+
+	Describe("WaitForKey", func() {
+		It("returns the key once it is written", func() {
+			go func() {
+				defer GinkgoRecover()
+				time.Sleep(50 * time.Millisecond)
+				_, err := backend.KV().Put(&api.KVPair{Key: "v1/locks/bbs", Value: []byte("bbs-1")}, nil)
+				Expect(err).NotTo(HaveOccurred())
+			}()
+
+			pair, err := consuladapter.WaitForKey(backend.KV(), "v1/locks/bbs", time.Second)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pair.Value).To(Equal([]byte("bbs-1")))
+		})
+
+		It("describes the key when it times out", func() {
+			_, err := consuladapter.WaitForKey(backend.KV(), "v1/locks/bbs", 100*time.Millisecond)
+			Expect(err).To(BeAssignableToTypeOf(consuladapter.WaitTimeoutError{}))
+			Expect(err.Error()).To(Equal("timed out after 100ms waiting for key 'v1/locks/bbs' to exist: it does not exist"))
+		})
+	})
+
+	Describe("WaitForSessionGone", func() {
+		var id string
+
+		BeforeEach(func() {
+			var err error
+			id, _, err = backend.Session().Create(&api.SessionEntry{Node: "cell-1", TTL: "10s"}, nil)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("returns once the session is destroyed", func() {
+			go func() {
+				defer GinkgoRecover()
+				time.Sleep(50 * time.Millisecond)
+				backend.Expire(id)
+			}()
+
+			Expect(consuladapter.WaitForSessionGone(backend.Session(), id, time.Second)).To(Succeed())
+		})
+
+		It("describes the session when it times out", func() {
+			err := consuladapter.WaitForSessionGone(backend.Session(), id, 100*time.Millisecond)
+			Expect(err).To(MatchError("timed out after 100ms waiting for session " + id + " to be gone: it still exists on node 'cell-1' with TTL 10s"))
+		})
+	})
+
+	Describe("WaitForIndexAdvance", func() {
+		It("returns the index after the next write under the prefix", func() {
+			_, meta, err := backend.KV().List("v1/presence/", nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			go func() {
+				defer GinkgoRecover()
+				time.Sleep(50 * time.Millisecond)
+				_, err := backend.KV().Put(&api.KVPair{Key: "v1/presence/cell-1"}, nil)
+				Expect(err).NotTo(HaveOccurred())
+			}()
+
+			index, err := consuladapter.WaitForIndexAdvance(backend.KV(), "v1/presence/", meta.LastIndex, time.Second)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(index).To(BeNumerically(">", meta.LastIndex))
+		})
+
+		It("reports the index it saw when it times out", func() {
+			_, meta, err := backend.KV().List("v1/presence/", nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = consuladapter.WaitForIndexAdvance(backend.KV(), "v1/presence/", meta.LastIndex, 100*time.Millisecond)
+			Expect(err).To(MatchError(ContainSubstring("waiting for the index of 'v1/presence/' to advance past")))
+		})
+	})
+})
@@ -0,0 +1,158 @@
+package consuladapter
+
+import (
+	"bytes"
+	"context"
+	"sort"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+)
+
+// DefaultWatchBackoff paces the queries of WatchKey and WatchPrefix after
+// errors.
+var DefaultWatchBackoff = BackoffRetry{Initial: 100 * time.Millisecond, Max: 10 * time.Second}
+
+const defaultWatchErrorBuffer = 16
+
+// WatchKey watches key with blocking queries until ctx is done, then closes
+// both channels. It sends the key's value once read and again each time it
+// changes, with a nil value while the key does not exist. Query errors are
+// sent on the error channel, dropped and counted by DroppedEvents if the
+// receiver falls behind, and retried with DefaultWatchBackoff.
+func WatchKey(ctx context.Context, kv KV, key string) (<-chan []byte, <-chan error) {
+	values := make(chan []byte)
+	var (
+		last []byte
+		read bool
+	)
+	errs := watch(ctx, KeyWatchErrorsChannel, func() { close(values) }, func(q *api.QueryOptions) (*api.QueryMeta, error) {
+		pair, meta, err := kv.Get(key, q)
+		if err != nil {
+			return nil, err
+		}
+
+		var value []byte
+		if pair != nil {
+			value = append([]byte{}, pair.Value...)
+		}
+		if read && (value == nil) == (last == nil) && bytes.Equal(value, last) {
+			return meta, nil
+		}
+
+		select {
+		case values <- value:
+			read, last = true, value
+		case <-ctx.Done():
+		}
+		return meta, nil
+	})
+	return values, errs
+}
+
+type KVEventType int
+
+const (
+	KeyCreated KVEventType = iota
+	KeyUpdated
+	KeyDeleted
+)
+
+func (t KVEventType) String() string {
+	switch t {
+	case KeyCreated:
+		return "created"
+	case KeyUpdated:
+		return "updated"
+	default:
+		return "deleted"
+	}
+}
+
+// KVEvent is a change to a key seen by WatchPrefix. Pair is the key as it
+// now is, or as it last was if it was deleted.
+type KVEvent struct {
+	Type KVEventType
+	Pair *api.KVPair
+}
+
+// WatchPrefix watches the keys under prefix with blocking queries until ctx
+// is done, then closes both channels. It sends a KeyCreated event for each
+// key once read, and then an event for each key created, updated or deleted
+// between successive reads, in key order. Errors are handled as by
+// WatchKey.
+func WatchPrefix(ctx context.Context, kv KV, prefix string) (<-chan KVEvent, <-chan error) {
+	events := make(chan KVEvent)
+	last := map[string]*api.KVPair{}
+	errs := watch(ctx, PrefixWatchErrorsChannel, func() { close(events) }, func(q *api.QueryOptions) (*api.QueryMeta, error) {
+		pairs, meta, err := kv.List(prefix, q)
+		if err != nil {
+			return nil, err
+		}
+
+		current := make(map[string]*api.KVPair, len(pairs))
+		for _, pair := range pairs {
+			current[pair.Key] = pair
+		}
+
+		for _, event := range diffPairs(last, current) {
+			select {
+			case events <- event:
+			case <-ctx.Done():
+				return meta, nil
+			}
+		}
+		last = current
+		return meta, nil
+	})
+	return events, errs
+}
+
+// diffPairs returns the events that turn previous into current, in key
+// order. Keys whose ModifyIndex is unchanged are left out.
+func diffPairs(previous, current map[string]*api.KVPair) []KVEvent {
+	var events []KVEvent
+	for key, pair := range current {
+		old, ok := previous[key]
+		switch {
+		case !ok:
+			events = append(events, KVEvent{Type: KeyCreated, Pair: pair})
+		case old.ModifyIndex != pair.ModifyIndex:
+			events = append(events, KVEvent{Type: KeyUpdated, Pair: pair})
+		}
+	}
+	for key, pair := range previous {
+		if _, ok := current[key]; !ok {
+			events = append(events, KVEvent{Type: KeyDeleted, Pair: pair})
+		}
+	}
+
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].Pair.Key < events[j].Pair.Key
+	})
+	return events
+}
+
+// watch runs query with BlockingQuery until ctx is done, sending errors on
+// the returned channel. Once it stops it calls stopped, and then closes the
+// error channel.
+func watch(ctx context.Context, channel string, stopped func(), query func(q *api.QueryOptions) (*api.QueryMeta, error)) <-chan error {
+	errs := make(chan error, defaultWatchErrorBuffer)
+
+	goBackground(nil, func() {
+		defer func() {
+			stopped()
+			close(errs)
+		}()
+		// Deferred after the close, so a panic is reported before it.
+		defer recoverPanic(func(err *PanicError) {
+			sendError(errs, err, channel)
+		})
+
+		BlockingQuery(ctx, DefaultWatchBackoff, func(err error) {
+			sendError(errs, err, channel)
+		}, query)
+	}, nil)
+
+	return errs
+}
@@ -0,0 +1,142 @@
+package consuladapter_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"code.cloudfoundry.org/consuladapter"
+	"code.cloudfoundry.org/consuladapter/fakes"
+	"github.com/hashicorp/consul/api"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("WatchKey", func() {
+	const key = "v1/locks/bbs"
+
+	var (
+		ctx    context.Context
+		cancel context.CancelFunc
+	)
+
+	BeforeEach(func() {
+		ctx, cancel = context.WithCancel(context.Background())
+	})
+
+	AfterEach(func() {
+		cancel()
+	})
+
+	It("sends the value, then each change, until cancelled", func() {
+		backend := fakes.NewFakeBackend()
+		values, errs := consuladapter.WatchKey(ctx, backend.KV(), key)
+		Eventually(values).Should(Receive(BeNil()))
+
+		_, err := backend.KV().Put(&api.KVPair{Key: key, Value: []byte("bbs-1")}, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(values).Should(Receive(Equal([]byte("bbs-1"))))
+
+		_, err = backend.KV().Put(&api.KVPair{Key: "v1/locks/auctioneer", Value: []byte("auctioneer-1")}, nil)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = backend.KV().Put(&api.KVPair{Key: key, Value: []byte("bbs-1")}, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Consistently(values).ShouldNot(Receive())
+
+		_, err = backend.KV().DeleteTree(key, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(values).Should(Receive(BeNil()))
+
+		cancel()
+		Eventually(values).Should(BeClosed())
+		Eventually(errs).Should(BeClosed())
+	})
+
+	It("reports errors and retries", func() {
+		kv := new(fakes.FakeKV)
+		kv.GetStub = func(string, *api.QueryOptions) (*api.KVPair, *api.QueryMeta, error) {
+			if kv.GetCallCount() == 1 {
+				return nil, nil, errors.New("connection refused")
+			}
+			return &api.KVPair{Key: key, Value: []byte("bbs-1")}, &api.QueryMeta{LastIndex: 1}, nil
+		}
+
+		values, errs := consuladapter.WatchKey(ctx, kv, key)
+		Eventually(errs).Should(Receive(MatchError("connection refused")))
+		Eventually(values).Should(Receive(Equal([]byte("bbs-1"))))
+	})
+
+	It("reports a panic before closing its channels", func() {
+		kv := new(fakes.FakeKV)
+		kv.GetStub = func(string, *api.QueryOptions) (*api.KVPair, *api.QueryMeta, error) {
+			panic("get exploded")
+		}
+
+		values, errs := consuladapter.WatchKey(ctx, kv, key)
+		var err error
+		Eventually(errs).Should(Receive(&err))
+		Expect(err).To(BeAssignableToTypeOf(&consuladapter.PanicError{}))
+		Eventually(errs).Should(BeClosed())
+		Eventually(values).Should(BeClosed())
+	})
+})
+
+var _ = Describe("WatchPrefix", func() {
+	var (
+		backend *fakes.FakeBackend
+		ctx     context.Context
+		cancel  context.CancelFunc
+	)
+
+	BeforeEach(func() {
+		backend = fakes.NewFakeBackend()
+		ctx, cancel = context.WithCancel(context.Background())
+	})
+
+	AfterEach(func() {
+		cancel()
+	})
+
+	put := func(key, value string) {
+		_, err := backend.KV().Put(&api.KVPair{Key: key, Value: []byte(value)}, nil)
+		Expect(err).NotTo(HaveOccurred())
+	}
+
+	receive := func(events <-chan consuladapter.KVEvent) string {
+		var event consuladapter.KVEvent
+		Eventually(events).Should(Receive(&event))
+		return fmt.Sprintf("%s %s %s", event.Type, event.Pair.Key, event.Pair.Value)
+	}
+
+	It("sends the existing keys as created, then each change under the prefix", func() {
+		put("v1/presence/cell-2", "cell-2")
+		put("v1/presence/cell-1", "cell-1")
+
+		events, errs := consuladapter.WatchPrefix(ctx, backend.KV(), "v1/presence/")
+		Expect(receive(events)).To(Equal("created v1/presence/cell-1 cell-1"))
+		Expect(receive(events)).To(Equal("created v1/presence/cell-2 cell-2"))
+
+		put("v1/locks/bbs", "bbs-1")
+		Consistently(events).ShouldNot(Receive())
+
+		put("v1/presence/cell-1", "cell-1-updated")
+		put("v1/presence/cell-3", "cell-3")
+		_, err := backend.KV().DeleteTree("v1/presence/cell-2", nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		var seen []string
+		for len(seen) < 3 {
+			seen = append(seen, receive(events))
+		}
+		Expect(seen).To(ConsistOf(
+			"updated v1/presence/cell-1 cell-1-updated",
+			"created v1/presence/cell-3 cell-3",
+			"deleted v1/presence/cell-2 cell-2",
+		))
+
+		cancel()
+		Eventually(events).Should(BeClosed())
+		Eventually(errs).Should(BeClosed())
+	})
+})
@@ -0,0 +1,186 @@
+package consuladapter
+
+import (
+	"context"
+	"sync"
+
+	"github.com/hashicorp/consul/api"
+)
+
+// WatchGroupSnapshot holds the latest pairs of each source in a WatchGroup,
+// by source name. A key source that does not exist has no pairs.
+type WatchGroupSnapshot map[string]api.KVPairs
+
+type watchSource struct {
+	name   string
+	key    string
+	prefix bool
+}
+
+// WatchGroup watches several keys and prefixes together so that components
+// with multiple config sources start from a complete view: it only becomes
+// ready once every source has delivered its initial snapshot.
+//
+// Each source is read by its own blocking query, so a snapshot is complete
+// but not atomic: a change spanning several sources, even one written in a
+// single transaction, may show up in one source's pairs before another's.
+// onChange is called again once the remaining sources catch up, so
+// components needing related values to agree should keep them under one
+// prefix and watch that.
+type WatchGroup struct {
+	kv      KV
+	sources []watchSource
+
+	mutex    sync.Mutex
+	snapshot WatchGroupSnapshot
+	pending  map[string]bool
+	ready    chan struct{}
+	updated  chan struct{}
+}
+
+func NewWatchGroup(kv KV) *WatchGroup {
+	return &WatchGroup{
+		kv:       kv,
+		snapshot: WatchGroupSnapshot{},
+		pending:  map[string]bool{},
+		ready:    make(chan struct{}),
+		updated:  make(chan struct{}, 1),
+	}
+}
+
+// WatchKey adds key as the source name. Sources must be added before Run.
+func (g *WatchGroup) WatchKey(name, key string) {
+	g.add(watchSource{name: name, key: key})
+}
+
+// WatchPrefix adds the keys under prefix as the source name. Sources must
+// be added before Run.
+func (g *WatchGroup) WatchPrefix(name, prefix string) {
+	g.add(watchSource{name: name, key: prefix, prefix: true})
+}
+
+func (g *WatchGroup) add(source watchSource) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	g.sources = append(g.sources, source)
+	g.pending[source.name] = true
+}
+
+// Ready is closed once every source has delivered its initial snapshot.
+func (g *WatchGroup) Ready() <-chan struct{} {
+	return g.ready
+}
+
+// Snapshot returns the latest pairs of every source. Before the group is
+// ready it only includes the sources that have been read.
+func (g *WatchGroup) Snapshot() WatchGroupSnapshot {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	snapshot := make(WatchGroupSnapshot, len(g.snapshot))
+	for name, pairs := range g.snapshot {
+		snapshot[name] = pairs
+	}
+	return snapshot
+}
+
+// Run watches every source until ctx is done. onChange, if set, is called
+// with the complete snapshot once the group is ready and again after each
+// change. Calls never overlap, and changes that arrive during a call are
+// coalesced into the next. Run may only be called once.
+func (g *WatchGroup) Run(ctx context.Context, onChange func(WatchGroupSnapshot)) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	g.mutex.Lock()
+	sources := g.sources
+	if len(sources) == 0 {
+		close(g.ready)
+		g.updated <- struct{}{}
+	}
+	g.mutex.Unlock()
+
+	var wg sync.WaitGroup
+	panics := make(chan error, len(sources))
+	for _, source := range sources {
+		source := source
+		goBackground(&wg, func() { g.watch(ctx, source) }, func(err *PanicError) {
+			panics <- err
+		})
+	}
+	defer wg.Wait()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-panics:
+			return err
+		case <-g.updated:
+			if onChange != nil {
+				onChange(g.Snapshot())
+			}
+		}
+	}
+}
+
+func (g *WatchGroup) watch(ctx context.Context, source watchSource) {
+	BlockingQuery(ctx, DefaultWatchBackoff, nil, func(q *api.QueryOptions) (*api.QueryMeta, error) {
+		pairs, meta, err := g.read(source, q)
+		if err != nil {
+			return nil, err
+		}
+
+		g.update(source.name, pairs)
+		return meta, nil
+	})
+}
+
+func (g *WatchGroup) read(source watchSource, q *api.QueryOptions) (api.KVPairs, *api.QueryMeta, error) {
+	if source.prefix {
+		return g.kv.List(source.key, q)
+	}
+
+	pair, meta, err := g.kv.Get(source.key, q)
+	if err != nil || pair == nil {
+		return nil, meta, err
+	}
+	return api.KVPairs{pair}, meta, nil
+}
+
+func (g *WatchGroup) update(name string, pairs api.KVPairs) {
+	g.mutex.Lock()
+	wasPending := g.pending[name]
+	if !wasPending && samePairs(g.snapshot[name], pairs) {
+		// the index moved for writes elsewhere
+		g.mutex.Unlock()
+		return
+	}
+	g.snapshot[name] = pairs
+	delete(g.pending, name)
+	becameReady := wasPending && len(g.pending) == 0
+	isReady := len(g.pending) == 0
+	g.mutex.Unlock()
+
+	if becameReady {
+		close(g.ready)
+	}
+	if isReady {
+		select {
+		case g.updated <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func samePairs(a, b api.KVPairs) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Key != b[i].Key || a[i].ModifyIndex != b[i].ModifyIndex {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,93 @@
+package consuladapter_test
+
+import (
+	"context"
+
+	"code.cloudfoundry.org/consuladapter"
+	"code.cloudfoundry.org/consuladapter/fakes"
+	"github.com/hashicorp/consul/api"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("WatchGroup", func() {
+	var (
+		backend *fakes.FakeBackend
+		kv      consuladapter.KV
+		group   *consuladapter.WatchGroup
+
+		ctx    context.Context
+		cancel context.CancelFunc
+		errCh  chan error
+	)
+
+	values := func(pairs api.KVPairs) []string {
+		var values []string
+		for _, pair := range pairs {
+			values = append(values, string(pair.Value))
+		}
+		return values
+	}
+
+	BeforeEach(func() {
+		backend = fakes.NewFakeBackend()
+		kv = backend.KV()
+		group = consuladapter.NewWatchGroup(kv)
+		ctx, cancel = context.WithCancel(context.Background())
+		errCh = make(chan error, 1)
+
+		_, err := kv.Put(&api.KVPair{Key: "v1/config/flags", Value: []byte("on")}, nil)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = kv.Put(&api.KVPair{Key: "v1/routes/a", Value: []byte("route-a")}, nil)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		cancel()
+		Eventually(errCh).Should(Receive(Equal(context.Canceled)))
+	})
+
+	It("becomes ready with the initial snapshot of every source", func() {
+		group.WatchKey("flags", "v1/config/flags")
+		group.WatchKey("missing", "v1/config/missing")
+		group.WatchPrefix("routes", "v1/routes/")
+
+		snapshots := make(chan consuladapter.WatchGroupSnapshot, 10)
+		go func() {
+			errCh <- group.Run(ctx, func(snapshot consuladapter.WatchGroupSnapshot) {
+				snapshots <- snapshot
+			})
+		}()
+
+		Eventually(group.Ready()).Should(BeClosed())
+
+		var snapshot consuladapter.WatchGroupSnapshot
+		Eventually(snapshots).Should(Receive(&snapshot))
+		Expect(snapshot).To(HaveLen(3))
+		Expect(values(snapshot["flags"])).To(Equal([]string{"on"}))
+		Expect(snapshot["missing"]).To(BeEmpty())
+		Expect(values(snapshot["routes"])).To(Equal([]string{"route-a"}))
+	})
+
+	It("delivers later changes to any source", func() {
+		group.WatchKey("flags", "v1/config/flags")
+		group.WatchPrefix("routes", "v1/routes/")
+
+		snapshots := make(chan consuladapter.WatchGroupSnapshot, 10)
+		go func() {
+			errCh <- group.Run(ctx, func(snapshot consuladapter.WatchGroupSnapshot) {
+				snapshots <- snapshot
+			})
+		}()
+		Eventually(snapshots).Should(Receive())
+
+		_, err := kv.Put(&api.KVPair{Key: "v1/routes/b", Value: []byte("route-b")}, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		Eventually(func() []string {
+			return values(group.Snapshot()["routes"])
+		}).Should(Equal([]string{"route-a", "route-b"}))
+		Eventually(snapshots).Should(Receive(HaveKeyWithValue("routes", HaveLen(2))))
+	})
+})